@@ -0,0 +1,455 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package automation
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/blang/semver"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend"
+	"github.com/pulumi/pulumi/pkg/v3/backend/httpstate"
+	"github.com/pulumi/pulumi/pkg/v3/resource/stack"
+	"github.com/pulumi/pulumi/pkg/v3/secrets"
+	"github.com/pulumi/pulumi/pkg/v3/secrets/cloud"
+	"github.com/pulumi/pulumi/pkg/v3/secrets/passphrase"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/config"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/deepcopy"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/workspace"
+)
+
+// GetConfig returns the value associated with the specified stack name and key,
+// scoped to the current workspace.
+func (w *InProcessWorkspace) GetConfig(ctx context.Context, stackName string, key string) (auto.ConfigValue, error) {
+	return w.GetConfigWithOptions(ctx, stackName, key, nil)
+}
+
+// GetConfigWithOptions returns the value associated with the specified stack name and key using the optional
+// ConfigOptions, scoped to the current workspace.
+func (w *InProcessWorkspace) GetConfigWithOptions(
+	ctx context.Context, stackName string, key string, opts *auto.ConfigOptions,
+) (auto.ConfigValue, error) {
+	defer w.setEnv()()
+	proj, s, err := w.getStack(ctx, stackName)
+	if err != nil {
+		return auto.ConfigValue{}, err
+	}
+	ps, err := w.loadStackSettings(ctx, proj, stackName)
+	if err != nil {
+		return auto.ConfigValue{}, fmt.Errorf("unable to read config: %w", err)
+	}
+	k, err := parseConfigKey(proj, key)
+	if err != nil {
+		return auto.ConfigValue{}, fmt.Errorf("unable to read config: %w", err)
+	}
+
+	v, ok, err := ps.Config.Get(k, opts != nil && opts.Path)
+	if err != nil {
+		return auto.ConfigValue{}, fmt.Errorf("unable to read config: %w", err)
+	}
+	if !ok {
+		return auto.ConfigValue{}, fmt.Errorf("configuration key '%s' not found for stack '%s'", key, stackName)
+	}
+
+	var d config.Decrypter = config.NewPanicCrypter()
+	if v.Secure() {
+		sm, err := w.stackSecretsManager(ctx, proj, s)
+		if err != nil {
+			return auto.ConfigValue{}, fmt.Errorf("unable to read config: %w", err)
+		}
+		if d, err = sm.Decrypter(); err != nil {
+			return auto.ConfigValue{}, fmt.Errorf("unable to read config: %w", err)
+		}
+	}
+	value, err := v.Value(d)
+	if err != nil {
+		return auto.ConfigValue{}, fmt.Errorf("unable to read config: %w", err)
+	}
+	return auto.ConfigValue{Value: value, Secret: v.Secure()}, nil
+}
+
+// GetAllConfig returns the config map for the specified stack name, scoped to the current workspace.
+// InProcessWorkspace reads this config from the matching Pulumi.stack.yaml file.
+func (w *InProcessWorkspace) GetAllConfig(ctx context.Context, stackName string) (auto.ConfigMap, error) {
+	defer w.setEnv()()
+	proj, s, err := w.getStack(ctx, stackName)
+	if err != nil {
+		return nil, err
+	}
+	ps, err := w.loadStackSettings(ctx, proj, stackName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read config: %w", err)
+	}
+	return w.configMap(ctx, proj, s, ps.Config)
+}
+
+// configMap decrypts the given configuration.
+func (w *InProcessWorkspace) configMap(
+	ctx context.Context, proj *workspace.Project, s backend.Stack, cfg config.Map,
+) (auto.ConfigMap, error) {
+	var d config.Decrypter = config.NewPanicCrypter()
+	if cfg.HasSecureValue() {
+		sm, err := w.stackSecretsManager(ctx, proj, s)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read config: %w", err)
+		}
+		if d, err = sm.Decrypter(); err != nil {
+			return nil, fmt.Errorf("unable to read config: %w", err)
+		}
+	}
+
+	result := make(auto.ConfigMap, len(cfg))
+	for k, v := range cfg {
+		value, err := v.Value(d)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read config: %w", err)
+		}
+		result[k.String()] = auto.ConfigValue{Value: value, Secret: v.Secure()}
+	}
+	return result, nil
+}
+
+// SetConfig sets the specified key-value pair on the provided stack name.
+// InProcessWorkspace writes this value to the matching Pulumi.<stack>.yaml file in Workspace.WorkDir().
+func (w *InProcessWorkspace) SetConfig(ctx context.Context, stackName string, key string, val auto.ConfigValue) error {
+	return w.SetConfigWithOptions(ctx, stackName, key, val, nil)
+}
+
+// SetConfigWithOptions sets the specified key-value pair on the provided stack name using the optional
+// ConfigOptions.
+// InProcessWorkspace writes this value to the matching Pulumi.<stack>.yaml file in Workspace.WorkDir().
+func (w *InProcessWorkspace) SetConfigWithOptions(
+	ctx context.Context, stackName string, key string, val auto.ConfigValue, opts *auto.ConfigOptions,
+) error {
+	return w.SetAllConfigWithOptions(ctx, stackName, auto.ConfigMap{key: val}, opts)
+}
+
+// SetAllConfig sets all values in the provided config map for the specified stack name.
+// InProcessWorkspace writes the config to the matching Pulumi.<stack>.yaml file in Workspace.WorkDir().
+func (w *InProcessWorkspace) SetAllConfig(ctx context.Context, stackName string, config auto.ConfigMap) error {
+	return w.SetAllConfigWithOptions(ctx, stackName, config, nil)
+}
+
+// SetAllConfigWithOptions sets all values in the provided config map for the specified stack name using the
+// optional ConfigOptions.
+// InProcessWorkspace writes the config to the matching Pulumi.<stack>.yaml file in Workspace.WorkDir().
+func (w *InProcessWorkspace) SetAllConfigWithOptions(
+	ctx context.Context, stackName string, cfg auto.ConfigMap, opts *auto.ConfigOptions,
+) error {
+	return w.editConfig(ctx, stackName, func(proj *workspace.Project, s backend.Stack,
+		ps *workspace.ProjectStack,
+	) error {
+		var enc config.Encrypter
+		for key, val := range cfg {
+			k, err := parseConfigKey(proj, key)
+			if err != nil {
+				return err
+			}
+
+			v := config.NewValue(val.Value)
+			if val.Secret {
+				if enc == nil {
+					sm, err := w.stackSecretsManager(ctx, proj, s)
+					if err != nil {
+						return err
+					}
+					if enc, err = sm.Encrypter(); err != nil {
+						return err
+					}
+				}
+				ciphertext, err := enc.EncryptValue(ctx, val.Value)
+				if err != nil {
+					return err
+				}
+				v = config.NewSecureValue(ciphertext)
+			}
+
+			if err := ps.Config.Set(k, v, opts != nil && opts.Path); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, "unable to set config")
+}
+
+// RemoveConfig removes the specified key-value pair on the provided stack name.
+// It will remove any matching values in the Pulumi.<stack>.yaml file in Workspace.WorkDir().
+func (w *InProcessWorkspace) RemoveConfig(ctx context.Context, stackName string, key string) error {
+	return w.RemoveConfigWithOptions(ctx, stackName, key, nil)
+}
+
+// RemoveConfigWithOptions removes the specified key-value pair on the provided stack name.
+// It will remove any matching values in the Pulumi.<stack>.yaml file in Workspace.WorkDir().
+func (w *InProcessWorkspace) RemoveConfigWithOptions(
+	ctx context.Context, stackName string, key string, opts *auto.ConfigOptions,
+) error {
+	return w.RemoveAllConfigWithOptions(ctx, stackName, []string{key}, opts)
+}
+
+// RemoveAllConfig removes all values in the provided key list for the specified stack name.
+// It will remove any matching values in the Pulumi.<stack>.yaml file in Workspace.WorkDir().
+func (w *InProcessWorkspace) RemoveAllConfig(ctx context.Context, stackName string, keys []string) error {
+	return w.RemoveAllConfigWithOptions(ctx, stackName, keys, nil)
+}
+
+// RemoveAllConfigWithOptions removes all values in the provided key list for the specified stack name using the
+// optional ConfigOptions.
+// It will remove any matching values in the Pulumi.<stack>.yaml file in Workspace.WorkDir().
+func (w *InProcessWorkspace) RemoveAllConfigWithOptions(
+	ctx context.Context, stackName string, keys []string, opts *auto.ConfigOptions,
+) error {
+	return w.editConfig(ctx, stackName, func(proj *workspace.Project, _ backend.Stack,
+		ps *workspace.ProjectStack,
+	) error {
+		for _, key := range keys {
+			k, err := parseConfigKey(proj, key)
+			if err != nil {
+				return err
+			}
+			if err := ps.Config.Remove(k, opts != nil && opts.Path); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, "could not remove config")
+}
+
+// RefreshConfig gets and sets the config map used with the last Update for Stack matching stack name.
+// It will overwrite all configuration in the Pulumi.<stack>.yaml file in Workspace.WorkDir().
+func (w *InProcessWorkspace) RefreshConfig(ctx context.Context, stackName string) (auto.ConfigMap, error) {
+	defer w.setEnv()()
+	var latest config.Map
+	err := w.editConfig(ctx, stackName, func(_ *workspace.Project, s backend.Stack,
+		ps *workspace.ProjectStack,
+	) error {
+		cfg, err := backend.GetLatestConfiguration(ctx, s)
+		if err != nil {
+			return err
+		}
+		ps.Config, latest = cfg, cfg
+		return nil
+	}, "could not refresh config")
+	if err != nil {
+		return nil, err
+	}
+
+	proj, s, err := w.getStack(ctx, stackName)
+	if err != nil {
+		return nil, err
+	}
+	return w.configMap(ctx, proj, s, latest)
+}
+
+// editConfig applies the given edit to the settings of the given stack and saves them.
+func (w *InProcessWorkspace) editConfig(ctx context.Context, stackName string,
+	edit func(proj *workspace.Project, s backend.Stack, ps *workspace.ProjectStack) error, message string,
+) error {
+	defer w.setEnv()()
+	proj, s, err := w.getStack(ctx, stackName)
+	if err != nil {
+		return err
+	}
+	// Create the secrets manager first, since it may save new secrets settings for the stack.
+	if _, err := w.stackSecretsManager(ctx, proj, s); err != nil {
+		return fmt.Errorf("%s: %w", message, err)
+	}
+	ps, err := w.loadStackSettings(ctx, proj, stackName)
+	if err != nil {
+		return fmt.Errorf("%s: %w", message, err)
+	}
+	if err := edit(proj, s, ps); err != nil {
+		return fmt.Errorf("%s: %w", message, err)
+	}
+	return w.SaveStackSettings(ctx, stackName, ps)
+}
+
+// parseConfigKey parses a configuration key. As a convenience, a key with no namespace is treated as if it belonged
+// to the project.
+func parseConfigKey(proj *workspace.Project, key string) (config.Key, error) {
+	if !strings.Contains(key, tokens.TokenDelimiter) {
+		return config.ParseKey(fmt.Sprintf("%s:%s", proj.Name, key))
+	}
+	return config.ParseKey(key)
+}
+
+// getStackConfiguration returns the configuration of the given stack, validated against the project's
+// configuration schema and with the project's configuration applied.
+func (w *InProcessWorkspace) getStackConfiguration(
+	ctx context.Context, proj *workspace.Project, s backend.Stack, sm secrets.Manager,
+) (backend.StackConfiguration, error) {
+	stackName := s.Ref().Name().String()
+	ps, err := w.loadStackSettings(ctx, proj, stackName)
+	if err != nil {
+		return backend.StackConfiguration{}, err
+	}
+	cfg := ps.Config
+
+	var decrypter config.Decrypter = config.NewPanicCrypter()
+	if cfg.HasSecureValue() || proj.Config != nil {
+		if decrypter, err = sm.Decrypter(); err != nil {
+			return backend.StackConfiguration{}, fmt.Errorf("getting configuration decrypter: %w", err)
+		}
+	}
+	if err := workspace.ValidateStackConfigAndApplyProjectConfig(stackName, proj, cfg, decrypter); err != nil {
+		return backend.StackConfiguration{}, fmt.Errorf("validating stack config: %w", err)
+	}
+	return backend.StackConfiguration{Config: cfg, Decrypter: decrypter}, nil
+}
+
+// createSecretsManager configures the secrets provider of a new stack.
+func (w *InProcessWorkspace) createSecretsManager(
+	ctx context.Context, proj *workspace.Project, s backend.Stack,
+) error {
+	isDefaultSecretsProvider := w.secretsProvider == "" || w.secretsProvider == "default"
+	// The service's secrets manager needs no configuration.
+	if _, isCloud := s.Backend().(httpstate.Backend); isCloud && isDefaultSecretsProvider {
+		return nil
+	}
+
+	ps, err := w.loadStackSettings(ctx, proj, s.Ref().Name().String())
+	if err != nil {
+		return err
+	}
+	old := deepcopy.Copy(ps).(*workspace.ProjectStack)
+	switch {
+	case isDefaultSecretsProvider:
+		_, err = s.DefaultSecretManager(ps)
+	case w.secretsProvider == passphrase.Type:
+		_, err = passphrase.NewPromptingPassphraseSecretsManager(ps, false /* rotateSecretsProvider */)
+	default:
+		_, err = cloud.NewCloudSecretsManager(ps, w.secretsProvider, false /* rotateSecretsProvider */)
+	}
+	if err != nil {
+		return err
+	}
+	return w.saveSecretsSettings(ctx, s, old, ps)
+}
+
+// stackSecretsManager returns the secrets manager of the given stack.
+func (w *InProcessWorkspace) stackSecretsManager(
+	ctx context.Context, proj *workspace.Project, s backend.Stack,
+) (secrets.Manager, error) {
+	ps, err := w.loadStackSettings(ctx, proj, s.Ref().Name().String())
+	if err != nil {
+		return nil, err
+	}
+	old := deepcopy.Copy(ps).(*workspace.ProjectStack)
+
+	var sm secrets.Manager
+	if ps.SecretsProvider != passphrase.Type && ps.SecretsProvider != "default" && ps.SecretsProvider != "" {
+		sm, err = cloud.NewCloudSecretsManager(ps, ps.SecretsProvider, false /* rotateSecretsProvider */)
+	} else if ps.EncryptionSalt != "" {
+		sm, err = passphrase.NewPromptingPassphraseSecretsManager(ps, false /* rotateSecretsProvider */)
+	} else {
+		sm, err = s.DefaultSecretManager(ps)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := w.saveSecretsSettings(ctx, s, old, ps); err != nil {
+		return nil, err
+	}
+	return stack.NewCachingSecretsManager(sm), nil
+}
+
+// saveSecretsSettings saves the stack's settings if creating its secrets manager changed them.
+func (w *InProcessWorkspace) saveSecretsSettings(
+	ctx context.Context, s backend.Stack, old, new *workspace.ProjectStack,
+) error {
+	if old.EncryptedKey != new.EncryptedKey ||
+		old.EncryptionSalt != new.EncryptionSalt ||
+		old.SecretsProvider != new.SecretsProvider {
+		return w.SaveStackSettings(ctx, s.Ref().Name().String(), new)
+	}
+	return nil
+}
+
+// InstallPlugin acquires the plugin matching the specified name and version.
+func (w *InProcessWorkspace) InstallPlugin(ctx context.Context, name string, version string) error {
+	return w.InstallPluginFromServer(ctx, name, version, "")
+}
+
+// InstallPluginFromServer acquires the plugin matching the specified name and version from the given server.
+func (w *InProcessWorkspace) InstallPluginFromServer(
+	ctx context.Context, name string, version string, server string,
+) error {
+	defer w.setEnv()()
+	v, err := semver.ParseTolerant(version)
+	if err != nil {
+		return fmt.Errorf("invalid plugin semver: %w", err)
+	}
+	spec := workspace.PluginSpec{
+		Kind:              workspace.ResourcePlugin,
+		Name:              name,
+		Version:           &v,
+		PluginDownloadURL: server,
+	}
+	if workspace.HasPlugin(spec) {
+		return nil
+	}
+
+	r, err := workspace.DownloadToFile(spec, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to install plugin: downloading %s: %w", spec, err)
+	}
+	defer func() { contract.IgnoreError(os.Remove(r.Name())) }()
+	if err := spec.InstallWithContext(ctx, workspace.TarPlugin(r), false /* reinstall */); err != nil {
+		return fmt.Errorf("failed to install plugin: %w", err)
+	}
+	return nil
+}
+
+// RemovePlugin deletes the resource plugins matching the specified name and version range.
+func (w *InProcessWorkspace) RemovePlugin(ctx context.Context, name string, version string) error {
+	defer w.setEnv()()
+	var versionRange semver.Range
+	if version != "" {
+		r, err := semver.ParseRange(version)
+		if err != nil {
+			return fmt.Errorf("invalid plugin semver: %w", err)
+		}
+		versionRange = r
+	}
+
+	plugins, err := workspace.GetPlugins()
+	if err != nil {
+		return fmt.Errorf("failed to remove plugin: %w", err)
+	}
+	for _, plugin := range plugins {
+		if plugin.Kind == workspace.ResourcePlugin && (name == "" || plugin.Name == name) &&
+			(versionRange == nil || plugin.Version != nil && versionRange(*plugin.Version)) {
+			if err := plugin.Delete(); err != nil {
+				return fmt.Errorf("failed to remove plugin %s: %w", plugin, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ListPlugins lists all installed plugins.
+func (w *InProcessWorkspace) ListPlugins(ctx context.Context) ([]workspace.PluginInfo, error) {
+	defer w.setEnv()()
+	plugins, err := workspace.GetPluginsWithMetadata()
+	if err != nil {
+		return nil, fmt.Errorf("could not list plugins: %w", err)
+	}
+	return plugins, nil
+}
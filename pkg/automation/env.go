@@ -0,0 +1,113 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package automation
+
+import (
+	"os"
+	"sync"
+)
+
+// processEnv tracks the environment variables that the running operations of InProcessWorkspaces have set in the
+// environment of the process.
+//
+// The engine and the backends read their settings from the process environment, so the environment variables of a
+// workspace are set there while each of its operations runs. Operations whose variables conflict cannot run at the
+// same time: an operation waits until none of its variables is set to a different value by another running operation.
+// Operations whose variables agree, such as those of workspaces that share a passphrase, run concurrently.
+type processEnv struct {
+	m    sync.Mutex
+	cond *sync.Cond
+	vars map[string]*processEnvVar
+}
+
+// processEnvVar is an environment variable set by running operations.
+type processEnvVar struct {
+	value string
+	users int // the number of running operations that set the variable.
+
+	original    string // the value of the variable before it was set, if any.
+	hadOriginal bool
+}
+
+var environ = newProcessEnv()
+
+func newProcessEnv() *processEnv {
+	e := &processEnv{vars: make(map[string]*processEnvVar)}
+	e.cond = sync.NewCond(&e.m)
+	return e
+}
+
+// set sets the given variables in the environment of the process, waiting until no running operation has set any of
+// them to a different value. It returns a function that releases the variables, restoring the values that they had
+// before once no running operation sets them.
+func (e *processEnv) set(vars map[string]string) (release func()) {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	for e.conflicts(vars) {
+		e.cond.Wait()
+	}
+	for k, v := range vars {
+		if set, has := e.vars[k]; has {
+			set.users++
+			continue
+		}
+		original, hadOriginal := os.LookupEnv(k)
+		e.vars[k] = &processEnvVar{value: v, users: 1, original: original, hadOriginal: hadOriginal}
+		os.Setenv(k, v)
+	}
+
+	return func() {
+		e.m.Lock()
+		defer e.m.Unlock()
+
+		for k := range vars {
+			set := e.vars[k]
+			if set.users--; set.users > 0 {
+				continue
+			}
+			delete(e.vars, k)
+			if set.hadOriginal {
+				os.Setenv(k, set.original)
+			} else {
+				os.Unsetenv(k)
+			}
+		}
+		e.cond.Broadcast()
+	}
+}
+
+// conflicts returns true if a running operation has set any of the given variables to a different value.
+func (e *processEnv) conflicts(vars map[string]string) bool {
+	for k, v := range vars {
+		if set, has := e.vars[k]; has && set.value != v {
+			return true
+		}
+	}
+	return false
+}
+
+// setEnv sets the workspace's environment variables in the environment of the process for the duration of an
+// operation, and returns a function to call once the operation is done.
+func (w *InProcessWorkspace) setEnv() (release func()) {
+	w.m.Lock()
+	vars := make(map[string]string, len(w.envvars))
+	for k, v := range w.envvars {
+		vars[k] = v
+	}
+	w.m.Unlock()
+
+	return environ.set(vars)
+}
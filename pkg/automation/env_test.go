@@ -0,0 +1,81 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package automation
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//nolint:paralleltest // sets environment variables
+func TestProcessEnv(t *testing.T) {
+	t.Setenv("AUTOMATION_TEST_SET", "original")
+	os.Unsetenv("AUTOMATION_TEST_UNSET")
+
+	e := newProcessEnv()
+	release := e.set(map[string]string{"AUTOMATION_TEST_SET": "a", "AUTOMATION_TEST_UNSET": "a"})
+	assert.Equal(t, "a", os.Getenv("AUTOMATION_TEST_SET"))
+	assert.Equal(t, "a", os.Getenv("AUTOMATION_TEST_UNSET"))
+
+	// Operations that agree on the values of their variables run at the same time.
+	releaseCompatible := e.set(map[string]string{"AUTOMATION_TEST_SET": "a", "AUTOMATION_TEST_UNSET": "a"})
+
+	// Operations that set a variable to a different value wait for the others to finish.
+	conflicting := make(chan func())
+	go func() {
+		conflicting <- e.set(map[string]string{"AUTOMATION_TEST_UNSET": "b"})
+	}()
+
+	release()
+	assert.Equal(t, "a", os.Getenv("AUTOMATION_TEST_SET"))
+	select {
+	case <-conflicting:
+		assert.Fail(t, "conflicting variables were set while in use")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	releaseCompatible()
+	releaseConflicting := <-conflicting
+	assert.Equal(t, "b", os.Getenv("AUTOMATION_TEST_UNSET"))
+	assert.Equal(t, "original", os.Getenv("AUTOMATION_TEST_SET"))
+
+	releaseConflicting()
+	_, has := os.LookupEnv("AUTOMATION_TEST_UNSET")
+	assert.False(t, has)
+}
+
+//nolint:paralleltest // sets environment variables
+func TestInProcessWorkspaceEnvVars(t *testing.T) {
+	os.Unsetenv("AUTOMATION_TEST_VAR")
+
+	w := &InProcessWorkspace{}
+	w.SetEnvVar("AUTOMATION_TEST_VAR", "value")
+	assert.Equal(t, map[string]string{"AUTOMATION_TEST_VAR": "value"}, w.GetEnvVars())
+
+	// The variable is only set in the environment of the process while an operation runs.
+	_, has := os.LookupEnv("AUTOMATION_TEST_VAR")
+	assert.False(t, has)
+	release := w.setEnv()
+	assert.Equal(t, "value", os.Getenv("AUTOMATION_TEST_VAR"))
+	release()
+	_, has = os.LookupEnv("AUTOMATION_TEST_VAR")
+	assert.False(t, has)
+
+	w.UnsetEnvVar("AUTOMATION_TEST_VAR")
+	assert.Empty(t, w.GetEnvVars())
+}
@@ -0,0 +1,632 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package automation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend"
+	"github.com/pulumi/pulumi/pkg/v3/backend/display"
+	"github.com/pulumi/pulumi/pkg/v3/engine"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/v3/resource/stack"
+	"github.com/pulumi/pulumi/pkg/v3/secrets"
+	"github.com/pulumi/pulumi/pkg/v3/util/cancel"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/debug"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optdestroy"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/opthistory"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optrefresh"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/constant"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag/colors"
+	sdkDisplay "github.com/pulumi/pulumi/sdk/v3/go/common/display"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/config"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/result"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/workspace"
+)
+
+// timeFormat is the format of the times in update summaries, which matches that of `pulumi stack history --json`.
+const timeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// errorDecryptingValue is the value of a secret configuration value in an update summary that cannot be decrypted.
+const errorDecryptingValue = "ERROR_UNABLE_TO_DECRYPT"
+
+// PreviewStack performs a dry-run update of the given stack. The UserAgent option is ignored, as are the debug
+// logging options other than Debug.
+func (w *InProcessWorkspace) PreviewStack(
+	ctx context.Context, stackName string, opts *optpreview.Options,
+) (auto.PreviewResult, error) {
+	op := operation{
+		kind:         apitype.PreviewUpdate,
+		message:      opts.Message,
+		color:        opts.Color,
+		diff:         opts.Diff,
+		debug:        opts.DebugLogOpts,
+		progress:     opts.ProgressStreams,
+		errProgress:  opts.ErrorProgressStreams,
+		eventStreams: opts.EventStreams,
+		eventSinks:   opts.EventSinks,
+		engine: engine.UpdateOptions{
+			LocalPolicyPacks: engine.MakeLocalPolicyPacks(opts.PolicyPacks, opts.PolicyPackConfigs),
+			Parallel:         opts.Parallel,
+			ReplaceTargets:   deploy.NewUrnTargets(opts.Replace),
+			UpdateTargets:    deploy.NewUrnTargets(opts.Target),
+			TargetDependents: opts.TargetDependents,
+			GeneratePlan:     opts.Plan != "",
+		},
+	}
+	res, err := w.runOperation(ctx, stackName, op,
+		func(s backend.Stack, update backend.UpdateOperation) (sdkDisplay.ResourceChanges, result.Result) {
+			plan, changes, res := s.Preview(ctx, update)
+			if res == nil && opts.Plan != "" {
+				if err := writePlan(opts.Plan, plan, update.SecretsManager); err != nil {
+					return changes, result.FromError(fmt.Errorf("failed to write plan: %w", err))
+				}
+			}
+			return changes, res
+		})
+	if err == nil && opts.ExpectNoChanges && engine.HasChanges(res.changes) {
+		err = errors.New("no changes were expected but changes were proposed")
+	}
+	if err != nil {
+		return auto.PreviewResult{ChangeSet: res.changeSet},
+			auto.NewEngineError(fmt.Errorf("failed to run preview: %w", err), res.stdout, res.stderr)
+	}
+
+	summary := make(map[apitype.OpType]int, len(res.changes))
+	for op, count := range res.changes {
+		summary[apitype.OpType(op)] = count
+	}
+	return auto.PreviewResult{
+		StdOut:        res.stdout,
+		StdErr:        res.stderr,
+		ChangeSummary: summary,
+		ChangeSet:     res.changeSet,
+	}, res.sinkErr
+}
+
+// UpStack creates or updates the resources of the given stack. The UserAgent option is ignored, as are the debug
+// logging options other than Debug.
+func (w *InProcessWorkspace) UpStack(
+	ctx context.Context, stackName string, opts *optup.Options,
+) (auto.UpResult, error) {
+	op := operation{
+		kind:         apitype.UpdateUpdate,
+		message:      opts.Message,
+		color:        opts.Color,
+		diff:         opts.Diff,
+		debug:        opts.DebugLogOpts,
+		progress:     opts.ProgressStreams,
+		errProgress:  opts.ErrorProgressStreams,
+		eventStreams: opts.EventStreams,
+		eventSinks:   opts.EventSinks,
+		engine: engine.UpdateOptions{
+			LocalPolicyPacks: engine.MakeLocalPolicyPacks(opts.PolicyPacks, opts.PolicyPackConfigs),
+			Parallel:         opts.Parallel,
+			ReplaceTargets:   deploy.NewUrnTargets(opts.Replace),
+			UpdateTargets:    deploy.NewUrnTargets(opts.Target),
+			TargetDependents: opts.TargetDependents,
+			Approvals:        newApprovalPolicy(opts.RequireApproval, opts.Approver),
+			GeneratePlan:     true,
+		},
+	}
+	res, err := w.runOperation(ctx, stackName, op,
+		func(s backend.Stack, update backend.UpdateOperation) (sdkDisplay.ResourceChanges, result.Result) {
+			switch {
+			case opts.DeploymentPlan != nil:
+				plan, err := deserializePlan(*opts.DeploymentPlan, update.SecretsManager)
+				if err != nil {
+					return nil, result.FromError(fmt.Errorf("failed to read plan: %w", err))
+				}
+				update.Opts.Engine.Plan = plan
+			case opts.Plan != "":
+				plan, err := readPlan(opts.Plan, update.SecretsManager)
+				if err != nil {
+					return nil, result.FromError(fmt.Errorf("failed to read plan: %w", err))
+				}
+				update.Opts.Engine.Plan = plan
+			}
+			return s.Update(ctx, update)
+		})
+	if err == nil && opts.ExpectNoChanges && engine.HasChanges(res.changes) {
+		err = errors.New("no changes were expected but changes occurred")
+	}
+	if err != nil {
+		return auto.UpResult{ChangeSet: res.changeSet},
+			auto.NewEngineError(fmt.Errorf("failed to run update: %w", err), res.stdout, res.stderr)
+	}
+
+	outputs, err := w.StackOutputs(ctx, stackName)
+	if err != nil {
+		return auto.UpResult{}, err
+	}
+	summary, err := w.lastUpdateSummary(ctx, stackName, opts.ShowSecrets)
+	if err != nil {
+		return auto.UpResult{}, err
+	}
+	return auto.UpResult{
+		StdOut:    res.stdout,
+		StdErr:    res.stderr,
+		Outputs:   outputs,
+		Summary:   summary,
+		ChangeSet: res.changeSet,
+	}, res.sinkErr
+}
+
+// RefreshStack refreshes the resources of the given stack from their providers. The UserAgent option is ignored, as
+// are the debug logging options other than Debug.
+func (w *InProcessWorkspace) RefreshStack(
+	ctx context.Context, stackName string, opts *optrefresh.Options,
+) (auto.RefreshResult, error) {
+	op := operation{
+		kind:         apitype.RefreshUpdate,
+		message:      opts.Message,
+		color:        opts.Color,
+		debug:        opts.DebugLogOpts,
+		progress:     opts.ProgressStreams,
+		errProgress:  opts.ErrorProgressStreams,
+		eventStreams: opts.EventStreams,
+		eventSinks:   opts.EventSinks,
+		engine: engine.UpdateOptions{
+			Parallel:       opts.Parallel,
+			RefreshTargets: deploy.NewUrnTargets(opts.Target),
+		},
+	}
+	res, err := w.runOperation(ctx, stackName, op,
+		func(s backend.Stack, update backend.UpdateOperation) (sdkDisplay.ResourceChanges, result.Result) {
+			return s.Refresh(ctx, update)
+		})
+	if err == nil && opts.ExpectNoChanges && engine.HasChanges(res.changes) {
+		err = errors.New("no changes were expected but changes occurred")
+	}
+	if err != nil {
+		return auto.RefreshResult{},
+			auto.NewEngineError(fmt.Errorf("failed to refresh stack: %w", err), res.stdout, res.stderr)
+	}
+
+	summary, err := w.lastUpdateSummary(ctx, stackName, opts.ShowSecrets)
+	if err != nil {
+		return auto.RefreshResult{}, err
+	}
+	return auto.RefreshResult{StdOut: res.stdout, StdErr: res.stderr, Summary: summary}, res.sinkErr
+}
+
+// DestroyStack deletes the resources of the given stack. The UserAgent option is ignored, as are the debug logging
+// options other than Debug.
+func (w *InProcessWorkspace) DestroyStack(
+	ctx context.Context, stackName string, opts *optdestroy.Options,
+) (auto.DestroyResult, error) {
+	op := operation{
+		kind:         apitype.DestroyUpdate,
+		message:      opts.Message,
+		color:        opts.Color,
+		debug:        opts.DebugLogOpts,
+		progress:     opts.ProgressStreams,
+		errProgress:  opts.ErrorProgressStreams,
+		eventStreams: opts.EventStreams,
+		eventSinks:   opts.EventSinks,
+		engine: engine.UpdateOptions{
+			Parallel:         opts.Parallel,
+			DestroyTargets:   deploy.NewUrnTargets(opts.Target),
+			TargetDependents: opts.TargetDependents,
+		},
+	}
+	res, err := w.runOperation(ctx, stackName, op,
+		func(s backend.Stack, update backend.UpdateOperation) (sdkDisplay.ResourceChanges, result.Result) {
+			return s.Destroy(ctx, update)
+		})
+	if err != nil {
+		return auto.DestroyResult{},
+			auto.NewEngineError(fmt.Errorf("failed to destroy stack: %w", err), res.stdout, res.stderr)
+	}
+
+	summary, err := w.lastUpdateSummary(ctx, stackName, opts.ShowSecrets)
+	if err != nil {
+		return auto.DestroyResult{}, err
+	}
+	return auto.DestroyResult{StdOut: res.stdout, StdErr: res.stderr, Summary: summary}, res.sinkErr
+}
+
+// StackHistory returns a page of the history of the given stack, most recent first. Secret configuration values are
+// shown unless the ShowSecrets option is false.
+func (w *InProcessWorkspace) StackHistory(
+	ctx context.Context, stackName string, pageSize, page int, opts *opthistory.Options,
+) ([]auto.UpdateSummary, error) {
+	defer w.setEnv()()
+	proj, s, err := w.getStack(ctx, stackName)
+	if err != nil {
+		return nil, err
+	}
+	updates, err := s.Backend().GetHistory(ctx, s.Ref(), pageSize, page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stack history: %w", err)
+	}
+
+	var decrypter config.Decrypter
+	if opts.ShowSecrets == nil || *opts.ShowSecrets {
+		for _, update := range updates {
+			if update.Config.HasSecureValue() {
+				sm, err := w.stackSecretsManager(ctx, proj, s)
+				if err != nil {
+					return nil, fmt.Errorf("failed to get stack history: %w", err)
+				}
+				if decrypter, err = sm.Decrypter(); err != nil {
+					return nil, fmt.Errorf("failed to get stack history: %w", err)
+				}
+				break
+			}
+		}
+	}
+
+	summaries := make([]auto.UpdateSummary, len(updates))
+	for i, update := range updates {
+		summary := auto.UpdateSummary{
+			Version:     update.Version,
+			Kind:        string(update.Kind),
+			StartTime:   time.Unix(update.StartTime, 0).UTC().Format(timeFormat),
+			Message:     update.Message,
+			Environment: update.Environment,
+			Config:      make(auto.ConfigMap, len(update.Config)),
+			Result:      string(update.Result),
+		}
+		for k, v := range update.Config {
+			value := auto.ConfigValue{Secret: v.Secure()}
+			if !v.Secure() || decrypter != nil {
+				if value.Value, err = v.Value(decrypter); err != nil {
+					value.Value = errorDecryptingValue
+				}
+			}
+			summary.Config[k.String()] = value
+		}
+		if update.Result != backend.InProgressResult {
+			endTime := time.Unix(update.EndTime, 0).UTC().Format(timeFormat)
+			summary.EndTime = &endTime
+			changes := make(map[string]int, len(update.ResourceChanges))
+			for op, count := range update.ResourceChanges {
+				changes[string(op)] = count
+			}
+			summary.ResourceChanges = &changes
+		}
+		summaries[i] = summary
+	}
+	return summaries, nil
+}
+
+// lastUpdateSummary returns the summary of the stack's most recent update.
+func (w *InProcessWorkspace) lastUpdateSummary(
+	ctx context.Context, stackName string, showSecrets *bool,
+) (auto.UpdateSummary, error) {
+	history, err := w.StackHistory(ctx, stackName, 1 /*pageSize*/, 1 /*page*/, &opthistory.Options{
+		ShowSecrets: showSecrets,
+	})
+	if err != nil {
+		return auto.UpdateSummary{}, fmt.Errorf("failed to get update summary: %w", err)
+	}
+	if len(history) == 0 {
+		return auto.UpdateSummary{}, errors.New("failed to get update summary: the stack has no history")
+	}
+	return history[0], nil
+}
+
+// operationResult is the outcome of an operation run in the workspace.
+type operationResult struct {
+	changes   sdkDisplay.ResourceChanges
+	changeSet auto.ChangeSet
+	stdout    string
+	stderr    string
+	// sinkErr is the first error returned by the operation's event sinks. It does not fail the operation, and is
+	// returned along with the operation's result.
+	sinkErr error
+}
+
+// operation describes a stack operation to run in the workspace.
+type operation struct {
+	kind         apitype.UpdateKind
+	message      string
+	color        string
+	diff         bool
+	debug        debug.LoggingOptions
+	progress     []io.Writer
+	errProgress  []io.Writer
+	eventStreams []chan<- events.EngineEvent
+	eventSinks   []events.Sink
+	engine       engine.UpdateOptions
+}
+
+// runOperation prepares an update of the given stack and calls run to perform it. It returns the changes that run
+// reports, the change set recorded from the operation's events and the operation's display output, even if the
+// operation fails.
+func (w *InProcessWorkspace) runOperation(
+	ctx context.Context, stackName string, op operation,
+	run func(s backend.Stack, update backend.UpdateOperation) (sdkDisplay.ResourceChanges, result.Result),
+) (operationResult, error) {
+	defer w.setEnv()()
+	proj, s, err := w.getStack(ctx, stackName)
+	if err != nil {
+		return operationResult{}, err
+	}
+	sm, err := w.stackSecretsManager(ctx, proj, s)
+	if err != nil {
+		return operationResult{}, fmt.Errorf("getting secrets manager: %w", err)
+	}
+	cfg, err := w.getStackConfiguration(ctx, proj, s, sm)
+	if err != nil {
+		return operationResult{}, fmt.Errorf("getting stack configuration: %w", err)
+	}
+
+	execKind := constant.ExecKindAutoLocal
+	if w.program != nil {
+		addr, stop, err := auto.ServeProgram(w.program)
+		if err != nil {
+			return operationResult{}, err
+		}
+		defer func() { contract.IgnoreError(stop()) }()
+		proj.Runtime = workspace.NewProjectRuntimeInfo("client", map[string]interface{}{
+			"address": addr,
+		})
+		execKind = constant.ExecKindAutoInline
+	}
+
+	var stdout, stderr bytes.Buffer
+	displayOpts := display.Options{
+		Color:         displayColor(op.color),
+		IsInteractive: false,
+		Type:          display.DisplayProgress,
+		Debug:         op.debug.Debug,
+		Stdout:        io.MultiWriter(append([]io.Writer{&stdout}, op.progress...)...),
+		Stderr:        io.MultiWriter(append([]io.Writer{&stderr}, op.errProgress...)...),
+	}
+	if op.diff {
+		displayOpts.Type = display.DisplayDiff
+	}
+
+	streams, sinksDone := op.eventStreams, func() error { return nil }
+	if len(op.eventSinks) > 0 {
+		var sinkStream chan<- events.EngineEvent
+		sinkStream, sinksDone = events.SinkStream(op.eventSinks...)
+		streams = append(append([]chan<- events.EngineEvent{}, streams...), sinkStream)
+	}
+	recorder := auto.NewChangeSetRecorder()
+	engineEvents, eventsDone := make(chan engine.Event), make(chan struct{})
+	go forwardEvents(engineEvents, streams, displayOpts.Color, recorder, eventsDone)
+	displayOpts.Events = engineEvents
+
+	engineOpts := op.engine
+	engineOpts.Debug = op.debug.Debug
+	engineOpts.Refresh = proj.Options != nil && proj.Options.Refresh == "always" && op.kind == apitype.UpdateUpdate
+
+	changes, res := run(s, backend.UpdateOperation{
+		Proj: proj,
+		Root: w.workDir,
+		M: &backend.UpdateMetadata{
+			Message: op.message,
+			Environment: map[string]string{
+				backend.ExecutionKind: execKind,
+				backend.UpdatePlan:    "false",
+			},
+		},
+		Opts: backend.UpdateOptions{
+			Engine:      engineOpts,
+			Display:     displayOpts,
+			AutoApprove: true,
+			SkipPreview: true,
+		},
+		StackConfiguration: cfg,
+		SecretsManager:     sm,
+		SecretsProvider:    stack.DefaultSecretsProvider,
+		Scopes:             cancellationScopeSource{ctx: ctx},
+	})
+	close(engineEvents)
+	<-eventsDone
+	sinkErr := sinksDone()
+
+	err = nil
+	if res != nil {
+		err = res.Error()
+		switch {
+		case res.IsBail():
+			err = errors.New("the operation failed; see the output for details")
+		case errors.Is(err, context.Canceled):
+			err = errors.New("the operation was cancelled")
+		case isConflictingUpdateError(err):
+			err = auto.ConcurrentUpdateError{Err: err}
+		}
+		fmt.Fprintf(displayOpts.Stderr, "error: %v\n", err)
+	}
+	if sinkErr != nil {
+		sinkErr = fmt.Errorf("failed to write events to sink: %w", sinkErr)
+	}
+	return operationResult{
+		changes:   changes,
+		changeSet: recorder.ChangeSet(),
+		stdout:    stdout.String(),
+		stderr:    stderr.String(),
+		sinkErr:   sinkErr,
+	}, err
+}
+
+// isConflictingUpdateError returns true if the error is the result of another update of the stack being in progress.
+func isConflictingUpdateError(err error) bool {
+	var conflict backend.ConflictingUpdateError
+	return errors.As(err, &conflict) || strings.Contains(err.Error(), "the stack is currently locked by")
+}
+
+// displayColor returns the colorization to use for the given color option. Since the display is not written to a
+// terminal, output is not colorized unless colors are requested explicitly.
+func displayColor(color string) colors.Colorization {
+	switch color {
+	case "always":
+		return colors.Always
+	case "raw":
+		return colors.Raw
+	default:
+		return colors.Never
+	}
+}
+
+// forwardEvents records each engine event and sends it to the given streams, in the form that they would have been
+// read from an event log, until the events channel is closed. It then closes the streams and done.
+func forwardEvents(
+	engineEvents <-chan engine.Event, streams []chan<- events.EngineEvent, color colors.Colorization,
+	recorder *auto.ChangeSetRecorder, done chan<- struct{},
+) {
+	defer close(done)
+
+	sequence := 0
+	for e := range engineEvents {
+		apiEvent, err := display.ConvertEngineEvent(e, false /* showSecrets */)
+		if err == nil {
+			apiEvent.Sequence = sequence
+			apiEvent.Timestamp = int(time.Now().Unix())
+			sequence++
+			if color == colors.Never {
+				removeEventColors(&apiEvent)
+			}
+		}
+		event := events.EngineEvent{EngineEvent: apiEvent, Error: err}
+		recorder.Record(event)
+		for _, s := range streams {
+			s <- event
+		}
+	}
+	for _, s := range streams {
+		close(s)
+	}
+}
+
+// removeEventColors removes the color directives from the messages of an event.
+func removeEventColors(e *apitype.EngineEvent) {
+	switch {
+	case e.DiagnosticEvent != nil:
+		e.DiagnosticEvent.Message = colors.Never.Colorize(e.DiagnosticEvent.Message)
+		e.DiagnosticEvent.Prefix = colors.Never.Colorize(e.DiagnosticEvent.Prefix)
+		e.DiagnosticEvent.Color = string(colors.Never)
+	case e.StdoutEvent != nil:
+		e.StdoutEvent.Message = colors.Never.Colorize(e.StdoutEvent.Message)
+		e.StdoutEvent.Color = string(colors.Never)
+	case e.PolicyEvent != nil:
+		e.PolicyEvent.Message = colors.Never.Colorize(e.PolicyEvent.Message)
+		e.PolicyEvent.Color = string(colors.Never)
+	}
+}
+
+// newApprovalPolicy returns the policy that requires approval from the given approver for the steps that match the
+// given patterns, or nil if there is no approver.
+func newApprovalPolicy(patterns []string, approver optup.ApprovalFunc) *deploy.ApprovalPolicy {
+	if approver == nil {
+		return nil
+	}
+	return deploy.NewApprovalPolicy(patterns, func(ctx context.Context, step deploy.Step) (bool, error) {
+		return approver(ctx, apitype.StepApprovalRequest{
+			URN:  string(step.URN()),
+			Type: string(step.Type()),
+			Op:   apitype.OpType(step.Op()),
+		}), nil
+	})
+}
+
+// writePlan writes the given plan to a file at the given path.
+func writePlan(path string, plan *deploy.Plan, sm secrets.Manager) error {
+	enc, err := sm.Encrypter()
+	if err != nil {
+		return err
+	}
+	deploymentPlan, err := stack.SerializePlan(plan, enc, false /* showSecrets */)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(deploymentPlan, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o600)
+}
+
+// readPlan reads a plan that was written by writePlan, or by `pulumi preview --save-plan`.
+func readPlan(path string, sm secrets.Manager) (*deploy.Plan, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var deploymentPlan apitype.DeploymentPlanV1
+	if err := json.Unmarshal(b, &deploymentPlan); err != nil {
+		return nil, err
+	}
+	return deserializePlan(deploymentPlan, sm)
+}
+
+// deserializePlan deserializes a plan whose secrets are encrypted with the given secrets manager.
+func deserializePlan(deploymentPlan apitype.DeploymentPlanV1, sm secrets.Manager) (*deploy.Plan, error) {
+	dec, err := sm.Decrypter()
+	if err != nil {
+		return nil, err
+	}
+	enc, err := sm.Encrypter()
+	if err != nil {
+		return nil, err
+	}
+	return stack.DeserializePlan(deploymentPlan, dec, enc)
+}
+
+// cancellationScopeSource creates cancellation scopes that cancel the operation when its context is done.
+type cancellationScopeSource struct {
+	ctx context.Context
+}
+
+func (c cancellationScopeSource) NewScope(_ chan<- engine.Event, _ bool) backend.CancellationScope {
+	cancelContext, cancelSource := cancel.NewContext(context.Background())
+	scope := &cancellationScope{
+		context: cancelContext,
+		closed:  make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go func() {
+		defer close(scope.done)
+		select {
+		case <-c.ctx.Done():
+			cancelSource.Cancel()
+		case <-scope.closed:
+		}
+	}()
+	return scope
+}
+
+type cancellationScope struct {
+	context *cancel.Context
+	closed  chan struct{}
+	done    chan struct{}
+}
+
+func (s *cancellationScope) Context() *cancel.Context {
+	return s.context
+}
+
+func (s *cancellationScope) Close() {
+	close(s.closed)
+	<-s.done
+}
@@ -0,0 +1,793 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package automation provides an implementation of the Automation API's auto.Workspace that runs in the same Go
+// process as the program that uses it. Rather than running the Pulumi CLI, an InProcessWorkspace calls the engine and
+// the backends directly, which avoids the cost of starting a CLI process for each operation and lets the caller
+// receive engine events over a channel without going through an event log file.
+//
+// Stacks created with an InProcessWorkspace have the same auto.Stack API as any other stack, and its operations
+// return the same typed errors, such as auto.StackNotFoundError and auto.ConcurrentUpdateError. Only the stack
+// lifecycle operations run in-process: Preview, PreviewPlan, Up, Refresh, Destroy, Watch and History. The stack methods
+// that run the Pulumi CLI, such as ImportResources, QueryState, Cancel and the State methods, return an error that
+// wraps auto.ErrUnsupportedByEngine; use an auto.LocalWorkspace for them.
+package automation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend"
+	"github.com/pulumi/pulumi/pkg/v3/backend/display"
+	"github.com/pulumi/pulumi/pkg/v3/backend/filestate"
+	"github.com/pulumi/pulumi/pkg/v3/backend/httpstate"
+	"github.com/pulumi/pulumi/pkg/v3/resource/stack"
+	"github.com/pulumi/pulumi/pkg/v3/version"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optremove"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag/colors"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/config"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/workspace"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// settingsExtensions are the file extensions of the project and stack settings files, in order of precedence.
+var settingsExtensions = []string{".yaml", ".yml", ".json"}
+
+// InProcessWorkspace is an auto.Workspace that runs stack operations in the current process. Like
+// auto.LocalWorkspace, it keeps the project and stack settings in Pulumi.yaml and Pulumi.<stack>.yaml files in its
+// working directory, and uses the backend that the project or the PULUMI_BACKEND_URL environment variable selects.
+//
+// Since the engine reads its settings from the process environment, the environment variables of an
+// InProcessWorkspace, including PULUMI_HOME if a PulumiHome is given, are set in the environment of the process while
+// each of its operations runs. Operations of workspaces whose environment variables differ wait for each other rather
+// than run at the same time.
+type InProcessWorkspace struct {
+	workDir         string
+	pulumiHome      string
+	program         pulumi.RunFunc
+	secretsProvider string
+
+	m            sync.Mutex
+	envvars      map[string]string // the environment variables set while the workspace's operations run.
+	currentStack string            // the name of the selected stack, if any.
+	backend      backend.Backend   // the backend of the project, created when it is first needed.
+}
+
+var (
+	_ auto.Workspace   = (*InProcessWorkspace)(nil)
+	_ auto.StackEngine = (*InProcessWorkspace)(nil)
+)
+
+// NewInProcessWorkspace creates and configures an InProcessWorkspace. If no working directory is given, a temporary
+// directory is created for it.
+func NewInProcessWorkspace(ctx context.Context, opts ...Option) (*InProcessWorkspace, error) {
+	o := &options{}
+	// for merging options, last specified value wins
+	for _, opt := range opts {
+		opt.applyOption(o)
+	}
+
+	workDir := o.WorkDir
+	if workDir == "" {
+		dir, err := os.MkdirTemp("", "pulumi_auto")
+		if err != nil {
+			return nil, fmt.Errorf("unable to create tmp directory for workspace: %w", err)
+		}
+		workDir = dir
+	}
+
+	w := &InProcessWorkspace{
+		workDir:         workDir,
+		program:         o.Program,
+		secretsProvider: o.SecretsProvider,
+	}
+
+	if o.PulumiHome != "" {
+		w.pulumiHome = o.PulumiHome
+		w.SetEnvVar("PULUMI_HOME", o.PulumiHome)
+	}
+	if o.EnvVars != nil {
+		if err := w.SetEnvVars(o.EnvVars); err != nil {
+			return nil, fmt.Errorf("failed to set environment values: %w", err)
+		}
+	}
+
+	if o.Project != nil {
+		if err := w.SaveProjectSettings(ctx, o.Project); err != nil {
+			return nil, fmt.Errorf("failed to create workspace, unable to save project settings: %w", err)
+		}
+	}
+	for stackName := range o.Stacks {
+		s := o.Stacks[stackName]
+		if err := w.SaveStackSettings(ctx, stackName, &s); err != nil {
+			return nil, fmt.Errorf("failed to create workspace: %w", err)
+		}
+	}
+
+	return w, nil
+}
+
+// NewStackInlineSource creates a stack with the given name in an InProcessWorkspace that runs the given inline
+// program. If no project settings are given and the working directory has none, a project with the given name is
+// used. It fails if the stack already exists.
+func NewStackInlineSource(
+	ctx context.Context, stackName, projectName string, program pulumi.RunFunc, opts ...Option,
+) (auto.Stack, error) {
+	w, err := newInlineWorkspace(ctx, projectName, program, opts)
+	if err != nil {
+		return auto.Stack{}, err
+	}
+	return auto.NewStack(ctx, stackName, w)
+}
+
+// UpsertStackInlineSource is like NewStackInlineSource, but selects the stack if it already exists.
+func UpsertStackInlineSource(
+	ctx context.Context, stackName, projectName string, program pulumi.RunFunc, opts ...Option,
+) (auto.Stack, error) {
+	w, err := newInlineWorkspace(ctx, projectName, program, opts)
+	if err != nil {
+		return auto.Stack{}, err
+	}
+	return auto.UpsertStack(ctx, stackName, w)
+}
+
+// NewStackLocalSource creates a stack with the given name in an InProcessWorkspace for the project in the given
+// directory. It fails if the stack already exists.
+func NewStackLocalSource(ctx context.Context, stackName, workDir string, opts ...Option) (auto.Stack, error) {
+	w, err := NewInProcessWorkspace(ctx, append(opts, WorkDir(workDir))...)
+	if err != nil {
+		return auto.Stack{}, fmt.Errorf("failed to create stack: %w", err)
+	}
+	return auto.NewStack(ctx, stackName, w)
+}
+
+// UpsertStackLocalSource is like NewStackLocalSource, but selects the stack if it already exists.
+func UpsertStackLocalSource(ctx context.Context, stackName, workDir string, opts ...Option) (auto.Stack, error) {
+	w, err := NewInProcessWorkspace(ctx, append(opts, WorkDir(workDir))...)
+	if err != nil {
+		return auto.Stack{}, fmt.Errorf("failed to create stack: %w", err)
+	}
+	return auto.UpsertStack(ctx, stackName, w)
+}
+
+func newInlineWorkspace(
+	ctx context.Context, projectName string, program pulumi.RunFunc, opts []Option,
+) (*InProcessWorkspace, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt.applyOption(o)
+	}
+	opts = append(opts, Program(program))
+
+	if o.Project == nil && (o.WorkDir == "" || !hasProjectSettings(o.WorkDir)) {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, Project(workspace.Project{
+			Name:    tokens.PackageName(projectName),
+			Runtime: workspace.NewProjectRuntimeInfo("go", nil),
+			Main:    cwd,
+		}))
+	}
+
+	w, err := NewInProcessWorkspace(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stack: %w", err)
+	}
+	return w, nil
+}
+
+func hasProjectSettings(dir string) bool {
+	for _, ext := range settingsExtensions {
+		if _, err := os.Stat(filepath.Join(dir, "Pulumi"+ext)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ProjectSettings returns the settings object for the current project if any.
+// InProcessWorkspace reads settings from the Pulumi.yaml in the workspace.
+func (w *InProcessWorkspace) ProjectSettings(ctx context.Context) (*workspace.Project, error) {
+	for _, ext := range settingsExtensions {
+		projectPath := filepath.Join(w.workDir, "Pulumi"+ext)
+		if _, err := os.Stat(projectPath); err == nil {
+			proj, err := workspace.LoadProject(projectPath)
+			if err != nil {
+				return nil, fmt.Errorf("found project settings, but failed to load: %w", err)
+			}
+			return proj, nil
+		}
+	}
+	return nil, fmt.Errorf("unable to find project settings in workspace")
+}
+
+// SaveProjectSettings overwrites the settings object in the current project.
+// InProcessWorkspace writes this value to a Pulumi.yaml file in Workspace.WorkDir().
+func (w *InProcessWorkspace) SaveProjectSettings(ctx context.Context, settings *workspace.Project) error {
+	return settings.Save(filepath.Join(w.workDir, "Pulumi.yaml"))
+}
+
+// StackSettings returns the settings object for the stack matching the specified stack name if any.
+// InProcessWorkspace reads this from a Pulumi.<stack>.yaml file in Workspace.WorkDir().
+func (w *InProcessWorkspace) StackSettings(ctx context.Context, stackName string) (*workspace.ProjectStack, error) {
+	project, err := w.ProjectSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ext := range settingsExtensions {
+		stackPath := w.stackSettingsPath(stackName, ext)
+		if _, err := os.Stat(stackPath); err == nil {
+			ps, err := workspace.LoadProjectStack(project, stackPath)
+			if err != nil {
+				return nil, fmt.Errorf("found stack settings, but failed to load: %w", err)
+			}
+			return ps, nil
+		}
+	}
+	return nil, fmt.Errorf("unable to find stack settings in workspace for %s", stackName)
+}
+
+// SaveStackSettings overwrites the settings object for the stack matching the specified stack name.
+// InProcessWorkspace writes this value to a Pulumi.<stack>.yaml file in Workspace.WorkDir().
+func (w *InProcessWorkspace) SaveStackSettings(
+	ctx context.Context, stackName string, settings *workspace.ProjectStack,
+) error {
+	if err := settings.Save(w.stackSettingsPath(stackName, ".yaml")); err != nil {
+		return fmt.Errorf("failed to save stack setttings for %s: %w", stackName, err)
+	}
+	return nil
+}
+
+// loadStackSettings returns the settings of the given stack, or empty settings if the stack has none yet.
+func (w *InProcessWorkspace) loadStackSettings(
+	ctx context.Context, proj *workspace.Project, stackName string,
+) (*workspace.ProjectStack, error) {
+	for _, ext := range settingsExtensions {
+		stackPath := w.stackSettingsPath(stackName, ext)
+		if _, err := os.Stat(stackPath); err == nil {
+			return workspace.LoadProjectStack(proj, stackPath)
+		}
+	}
+	return workspace.LoadProjectStack(proj, w.stackSettingsPath(stackName, ".yaml"))
+}
+
+func (w *InProcessWorkspace) stackSettingsPath(stackName, ext string) string {
+	// Only the last part of a fully qualified stack name is used in the name of its settings file.
+	name := stackName
+	if i := strings.LastIndex(stackName, "/"); i >= 0 {
+		name = stackName[i+1:]
+	}
+	return filepath.Join(w.workDir, fmt.Sprintf("Pulumi.%s%s", name, ext))
+}
+
+// SerializeArgsForOp is hook to provide additional args to every CLI commands before they are executed.
+// InProcessWorkspace does not run the CLI, so it does not utilize this extensibility point.
+func (w *InProcessWorkspace) SerializeArgsForOp(ctx context.Context, stackName string) ([]string, error) {
+	return nil, nil
+}
+
+// PostCommandCallback is a hook executed after every command. Called with the stack name.
+// InProcessWorkspace does not utilize this extensibility point.
+func (w *InProcessWorkspace) PostCommandCallback(ctx context.Context, stackName string) error {
+	return nil
+}
+
+// GetEnvVars returns the environment values scoped to the current workspace.
+func (w *InProcessWorkspace) GetEnvVars() map[string]string {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	if w.envvars == nil {
+		return nil
+	}
+	envvars := make(map[string]string, len(w.envvars))
+	for k, v := range w.envvars {
+		envvars[k] = v
+	}
+	return envvars
+}
+
+// SetEnvVars sets the specified map of environment values scoped to the current workspace. The values are set in
+// the environment of the process while the workspace's operations run.
+func (w *InProcessWorkspace) SetEnvVars(envvars map[string]string) error {
+	if envvars == nil {
+		return errors.New("unable to set nil environment values")
+	}
+	for k, v := range envvars {
+		w.SetEnvVar(k, v)
+	}
+	return nil
+}
+
+// SetEnvVar sets the specified environment value scoped to the current workspace. The value is set in the
+// environment of the process while the workspace's operations run.
+func (w *InProcessWorkspace) SetEnvVar(key, value string) {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	if w.envvars == nil {
+		w.envvars = map[string]string{}
+	}
+	w.envvars[key] = value
+}
+
+// UnsetEnvVar unsets the specified environment value scoped to the current workspace.
+func (w *InProcessWorkspace) UnsetEnvVar(key string) {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	delete(w.envvars, key)
+}
+
+// WorkDir returns the working directory of the workspace, which contains its Pulumi.yaml file.
+func (w *InProcessWorkspace) WorkDir() string {
+	return w.workDir
+}
+
+// PulumiHome returns the directory override for CLI metadata if set.
+func (w *InProcessWorkspace) PulumiHome() string {
+	return w.pulumiHome
+}
+
+// PulumiVersion returns the version of the engine that the workspace runs.
+func (w *InProcessWorkspace) PulumiVersion() string {
+	return version.Version
+}
+
+// Program returns the program `pulumi.RunFunc` to be used for Preview/Update if any.
+// If none is specified, the stack will refer to ProjectSettings for this information.
+func (w *InProcessWorkspace) Program() pulumi.RunFunc {
+	return w.program
+}
+
+// SetProgram sets the program associated with the Workspace to the specified `pulumi.RunFunc`.
+func (w *InProcessWorkspace) SetProgram(fn pulumi.RunFunc) {
+	w.program = fn
+}
+
+// getBackend returns the backend of the workspace's project, creating it if this is the first time it is needed.
+func (w *InProcessWorkspace) getBackend(ctx context.Context) (*workspace.Project, backend.Backend, error) {
+	proj, err := w.ProjectSettings(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	w.m.Lock()
+	defer w.m.Unlock()
+	if w.backend != nil {
+		w.backend.SetCurrentProject(proj)
+		return proj, w.backend, nil
+	}
+
+	url, err := workspace.GetCurrentCloudURL(proj)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not get cloud url: %w", err)
+	}
+
+	sink := diag.DefaultSink(io.Discard, os.Stderr, diag.FormatOptions{Color: colors.Never})
+	var b backend.Backend
+	if filestate.IsFileStateBackendURL(url) {
+		b, err = filestate.New(ctx, sink, url, proj)
+	} else {
+		b, err = httpstate.NewLoginManager().Login(ctx, sink, url, proj, workspace.GetCloudInsecure(url),
+			display.Options{Color: colors.Never})
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	w.backend = b
+	return proj, b, nil
+}
+
+// getStack returns the stack with the given name, or an auto.StackNotFoundError if there is none.
+func (w *InProcessWorkspace) getStack(
+	ctx context.Context, stackName string,
+) (*workspace.Project, backend.Stack, error) {
+	proj, b, err := w.getBackend(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	ref, err := b.ParseStackReference(stackName)
+	if err != nil {
+		return nil, nil, err
+	}
+	s, err := b.GetStack(ctx, ref)
+	if err != nil {
+		return nil, nil, err
+	}
+	if s == nil {
+		return nil, nil, auto.StackNotFoundError{StackName: stackName}
+	}
+	return proj, s, nil
+}
+
+// WhoAmI returns the currently authenticated user.
+func (w *InProcessWorkspace) WhoAmI(ctx context.Context) (string, error) {
+	defer w.setEnv()()
+	_, b, err := w.getBackend(ctx)
+	if err != nil {
+		return "", err
+	}
+	user, _, err := b.CurrentUser()
+	if err != nil {
+		return "", fmt.Errorf("could not determine authenticated user: %w", err)
+	}
+	return user, nil
+}
+
+// WhoAmIDetails returns detailed information about the currently logged-in Pulumi identity.
+func (w *InProcessWorkspace) WhoAmIDetails(ctx context.Context) (auto.WhoAmIResult, error) {
+	defer w.setEnv()()
+	_, b, err := w.getBackend(ctx)
+	if err != nil {
+		return auto.WhoAmIResult{}, err
+	}
+	user, orgs, err := b.CurrentUser()
+	if err != nil {
+		return auto.WhoAmIResult{}, fmt.Errorf("could not determine authenticated user: %w", err)
+	}
+	return auto.WhoAmIResult{User: user, Organizations: orgs, URL: b.URL()}, nil
+}
+
+// Stack returns a summary of the currently selected stack, if any.
+func (w *InProcessWorkspace) Stack(ctx context.Context) (*auto.StackSummary, error) {
+	stacks, err := w.ListStacks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range stacks {
+		if s.Current {
+			return &s, nil
+		}
+	}
+	return nil, nil
+}
+
+// CreateStack creates and sets a new stack with the stack name, failing with an auto.StackAlreadyExistsError if one
+// already exists. The stack's secrets provider is configured from the workspace's SecretsProvider option, if any.
+func (w *InProcessWorkspace) CreateStack(ctx context.Context, stackName string) error {
+	defer w.setEnv()()
+	proj, b, err := w.getBackend(ctx)
+	if err != nil {
+		return err
+	}
+	ref, err := b.ParseStackReference(stackName)
+	if err != nil {
+		return err
+	}
+	s, err := b.CreateStack(ctx, ref, w.workDir, nil)
+	if err != nil {
+		var exists *backend.StackAlreadyExistsError
+		if errors.As(err, &exists) {
+			return auto.StackAlreadyExistsError{StackName: stackName}
+		}
+		return fmt.Errorf("failed to create stack: %w", err)
+	}
+	if err := w.createSecretsManager(ctx, proj, s); err != nil {
+		return fmt.Errorf("failed to create stack: %w", err)
+	}
+
+	w.m.Lock()
+	defer w.m.Unlock()
+	w.currentStack = stackName
+	return nil
+}
+
+// SelectStack selects and sets an existing stack matching the stack name, failing with an auto.StackNotFoundError
+// if none exists.
+func (w *InProcessWorkspace) SelectStack(ctx context.Context, stackName string) error {
+	defer w.setEnv()()
+	if _, _, err := w.getStack(ctx, stackName); err != nil {
+		return err
+	}
+
+	w.m.Lock()
+	defer w.m.Unlock()
+	w.currentStack = stackName
+	return nil
+}
+
+// RemoveStack deletes the stack and all associated configuration and history.
+func (w *InProcessWorkspace) RemoveStack(ctx context.Context, stackName string, opts ...optremove.Option) error {
+	defer w.setEnv()()
+	var o optremove.Options
+	for _, opt := range opts {
+		opt.ApplyOption(&o)
+	}
+
+	_, s, err := w.getStack(ctx, stackName)
+	if err != nil {
+		return err
+	}
+	hasResources, err := s.Remove(ctx, o.Force)
+	if err != nil {
+		if hasResources {
+			return fmt.Errorf("'%s' still has resources; removal rejected", s.Ref())
+		}
+		return fmt.Errorf("failed to remove stack: %w", err)
+	}
+
+	for _, ext := range settingsExtensions {
+		if err := os.Remove(w.stackSettingsPath(stackName, ext)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove stack settings: %w", err)
+		}
+	}
+
+	w.m.Lock()
+	defer w.m.Unlock()
+	if w.currentStack == stackName {
+		w.currentStack = ""
+	}
+	return nil
+}
+
+// ListStacks returns all Stacks created under the current Project.
+// This queries underlying backend and may return stacks not present in the Workspace.
+func (w *InProcessWorkspace) ListStacks(ctx context.Context) ([]auto.StackSummary, error) {
+	defer w.setEnv()()
+	proj, b, err := w.getBackend(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	projName := string(proj.Name)
+	filter := backend.ListStacksFilter{Project: &projName}
+	var summaries []backend.StackSummary
+	var token backend.ContinuationToken
+	for {
+		page, next, err := b.ListStacks(ctx, filter, token)
+		if err != nil {
+			return nil, fmt.Errorf("could not list stacks: %w", err)
+		}
+		summaries = append(summaries, page...)
+		if next == nil {
+			break
+		}
+		token = next
+	}
+
+	w.m.Lock()
+	current := w.currentStack
+	w.m.Unlock()
+
+	stacks := make([]auto.StackSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		name := summary.Name().String()
+		s := auto.StackSummary{
+			Name:          name,
+			Current:       current != "" && (name == current || summary.Name().Name().String() == current),
+			ResourceCount: summary.ResourceCount(),
+		}
+		if last := summary.LastUpdate(); last != nil {
+			s.LastUpdate = last.UTC().Format(time.RFC3339)
+		}
+		stacks = append(stacks, s)
+	}
+	sort.Slice(stacks, func(i, j int) bool { return stacks[i].Name < stacks[j].Name })
+	return stacks, nil
+}
+
+// GetTag returns the value associated with the specified stack name and key.
+func (w *InProcessWorkspace) GetTag(ctx context.Context, stackName string, key string) (string, error) {
+	tags, err := w.ListTags(ctx, stackName)
+	if err != nil {
+		return "", err
+	}
+	value, ok := tags[key]
+	if !ok {
+		return "", fmt.Errorf("stack tag '%s' not found for stack '%s'", key, stackName)
+	}
+	return value, nil
+}
+
+// SetTag sets the specified key-value pair on the provided stack name.
+func (w *InProcessWorkspace) SetTag(ctx context.Context, stackName string, key string, value string) error {
+	defer w.setEnv()()
+	_, s, err := w.getStack(ctx, stackName)
+	if err != nil {
+		return err
+	}
+	tags := s.Tags()
+	if tags == nil {
+		tags = map[apitype.StackTagName]string{}
+	}
+	tags[key] = value
+	return backend.UpdateStackTags(ctx, s, tags)
+}
+
+// RemoveTag removes the specified key-value pair on the provided stack name.
+func (w *InProcessWorkspace) RemoveTag(ctx context.Context, stackName string, key string) error {
+	defer w.setEnv()()
+	_, s, err := w.getStack(ctx, stackName)
+	if err != nil {
+		return err
+	}
+	tags := s.Tags()
+	delete(tags, key)
+	return backend.UpdateStackTags(ctx, s, tags)
+}
+
+// ListTags returns the tag map for the specified stack name.
+func (w *InProcessWorkspace) ListTags(ctx context.Context, stackName string) (map[string]string, error) {
+	defer w.setEnv()()
+	_, s, err := w.getStack(ctx, stackName)
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string]string, len(s.Tags()))
+	for k, v := range s.Tags() {
+		tags[k] = v
+	}
+	return tags, nil
+}
+
+// ExportStack exports the deployment state of the stack matching the given name.
+func (w *InProcessWorkspace) ExportStack(ctx context.Context, stackName string) (apitype.UntypedDeployment, error) {
+	defer w.setEnv()()
+	_, s, err := w.getStack(ctx, stackName)
+	if err != nil {
+		return apitype.UntypedDeployment{}, err
+	}
+	deployment, err := s.ExportDeployment(ctx)
+	if err != nil {
+		return apitype.UntypedDeployment{}, fmt.Errorf("could not export stack: %w", err)
+	}
+	return *deployment, nil
+}
+
+// ImportStack imports the specified deployment state into a pre-existing stack.
+func (w *InProcessWorkspace) ImportStack(
+	ctx context.Context, stackName string, state apitype.UntypedDeployment,
+) error {
+	defer w.setEnv()()
+	_, s, err := w.getStack(ctx, stackName)
+	if err != nil {
+		return err
+	}
+	if err := s.ImportDeployment(ctx, &state); err != nil {
+		return fmt.Errorf("could not import stack: %w", err)
+	}
+	return nil
+}
+
+// StackOutputs gets the current set of Stack outputs from the last Stack.Up().
+func (w *InProcessWorkspace) StackOutputs(ctx context.Context, stackName string) (auto.OutputMap, error) {
+	defer w.setEnv()()
+	_, s, err := w.getStack(ctx, stackName)
+	if err != nil {
+		return nil, err
+	}
+	snap, err := s.Snapshot(ctx, stack.DefaultSecretsProvider)
+	if err != nil {
+		return nil, fmt.Errorf("could not get outputs: %w", err)
+	}
+	root, err := stack.GetRootStackResource(snap)
+	if err != nil {
+		return nil, fmt.Errorf("could not get outputs: %w", err)
+	}
+
+	outputs := auto.OutputMap{}
+	if root == nil {
+		return outputs, nil
+	}
+	// MassageSecrets removes the secrets from the outputs, so the panic crypter is never used.
+	values, err := stack.SerializeProperties(display.MassageSecrets(root.Outputs, true),
+		config.NewPanicCrypter(), true /* showSecrets */)
+	if err != nil {
+		return nil, fmt.Errorf("could not get outputs: %w", err)
+	}
+	for k, v := range root.Outputs {
+		outputs[string(k)] = auto.OutputValue{
+			Value:  values[string(k)],
+			Secret: v.ContainsSecrets(),
+		}
+	}
+	return outputs, nil
+}
+
+// Option is a parameter to be applied to an InProcessWorkspace. Whatever its options, an InProcessWorkspace does not
+// run the Pulumi CLI, so the stack methods that need the CLI, such as ImportResources, QueryState, Cancel and the
+// State methods, return an error that wraps auto.ErrUnsupportedByEngine.
+type Option interface {
+	applyOption(*options)
+}
+
+// options are the configuration values of an InProcessWorkspace.
+type options struct {
+	// WorkDir is the directory of the workspace's project and stack settings.
+	WorkDir string
+	// Program is the inline program to run, if any.
+	Program pulumi.RunFunc
+	// PulumiHome overrides the metadata directory for pulumi commands.
+	PulumiHome string
+	// Project is the project settings to save in the workspace.
+	Project *workspace.Project
+	// Stacks is a map of stack settings to save in the workspace.
+	Stacks map[string]workspace.ProjectStack
+	// SecretsProvider is the secrets provider to use for new stacks.
+	SecretsProvider string
+	// EnvVars is a map of environment values to set.
+	EnvVars map[string]string
+}
+
+type optionFunc func(*options)
+
+func (o optionFunc) applyOption(opts *options) {
+	o(opts)
+}
+
+// WorkDir is the directory of the workspace's project and stack settings.
+func WorkDir(workDir string) Option {
+	return optionFunc(func(o *options) {
+		o.WorkDir = workDir
+	})
+}
+
+// Program is the Pulumi program to run in the workspace.
+func Program(program pulumi.RunFunc) Option {
+	return optionFunc(func(o *options) {
+		o.Program = program
+	})
+}
+
+// PulumiHome overrides the metadata directory for pulumi commands, by setting PULUMI_HOME in the process
+// environment while the workspace's operations run.
+func PulumiHome(dir string) Option {
+	return optionFunc(func(o *options) {
+		o.PulumiHome = dir
+	})
+}
+
+// Project sets project settings for the workspace, overwriting any Pulumi.yaml in the working directory.
+func Project(settings workspace.Project) Option {
+	return optionFunc(func(o *options) {
+		o.Project = &settings
+	})
+}
+
+// Stacks is a list of stack settings objects to seed the workspace.
+func Stacks(settings map[string]workspace.ProjectStack) Option {
+	return optionFunc(func(o *options) {
+		o.Stacks = settings
+	})
+}
+
+// SecretsProvider is the secrets provider to use with any new stacks created in the workspace.
+func SecretsProvider(secretsProvider string) Option {
+	return optionFunc(func(o *options) {
+		o.SecretsProvider = secretsProvider
+	})
+}
+
+// EnvVars is a map of environment values to set in the workspace, and in the environment of the process.
+func EnvVars(envvars map[string]string) Option {
+	return optionFunc(func(o *options) {
+		o.EnvVars = envvars
+	})
+}
@@ -0,0 +1,272 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package automation
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optwatch"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/display"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+)
+
+func setupInProcessTest(t *testing.T) string {
+	t.Setenv("PULUMI_BACKEND_URL", "file://"+filepath.ToSlash(t.TempDir()))
+	t.Setenv("PULUMI_CONFIG_PASSPHRASE", "correct horse battery staple")
+	return t.TempDir()
+}
+
+//nolint:paralleltest // sets environment variables
+func TestInProcessStackLifecycle(t *testing.T) {
+	ctx := context.Background()
+	workDir := setupInProcessTest(t)
+
+	program := func(ctx *pulumi.Context) error {
+		cfg := config.New(ctx, "")
+		ctx.Export("greeting", pulumi.String(cfg.Require("greeting")))
+		ctx.Export("password", cfg.RequireSecret("password"))
+		return nil
+	}
+	s, err := NewStackInlineSource(ctx, "dev", "inproc", program, WorkDir(workDir))
+	require.NoError(t, err)
+
+	_, err = NewStackInlineSource(ctx, "dev", "inproc", program, WorkDir(workDir))
+	assert.True(t, auto.IsCreateStack409Error(err), "expected a 409 error, got %v", err)
+	_, err = auto.SelectStack(ctx, "missing", s.Workspace())
+	assert.True(t, auto.IsSelectStack404Error(err), "expected a 404 error, got %v", err)
+
+	require.NoError(t, s.SetAllConfig(ctx, auto.ConfigMap{
+		"greeting": {Value: "hello"},
+		"password": {Value: "hunter2", Secret: true},
+	}))
+	password, err := s.GetConfig(ctx, "password")
+	require.NoError(t, err)
+	assert.Equal(t, auto.ConfigValue{Value: "hunter2", Secret: true}, password)
+
+	// The preview's events are sent directly to the event stream.
+	eventStream := make(chan events.EngineEvent)
+	var previewEvents []events.EngineEvent
+	eventsDone := make(chan struct{})
+	go func() {
+		defer close(eventsDone)
+		for e := range eventStream {
+			previewEvents = append(previewEvents, e)
+		}
+	}()
+	preview, err := s.Preview(ctx, optpreview.EventStreams(eventStream))
+	require.NoError(t, err)
+	<-eventsDone
+	assert.Equal(t, 1, preview.ChangeSummary[apitype.OpCreate])
+	require.Greater(t, len(previewEvents), 2)
+	assert.NotNil(t, previewEvents[0].PreludeEvent)
+	assert.NotNil(t, previewEvents[len(previewEvents)-2].SummaryEvent)
+	assert.NotNil(t, previewEvents[len(previewEvents)-1].CancelEvent)
+	require.Len(t, preview.ChangeSet.Resources, 1)
+	assert.Equal(t, "pulumi:pulumi:Stack", preview.ChangeSet.Resources[0].Type)
+	assert.Equal(t, display.StepOp("create"), preview.ChangeSet.Resources[0].Op)
+
+	logPath := filepath.Join(t.TempDir(), "events.jsonl")
+	eventLog, err := events.OpenLogFile(logPath)
+	require.NoError(t, err)
+	up, err := s.Up(ctx, optup.EventSinks(eventLog))
+	require.NoError(t, err)
+	require.NoError(t, eventLog.Close())
+	upEvents, err := events.ReadLogFile(logPath, 1)
+	require.NoError(t, err)
+	require.NotEmpty(t, upEvents)
+	assert.Equal(t, 1, upEvents[0].Sequence)
+	assert.NotNil(t, upEvents[len(upEvents)-1].CancelEvent)
+	assert.Equal(t, auto.OutputValue{Value: "hello"}, up.Outputs["greeting"])
+	assert.Equal(t, auto.OutputValue{Value: "hunter2", Secret: true}, up.Outputs["password"])
+	assert.Equal(t, "update", up.Summary.Kind)
+	assert.Equal(t, "succeeded", up.Summary.Result)
+	assert.Equal(t, auto.ConfigValue{Value: "hunter2", Secret: true}, up.Summary.Config["inproc:password"])
+	assert.Contains(t, up.StdOut, "Outputs:")
+	require.Len(t, up.ChangeSet.Resources, 1)
+	assert.Equal(t, display.StepOp("create"), up.ChangeSet.Resources[0].Op)
+	assert.Equal(t, 1, up.ChangeSet.Summary[apitype.OpCreate])
+
+	refresh, err := s.Refresh(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "refresh", refresh.Summary.Kind)
+
+	destroy, err := s.Destroy(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "destroy", destroy.Summary.Kind)
+
+	history, err := s.History(ctx, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.Equal(t, "destroy", history[0].Kind)
+	assert.Equal(t, "update", history[2].Kind)
+
+	require.NoError(t, s.Workspace().RemoveStack(ctx, "dev"))
+	stacks, err := s.Workspace().ListStacks(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, stacks)
+}
+
+//nolint:paralleltest // sets environment variables
+func TestInProcessProgramError(t *testing.T) {
+	ctx := context.Background()
+	workDir := setupInProcessTest(t)
+
+	s, err := NewStackInlineSource(ctx, "dev", "inproc", func(ctx *pulumi.Context) error {
+		return errors.New("the program failed")
+	}, WorkDir(workDir))
+	require.NoError(t, err)
+
+	var after *auto.Operation
+	s.Use(auto.AfterOperation(func(ctx context.Context, op *auto.Operation) {
+		after = op
+	}, auto.OperationUp))
+
+	up, err := s.Up(ctx)
+	require.Error(t, err)
+	assert.True(t, auto.IsRuntimeError(err), "expected a runtime error, got %v", err)
+	assert.Contains(t, err.Error(), "the program failed")
+
+	// The changes made before the program failed are still reported, to the caller and to the stack's hooks.
+	require.Len(t, up.ChangeSet.Resources, 1)
+	assert.Equal(t, "pulumi:pulumi:Stack", up.ChangeSet.Resources[0].Type)
+	require.NotNil(t, after)
+	require.NotNil(t, after.ChangeSet)
+	assert.Equal(t, up.ChangeSet, *after.ChangeSet)
+	assert.Nil(t, after.Summary)
+}
+
+//nolint:paralleltest // sets environment variables
+func TestInProcessWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	workDir := setupInProcessTest(t)
+
+	greeting := "hello"
+	s, err := NewStackInlineSource(ctx, "dev", "inproc", func(ctx *pulumi.Context) error {
+		ctx.Export("greeting", pulumi.String(greeting))
+		return nil
+	}, WorkDir(workDir))
+	require.NoError(t, err)
+
+	trigger, eventStream := make(chan struct{}), make(chan events.EngineEvent)
+	var cancelEvents int
+	eventsDone := make(chan struct{})
+	go func() {
+		defer close(eventsDone)
+		for e := range eventStream {
+			if e.CancelEvent != nil {
+				cancelEvents++
+			}
+		}
+	}()
+
+	results, err := s.Watch(ctx, optwatch.Trigger(trigger), optwatch.EventStreams(eventStream))
+	require.NoError(t, err)
+
+	first := <-results
+	require.NoError(t, first.Err)
+	assert.Equal(t, 1, first.Iteration)
+	assert.Equal(t, auto.OutputValue{Value: "hello"}, first.Result.Outputs["greeting"])
+
+	greeting = "goodbye"
+	trigger <- struct{}{}
+	second := <-results
+	require.NoError(t, second.Err)
+	assert.Equal(t, 2, second.Iteration)
+	assert.Equal(t, auto.OutputValue{Value: "goodbye"}, second.Result.Outputs["greeting"])
+
+	// Cancelling the watch closes its results and event streams.
+	cancel()
+	_, ok := <-results
+	assert.False(t, ok)
+	<-eventsDone
+	assert.Equal(t, 2, cancelEvents)
+
+	_, err = s.Watch(context.Background())
+	assert.ErrorContains(t, err, "an inline program must be watched with paths or a trigger")
+}
+
+//nolint:paralleltest // sets environment variables
+func TestInProcessPreviewPlan(t *testing.T) {
+	ctx := context.Background()
+	workDir := setupInProcessTest(t)
+
+	s, err := NewStackInlineSource(ctx, "dev", "inproc", func(ctx *pulumi.Context) error {
+		var component pulumi.ResourceState
+		return ctx.RegisterComponentResource("inproc:index:Component", "component", &component)
+	}, WorkDir(workDir))
+	require.NoError(t, err)
+
+	res, err := s.PreviewPlan(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, res.ChangeSummary[apitype.OpCreate])
+	require.Len(t, res.Plan.ResourcePlans, 2)
+	var componentURN resource.URN
+	for urn, plan := range res.Plan.ResourcePlans {
+		assert.Equal(t, []apitype.OpType{apitype.OpCreate}, plan.Steps)
+		if plan.Goal.Type == "inproc:index:Component" {
+			componentURN = urn
+		}
+	}
+	require.NotEmpty(t, componentURN)
+
+	// An update that the plan does not allow fails.
+	denied := *res.Plan
+	denied.ResourcePlans = map[resource.URN]apitype.ResourcePlanV1{}
+	for urn, plan := range res.Plan.ResourcePlans {
+		if urn != componentURN {
+			denied.ResourcePlans[urn] = plan
+		}
+	}
+	_, err = s.Up(ctx, optup.WithPlan(&denied))
+	assert.ErrorContains(t, err, "create is not allowed by the plan")
+
+	// The stack itself was created by the failed update, so only the component remains to be created.
+	up, err := s.Up(ctx, optup.WithPlan(res.Plan))
+	require.NoError(t, err)
+	assert.Equal(t, 1, up.ChangeSet.Summary[apitype.OpCreate])
+}
+
+//nolint:paralleltest // sets environment variables
+func TestInProcessUnsupportedOperations(t *testing.T) {
+	ctx := context.Background()
+	workDir := setupInProcessTest(t)
+
+	s, err := NewStackInlineSource(ctx, "dev", "inproc", func(ctx *pulumi.Context) error {
+		return nil
+	}, WorkDir(workDir))
+	require.NoError(t, err)
+
+	// The stack methods that would run the Pulumi CLI fail without running it.
+	_, err = s.QueryState(ctx, "true")
+	assert.ErrorIs(t, err, auto.ErrUnsupportedByEngine)
+	err = s.StateDelete(ctx, []string{"urn:pulumi:dev::inproc::pulumi:pulumi:Stack::inproc-dev"})
+	assert.ErrorIs(t, err, auto.ErrUnsupportedByEngine)
+	_, err = s.ImportResources(ctx, []auto.ImportResource{{Type: "random:index:RandomId", Name: "id", ID: "abc"}})
+	assert.ErrorIs(t, err, auto.ErrUnsupportedByEngine)
+}
@@ -66,6 +66,9 @@ func ShowEvents(
 	if opts.EventLogPath != "" {
 		events, done = startEventLogger(events, done, opts)
 	}
+	if opts.Events != nil {
+		events, done = startEventForwarder(events, done, opts.Events)
+	}
 
 	streamPreview := cmdutil.IsTruthy(os.Getenv("PULUMI_ENABLE_STREAMING_JSON_PREVIEW"))
 
@@ -165,6 +168,58 @@ func startEventLogger(events <-chan engine.Event, done chan<- bool, opts Options
 	return outEvents, outDone
 }
 
+// startEventForwarder sends each event to the given channel before passing it on to be displayed. Events are queued
+// until the receiver of the forwarded events is ready for them, so a slow receiver does not hold up the display; the
+// returned done channel is only closed once every event has been forwarded.
+func startEventForwarder(
+	events <-chan engine.Event, done chan<- bool, forward chan<- engine.Event,
+) (<-chan engine.Event, chan<- bool) {
+	queue, forwarded := make(chan engine.Event), make(chan struct{})
+	go func(queue <-chan engine.Event) {
+		defer close(forwarded)
+
+		var pending []engine.Event
+		for queue != nil || len(pending) > 0 {
+			var next chan<- engine.Event
+			var head engine.Event
+			if len(pending) > 0 {
+				next, head = forward, pending[0]
+			}
+
+			select {
+			case e, ok := <-queue:
+				if !ok {
+					queue = nil
+					continue
+				}
+				pending = append(pending, e)
+			case next <- head:
+				pending = pending[1:]
+			}
+		}
+	}(queue)
+
+	outEvents, outDone := make(chan engine.Event), make(chan bool)
+	go func() {
+		defer close(done)
+
+		for e := range events {
+			queue <- e
+			outEvents <- e
+
+			if e.Type == engine.CancelEvent {
+				break
+			}
+		}
+
+		<-outDone
+		close(queue)
+		<-forwarded
+	}()
+
+	return outEvents, outDone
+}
+
 type nopSpinner struct{}
 
 func (s *nopSpinner) Tick() {
@@ -0,0 +1,52 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/v3/engine"
+)
+
+func TestEventForwarderDoesNotBlockDisplay(t *testing.T) {
+	t.Parallel()
+
+	events, done, forward := make(chan engine.Event), make(chan bool), make(chan engine.Event)
+	outEvents, outDone := startEventForwarder(events, done, forward)
+
+	// The events are displayed even though nothing receives the forwarded events yet.
+	sent := []engine.Event{
+		engine.NewEvent(engine.StdoutColorEvent, engine.StdoutEventPayload{Message: "one"}),
+		engine.NewEvent(engine.StdoutColorEvent, engine.StdoutEventPayload{Message: "two"}),
+		engine.NewEvent(engine.CancelEvent, nil),
+	}
+	go func() {
+		for _, e := range sent {
+			events <- e
+		}
+	}()
+	for _, e := range sent {
+		assert.Equal(t, e, <-outEvents)
+	}
+	close(outDone)
+
+	// Every event is still forwarded, in order, before the display is done.
+	for _, e := range sent {
+		assert.Equal(t, e, <-forward)
+	}
+	<-done
+}
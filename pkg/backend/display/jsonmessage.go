@@ -18,9 +18,11 @@ package display
 // so we can customize parts of the display of our progress messages
 
 import (
+	"bufio"
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"unicode/utf8"
 
 	"github.com/pulumi/pulumi/pkg/v3/backend/display/internal/terminal"
@@ -92,6 +94,9 @@ type messageRenderer struct {
 	// Cache of lines we've already printed.  We don't print a progress message again if it hasn't
 	// changed between the last time we printed and now.
 	printedProgressCache map[string]Progress
+
+	// The reader from which the answers to prompts are read.
+	stdin *bufio.Reader
 }
 
 func newInteractiveMessageRenderer(term terminal.Terminal, opts Options) progressRenderer {
@@ -177,6 +182,26 @@ func (r *messageRenderer) println(display *ProgressDisplay, line string) {
 	r.writeSimpleMessage(line)
 }
 
+// prompt asks the question and reads the answer from stdin. The display is paused until the question is answered.
+func (r *messageRenderer) prompt(display *ProgressDisplay, p Prompt) {
+	if r.stdin == nil {
+		in := r.opts.Stdin
+		if in == nil {
+			in = os.Stdin
+		}
+		r.stdin = bufio.NewReader(in)
+	}
+
+	r.writeSimpleMessage(p.Message + " [y/N]")
+	line, err := r.stdin.ReadString('\n')
+	if err != nil && line == "" {
+		p.reply(false, fmt.Errorf("reading answer: %w", err))
+		return
+	}
+	answer := strings.ToLower(strings.TrimSpace(line))
+	p.reply(answer == "y" || answer == "yes", nil)
+}
+
 func (r *messageRenderer) tick(display *ProgressDisplay) {
 	if r.isInteractive {
 		r.render(display, false)
@@ -69,6 +69,7 @@ type progressRenderer interface {
 	systemMessage(display *ProgressDisplay, payload engine.StdoutEventPayload)
 	done(display *ProgressDisplay)
 	println(display *ProgressDisplay, line string)
+	prompt(display *ProgressDisplay, p Prompt)
 }
 
 // ProgressDisplay organizes all the information needed for a dynamically updated "progress" view of an update.
@@ -959,6 +960,9 @@ func (display *ProgressDisplay) processEvents(ticker *time.Ticker, events <-chan
 			}
 
 			display.processNormalEvent(event)
+
+		case p := <-display.opts.Prompts:
+			display.renderer.prompt(display, p)
 		}
 	}
 }
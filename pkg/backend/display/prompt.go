@@ -0,0 +1,73 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"context"
+	"errors"
+)
+
+// A Prompt is a yes/no question to ask the user while the progress display is running. Questions are sent to the
+// display on Options.Prompts so that asking them does not interfere with its rendering or its handling of input: the
+// interactive display shows the question in its status line and answers it with the next y or n key, and other
+// displays pause while they read the answer from stdin.
+type Prompt struct {
+	// Message is the question to ask. It may contain color directives.
+	Message string
+
+	answer chan promptAnswer
+}
+
+type promptAnswer struct {
+	yes bool
+	err error
+}
+
+// errDisplayDone is the error returned for questions that are still unanswered when the display finishes.
+var errDisplayDone = errors.New("the display finished before the question was answered")
+
+// Ask sends a yes/no question to the display that reads from the given channel and waits for the user's answer.
+func Ask(ctx context.Context, prompts chan<- Prompt, message string) (bool, error) {
+	p := Prompt{Message: message, answer: make(chan promptAnswer, 1)}
+	select {
+	case prompts <- p:
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+
+	select {
+	case a := <-p.answer:
+		return a.yes, a.err
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// reply answers the question.
+func (p Prompt) reply(yes bool, err error) {
+	p.answer <- promptAnswer{yes: yes, err: err}
+}
+
+// promptAnswerKey returns the answer given by a key pressed in response to a question, if the key answers it. Enter
+// declines, as the question's default is no.
+func promptAnswerKey(key string) (yes bool, ok bool) {
+	switch key {
+	case "y", "Y":
+		return true, true
+	case "n", "N", "\r", "\n":
+		return false, true
+	}
+	return false, false
+}
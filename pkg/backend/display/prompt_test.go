@@ -0,0 +1,95 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend/display/internal/terminal"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag/colors"
+)
+
+func newTestPrompt(message string) Prompt {
+	return Prompt{Message: message, answer: make(chan promptAnswer, 1)}
+}
+
+func TestTreeRendererPrompt(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	term := terminal.NewMockTerminal(&buf, 80, 24, true)
+	r := newInteractiveRenderer(term, "", Options{Color: colors.Raw}).(*treeRenderer)
+	r.ticker.Stop()
+
+	p := newTestPrompt("Do you want to delete resA?")
+	r.prompt(nil, p)
+	r.frame(false /* locked */, false /* done */)
+	assert.Contains(t, buf.String(), "Do you want to delete resA? [y/N]")
+
+	// Keys that do not answer the question are handled as usual.
+	term.SendKey(terminal.KeyUp)
+	term.SendKey("y")
+	a := <-p.answer
+	require.NoError(t, a.err)
+	assert.True(t, a.yes)
+
+	// Unanswered questions are declined when the display finishes.
+	p = newTestPrompt("Do you want to delete resB?")
+	r.prompt(nil, p)
+	r.done(&ProgressDisplay{})
+	a = <-p.answer
+	assert.ErrorIs(t, a.err, errDisplayDone)
+	assert.False(t, a.yes)
+}
+
+func TestMessageRendererPrompt(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	r := newNonInteractiveRenderer(&out, "update", Options{
+		Color: colors.Raw,
+		Stdin: strings.NewReader("yes\n\n"),
+	}).(*messageRenderer)
+
+	for _, expected := range []bool{true, false} {
+		p := newTestPrompt("Do you want to delete resA?")
+		r.prompt(nil, p)
+		a := <-p.answer
+		require.NoError(t, a.err)
+		assert.Equal(t, expected, a.yes)
+	}
+
+	p := newTestPrompt("Do you want to delete resB?")
+	r.prompt(nil, p)
+	assert.Error(t, (<-p.answer).err)
+
+	require.NoError(t, r.Close())
+	assert.Contains(t, out.String(), "Do you want to delete resA? [y/N]")
+}
+
+func TestAskCanceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Ask(ctx, make(chan Prompt), "Do you want to delete resA?")
+	assert.ErrorIs(t, err, context.Canceled)
+}
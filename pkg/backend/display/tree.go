@@ -47,6 +47,8 @@ type treeRenderer struct {
 	statusMessage         string
 	statusMessageDeadline time.Time
 
+	prompts []Prompt // The unanswered questions. The first is shown in place of the status message.
+
 	ticker *time.Ticker
 	keys   chan string
 	closed chan bool
@@ -103,9 +105,44 @@ func (r *treeRenderer) done(display *ProgressDisplay) {
 	r.closed <- true
 	close(r.closed)
 
+	r.m.Lock()
+	for _, p := range r.prompts {
+		p.reply(false, errDisplayDone)
+	}
+	r.prompts = nil
+	r.m.Unlock()
+
 	r.frame(false, true)
 }
 
+func (r *treeRenderer) prompt(display *ProgressDisplay, p Prompt) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	r.prompts = append(r.prompts, p)
+	r.dirty = true
+}
+
+// answerPrompt answers the question shown in the status line with the given key, and reports whether the key
+// answered it.
+func (r *treeRenderer) answerPrompt(key string) bool {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if len(r.prompts) == 0 {
+		return false
+	}
+	yes, ok := promptAnswerKey(key)
+	if !ok {
+		return false
+	}
+
+	r.prompts[0].reply(yes, nil)
+	r.prompts = r.prompts[1:]
+	r.dirty = true
+	return true
+}
+
 func (r *treeRenderer) showStatusMessage(msg string, duration time.Duration) {
 	r.m.Lock()
 	defer r.m.Unlock()
@@ -211,6 +248,9 @@ func (r *treeRenderer) frame(locked, done bool) {
 	treeTableRows := r.treeTableRows
 	systemMessages := r.systemMessages
 	statusMessage := r.statusMessage
+	if len(r.prompts) != 0 {
+		statusMessage = r.prompts[0].Message + " [y/N]"
+	}
 
 	var treeTableHeight int
 	var treeTableHeader string
@@ -225,7 +265,7 @@ func (r *treeRenderer) frame(locked, done bool) {
 	}
 
 	statusMessageHeight := 0
-	if !done && r.statusMessage != "" {
+	if !done && statusMessage != "" {
 		statusMessageHeight = 1
 	}
 
@@ -376,6 +416,10 @@ func (r *treeRenderer) handleEvents() {
 		case <-r.ticker.C:
 			r.frame(false, false)
 		case key := <-r.keys:
+			if r.answerPrompt(key) {
+				continue
+			}
+
 			switch key {
 			case terminal.KeyCtrlC:
 				sigint()
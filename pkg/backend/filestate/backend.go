@@ -42,9 +42,11 @@ import (
 	"github.com/pulumi/pulumi/pkg/v3/authhelpers"
 	"github.com/pulumi/pulumi/pkg/v3/backend"
 	"github.com/pulumi/pulumi/pkg/v3/backend/display"
+	"github.com/pulumi/pulumi/pkg/v3/backend/timing"
 	"github.com/pulumi/pulumi/pkg/v3/engine"
 	"github.com/pulumi/pulumi/pkg/v3/operations"
 	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy/replay"
 	"github.com/pulumi/pulumi/pkg/v3/resource/edit"
 	"github.com/pulumi/pulumi/pkg/v3/resource/stack"
 	"github.com/pulumi/pulumi/pkg/v3/secrets"
@@ -927,9 +929,15 @@ func (b *localBackend) apply(
 	stackName := stackRef.FullyQualifiedName()
 	actionLabel := backend.ActionLabel(kind, opts.DryRun)
 
+	// The banner and permalink are written with the rest of the display's output.
+	stdout := op.Opts.Display.Stdout
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+
 	if !(op.Opts.Display.JSONDisplay || op.Opts.Display.Type == display.DisplayWatch) {
 		// Print a banner so it's clear this is a local deployment.
-		fmt.Printf(op.Opts.Display.Color.Colorize(
+		fmt.Fprintf(stdout, op.Opts.Display.Color.Colorize(
 			colors.SpecHeadline+"%s (%s):"+colors.Reset+"\n"), actionLabel, stackRef)
 	}
 
@@ -950,10 +958,12 @@ func (b *localBackend) apply(
 	engineEvents := make(chan engine.Event)
 
 	scope := op.Scopes.NewScope(engineEvents, opts.DryRun)
+	timings := timing.NewRecorder()
 	eventsDone := make(chan bool)
 	go func() {
 		// Pull in all events from the engine and send them to the two listeners.
 		for e := range engineEvents {
+			timings.Record(e)
 			displayEvents <- e
 
 			// If the caller also wants to see the events, stream them there also.
@@ -975,6 +985,14 @@ func (b *localBackend) apply(
 		BackendClient:   backend.NewBackendClient(b, op.SecretsProvider),
 	}
 
+	// If the deployment is being recorded, record the state that it starts from.
+	if recording := env.RecordDeployment.Value(); recording != "" {
+		preview := kind == apitype.PreviewUpdate || opts.DryRun
+		if err := replay.WriteHeader(recording, kind, preview, op.Proj.Name, update.GetTarget()); err != nil {
+			return nil, nil, result.FromError(err)
+		}
+	}
+
 	// Perform the update
 	start := time.Now().Unix()
 	var plan *deploy.Plan
@@ -1023,6 +1041,7 @@ func (b *localBackend) apply(
 		//     rudely assume it knows where the checkpoint file is on disk as it makes a copy of it.  This isn't
 		//     trivial to achieve today given the event driven nature of plan-walking, however.
 		ResourceChanges: changes,
+		StepTimings:     timings.Steps(),
 	}
 
 	var saveErr error
@@ -1073,7 +1092,7 @@ func (b *localBackend) apply(
 		}
 
 		if link != "" {
-			fmt.Printf(op.Opts.Display.Color.Colorize(
+			fmt.Fprintf(stdout, op.Opts.Display.Color.Colorize(
 				colors.SpecHeadline+"Permalink: "+
 					colors.Underline+colors.BrightBlue+"%s"+colors.Reset+"\n"), link)
 		}
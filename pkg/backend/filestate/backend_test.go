@@ -1138,3 +1138,48 @@ func mapGetenv(m map[string]string) func(string) string {
 		return m[key]
 	}
 }
+
+func TestWithoutIntegrityChecking(t *testing.T) {
+	t.Parallel()
+
+	// The resource's parent does not exist, so the state fails its integrity checks.
+	sm := b64.NewBase64SecretsManager()
+	resources := []*resource.State{
+		{
+			URN:    resource.NewURN("a", "project", "", "a:b:c", "name"),
+			Type:   "a:b:c",
+			Parent: resource.NewURN("a", "project", "", "a:b:c", "missing"),
+		},
+	}
+	snap := deploy.NewSnapshot(deploy.Manifest{}, sm, resources, nil)
+	sdep, err := stack.SerializeDeployment(snap, snap.SecretsManager, false /* showSecrets */)
+	require.NoError(t, err)
+	data, err := encoding.JSON.Marshal(sdep)
+	require.NoError(t, err)
+
+	tmpDir := t.TempDir()
+	ctx := context.Background()
+	b, err := New(ctx, diagtest.LogSink(t), "file://"+filepath.ToSlash(tmpDir), nil)
+	require.NoError(t, err)
+
+	aStackRef, err := b.ParseStackReference("organization/project/a")
+	require.NoError(t, err)
+	aStack, err := b.CreateStack(ctx, aStackRef, "", nil)
+	require.NoError(t, err)
+	err = b.ImportDeployment(ctx, aStack, &apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(data)})
+	require.NoError(t, err)
+
+	_, err = b.GetStack(ctx, aStackRef)
+	assert.ErrorContains(t, err, "snapshot integrity failure")
+
+	// The stack can be loaded under a context that disables the checks, which does not affect other contexts.
+	aStack, err = b.GetStack(WithoutIntegrityChecking(ctx), aStackRef)
+	require.NoError(t, err)
+	loaded, err := aStack.Snapshot(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, loaded.Resources, 1)
+	assert.Error(t, loaded.VerifyIntegrity())
+
+	_, err = b.GetStack(ctx, aStackRef)
+	assert.ErrorContains(t, err, "snapshot integrity failure")
+}
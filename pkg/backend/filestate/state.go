@@ -51,6 +51,21 @@ const DisableCheckpointBackupsEnvVar = "PULUMI_DISABLE_CHECKPOINT_BACKUPS"
 // be used as a last resort when a command absolutely must be run.
 var DisableIntegrityChecking bool
 
+type integrityCheckingDisabledKey struct{}
+
+// WithoutIntegrityChecking returns a context under which stacks are loaded without verifying the integrity of their
+// checkpoint state, as if DisableIntegrityChecking were set, for commands that must operate on an invalid state.
+func WithoutIntegrityChecking(ctx context.Context) context.Context {
+	return context.WithValue(ctx, integrityCheckingDisabledKey{}, true)
+}
+
+// integrityCheckingDisabled returns true if checkpoint state integrity verification is disabled, either globally or
+// for the given context.
+func integrityCheckingDisabled(ctx context.Context) bool {
+	disabled, _ := ctx.Value(integrityCheckingDisabledKey{}).(bool)
+	return DisableIntegrityChecking || disabled
+}
+
 type localQuery struct {
 	root string
 	proj *workspace.Project
@@ -154,7 +169,7 @@ func (b *localBackend) getStack(
 	}
 
 	// Ensure the snapshot passes verification before returning it, to catch bugs early.
-	if !DisableIntegrityChecking {
+	if !integrityCheckingDisabled(ctx) {
 		if verifyerr := snapshot.VerifyIntegrity(); verifyerr != nil {
 			return nil, file, fmt.Errorf("%s: snapshot integrity failure; refusing to use it: %w", file, verifyerr)
 		}
@@ -237,6 +237,12 @@ func (ssm *sameSnapshotMutation) mustWrite(step *deploy.SameStep) bool {
 		return true
 	}
 
+	// If the lifecycle hooks of this resource have changed, we must write the checkpoint.
+	if !sameHooks(old.Hooks, new.Hooks) {
+		logging.V(9).Infof("SnapshotManager: mustWrite() true because of Hooks")
+		return true
+	}
+
 	// If the protection attribute of this resource has changed, we must write the checkpoint.
 	if old.Protect != new.Protect {
 		logging.V(9).Infof("SnapshotManager: mustWrite() true because of Protect")
@@ -277,6 +283,20 @@ func (ssm *sameSnapshotMutation) mustWrite(step *deploy.SameStep) bool {
 	return false
 }
 
+// sameHooks returns true if both lists register the same hooks in the same order. The addresses of the programs that
+// serve the hooks are not part of the checkpoint, so they are not compared.
+func sameHooks(old, new []resource.Hook) bool {
+	if len(old) != len(new) {
+		return false
+	}
+	for i := range old {
+		if old[i].Name != new[i].Name || old[i].Kind != new[i].Kind {
+			return false
+		}
+	}
+	return true
+}
+
 func (ssm *sameSnapshotMutation) End(step deploy.Step, successful bool) error {
 	contract.Requiref(step != nil, "step", "must not be nil")
 	contract.Requiref(step.Op() == deploy.OpSame, "step.Op()", "must be %q, got %q", deploy.OpSame, step.Op())
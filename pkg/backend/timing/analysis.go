@@ -0,0 +1,180 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timing
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+// PathStep is a step on the critical path.
+type PathStep struct {
+	Step
+	// Wait is the time between the end of the previous step on the critical path, or the start of the update, and
+	// the start of this step. Time spent waiting is usually time spent running the program.
+	Wait time.Duration `json:"wait"`
+}
+
+// Analysis is the result of analyzing the timing of an update.
+type Analysis struct {
+	// Start and End are the times at which the first step started and the last step finished.
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// Duration is the time between Start and End, in nanoseconds when serialized.
+	Duration time.Duration `json:"duration"`
+	// CriticalPath is the chain of steps that determined the duration of the update, in the order in which they ran.
+	// Each step on the path is the step that finished last among those that the next step had to wait for.
+	CriticalPath []PathStep `json:"criticalPath"`
+}
+
+// Analyze computes the critical path of an update from the timing of its steps.
+//
+// The critical path is found by walking backwards from the step that finished last. At each step, the walk moves to the
+// latest-finishing step that the step had to wait for: a step of a resource that it depends on, or an earlier step of
+// the same resource. Shortening any step on the critical path shortens the update, while shortening a step that is not
+// on the path does not.
+func Analyze(steps []Step) *Analysis {
+	if len(steps) == 0 {
+		return &Analysis{}
+	}
+
+	byURN := make(map[resource.URN][]int)
+	start, last := steps[0].Start, 0
+	for i, s := range steps {
+		byURN[s.URN] = append(byURN[s.URN], i)
+		if s.Start.Before(start) {
+			start = s.Start
+		}
+		if s.End.After(steps[last].End) {
+			last = i
+		}
+	}
+
+	// predecessor returns the index of the latest-finishing step that the given step waited for, or -1 if there is none.
+	predecessor := func(i int) int {
+		s, pred := steps[i], -1
+		consider := func(j int) {
+			if j == i || steps[j].End.After(s.Start) {
+				return
+			}
+			if pred == -1 || steps[j].End.After(steps[pred].End) {
+				pred = j
+			}
+		}
+		for _, j := range byURN[s.URN] {
+			consider(j)
+		}
+		for _, dep := range s.Dependencies {
+			for _, j := range byURN[dep] {
+				consider(j)
+			}
+		}
+		return pred
+	}
+
+	var path []PathStep
+	for i := last; i != -1; {
+		pred := predecessor(i)
+		prevEnd := start
+		if pred != -1 {
+			prevEnd = steps[pred].End
+		}
+		path = append(path, PathStep{Step: steps[i], Wait: steps[i].Start.Sub(prevEnd)})
+		i = pred
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+
+	end := steps[last].End
+	return &Analysis{
+		Start:        start,
+		End:          end,
+		Duration:     end.Sub(start),
+		CriticalPath: path,
+	}
+}
+
+// traceEvent is an event in the Chrome trace event format.
+type traceEvent struct {
+	Name      string                 `json:"name"`
+	Category  string                 `json:"cat,omitempty"`
+	Phase     string                 `json:"ph"`
+	Timestamp int64                  `json:"ts"`
+	Duration  int64                  `json:"dur,omitempty"`
+	PID       int                    `json:"pid"`
+	TID       int                    `json:"tid"`
+	Args      map[string]interface{} `json:"args,omitempty"`
+}
+
+// WriteChromeTrace writes the given steps to w in the Chrome trace event format, which can be loaded into
+// chrome://tracing or Perfetto. The steps on the critical path of the given analysis are shown on their own track;
+// the other steps are spread over as many tracks as are needed to show the steps that ran concurrently.
+func WriteChromeTrace(w io.Writer, steps []Step, analysis *Analysis) error {
+	onPath := make(map[stepKey]bool)
+	for _, s := range analysis.CriticalPath {
+		onPath[stepKey{s.URN, s.Op}] = true
+	}
+
+	sorted := make([]Step, len(steps))
+	copy(sorted, steps)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+
+	events := []traceEvent{{
+		Name: "thread_name", Phase: "M", PID: 1, TID: 0,
+		Args: map[string]interface{}{"name": "critical path"},
+	}}
+
+	// Assign each step that is not on the critical path to the first track that is free when the step starts.
+	var tracks []time.Time
+	for _, s := range sorted {
+		tid := 0
+		if !onPath[stepKey{s.URN, s.Op}] {
+			for tid = 1; tid <= len(tracks); tid++ {
+				if !tracks[tid-1].After(s.Start) {
+					break
+				}
+			}
+			if tid > len(tracks) {
+				tracks = append(tracks, time.Time{})
+			}
+			tracks[tid-1] = s.End
+		}
+
+		args := map[string]interface{}{"urn": string(s.URN), "type": string(s.Type)}
+		if s.Failed {
+			args["failed"] = true
+		}
+		events = append(events, traceEvent{
+			Name:      string(s.URN.Name()),
+			Category:  string(s.Op),
+			Phase:     "X",
+			Timestamp: s.Start.Sub(analysis.Start).Microseconds(),
+			Duration:  s.Duration().Microseconds(),
+			PID:       1,
+			TID:       tid,
+			Args:      args,
+		})
+	}
+
+	return json.NewEncoder(w).Encode(map[string]interface{}{
+		"traceEvents":     events,
+		"displayTimeUnit": "ms",
+	})
+}
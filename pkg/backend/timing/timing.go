@@ -0,0 +1,186 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package timing records the timing of the steps executed during an update and analyzes it to find the update's
+// critical path: the chain of dependent steps that determined how long the update took.
+package timing
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pulumi/pulumi/pkg/v3/engine"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/v3/resource/graph"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/display"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+)
+
+// Step is the timing of a single step executed during an update.
+type Step struct {
+	URN  resource.URN   `json:"urn"`
+	Type tokens.Type    `json:"type"`
+	Op   display.StepOp `json:"op"`
+	// Start and End are the times at which the step started and finished.
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// Failed is true if the step failed.
+	Failed bool `json:"failed,omitempty"`
+	// Dependencies are the URNs of the resources whose steps had to finish before this step could start.
+	Dependencies []resource.URN `json:"dependencies,omitempty"`
+}
+
+// Duration returns the time taken by the step.
+func (s Step) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// isDelete returns true if the step removes its resource. Such steps wait for the resources that depend on their
+// resource rather than for the resources that their resource depends on.
+func isDelete(op display.StepOp) bool {
+	switch op {
+	case deploy.OpDelete, deploy.OpDeleteReplaced, deploy.OpReadDiscard, deploy.OpDiscardReplaced,
+		deploy.OpRemovePendingReplace:
+		return true
+	default:
+		return false
+	}
+}
+
+type stepKey struct {
+	urn resource.URN
+	op  display.StepOp
+}
+
+type recordedStep struct {
+	Step
+	state *resource.State
+}
+
+// Recorder records the timing of steps from the events emitted by the engine during an update.
+type Recorder struct {
+	m       sync.Mutex
+	now     func() time.Time
+	steps   []*recordedStep
+	running map[stepKey]*recordedStep
+}
+
+// NewRecorder creates a new recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{
+		now:     time.Now,
+		running: make(map[stepKey]*recordedStep),
+	}
+}
+
+// Record records the given engine event. Events must be recorded as they are emitted: the time at which an event is
+// recorded is taken as the time at which its step started or finished.
+func (r *Recorder) Record(e engine.Event) {
+	switch e.Type {
+	case engine.ResourcePreEvent:
+		p := e.Payload().(engine.ResourcePreEventPayload)
+		if !p.Planning {
+			r.start(p.Metadata)
+		}
+	case engine.ResourceOutputsEvent:
+		p := e.Payload().(engine.ResourceOutputsEventPayload)
+		if !p.Planning {
+			r.finish(p.Metadata, false)
+		}
+	case engine.ResourceOperationFailed:
+		p := e.Payload().(engine.ResourceOperationFailedPayload)
+		r.finish(p.Metadata, true)
+	}
+}
+
+func (r *Recorder) start(md engine.StepEventMetadata) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	step := &recordedStep{Step: Step{URN: md.URN, Type: md.Type, Op: md.Op, Start: r.now()}}
+	if md.Res != nil {
+		step.state = md.Res.State
+	}
+	r.steps = append(r.steps, step)
+	r.running[stepKey{md.URN, md.Op}] = step
+}
+
+func (r *Recorder) finish(md engine.StepEventMetadata, failed bool) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	key := stepKey{md.URN, md.Op}
+	if step, ok := r.running[key]; ok {
+		step.End, step.Failed = r.now(), failed
+		delete(r.running, key)
+	}
+}
+
+// Steps returns the steps that have finished, in the order in which they started, along with their dependencies.
+func (r *Recorder) Steps() []Step {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	// Steps start in topological order, so the states of the steps that create or update resources are in a valid order
+	// for a dependency graph. Deletes start in reverse topological order.
+	var creates, deletes []*resource.State
+	seen := make(map[*resource.State]bool)
+	for _, s := range r.steps {
+		if s.state == nil || seen[s.state] {
+			continue
+		}
+		seen[s.state] = true
+		if isDelete(s.Op) {
+			deletes = append([]*resource.State{s.state}, deletes...)
+		} else {
+			creates = append(creates, s.state)
+		}
+	}
+	createGraph, deleteGraph := graph.NewDependencyGraph(creates), graph.NewDependencyGraph(deletes)
+
+	steps := make([]Step, 0, len(r.steps))
+	for _, s := range r.steps {
+		if s.End.IsZero() {
+			continue
+		}
+
+		step := s.Step
+		if s.state != nil {
+			var deps []*resource.State
+			if isDelete(s.Op) {
+				deps = deleteGraph.DependingOn(s.state, nil, true)
+			} else {
+				deps = createGraph.DependenciesOf(s.state).ToArray()
+			}
+			step.Dependencies = dependencyURNs(deps)
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+func dependencyURNs(deps []*resource.State) []resource.URN {
+	seen := make(map[resource.URN]bool)
+	var urns []resource.URN
+	for _, dep := range deps {
+		if !seen[dep.URN] {
+			seen[dep.URN] = true
+			urns = append(urns, dep.URN)
+		}
+	}
+	sort.Slice(urns, func(i, j int) bool { return urns[i] < urns[j] })
+	return urns
+}
@@ -0,0 +1,163 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timing
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/pkg/v3/engine"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/display"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+)
+
+type testRecorder struct {
+	*Recorder
+	t0  time.Time
+	now time.Time
+}
+
+func newTestRecorder() *testRecorder {
+	t0 := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := &testRecorder{Recorder: NewRecorder(), t0: t0, now: t0}
+	r.Recorder.now = func() time.Time { return r.now }
+	return r
+}
+
+func (r *testRecorder) at(seconds int) *testRecorder {
+	r.now = r.t0.Add(time.Duration(seconds) * time.Second)
+	return r
+}
+
+func (r *testRecorder) start(op display.StepOp, state *resource.State) {
+	r.Record(engine.NewEvent(engine.ResourcePreEvent, engine.ResourcePreEventPayload{
+		Metadata: metadata(op, state),
+	}))
+}
+
+func (r *testRecorder) finish(op display.StepOp, state *resource.State) {
+	r.Record(engine.NewEvent(engine.ResourceOutputsEvent, engine.ResourceOutputsEventPayload{
+		Metadata: metadata(op, state),
+	}))
+}
+
+func metadata(op display.StepOp, state *resource.State) engine.StepEventMetadata {
+	return engine.StepEventMetadata{
+		Op:   op,
+		URN:  state.URN,
+		Type: state.Type,
+		Res:  &engine.StepEventStateMetadata{State: state},
+	}
+}
+
+func newState(name string, deps ...*resource.State) *resource.State {
+	urn := resource.NewURN("test", "test", "", "pkgA:m:typA", tokens.QName(name))
+	var depURNs []resource.URN
+	for _, d := range deps {
+		depURNs = append(depURNs, d.URN)
+	}
+	return &resource.State{URN: urn, Type: urn.Type(), Custom: true, Dependencies: depURNs}
+}
+
+func TestCriticalPath(t *testing.T) {
+	t.Parallel()
+
+	a := newState("a")
+	b := newState("b", a)
+	c := newState("c")
+	d := newState("d", c)
+
+	r := newTestRecorder()
+	r.at(0).start(deploy.OpCreate, a)
+	r.at(0).start(deploy.OpCreate, c)
+	r.at(2).finish(deploy.OpCreate, c)
+	r.at(3).start(deploy.OpCreate, d)
+	r.at(5).finish(deploy.OpCreate, d)
+	r.at(10).finish(deploy.OpCreate, a)
+	r.at(11).start(deploy.OpUpdate, b)
+	r.at(15).finish(deploy.OpUpdate, b)
+
+	steps := r.Steps()
+	require.Len(t, steps, 4)
+	assert.Equal(t, []resource.URN{a.URN}, steps[3].Dependencies)
+
+	analysis := Analyze(steps)
+	assert.Equal(t, 15*time.Second, analysis.Duration)
+	require.Len(t, analysis.CriticalPath, 2)
+	assert.Equal(t, a.URN, analysis.CriticalPath[0].URN)
+	assert.Equal(t, time.Duration(0), analysis.CriticalPath[0].Wait)
+	assert.Equal(t, b.URN, analysis.CriticalPath[1].URN)
+	assert.Equal(t, time.Second, analysis.CriticalPath[1].Wait)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteChromeTrace(&buf, steps, analysis))
+	var trace struct {
+		TraceEvents []traceEvent `json:"traceEvents"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &trace))
+	require.Len(t, trace.TraceEvents, 5)
+	tids := make(map[string]int)
+	for _, e := range trace.TraceEvents[1:] {
+		tids[e.Name] = e.TID
+	}
+	// a and b are on the critical path; c and d ran one after the other on a single track.
+	assert.Equal(t, map[string]int{"a": 0, "b": 0, "c": 1, "d": 1}, tids)
+	assert.Equal(t, int64(11_000_000), trace.TraceEvents[4].Timestamp)
+	assert.Equal(t, int64(4_000_000), trace.TraceEvents[4].Duration)
+}
+
+func TestCriticalPathDeletes(t *testing.T) {
+	t.Parallel()
+
+	a := newState("a")
+	b := newState("b", a)
+	c := newState("c")
+
+	// b must be deleted before a, while c is deleted concurrently.
+	r := newTestRecorder()
+	r.at(0).start(deploy.OpDelete, b)
+	r.at(0).start(deploy.OpDelete, c)
+	r.at(1).finish(deploy.OpDelete, c)
+	r.at(4).finish(deploy.OpDelete, b)
+	r.at(4).start(deploy.OpDelete, a)
+	r.at(6).finish(deploy.OpDelete, a)
+
+	steps := r.Steps()
+	require.Len(t, steps, 3)
+
+	analysis := Analyze(steps)
+	require.Len(t, analysis.CriticalPath, 2)
+	assert.Equal(t, b.URN, analysis.CriticalPath[0].URN)
+	assert.Equal(t, a.URN, analysis.CriticalPath[1].URN)
+	assert.Equal(t, 6*time.Second, analysis.Duration)
+}
+
+func TestUnfinishedStepsAreIgnored(t *testing.T) {
+	t.Parallel()
+
+	a := newState("a")
+	r := newTestRecorder()
+	r.at(0).start(deploy.OpCreate, a)
+
+	assert.Empty(t, r.Steps())
+	assert.Empty(t, Analyze(r.Steps()).CriticalPath)
+}
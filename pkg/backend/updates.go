@@ -15,6 +15,7 @@
 package backend
 
 import (
+	"github.com/pulumi/pulumi/pkg/v3/backend/timing"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/display"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/config"
@@ -117,4 +118,7 @@ type UpdateInfo struct {
 	Result          UpdateResult            `json:"result"`
 	EndTime         int64                   `json:"endTime"`
 	ResourceChanges display.ResourceChanges `json:"resourceChanges,omitempty"`
+
+	// StepTimings records the timing of the steps executed by the update. Only the filestate backends record it.
+	StepTimings []timing.Step `json:"stepTimings,omitempty"`
 }
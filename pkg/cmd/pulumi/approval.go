@@ -0,0 +1,134 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend/display"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag/colors"
+)
+
+// approvalCallbackTimeout is the time allowed for each call to an approval callback.
+const approvalCallbackTimeout = 10 * time.Minute
+
+// newApprovalPolicy returns the approval policy for the patterns passed to --require-approval. Approval is requested
+// from the callback URL if one is given, and from the user otherwise. The user is asked by the progress display, if
+// that is the display that the given options select, so that the question does not interfere with its rendering.
+func newApprovalPolicy(patterns []string, callback string, opts *display.Options) (*deploy.ApprovalPolicy, error) {
+	if len(patterns) == 0 && callback == "" {
+		return nil, nil
+	}
+
+	if callback != "" {
+		return deploy.NewApprovalPolicy(patterns, approveWithCallback(callback)), nil
+	}
+	if !opts.IsInteractive {
+		return nil, errors.New("--require-approval can only be used in interactive mode")
+	}
+	if opts.Type == display.DisplayProgress && !opts.JSONDisplay {
+		opts.Prompts = make(chan display.Prompt)
+		return deploy.NewApprovalPolicy(patterns, approveWithDisplay(opts.Prompts)), nil
+	}
+	return deploy.NewApprovalPolicy(patterns, approveWithPrompt(*opts)), nil
+}
+
+// approvalMessage returns the question that asks the user to approve the given step.
+func approvalMessage(step deploy.Step) string {
+	verb := "delete"
+	if step.Op() == deploy.OpReplace {
+		verb = "replace"
+	}
+	return fmt.Sprintf("%sDo you want to %s %s?%s", colors.SpecAttention, verb, step.URN(), colors.Reset)
+}
+
+// approveWithDisplay asks the user to approve each step through the progress display that reads from prompts.
+func approveWithDisplay(prompts chan<- display.Prompt) deploy.StepApprovalFunc {
+	return func(ctx context.Context, step deploy.Step) (bool, error) {
+		return display.Ask(ctx, prompts, approvalMessage(step))
+	}
+}
+
+// approveWithPrompt asks the user to approve each step on stdout, for displays that only print lines.
+func approveWithPrompt(opts display.Options) deploy.StepApprovalFunc {
+	out := opts.Stdout
+	if out == nil {
+		out = os.Stdout
+	}
+	in := opts.Stdin
+	if in == nil {
+		in = os.Stdin
+	}
+	reader := bufio.NewReader(in)
+
+	return func(_ context.Context, step deploy.Step) (bool, error) {
+		fmt.Fprint(out, opts.Color.Colorize("\n"+approvalMessage(step)+" [y/N]: "))
+
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			return false, fmt.Errorf("reading approval: %w", err)
+		}
+		answer := strings.ToLower(strings.TrimSpace(line))
+		return answer == "y" || answer == "yes", nil
+	}
+}
+
+// approveWithCallback asks the approval callback at the given URL to approve each step. The callback is sent an
+// apitype.StepApprovalRequest and must respond with an apitype.StepApprovalResponse.
+func approveWithCallback(url string) deploy.StepApprovalFunc {
+	client := &http.Client{Timeout: approvalCallbackTimeout}
+	return func(ctx context.Context, step deploy.Step) (bool, error) {
+		body, err := json.Marshal(apitype.StepApprovalRequest{
+			URN:  string(step.URN()),
+			Type: string(step.Type()),
+			Op:   apitype.OpType(step.Op()),
+		})
+		if err != nil {
+			return false, err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return false, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return false, fmt.Errorf("calling approval callback: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return false, fmt.Errorf("approval callback returned %v", resp.Status)
+		}
+
+		var approval apitype.StepApprovalResponse
+		if err := json.NewDecoder(resp.Body).Decode(&approval); err != nil {
+			return false, fmt.Errorf("decoding approval callback response: %w", err)
+		}
+		return approval.Approved, nil
+	}
+}
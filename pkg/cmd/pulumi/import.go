@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/blang/semver"
@@ -360,12 +361,91 @@ func generateImportedDefinitions(ctx *plugin.Context,
 	}, resources, names)
 }
 
+// importProgramGenerator returns the function that generates code for imported resources in the given language.
+func importProgramGenerator(language string) (programGeneratorFunc, error) {
+	switch language {
+	case "dotnet":
+		return dotnet.GenerateProgram, nil
+	case "go":
+		return gogen.GenerateProgram, nil
+	case "nodejs":
+		return nodejs.GenerateProgram, nil
+	case "python":
+		return python.GenerateProgram, nil
+	case "java":
+		return javagen.GenerateProgram, nil
+	case "yaml":
+		return yamlgen.GenerateProgram, nil
+	default:
+		return nil, fmt.Errorf("cannot generate resource definitions for %v", language)
+	}
+}
+
+// importCodeExtensions are the extensions of the files that generated code is written to when --out is a directory.
+// The Automation API reads the generated code from these files, so sdk/go/auto must be updated when they change.
+var importCodeExtensions = map[string]string{
+	"dotnet": ".cs",
+	"go":     ".go",
+	"nodejs": ".ts",
+	"python": ".py",
+	"java":   ".java",
+	"yaml":   ".yaml",
+}
+
+// importOutputDir returns the directory that the generated code in each language is written to, or "" if --out is
+// not a directory.
+func importOutputDir(outputFilePath string, languages []string, generateCode bool) (string, error) {
+	if info, err := os.Stat(outputFilePath); err == nil && info.IsDir() {
+		return outputFilePath, nil
+	}
+	if len(languages) > 1 && generateCode {
+		return "", errors.New("--out must be a directory when code is generated in more than one language")
+	}
+	return "", nil
+}
+
+// writeImportedDefinitions generates the definitions of the imported resources in each of the given languages. If
+// outputDir is set, the definitions in each language are written to a file in it named after the language; otherwise
+// they are written to out.
+func writeImportedDefinitions(ctx *plugin.Context,
+	out io.Writer, outputDir string, languages []string, stackName tokens.Name, projectName tokens.PackageName,
+	snap *deploy.Snapshot, names importer.NameTable, imports []deploy.Import, protectResources bool,
+) (bool, error) {
+	var validImports bool
+	for _, language := range languages {
+		programGenerator, err := importProgramGenerator(language)
+		if err != nil {
+			return false, err
+		}
+
+		var code bytes.Buffer
+		w := out
+		if outputDir != "" {
+			w = &code
+		}
+		validImports, err = generateImportedDefinitions(
+			ctx, w, stackName, projectName, snap, programGenerator, names, imports, protectResources)
+		if err != nil {
+			return false, err
+		}
+
+		if outputDir != "" && validImports {
+			path := filepath.Join(outputDir, language+importCodeExtensions[language])
+			if err := os.WriteFile(path, code.Bytes(), 0o600); err != nil {
+				return false, fmt.Errorf("could not write output file: %w", err)
+			}
+		}
+	}
+	return validImports, nil
+}
+
 func newImportCmd() *cobra.Command {
 	var parentSpec string
 	var providerSpec string
 	var importFilePath string
 	var outputFilePath string
 	var generateCode bool
+	var languages []string
 
 	var debug bool
 	var message string
@@ -459,7 +539,12 @@ func newImportCmd() *cobra.Command {
 			"Each resource may specify which input properties to import with;\n" +
 			"\n" +
 			"If a resource does not specify any properties the default behaviour is to\n" +
-			"import using all required properties.\n",
+			"import using all required properties.\n" +
+			"\n" +
+			"Definitions can be generated in other languages than the project's with --language.\n" +
+			"If --out is a directory, the definitions in each language are written to a file in it\n" +
+			"named after the language, such as nodejs.ts or python.py. --out must be a directory\n" +
+			"if more than one language is given.\n",
 		Run: cmdutil.RunResultFunc(func(cmd *cobra.Command, args []string) result.Result {
 			ctx := commandContext()
 
@@ -538,7 +623,11 @@ func newImportCmd() *cobra.Command {
 
 			var outputResult bytes.Buffer
 			output := io.Writer(&outputResult)
-			if outputFilePath != "" {
+			outputDir, err := importOutputDir(outputFilePath, languages, generateCode)
+			if err != nil {
+				return result.FromError(err)
+			}
+			if outputDir == "" && outputFilePath != "" {
 				f, err := os.Create(outputFilePath)
 				if err != nil {
 					return result.Errorf("could not open output file: %v", err)
@@ -604,22 +693,14 @@ func newImportCmd() *cobra.Command {
 				return result.FromError(err)
 			}
 
-			var programGenerator programGeneratorFunc
-			switch proj.Runtime.Name() {
-			case "dotnet":
-				programGenerator = dotnet.GenerateProgram
-			case "go":
-				programGenerator = gogen.GenerateProgram
-			case "nodejs":
-				programGenerator = nodejs.GenerateProgram
-			case "python":
-				programGenerator = python.GenerateProgram
-			case "java":
-				programGenerator = javagen.GenerateProgram
-			case "yaml":
-				programGenerator = yamlgen.GenerateProgram
-			default:
-				return result.Errorf("cannot generate resource definitions for %v", proj.Runtime.Name())
+			if len(languages) == 0 {
+				languages = []string{proj.Runtime.Name()}
+			}
+			// Check that code can be generated in every language before anything is imported.
+			for _, language := range languages {
+				if _, err = importProgramGenerator(language); err != nil {
+					return result.FromError(err)
+				}
 			}
 
 			// Fetch the current stack.
@@ -678,8 +759,8 @@ func newImportCmd() *cobra.Command {
 					return result.FromError(err)
 				}
 
-				validImports, err := generateImportedDefinitions(
-					pCtx, output, s.Ref().Name(), proj.Name, deployment, programGenerator, nameTable, imports,
+				validImports, err := writeImportedDefinitions(
+					pCtx, output, outputDir, languages, s.Ref().Name(), proj.Name, deployment, nameTable, imports,
 					protectResources)
 				if err != nil {
 					if _, ok := err.(*importer.DiagnosticsError); ok {
@@ -730,9 +811,13 @@ func newImportCmd() *cobra.Command {
 	cmd.PersistentFlags().StringVarP(
 		&importFilePath, "file", "f", "", "The path to a JSON-encoded file containing a list of resources to import")
 	cmd.PersistentFlags().StringVarP(
-		&outputFilePath, "out", "o", "", "The path to the file that will contain the generated resource declarations")
+		&outputFilePath, "out", "o", "",
+		"The path to the file, or the directory, that will contain the generated resource declarations")
 	cmd.PersistentFlags().BoolVar(
 		&generateCode, "generate-code", true, "Generate resource declaration code for the imported resources")
+	cmd.PersistentFlags().StringSliceVar(
+		&languages, "language", nil,
+		"The languages to generate resource declaration code in. Defaults to the language of the project")
 
 	cmd.PersistentFlags().BoolVarP(
 		&debug, "debug", "d", false,
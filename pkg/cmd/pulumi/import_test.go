@@ -1,10 +1,20 @@
 package main
 
 import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/pkg/v3/codegen/testing/utils"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/plugin"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
 )
 
 func TestParseImportFile_errors(t *testing.T) {
@@ -164,3 +174,90 @@ func TestParseImportFile_errors(t *testing.T) {
 		})
 	}
 }
+
+func TestImportOutputDir(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "index.ts")
+
+	outputDir, err := importOutputDir(dir, []string{"nodejs", "python"}, true)
+	require.NoError(t, err)
+	assert.Equal(t, dir, outputDir)
+
+	outputDir, err = importOutputDir(file, []string{"nodejs"}, true)
+	require.NoError(t, err)
+	assert.Equal(t, "", outputDir)
+
+	_, err = importOutputDir(file, []string{"nodejs", "python"}, true)
+	assert.ErrorContains(t, err, "--out must be a directory")
+
+	// An existing file is overwritten with the code in a single language, but can't hold the code in several.
+	require.NoError(t, os.WriteFile(file, []byte("// existing"), 0o600))
+	outputDir, err = importOutputDir(file, []string{"python"}, true)
+	require.NoError(t, err)
+	assert.Equal(t, "", outputDir)
+	_, err = importOutputDir(file, []string{"nodejs", "python", "go"}, true)
+	assert.ErrorContains(t, err, "--out must be a directory")
+
+	// The code in every language is written to the directory.
+	outputDir, err = importOutputDir(dir, []string{"dotnet", "go", "java", "nodejs", "python", "yaml"}, true)
+	require.NoError(t, err)
+	assert.Equal(t, dir, outputDir)
+
+	// No code is written, so the languages don't matter.
+	outputDir, err = importOutputDir(file, []string{"nodejs", "python"}, false)
+	require.NoError(t, err)
+	assert.Equal(t, "", outputDir)
+}
+
+func TestWriteImportedDefinitions(t *testing.T) {
+	t.Parallel()
+
+	host := utils.NewHost(filepath.Join("..", "..", "codegen", "testing", "test", "testdata"))
+	ctx := &plugin.Context{Host: host}
+
+	typ := tokens.Type("random:index/randomId:RandomId")
+	urn := resource.NewURN("dev", "proj", "", typ, "id")
+	snap := &deploy.Snapshot{
+		Resources: []*resource.State{{
+			URN:    urn,
+			Type:   typ,
+			Custom: true,
+			ID:     "abc",
+			Inputs: resource.PropertyMap{"byteLength": resource.NewNumberProperty(8)},
+		}},
+	}
+	imports := []deploy.Import{{Type: typ, Name: "id", ID: "abc"}}
+
+	// The code in each language is written to a file named after the language.
+	dir := t.TempDir()
+	valid, err := writeImportedDefinitions(
+		ctx, io.Discard, dir, []string{"nodejs", "python"}, "dev", "proj", snap, nil, imports, false)
+	require.NoError(t, err)
+	assert.True(t, valid)
+	nodejs, err := os.ReadFile(filepath.Join(dir, "nodejs.ts"))
+	require.NoError(t, err)
+	assert.Contains(t, string(nodejs), "new random.RandomId(")
+	python, err := os.ReadFile(filepath.Join(dir, "python.py"))
+	require.NoError(t, err)
+	assert.Contains(t, string(python), "random.RandomId(")
+
+	// Without a directory, the code is written to the output.
+	var out bytes.Buffer
+	valid, err = writeImportedDefinitions(ctx, &out, "", []string{"python"}, "dev", "proj", snap, nil, imports, false)
+	require.NoError(t, err)
+	assert.True(t, valid)
+	assert.Contains(t, out.String(), "random.RandomId(")
+
+	// Nothing is generated for resources that were not imported.
+	missing := []deploy.Import{{Type: typ, Name: "missing", ID: "def"}}
+	valid, err = writeImportedDefinitions(
+		ctx, io.Discard, dir, []string{"go"}, "dev", "proj", snap, nil, missing, false)
+	require.NoError(t, err)
+	assert.False(t, valid)
+	assert.NoFileExists(t, filepath.Join(dir, "go.go"))
+
+	_, err = writeImportedDefinitions(ctx, io.Discard, "", []string{"cobol"}, "dev", "proj", snap, nil, imports, false)
+	assert.ErrorContains(t, err, "cannot generate resource definitions for cobol")
+}
@@ -365,6 +365,7 @@ func NewPulumiCmd() *cobra.Command {
 				newViewTraceCmd(),
 				newConvertTraceCmd(),
 				newReplayEventsCmd(),
+				newReplayDeploymentCmd(),
 			},
 		},
 	})
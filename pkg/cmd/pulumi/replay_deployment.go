@@ -0,0 +1,214 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend/display"
+	"github.com/pulumi/pulumi/pkg/v3/engine"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy/replay"
+	"github.com/pulumi/pulumi/pkg/v3/util/cancel"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/config"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/result"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/workspace"
+)
+
+func newReplayDeploymentCmd() *cobra.Command {
+	var deployment int
+
+	var jsonDisplay bool
+	var diffDisplay bool
+	var showSames bool
+	var debug bool
+
+	cmd := &cobra.Command{
+		Use:   "replay-deployment [recording-file]",
+		Short: "Replay deployments from a recording",
+		Long: "Replay deployments from a recording.\n" +
+			"\n" +
+			"This command re-runs the deployments recorded by a prior invocation of the\n" +
+			"Pulumi CLI with PULUMI_RECORD_DEPLOYMENT set to the recording file.\n" +
+			"\n" +
+			"Each deployment is run by the engine against the state that it started from,\n" +
+			"with stub providers that answer each call with its recorded response and a stub\n" +
+			"program that registers the recorded resources. No real providers are called and\n" +
+			"the stack's state is not changed.\n",
+		Args:   cmdutil.ExactArgs(1),
+		Hidden: !hasDebugCommands(),
+		Run: cmdutil.RunResultFunc(func(cmd *cobra.Command, args []string) result.Result {
+			ctx := commandContext()
+
+			displayType := display.DisplayProgress
+			if diffDisplay {
+				displayType = display.DisplayDiff
+			}
+			displayOpts := display.Options{
+				Color:             cmdutil.GetGlobalColorization(),
+				ShowSameResources: showSames,
+				IsInteractive:     cmdutil.Interactive(),
+				Type:              displayType,
+				JSONDisplay:       jsonDisplay,
+				Debug:             debug,
+			}
+
+			recordings, err := replay.Load(ctx, args[0])
+			if err != nil {
+				return result.FromError(fmt.Errorf("error reading recording: %w", err))
+			}
+			if deployment < 0 || deployment > len(recordings) {
+				return result.Errorf("the recording holds %d deployment(s)", len(recordings))
+			}
+			if deployment != 0 {
+				recordings = recordings[deployment-1 : deployment]
+			}
+
+			var failed bool
+			for _, rec := range recordings {
+				if res := replayDeployment(ctx, rec, displayOpts); res != nil {
+					if res.IsBail() {
+						failed = true
+						continue
+					}
+					return res
+				}
+			}
+			if failed {
+				return result.Bail()
+			}
+			return nil
+		}),
+	}
+
+	cmd.PersistentFlags().IntVar(
+		&deployment, "deployment", 0,
+		"The number of the deployment in the recording to replay, starting at 1. Defaults to all deployments.")
+
+	cmd.PersistentFlags().BoolVarP(
+		&debug, "debug", "d", false,
+		"Print detailed debugging output during resource operations")
+	cmd.PersistentFlags().BoolVar(
+		&diffDisplay, "diff", false,
+		"Display operation as a rich diff showing the overall change")
+	cmd.Flags().BoolVarP(
+		&jsonDisplay, "json", "j", false,
+		"Serialize the preview diffs, operations, and overall output as JSON")
+	cmd.PersistentFlags().BoolVar(
+		&showSames, "show-sames", false,
+		"Show resources that needn't be updated because they haven't changed, alongside those that do")
+
+	return cmd
+}
+
+// replayDeployment runs the engine against the given recorded deployment and displays its events.
+func replayDeployment(ctx context.Context, rec *replay.Recording, opts display.Options) result.Result {
+	kind := rec.Kind
+	if kind == "" {
+		kind = apitype.UpdateUpdate
+	}
+
+	switch kind {
+	case apitype.PreviewUpdate, apitype.UpdateUpdate, apitype.RefreshUpdate, apitype.DestroyUpdate:
+	default:
+		// The resources to import are passed to the engine directly rather than by the program, so they are not
+		// recorded.
+		return result.Errorf("replaying %v deployments is not supported", kind)
+	}
+
+	host, err := rec.PluginHost(cmdutil.Diag(), cmdutil.Diag())
+	if err != nil {
+		return result.FromError(fmt.Errorf("loading recorded deployment: %w", err))
+	}
+	info := &replayUpdateInfo{
+		project: workspace.Project{Name: rec.Project, Runtime: workspace.NewProjectRuntimeInfo("replay", nil)},
+		target: deploy.Target{
+			Name:      rec.Stack,
+			Decrypter: config.NopDecrypter,
+			Snapshot:  rec.Snapshot,
+		},
+	}
+
+	displayEvents, displayDone := make(chan engine.Event), make(chan bool)
+	go display.ShowEvents(
+		"replay", kind, rec.Stack, rec.Project, "", displayEvents, displayDone, opts, rec.Preview)
+
+	cancelCtx, cancelSrc := cancel.NewContext(ctx)
+	defer cancelSrc.Cancel()
+	journal := engine.NewJournal()
+	engineCtx := &engine.Context{
+		Cancel:          cancelCtx,
+		Events:          displayEvents,
+		SnapshotManager: journal,
+		BackendClient:   replayBackendClient{},
+	}
+
+	engineOpts := engine.UpdateOptions{Host: host}
+	var res result.Result
+	switch kind {
+	case apitype.PreviewUpdate, apitype.UpdateUpdate:
+		_, _, res = engine.Update(info, engineCtx, engineOpts, rec.Preview)
+	case apitype.RefreshUpdate:
+		_, _, res = engine.Refresh(info, engineCtx, engineOpts, rec.Preview)
+	case apitype.DestroyUpdate:
+		_, _, res = engine.Destroy(info, engineCtx, engineOpts, rec.Preview)
+	}
+	<-displayDone
+	close(displayEvents)
+	contract.IgnoreClose(journal)
+	return res
+}
+
+// replayUpdateInfo describes a replayed deployment to the engine.
+type replayUpdateInfo struct {
+	project workspace.Project
+	target  deploy.Target
+}
+
+func (u *replayUpdateInfo) GetRoot() string {
+	return ""
+}
+
+func (u *replayUpdateInfo) GetProject() *workspace.Project {
+	return &u.project
+}
+
+func (u *replayUpdateInfo) GetTarget() *deploy.Target {
+	return &u.target
+}
+
+// replayBackendClient is the backend client used by replayed deployments. The outputs of other stacks are read by the
+// engine's builtin provider rather than by a plugin, so they are not recorded and cannot be replayed.
+type replayBackendClient struct{}
+
+var errReplayStackReference = errors.New("stack references cannot be replayed")
+
+func (replayBackendClient) GetStackOutputs(ctx context.Context, name string) (resource.PropertyMap, error) {
+	return nil, fmt.Errorf("reading %v: %w", name, errReplayStackReference)
+}
+
+func (replayBackendClient) GetStackResourceOutputs(
+	ctx context.Context, name string,
+) (resource.PropertyMap, error) {
+	return nil, fmt.Errorf("reading %v: %w", name, errReplayStackReference)
+}
@@ -0,0 +1,64 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend/display"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy/replay"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag/colors"
+)
+
+func TestReplayDeployment(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "recording.json")
+	target := &deploy.Target{Name: "stack"}
+	require.NoError(t, replay.WriteHeader(path, apitype.UpdateUpdate, false, "proj", target))
+
+	// The recorded program registers its stack.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	require.NoError(t, json.NewEncoder(f).Encode(replay.Entry{
+		Method:   "/pulumirpc.ResourceMonitor/RegisterResource",
+		Request:  json.RawMessage(`{"type":"pulumi:pulumi:Stack","name":"proj-stack"}`),
+		Response: json.RawMessage(`{"urn":"urn:pulumi:stack::proj::pulumi:pulumi:Stack::proj-stack"}`),
+		Metadata: map[string]interface{}{"mode": "server"},
+	}))
+	require.NoError(t, f.Close())
+	require.NoError(t, replay.WriteHeader(path, apitype.ResourceImportUpdate, false, "proj", target))
+
+	recordings, err := replay.Load(context.Background(), path)
+	require.NoError(t, err)
+	require.Len(t, recordings, 2)
+
+	opts := display.Options{Color: colors.Never, Type: display.DisplayDiff}
+	assert.Nil(t, replayDeployment(context.Background(), recordings[0], opts))
+
+	// Imports cannot be replayed.
+	res := replayDeployment(context.Background(), recordings[1], opts)
+	require.NotNil(t, res)
+	assert.ErrorContains(t, res.Error(), "replaying resource-import deployments is not supported")
+}
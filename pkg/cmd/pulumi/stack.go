@@ -179,6 +179,8 @@ func newStackCmd() *cobra.Command {
 	cmd.Flags().BoolVar(
 		&showStackName, "show-name", false, "Display only the stack name")
 
+	cmd.AddCommand(newStackAnalyzeTimingCmd())
+	cmd.AddCommand(newStackDiffCmd())
 	cmd.AddCommand(newStackExportCmd())
 	cmd.AddCommand(newStackGraphCmd())
 	cmd.AddCommand(newStackImportCmd())
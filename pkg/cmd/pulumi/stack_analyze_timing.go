@@ -0,0 +1,146 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend/display"
+	"github.com/pulumi/pulumi/pkg/v3/backend/httpstate"
+	"github.com/pulumi/pulumi/pkg/v3/backend/timing"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
+)
+
+func newStackAnalyzeTimingCmd() *cobra.Command {
+	var stackName string
+	var update int
+	var format string
+	var outputFile string
+
+	cmd := &cobra.Command{
+		Use:   "analyze-timing",
+		Args:  cmdutil.NoArgs,
+		Short: "Show the critical path of a stack's update",
+		Long: "Show the critical path of a stack's update.\n" +
+			"\n" +
+			"This command shows the chain of dependent steps that determined how long an update took. Shortening\n" +
+			"any step on the critical path shortens the update; shortening any other step does not. The time\n" +
+			"spent waiting before each step on the path is usually time spent running the program.\n" +
+			"\n" +
+			"The analysis can also be written as JSON, or as a Chrome trace of every step in the update that can\n" +
+			"be loaded into chrome://tracing or Perfetto.\n" +
+			"\n" +
+			"The update to analyze is given by --update as a count back through the stack's history: 1 is the\n" +
+			"most recent update, 2 the one before it, and so on. It is not an update's version number.\n" +
+			"\n" +
+			"Step timings are only recorded by the self-managed backends; the Pulumi service backend does not\n" +
+			"record them.",
+		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
+			ctx := commandContext()
+			opts := display.Options{
+				Color: cmdutil.GetGlobalColorization(),
+			}
+
+			if update < 1 {
+				return errors.New("--update must be at least 1")
+			}
+			switch format {
+			case "text", "json", "chrome-trace":
+			default:
+				return fmt.Errorf("unknown format %q: expected one of text, json, or chrome-trace", format)
+			}
+
+			s, err := requireStack(ctx, stackName, stackLoadOnly, opts)
+			if err != nil {
+				return err
+			}
+			if _, isCloud := s.Backend().(httpstate.Backend); isCloud {
+				return errors.New("the Pulumi service backend does not record step timings; " +
+					"analyze-timing only supports stacks in self-managed backends")
+			}
+			updates, err := s.Backend().GetHistory(ctx, s.Ref(), 1 /*pageSize*/, update /*page*/)
+			if err != nil {
+				return fmt.Errorf("getting history: %w", err)
+			}
+			if len(updates) == 0 {
+				return fmt.Errorf("stack %s has fewer than %d updates", s.Ref(), update)
+			}
+			steps := updates[0].StepTimings
+			if len(steps) == 0 {
+				return errors.New("no step timings were recorded for this update")
+			}
+			analysis := timing.Analyze(steps)
+
+			out := io.Writer(os.Stdout)
+			if outputFile != "" {
+				f, err := os.Create(outputFile)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				out = f
+			}
+
+			switch format {
+			case "json":
+				return fprintJSON(out, analysis)
+			case "chrome-trace":
+				return timing.WriteChromeTrace(out, steps, analysis)
+			default:
+				return printCriticalPath(out, analysis)
+			}
+		}),
+	}
+
+	cmd.PersistentFlags().StringVarP(
+		&stackName, "stack", "s", "", "The name of the stack to operate on. Defaults to the current stack")
+	cmd.PersistentFlags().IntVar(
+		&update, "update", 1,
+		"The update to analyze, counting back from the most recent update (1); this is not a version number")
+	cmd.PersistentFlags().StringVar(
+		&format, "format", "text", "The output format: text, json, or chrome-trace")
+	cmd.PersistentFlags().StringVarP(
+		&outputFile, "output", "o", "", "Write the output to this file instead of stdout")
+	return cmd
+}
+
+func printCriticalPath(w io.Writer, analysis *timing.Analysis) error {
+	var onPath time.Duration
+	for _, s := range analysis.CriticalPath {
+		onPath += s.Duration()
+	}
+	fmt.Fprintf(w, "Critical path: %d steps, %v of %v spent in steps on the path\n\n",
+		len(analysis.CriticalPath), onPath.Round(time.Millisecond), analysis.Duration.Round(time.Millisecond))
+
+	rows := make([]cmdutil.TableRow, 0, len(analysis.CriticalPath))
+	for _, s := range analysis.CriticalPath {
+		rows = append(rows, cmdutil.TableRow{Columns: []string{
+			s.Wait.Round(time.Millisecond).String(),
+			s.Duration().Round(time.Millisecond).String(),
+			string(s.Op),
+			string(s.URN),
+		}})
+	}
+	return cmdutil.FprintTable(w, cmdutil.Table{
+		Headers: []string{"WAIT", "DURATION", "OP", "URN"},
+		Rows:    rows,
+	})
+}
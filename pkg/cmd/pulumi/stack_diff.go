@@ -0,0 +1,341 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend"
+	"github.com/pulumi/pulumi/pkg/v3/backend/display"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/v3/resource/edit"
+	"github.com/pulumi/pulumi/pkg/v3/resource/stack"
+	"github.com/pulumi/pulumi/pkg/v3/secrets"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag/colors"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/config"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
+)
+
+func newStackDiffCmd() *cobra.Command {
+	var stackName string
+	var from, to string
+	var fromFile, toFile string
+	var showSecrets bool
+	var jsonOut bool
+
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Args:  cmdutil.NoArgs,
+		Short: "Show the changes to a stack's state between two versions",
+		Long: "Show the changes to a stack's state between two versions.\n" +
+			"\n" +
+			"This command compares two versions of a stack's state, as listed by `pulumi stack history`,\n" +
+			"and prints the resources that were added, removed or modified between them, along with the\n" +
+			"changes to their inputs and outputs. Either version can instead be read from a file that was\n" +
+			"written by `pulumi stack export`. If no newer version is given, the stack's current state is\n" +
+			"used.\n" +
+			"\n" +
+			"Resources are matched by URN, or by alias if they were renamed. Secret values are not decrypted\n" +
+			"or shown unless --show-secrets is passed; without it, a secret is reported as changed if its\n" +
+			"ciphertext changed.",
+		Example: "pulumi stack diff --from 41 --to 57\n" +
+			"pulumi stack diff --from-file before.json --to-file after.json --json",
+		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
+			ctx := commandContext()
+			opts := display.Options{
+				Color: cmdutil.GetGlobalColorization(),
+			}
+
+			if (from == "") == (fromFile == "") {
+				return errors.New("exactly one of --from and --from-file must be given")
+			}
+			if to != "" && toFile != "" {
+				return errors.New("only one of --to and --to-file may be given")
+			}
+
+			// The stack is only loaded if one of the versions is read from its history.
+			var s backend.Stack
+			requireDiffStack := func() (backend.Stack, error) {
+				if s != nil {
+					return s, nil
+				}
+				var err error
+				s, err = requireStack(ctx, stackName, stackLoadOnly, opts)
+				return s, err
+			}
+
+			older, err := loadStackDiffSnapshot(ctx, requireDiffStack, from, fromFile, showSecrets)
+			if err != nil {
+				return err
+			}
+			newer, err := loadStackDiffSnapshot(ctx, requireDiffStack, to, toFile, showSecrets)
+			if err != nil {
+				return err
+			}
+
+			if showSecrets {
+				revealSnapshotSecrets(older)
+				revealSnapshotSecrets(newer)
+				if s != nil {
+					log3rdPartySecretsProviderDecryptionEvent(ctx, s, "", "pulumi stack diff")
+				}
+			}
+
+			deltas := edit.CompareSnapshots(older, newer)
+			if jsonOut {
+				return printJSON(stackDiffJSON(deltas))
+			}
+			printStackDiff(opts, deltas)
+			return nil
+		}),
+	}
+
+	cmd.PersistentFlags().StringVarP(
+		&stackName, "stack", "s", "", "The name of the stack to operate on. Defaults to the current stack")
+	cmd.Flags().StringVar(&from, "from", "", "The version of the stack's state to compare from")
+	cmd.Flags().StringVar(&to, "to", "",
+		"The version of the stack's state to compare to. Defaults to the current state")
+	cmd.Flags().StringVar(&fromFile, "from-file", "", "A file written by `pulumi stack export` to compare from")
+	cmd.Flags().StringVar(&toFile, "to-file", "", "A file written by `pulumi stack export` to compare to")
+	cmd.Flags().BoolVar(&showSecrets, "show-secrets", false, "Show secret values in plaintext")
+	cmd.Flags().BoolVarP(&jsonOut, "json", "j", false, "Emit output as JSON")
+	return cmd
+}
+
+// loadStackDiffSnapshot reads a snapshot from the given file, or from the given version of the stack's history, or,
+// if neither is given, from the stack's current state. Its secrets are only decrypted if showSecrets is set.
+func loadStackDiffSnapshot(
+	ctx context.Context, requireStack func() (backend.Stack, error), version, file string, showSecrets bool,
+) (*deploy.Snapshot, error) {
+	var deployment *apitype.UntypedDeployment
+	stackName := ""
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, fmt.Errorf("could not open file: %w", err)
+		}
+		defer f.Close()
+		if err = json.NewDecoder(f).Decode(&deployment); err != nil {
+			return nil, fmt.Errorf("could not read %s: %w", file, err)
+		}
+	} else {
+		s, err := requireStack()
+		if err != nil {
+			return nil, err
+		}
+		stackName = s.Ref().Name().String()
+
+		if version == "" {
+			deployment, err = s.ExportDeployment(ctx)
+		} else {
+			be := s.Backend()
+			specificExpBE, ok := be.(backend.SpecificDeploymentExporter)
+			if !ok {
+				return nil, fmt.Errorf(
+					"the current backend (%s) does not provide the ability to export previous deployments; "+
+						"use --from-file and --to-file with files written by `pulumi stack export`", be.Name())
+			}
+			deployment, err = specificExpBE.ExportDeploymentForVersion(ctx, s, version)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var secretsProvider secrets.Provider = ciphertextSecretsProvider{}
+	if showSecrets {
+		secretsProvider = stack.DefaultSecretsProvider
+	}
+	snap, err := stack.DeserializeUntypedDeployment(ctx, deployment, secretsProvider)
+	if err != nil {
+		return nil, checkDeploymentVersionError(err, stackName)
+	}
+	return snap, nil
+}
+
+// ciphertextSecretsProvider is a secrets.Provider for reading snapshots without decrypting their secrets: each secret
+// is read as its ciphertext, so two secrets compare equal only if their ciphertexts do.
+type ciphertextSecretsProvider struct{}
+
+func (ciphertextSecretsProvider) OfType(ty string, state json.RawMessage) (secrets.Manager, error) {
+	return &ciphertextSecretsManager{ty: ty, state: state}, nil
+}
+
+type ciphertextSecretsManager struct {
+	ty    string
+	state json.RawMessage
+}
+
+func (sm *ciphertextSecretsManager) Type() string       { return sm.ty }
+func (sm *ciphertextSecretsManager) State() interface{} { return sm.state }
+func (sm *ciphertextSecretsManager) Encrypter() (config.Encrypter, error) {
+	return config.BlindingCrypter, nil
+}
+func (sm *ciphertextSecretsManager) Decrypter() (config.Decrypter, error) {
+	return ciphertextDecrypter{}, nil
+}
+
+// ciphertextDecrypter "decrypts" a secret to its ciphertext, as a JSON string.
+type ciphertextDecrypter struct{}
+
+func (ciphertextDecrypter) DecryptValue(ctx context.Context, ciphertext string) (string, error) {
+	plaintext, err := json.Marshal(ciphertext)
+	return string(plaintext), err
+}
+
+func (d ciphertextDecrypter) BulkDecrypt(ctx context.Context, ciphertexts []string) (map[string]string, error) {
+	return config.DefaultBulkDecrypt(ctx, d, ciphertexts)
+}
+
+// revealSnapshotSecrets replaces the secret values in the inputs and outputs of the snapshot's resources with their
+// plaintext values.
+func revealSnapshotSecrets(snap *deploy.Snapshot) {
+	for _, res := range snap.Resources {
+		res.Inputs = revealSecrets(resource.NewObjectProperty(res.Inputs)).ObjectValue()
+		res.Outputs = revealSecrets(resource.NewObjectProperty(res.Outputs)).ObjectValue()
+	}
+}
+
+func revealSecrets(v resource.PropertyValue) resource.PropertyValue {
+	switch {
+	case v.IsSecret():
+		return revealSecrets(v.SecretValue().Element)
+	case v.IsOutput():
+		o := v.OutputValue()
+		o.Element, o.Secret = revealSecrets(o.Element), false
+		return resource.NewOutputProperty(o)
+	case v.IsArray():
+		arr := make([]resource.PropertyValue, len(v.ArrayValue()))
+		for i, e := range v.ArrayValue() {
+			arr[i] = revealSecrets(e)
+		}
+		return resource.NewArrayProperty(arr)
+	case v.IsObject():
+		obj := make(resource.PropertyMap, len(v.ObjectValue()))
+		for k, e := range v.ObjectValue() {
+			obj[k] = revealSecrets(e)
+		}
+		return resource.NewObjectProperty(obj)
+	default:
+		return v
+	}
+}
+
+// printStackDiff prints each changed resource, followed by the changes to its fields and properties.
+func printStackDiff(opts display.Options, deltas []edit.ResourceDelta) {
+	var added, removed, modified int
+	for _, d := range deltas {
+		var line string
+		switch d.Kind {
+		case edit.ChangeAdded:
+			added++
+			line = colors.SpecCreate + "+ " + string(d.URN()) + colors.Reset
+		case edit.ChangeRemoved:
+			removed++
+			line = colors.SpecDelete + "- " + string(d.URN()) + colors.Reset
+		default:
+			modified++
+			line = colors.SpecUpdate + "~ " + string(d.URN()) + colors.Reset
+			if d.Renamed() {
+				line += fmt.Sprintf(" (renamed from %s)", d.Old.URN)
+			}
+		}
+		fmt.Println(opts.Color.Colorize(line))
+
+		if len(d.Fields) > 0 {
+			fmt.Printf("    changed: %s\n", strings.Join(d.Fields, ", "))
+		}
+		for _, props := range []struct {
+			name string
+			diff *resource.ObjectDiff
+		}{{"inputs", d.Inputs}, {"outputs", d.Outputs}} {
+			if props.diff == nil {
+				continue
+			}
+			var buf bytes.Buffer
+			display.PrintObjectDiff(&buf, *props.diff, nil, false /*planning*/, 2, false /*summary*/, false, false)
+			fmt.Printf("    %s:\n%s", props.name, opts.Color.Colorize(buf.String()))
+		}
+	}
+	fmt.Printf("%d added, %d removed, %d modified\n", added, removed, modified)
+}
+
+type resourceDeltaJSON struct {
+	URN     resource.URN                  `json:"urn"`
+	OldURN  resource.URN                  `json:"oldUrn,omitempty"`
+	Kind    edit.ChangeKind               `json:"kind"`
+	Fields  []string                      `json:"fields,omitempty"`
+	Inputs  map[string]propertyChangeJSON `json:"inputs,omitempty"`
+	Outputs map[string]propertyChangeJSON `json:"outputs,omitempty"`
+}
+
+// A propertyChangeJSON holds the old and new values of a changed property. A value is omitted if the property was
+// added or deleted.
+type propertyChangeJSON struct {
+	Old interface{} `json:"old,omitempty"`
+	New interface{} `json:"new,omitempty"`
+}
+
+func stackDiffJSON(deltas []edit.ResourceDelta) []resourceDeltaJSON {
+	changes := func(diff *resource.ObjectDiff) map[string]propertyChangeJSON {
+		if diff == nil {
+			return nil
+		}
+		// Secrets that were not revealed are redacted.
+		redact := func(v resource.PropertyValue) (interface{}, bool) {
+			if v.IsSecret() || v.IsOutput() && v.OutputValue().Secret {
+				return "[secret]", true
+			}
+			return nil, false
+		}
+		result := make(map[string]propertyChangeJSON)
+		for k, v := range diff.Adds {
+			result[string(k)] = propertyChangeJSON{New: v.MapRepl(nil, redact)}
+		}
+		for k, v := range diff.Deletes {
+			result[string(k)] = propertyChangeJSON{Old: v.MapRepl(nil, redact)}
+		}
+		for k, v := range diff.Updates {
+			result[string(k)] = propertyChangeJSON{Old: v.Old.MapRepl(nil, redact), New: v.New.MapRepl(nil, redact)}
+		}
+		return result
+	}
+
+	result := make([]resourceDeltaJSON, 0, len(deltas))
+	for _, d := range deltas {
+		j := resourceDeltaJSON{
+			URN:     d.URN(),
+			Kind:    d.Kind,
+			Fields:  d.Fields,
+			Inputs:  changes(d.Inputs),
+			Outputs: changes(d.Outputs),
+		}
+		if d.Renamed() {
+			j.OldURN = d.Old.URN
+		}
+		result = append(result, j)
+	}
+	return result
+}
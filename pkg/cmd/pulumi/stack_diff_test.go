@@ -0,0 +1,77 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/v3/resource/stack"
+	"github.com/pulumi/pulumi/pkg/v3/secrets/b64"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+func TestLoadStackDiffSnapshotSecrets(t *testing.T) {
+	t.Parallel()
+
+	sm := b64.NewBase64SecretsManager()
+	snap := deploy.NewSnapshot(deploy.Manifest{}, sm, []*resource.State{{
+		URN:     "urn:pulumi:stack::project::pkg:index:typ::res",
+		Type:    "pkg:index:typ",
+		Custom:  true,
+		Outputs: resource.PropertyMap{"password": resource.MakeSecret(resource.NewStringProperty("hunter2"))},
+	}}, nil)
+	deployment, err := stack.SerializeDeployment(snap, sm, false /* showSecrets */)
+	require.NoError(t, err)
+	raw, err := json.Marshal(deployment)
+	require.NoError(t, err)
+	bytes, err := json.Marshal(apitype.UntypedDeployment{Version: 3, Deployment: raw})
+	require.NoError(t, err)
+	file := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, os.WriteFile(file, bytes, 0o600))
+
+	noStack := func() (backend.Stack, error) {
+		t.Fatal("the stack should not be loaded")
+		return nil, nil
+	}
+	password := func(showSecrets bool) resource.PropertyValue {
+		loaded, err := loadStackDiffSnapshot(context.Background(), noStack, "", file, showSecrets)
+		require.NoError(t, err)
+		require.Len(t, loaded.Resources, 1)
+		return loaded.Resources[0].Outputs["password"]
+	}
+
+	// Without --show-secrets, the secret is read as its ciphertext rather than decrypted.
+	hidden := password(false)
+	require.True(t, hidden.IsSecret())
+	enc, err := sm.Encrypter()
+	require.NoError(t, err)
+	expected, err := enc.EncryptValue(context.Background(), `"hunter2"`)
+	require.NoError(t, err)
+	assert.Equal(t, resource.NewStringProperty(expected), hidden.SecretValue().Element)
+
+	shown := password(true)
+	require.True(t, shown.IsSecret())
+	assert.Equal(t, resource.NewStringProperty("hunter2"), shown.SecretValue().Element)
+}
@@ -16,13 +16,19 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
 	"github.com/pulumi/pulumi/pkg/v3/backend/display"
 	"github.com/pulumi/pulumi/pkg/v3/graph"
 	"github.com/pulumi/pulumi/pkg/v3/graph/dotconv"
+	"github.com/pulumi/pulumi/pkg/v3/graph/graphmlconv"
+	"github.com/pulumi/pulumi/pkg/v3/graph/jsonconv"
+	"github.com/pulumi/pulumi/pkg/v3/graph/mermaidconv"
 	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy/providers"
+	resourceGraph "github.com/pulumi/pulumi/pkg/v3/resource/graph"
 	"github.com/pulumi/pulumi/pkg/v3/resource/stack"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
@@ -44,8 +50,19 @@ var parentEdgeColor string
 // Whether or not to return resource name as the node label for each node of the graph.
 var shortNodeName bool
 
+// graphPrinters are the printers for each of the formats that a stack's graph can be exported to.
+var graphPrinters = map[string]func(graph.Graph, io.Writer) error{
+	"dot":     dotconv.Print,
+	"mermaid": mermaidconv.Print,
+	"graphml": graphmlconv.Print,
+	"json":    jsonconv.Print,
+}
+
 func newStackGraphCmd() *cobra.Command {
 	var stackName string
+	var format string
+	var filter graphFilter
+	var root, dependentsOf string
 
 	cmd := &cobra.Command{
 		Use:   "graph [filename]",
@@ -54,14 +71,30 @@ func newStackGraphCmd() *cobra.Command {
 		Long: "Export a stack's dependency graph to a file.\n" +
 			"\n" +
 			"This command can be used to view the dependency graph that a Pulumi program\n" +
-			"emitted when it was run. This graph is output in the DOT format. This command operates\n" +
-			"on your stack's most recent deployment.",
+			"emitted when it was run. This graph is output in the DOT format by default, or in the\n" +
+			"Mermaid, GraphML or JSON formats with --format. This command operates on your stack's\n" +
+			"most recent deployment.\n" +
+			"\n" +
+			"The graph can be limited to part of the stack: --root keeps a resource and its children,\n" +
+			"--dependents-of keeps a resource and the resources that depend on it in any of the ways that\n" +
+			"`pulumi state dependents` reports, --type keeps the resources whose type matches a glob, and\n" +
+			"--depth limits how far from the starting resources the graph extends. --collapse-components\n" +
+			"draws each component resource as a single node that stands for all of its children.",
 		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
 			ctx := commandContext()
 			opts := display.Options{
 				Color: cmdutil.GetGlobalColorization(),
 			}
 
+			printGraph, ok := graphPrinters[format]
+			if !ok {
+				return fmt.Errorf("unknown format %q: expected one of dot, mermaid, graphml, or json", format)
+			}
+			if root != "" && dependentsOf != "" {
+				return fmt.Errorf("only one of --root and --dependents-of may be given")
+			}
+			filter.root, filter.dependentsOf = resource.URN(root), resource.URN(dependentsOf)
+
 			s, err := requireStack(ctx, stackName, stackLoadOnly, opts)
 			if err != nil {
 				return err
@@ -76,13 +109,16 @@ func newStackGraphCmd() *cobra.Command {
 				return fmt.Errorf("unable to find snapshot for stack %q", stackName)
 			}
 
-			dg := makeDependencyGraph(snap)
+			dg, err := makeDependencyGraph(snap, filter)
+			if err != nil {
+				return err
+			}
 			file, err := os.Create(args[0])
 			if err != nil {
 				return err
 			}
 
-			if err := dotconv.Print(dg, file); err != nil {
+			if err := printGraph(dg, file); err != nil {
 				_ = file.Close()
 				return err
 			}
@@ -104,12 +140,25 @@ func newStackGraphCmd() *cobra.Command {
 		"Sets the color of parent edges in the graph")
 	cmd.PersistentFlags().BoolVar(&shortNodeName, "short-node-name", false,
 		"Sets the resource name as the node label for each node of the graph")
+	cmd.PersistentFlags().StringVar(&format, "format", "dot",
+		"The format of the graph: dot, mermaid, graphml, or json")
+	cmd.PersistentFlags().StringVar(&root, "root", "",
+		"Only include the resource with this URN and its children")
+	cmd.PersistentFlags().StringVar(&dependentsOf, "dependents-of", "",
+		"Only include the resource with this URN and the resources that depend on it")
+	cmd.PersistentFlags().StringArrayVar(&filter.types, "type", nil,
+		"Only include resources whose type matches this glob, such as 'aws:s3/**'. May be given multiple times")
+	cmd.PersistentFlags().IntVar(&filter.depth, "depth", -1,
+		"Only include resources at most this many levels of children (or dependents, with --dependents-of) "+
+			"away from the starting resources")
+	cmd.PersistentFlags().BoolVar(&filter.collapseComponents, "collapse-components", false,
+		"Draw each component resource as a single node in place of its children")
 	return cmd
 }
 
 // All of the types and code within this file are to provide implementations of the interfaces
-// in the `graph` package, so that we can use the `dotconv` package to output our graph in the
-// DOT format.
+// in the `graph` package, so that we can use the `dotconv` package (and the other converters)
+// to output our graph.
 //
 // `dependencyEdge` implements graph.Edge, `dependencyVertex` implements graph.Vertex, and
 // `dependencyGraph` implements `graph.Graph`.
@@ -140,6 +189,10 @@ func (edge *dependencyEdge) Color() string {
 	return dependencyEdgeColor
 }
 
+func (edge *dependencyEdge) Attributes() map[string]interface{} {
+	return map[string]interface{}{"kind": "dependency"}
+}
+
 // parentEdges represent edges in the parent-child graph, which
 // exists alongside the dependency graph. An edge exists from node
 // A to node B if node B is considered to be a parent of node A.
@@ -169,6 +222,10 @@ func (edge *parentEdge) Color() string {
 	return parentEdgeColor
 }
 
+func (edge *parentEdge) Attributes() map[string]interface{} {
+	return map[string]interface{}{"kind": "parent"}
+}
+
 // A dependencyVertex contains a reference to the graph to which it belongs
 // and to the resource state that it represents. Incoming and outgoing edges
 // are calculated on-demand using the combination of the graph and the state.
@@ -177,6 +234,9 @@ type dependencyVertex struct {
 	resource      *resource.State
 	incomingEdges []graph.Edge
 	outgoingEdges []graph.Edge
+
+	// The number of children that were collapsed into this vertex by --collapse-components.
+	collapsed int
 }
 
 func (vertex *dependencyVertex) Data() interface{} {
@@ -201,17 +261,33 @@ func (vertex *dependencyVertex) Outs() []graph.Edge {
 	return vertex.outgoingEdges
 }
 
+func (vertex *dependencyVertex) Attributes() map[string]interface{} {
+	attrs := map[string]interface{}{
+		"urn":      string(vertex.resource.URN),
+		"type":     string(vertex.resource.Type),
+		"provider": vertex.resource.Provider,
+		"protect":  vertex.resource.Protect,
+		"custom":   vertex.resource.Custom,
+	}
+	if vertex.collapsed > 0 {
+		attrs["collapsed"] = vertex.collapsed
+	}
+	return attrs
+}
+
 // A dependencyGraph is a thin wrapper around a map of URNs to vertices in
 // the graph. It is constructed directly from a snapshot.
 type dependencyGraph struct {
 	vertices map[resource.URN]*dependencyVertex
+	// The vertices in the order of their resources in the snapshot, so that the graph is printed deterministically.
+	order []*dependencyVertex
 }
 
 // Roots are edges that point to the root set of our graph. In our case,
 // for simplicity, we define the root set of our dependency graph to be everything.
 func (dg *dependencyGraph) Roots() []graph.Edge {
 	rootEdges := []graph.Edge{}
-	for _, vertex := range dg.vertices {
+	for _, vertex := range dg.order {
 		edge := &dependencyEdge{
 			to:   vertex,
 			from: nil,
@@ -223,28 +299,185 @@ func (dg *dependencyGraph) Roots() []graph.Edge {
 	return rootEdges
 }
 
+// graphFilter selects the part of a stack's resources that is included in its dependency graph.
+type graphFilter struct {
+	// If set, only this resource and its descendants are included.
+	root resource.URN
+	// If set, only this resource and the resources that transitively depend on it are included. A resource depends on
+	// another through any of the relationships that `pulumi state dependents` follows: as its child, through its
+	// dependencies or property dependencies, by being deleted with it, or as a resource that it provides.
+	dependentsOf resource.URN
+	// If non-empty, only resources whose types match one of these globs are included.
+	types []string
+	// If non-negative, only resources at most this many parent (or, with dependentsOf, dependency) edges away from
+	// the starting resources are included.
+	depth int
+	// Whether the descendants of each component resource are drawn as the component.
+	collapseComponents bool
+}
+
+// include returns the URNs of the resources in the snapshot that pass the filter.
+func (f graphFilter) include(snapshot *deploy.Snapshot) (map[resource.URN]bool, error) {
+	byURN := make(map[resource.URN]*resource.State)
+	children := make(map[resource.URN][]resource.URN)
+	dependents := make(map[resource.URN][]resource.URN)
+	for _, res := range snapshot.Resources {
+		byURN[res.URN] = res
+		children[res.Parent] = append(children[res.Parent], res.URN)
+		deps, err := resourceGraph.DependencyURNs(res)
+		if err != nil {
+			return nil, err
+		}
+		for _, dep := range deps {
+			dependents[dep] = append(dependents[dep], res.URN)
+		}
+	}
+
+	// Find the resources to start from and the edges to follow from them.
+	var start []resource.URN
+	next := children
+	switch {
+	case f.root != "":
+		if byURN[f.root] == nil {
+			return nil, fmt.Errorf("no resource with URN %s exists in the stack", f.root)
+		}
+		start = []resource.URN{f.root}
+	case f.dependentsOf != "":
+		if byURN[f.dependentsOf] == nil {
+			return nil, fmt.Errorf("no resource with URN %s exists in the stack", f.dependentsOf)
+		}
+		start, next = []resource.URN{f.dependentsOf}, dependents
+	default:
+		// Start from the top-level resources: the root stack and the resources whose parent is the root stack.
+		for _, res := range snapshot.Resources {
+			parent := byURN[res.Parent]
+			if parent == nil || parent.Type == resource.RootStackType {
+				start = append(start, res.URN)
+			}
+		}
+	}
+
+	distance := make(map[resource.URN]int)
+	for _, urn := range start {
+		distance[urn] = 0
+	}
+	for queue := start; len(queue) > 0; queue = queue[1:] {
+		urn := queue[0]
+		if f.depth >= 0 && distance[urn] >= f.depth {
+			continue
+		}
+		for _, n := range next[urn] {
+			if _, seen := distance[n]; !seen {
+				distance[n] = distance[urn] + 1
+				queue = append(queue, n)
+			}
+		}
+	}
+
+	types := deploy.NewUrnTargets(f.types)
+	included := make(map[resource.URN]bool, len(distance))
+	for urn := range distance {
+		if types.Contains(resource.URN(byURN[urn].Type)) {
+			included[urn] = true
+		}
+	}
+	return included, nil
+}
+
+// representatives maps each included resource to the resource whose vertex stands for it: with collapseComponents,
+// the top-most included component resource among its ancestors, and otherwise itself.
+func (f graphFilter) representatives(
+	snapshot *deploy.Snapshot, included map[resource.URN]bool,
+) map[resource.URN]resource.URN {
+	byURN := make(map[resource.URN]*resource.State)
+	for _, res := range snapshot.Resources {
+		byURN[res.URN] = res
+	}
+	isComponent := func(res *resource.State) bool {
+		return !res.Custom && res.Type != resource.RootStackType && !providers.IsProviderType(res.Type)
+	}
+
+	reps := make(map[resource.URN]resource.URN, len(included))
+	for urn := range included {
+		reps[urn] = urn
+		if !f.collapseComponents {
+			continue
+		}
+		visited := map[resource.URN]bool{urn: true}
+		for parent := byURN[byURN[urn].Parent]; parent != nil && !visited[parent.URN]; parent = byURN[parent.Parent] {
+			visited[parent.URN] = true
+			if included[parent.URN] && isComponent(parent) {
+				reps[urn] = parent.URN
+			}
+		}
+	}
+	return reps
+}
+
 // Makes a dependency graph from a deployment snapshot, allocating a vertex
-// for every resource in the graph.
-func makeDependencyGraph(snapshot *deploy.Snapshot) *dependencyGraph {
+// for every resource in the graph that passes the given filter.
+func makeDependencyGraph(snapshot *deploy.Snapshot, filter graphFilter) (*dependencyGraph, error) {
 	dg := &dependencyGraph{
 		vertices: make(map[resource.URN]*dependencyVertex),
 	}
 
+	included, err := filter.include(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	reps := filter.representatives(snapshot, included)
+
 	for _, resource := range snapshot.Resources {
+		if !included[resource.URN] {
+			continue
+		}
+		if rep := reps[resource.URN]; rep != resource.URN {
+			continue
+		}
+		if _, has := dg.vertices[resource.URN]; has {
+			continue
+		}
+
 		vertex := &dependencyVertex{
 			graph:    dg,
 			resource: resource,
 		}
 
 		dg.vertices[resource.URN] = vertex
+		dg.order = append(dg.order, vertex)
+	}
+
+	// vertexFor returns the vertex that stands for the resource with the given URN, or nil if the resource is not in
+	// the graph.
+	vertexFor := func(urn resource.URN) *dependencyVertex {
+		rep, ok := reps[urn]
+		if !ok {
+			return nil
+		}
+		return dg.vertices[rep]
 	}
 
-	for _, vertex := range dg.vertices {
+	// Edges are added once between each pair of vertices, since collapsing components can produce duplicates.
+	type edgeKey struct {
+		from, to *dependencyVertex
+		parent   bool
+	}
+	added := make(map[edgeKey]*dependencyEdge)
+
+	for _, res := range snapshot.Resources {
+		vertex := vertexFor(res.URN)
+		if vertex == nil {
+			continue
+		}
+		if reps[res.URN] != res.URN {
+			vertex.collapsed++
+		}
+
 		if !ignoreDependencyEdges {
 			// If we have per-property dependency information, annotate the dependency edges
 			// we generate with the names of the properties associated with each dependency.
 			depBlame := make(map[resource.URN][]string)
-			for k, deps := range vertex.resource.PropertyDependencies {
+			for k, deps := range res.PropertyDependencies {
 				for _, dep := range deps {
 					depBlame[dep] = append(depBlame[dep], string(k))
 				}
@@ -252,9 +485,18 @@ func makeDependencyGraph(snapshot *deploy.Snapshot) *dependencyGraph {
 
 			// Incoming edges are directly stored within the checkpoint file; they represent
 			// resources on which this vertex immediately depends upon.
-			for _, dep := range vertex.resource.Dependencies {
-				vertexWeDependOn := vertex.graph.vertices[dep]
+			for _, dep := range res.Dependencies {
+				vertexWeDependOn := vertexFor(dep)
+				if vertexWeDependOn == nil || vertexWeDependOn == vertex {
+					continue
+				}
+				key := edgeKey{from: vertexWeDependOn, to: vertex}
+				if edge, has := added[key]; has {
+					edge.labels = append(edge.labels, depBlame[dep]...)
+					continue
+				}
 				edge := &dependencyEdge{to: vertex, from: vertexWeDependOn, labels: depBlame[dep]}
+				added[key] = edge
 				vertex.incomingEdges = append(vertex.incomingEdges, edge)
 				vertexWeDependOn.outgoingEdges = append(vertexWeDependOn.outgoingEdges, edge)
 			}
@@ -264,15 +506,18 @@ func makeDependencyGraph(snapshot *deploy.Snapshot) *dependencyGraph {
 		// is also displayed as part of this graph, although with different colored
 		// edges.
 		if !ignoreParentEdges {
-			if parent := vertex.resource.Parent; parent != resource.URN("") {
-				parentVertex := dg.vertices[parent]
-				vertex.outgoingEdges = append(vertex.outgoingEdges, &parentEdge{
-					to:   parentVertex,
-					from: vertex,
-				})
+			if parentVertex := vertexFor(res.Parent); parentVertex != nil && parentVertex != vertex {
+				key := edgeKey{from: vertex, to: parentVertex, parent: true}
+				if _, has := added[key]; !has {
+					added[key] = nil
+					vertex.outgoingEdges = append(vertex.outgoingEdges, &parentEdge{
+						to:   parentVertex,
+						from: vertex,
+					})
+				}
 			}
 		}
 	}
 
-	return dg
+	return dg, nil
 }
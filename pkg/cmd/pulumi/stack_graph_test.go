@@ -0,0 +1,114 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+)
+
+//nolint:paralleltest // makeDependencyGraph reads the package-level flags.
+func TestDependencyGraphFilters(t *testing.T) {
+	newResource := func(typ tokens.Type, name string, custom bool, parent *resource.State,
+		deps ...*resource.State,
+	) *resource.State {
+		res := &resource.State{
+			Type:   typ,
+			URN:    resource.NewURN("test", "test", "", typ, tokens.QName(name)),
+			Custom: custom,
+		}
+		if parent != nil {
+			res.Parent = parent.URN
+		}
+		for _, dep := range deps {
+			res.Dependencies = append(res.Dependencies, dep.URN)
+		}
+		return res
+	}
+
+	// stack
+	// ├── vpc (component)
+	// │   ├── subnet
+	// │   └── sg (depends on subnet)
+	// └── bucket
+	//     └── object (depends on sg)
+	stack := newResource(resource.RootStackType, "test-test", false, nil)
+	vpc := newResource("my:index:Vpc", "vpc", false, stack)
+	subnet := newResource("aws:ec2/subnet:Subnet", "subnet", true, vpc)
+	sg := newResource("aws:ec2/securityGroup:SecurityGroup", "sg", true, vpc, subnet)
+	bucket := newResource("aws:s3/bucket:Bucket", "bucket", true, stack)
+	object := newResource("aws:s3/bucketObject:BucketObject", "object", true, bucket, sg)
+	snap := &deploy.Snapshot{Resources: []*resource.State{stack, vpc, subnet, sg, bucket, object}}
+
+	labelsOf := func(snap *deploy.Snapshot, filter graphFilter) []string {
+		dg, err := makeDependencyGraph(snap, filter)
+		require.NoError(t, err)
+		var labels []string
+		for _, v := range dg.order {
+			labels = append(labels, string(v.resource.URN.Name()))
+		}
+		return labels
+	}
+	labels := func(filter graphFilter) []string { return labelsOf(snap, filter) }
+
+	assert.Equal(t, []string{"test-test", "vpc", "subnet", "sg", "bucket", "object"}, labels(graphFilter{depth: -1}))
+	assert.Equal(t, []string{"test-test", "vpc", "bucket"}, labels(graphFilter{depth: 0}))
+	assert.Equal(t, []string{"vpc", "subnet", "sg"}, labels(graphFilter{root: vpc.URN, depth: -1}))
+	assert.Equal(t, []string{"subnet", "sg", "object"}, labels(graphFilter{dependentsOf: subnet.URN, depth: -1}))
+	assert.Equal(t, []string{"subnet", "sg"}, labels(graphFilter{dependentsOf: subnet.URN, depth: 1}))
+	assert.Equal(t, []string{"bucket", "object"}, labels(graphFilter{types: []string{"aws:s3/**"}, depth: -1}))
+
+	// --dependents-of follows property dependencies, deleted-with relationships, children and providers as well as
+	// dependencies.
+	provider := newResource("pulumi:providers:aws", "provider", true, stack)
+	policy := newResource("aws:s3/bucketPolicy:BucketPolicy", "policy", true, stack)
+	policy.PropertyDependencies = map[resource.PropertyKey][]resource.URN{"bucket": {bucket.URN}}
+	notification := newResource("aws:s3/bucketNotification:BucketNotification", "notification", true, stack)
+	notification.DeletedWith = policy.URN
+	notification.Provider = string(provider.URN) + "::id"
+	related := &deploy.Snapshot{Resources: []*resource.State{stack, bucket, object, provider, policy, notification}}
+	dependents := func(filter graphFilter) []string { return labelsOf(related, filter) }
+	assert.Equal(t, []string{"bucket", "object", "policy", "notification"},
+		dependents(graphFilter{dependentsOf: bucket.URN, depth: -1}))
+	assert.Equal(t, []string{"bucket", "object", "policy"}, dependents(graphFilter{dependentsOf: bucket.URN, depth: 1}))
+	assert.Equal(t, []string{"provider", "notification"}, dependents(graphFilter{dependentsOf: provider.URN, depth: -1}))
+
+	_, err := makeDependencyGraph(snap, graphFilter{root: "urn:pulumi:test::test::a:b:c::missing", depth: -1})
+	assert.ErrorContains(t, err, "no resource with URN")
+
+	// A malformed provider reference is reported rather than crashing the CLI.
+	malformed := newResource("aws:s3/bucketObject:BucketObject", "malformed", true, bucket)
+	malformed.Provider = "not-a-reference"
+	_, err = makeDependencyGraph(&deploy.Snapshot{Resources: []*resource.State{stack, bucket, malformed}},
+		graphFilter{depth: -1})
+	assert.ErrorContains(t, err, `cannot parse provider reference "not-a-reference"`)
+
+	// Collapsing the VPC component redirects the dependency of the object on the security group to the VPC.
+	dg, err := makeDependencyGraph(snap, graphFilter{depth: -1, collapseComponents: true})
+	require.NoError(t, err)
+	require.Len(t, dg.order, 4)
+	vpcVertex := dg.vertices[vpc.URN]
+	assert.Equal(t, 2, vpcVertex.collapsed)
+	require.Len(t, vpcVertex.Outs(), 2)
+	assert.Equal(t, dg.vertices[stack.URN], vpcVertex.Outs()[0].To())
+	assert.Equal(t, dg.vertices[object.URN], vpcVertex.Outs()[1].To())
+	assert.Equal(t, "dependency", vpcVertex.Outs()[1].(*dependencyEdge).Attributes()["kind"])
+}
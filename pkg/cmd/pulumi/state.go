@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 
 	survey "github.com/AlecAivazis/survey/v2"
 	surveycore "github.com/AlecAivazis/survey/v2/core"
@@ -49,7 +50,16 @@ troubleshooting a stack or when performing specific edits that otherwise would r
 
 	cmd.AddCommand(newStateDeleteCommand())
 	cmd.AddCommand(newStateUnprotectCommand())
+	cmd.AddCommand(newStateProtectCommand())
+	cmd.AddCommand(newStateRetainCommand())
 	cmd.AddCommand(newStateRenameCommand())
+	cmd.AddCommand(newStateMoveCommand())
+	cmd.AddCommand(newStateEditCommand())
+	cmd.AddCommand(newStateRepairCommand())
+	cmd.AddCommand(newStatePendingCommand())
+	cmd.AddCommand(newStateDependentsCommand())
+	cmd.AddCommand(newStateDependenciesCommand())
+	cmd.AddCommand(newStateQueryCommand())
 	cmd.AddCommand(newStateUpgradeCommand())
 	return cmd
 }
@@ -125,6 +135,112 @@ func runStateEdit(
 	})
 }
 
+// stateSelectorFlags are the flags of the state commands that can operate on every resource that matches a set of
+// URN globs and filters.
+type stateSelectorFlags struct {
+	types       []string
+	where       []string
+	showSecrets bool
+}
+
+func (f *stateSelectorFlags) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&f.types, "type", nil,
+		"Only select resources whose type matches this glob, such as 'aws:s3/**'. May be given multiple times")
+	cmd.Flags().StringArrayVar(&f.where, "where", nil,
+		"Only select resources with a property that satisfies this predicate, of the form 'path', 'path=value' "+
+			"or 'path!=value'. May be given multiple times")
+	cmd.Flags().BoolVar(&f.showSecrets, "show-secrets", false,
+		"Compare the plaintext values of secrets with --where. Without this flag a secret value is present but "+
+			"is neither equal nor unequal to any value")
+}
+
+// bulk returns true if the given URN arguments and the flags select resources by glob or by filter, rather than
+// naming a single resource.
+func (f *stateSelectorFlags) bulk(urns []string) bool {
+	return len(urns) != 1 || strings.ContainsRune(urns[0], '*') || len(f.types) > 0 || len(f.where) > 0
+}
+
+// selector returns the selector for the given URN arguments and the flags. If there are no URN arguments, every
+// resource that passes the filters is selected.
+func (f *stateSelectorFlags) selector(urns []string) (edit.Selector, error) {
+	if len(urns) == 0 && len(f.types) == 0 && len(f.where) == 0 {
+		return edit.Selector{}, errors.New("must provide a URN, a URN glob, --type or --where to select resources")
+	}
+	for _, urn := range urns {
+		if !strings.ContainsRune(urn, '*') && !resource.URN(urn).IsValid() {
+			return edit.Selector{}, fmt.Errorf("The provided input URN %q is not valid", urn)
+		}
+	}
+
+	selector := edit.Selector{
+		URNs:  deploy.NewUrnTargets(urns),
+		Types: deploy.NewUrnTargets(f.types),
+	}
+	for _, w := range f.where {
+		p, err := edit.ParsePredicate(w)
+		if err != nil {
+			return edit.Selector{}, err
+		}
+		p.ShowSecrets = f.showSecrets
+		selector.Where = append(selector.Where, p)
+	}
+	return selector, nil
+}
+
+// runBulkStateEdit runs the given operation on the resources in the given stack's state that match the given
+// selector. The resources are listed and the user is asked to confirm the edit, if the session is interactive and
+// showPrompt is true, before the operation is run. The state is saved once, after the operation has succeeded. The
+// number of selected resources is returned.
+func runBulkStateEdit(
+	ctx context.Context, stackName string, showPrompt bool, selector edit.Selector, verb string,
+	operation func(snap *deploy.Snapshot, selected []*resource.State) error,
+) (int, result.Result) {
+	var count int
+	res := runTotalStateEdit(ctx, stackName, false, func(opts display.Options, snap *deploy.Snapshot) error {
+		selected := selector.Select(snap)
+		if len(selected) == 0 {
+			return errors.New("no resources match the given URNs and filters")
+		}
+		count = len(selected)
+
+		fmt.Printf("The following %d resources will be %s:\n", len(selected), verb)
+		for _, res := range selected {
+			fmt.Printf("  - %s\n", res.URN)
+		}
+		if showPrompt && !confirmStateEdit(opts) {
+			return errStateEditDeclined
+		}
+		return operation(snap, selected)
+	})
+	return count, res
+}
+
+// runSelectedStateEdit runs the given operation on the resource with the given URN or, if the URN arguments and the
+// flags select resources by glob or by filter, on each of the selected resources as a single edit. The number of
+// resources that were edited is returned.
+func runSelectedStateEdit(
+	ctx context.Context, stackName string, showPrompt bool, urns []string, filters *stateSelectorFlags, verb string,
+	operation edit.OperationFunc,
+) (int, result.Result) {
+	if !filters.bulk(urns) {
+		return 1, runStateEdit(ctx, stackName, showPrompt, resource.URN(urns[0]), operation)
+	}
+
+	selector, err := filters.selector(urns)
+	if err != nil {
+		return 0, result.FromError(err)
+	}
+	return runBulkStateEdit(ctx, stackName, showPrompt, selector, verb,
+		func(snap *deploy.Snapshot, selected []*resource.State) error {
+			for _, res := range selected {
+				if err := operation(snap, res); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+}
+
 // runTotalStateEdit runs a snapshot-mutating function on the entirety of the given stack's snapshot.
 // Before mutating, the user may be prompted to for confirmation if the current session is interactive.
 func runTotalStateEdit(
@@ -141,6 +257,14 @@ func runTotalStateEdit(
 	return totalStateEdit(ctx, s, showPrompt, opts, operation)
 }
 
+// errStateUnchanged is returned by a state edit operation that made no changes to the snapshot, so that the snapshot
+// is not saved.
+var errStateUnchanged = errors.New("the state was not changed")
+
+// errStateEditDeclined is returned by a state edit operation when the user declines to confirm the edit, so that the
+// snapshot is not saved and the command bails.
+var errStateEditDeclined = errors.New("confirmation declined")
+
 func totalStateEdit(ctx context.Context, s backend.Stack, showPrompt bool, opts display.Options,
 	operation func(opts display.Options, snap *deploy.Snapshot) error,
 ) result.Result {
@@ -151,17 +275,8 @@ func totalStateEdit(ctx context.Context, s backend.Stack, showPrompt bool, opts
 		return nil
 	}
 
-	if showPrompt && cmdutil.Interactive() {
-		confirm := false
-		surveycore.DisableColor = true
-		prompt := opts.Color.Colorize(colors.Yellow + "warning" + colors.Reset + ": ")
-		prompt += "This command will edit your stack's state directly. Confirm?"
-		if err = survey.AskOne(&survey.Confirm{
-			Message: prompt,
-		}, &confirm, surveyIcons(opts.Color)); err != nil || !confirm {
-			fmt.Println("confirmation declined")
-			return result.Bail()
-		}
+	if showPrompt && !confirmStateEdit(opts) {
+		return result.Bail()
 	}
 
 	// The `operation` callback will mutate `snap` in-place. In order to validate the correctness of the transformation
@@ -169,6 +284,12 @@ func totalStateEdit(ctx context.Context, s backend.Stack, showPrompt bool, opts
 	// before we mutated it, we'll assert that we didn't make it invalid by mutating it.
 	stackIsAlreadyHosed := snap.VerifyIntegrity() != nil
 	if err = operation(opts, snap); err != nil {
+		if errors.Is(err, errStateUnchanged) {
+			return nil
+		}
+		if errors.Is(err, errStateEditDeclined) {
+			return result.Bail()
+		}
 		return result.FromError(err)
 	}
 
@@ -177,19 +298,43 @@ func totalStateEdit(ctx context.Context, s backend.Stack, showPrompt bool, opts
 		contract.AssertNoErrorf(snap.VerifyIntegrity(), "state edit produced an invalid snapshot")
 	}
 
+	// Once we've mutated the snapshot, import it back into the backend so that it can be persisted.
+	return result.WrapIfNonNil(saveSnapshot(ctx, s, snap))
+}
+
+// confirmStateEdit asks the user to confirm a direct edit of a stack's state, if the terminal is interactive.
+func confirmStateEdit(opts display.Options) bool {
+	if !cmdutil.Interactive() {
+		return true
+	}
+
+	confirm := false
+	surveycore.DisableColor = true
+	prompt := opts.Color.Colorize(colors.Yellow + "warning" + colors.Reset + ": ")
+	prompt += "This command will edit your stack's state directly. Confirm?"
+	if err := survey.AskOne(&survey.Confirm{
+		Message: prompt,
+	}, &confirm, surveyIcons(opts.Color)); err != nil || !confirm {
+		fmt.Println("confirmation declined")
+		return false
+	}
+	return true
+}
+
+// saveSnapshot replaces the state of the given stack with the given snapshot.
+func saveSnapshot(ctx context.Context, s backend.Stack, snap *deploy.Snapshot) error {
 	sdep, err := stack.SerializeDeployment(snap, snap.SecretsManager, false /* showSecrets */)
 	if err != nil {
-		return result.FromError(fmt.Errorf("serializing deployment: %w", err))
+		return fmt.Errorf("serializing deployment: %w", err)
 	}
 
-	// Once we've mutated the snapshot, import it back into the backend so that it can be persisted.
 	bytes, err := json.Marshal(sdep)
 	if err != nil {
-		return result.FromError(err)
+		return err
 	}
 	dep := apitype.UntypedDeployment{
 		Version:    apitype.DeploymentSchemaVersionCurrent,
 		Deployment: bytes,
 	}
-	return result.WrapIfNonNil(s.ImportDeployment(ctx, &dep))
+	return s.ImportDeployment(ctx, &dep)
 }
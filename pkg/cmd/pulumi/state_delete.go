@@ -32,9 +32,10 @@ func newStateDeleteCommand() *cobra.Command {
 	var stack string
 	var yes bool
 	var targetDepenedents bool
+	var filters stateSelectorFlags
 
 	cmd := &cobra.Command{
-		Use:   "delete <resource URN>",
+		Use:   "delete <resource URN>...",
 		Short: "Deletes a resource from a stack's state",
 		Long: `Deletes a resource from a stack's state
 
@@ -44,48 +45,65 @@ by its Pulumi URN (use ` + "`pulumi stack --show-urns`" + ` to get it).
 Resources can't be deleted if there exist other resources that depend on it or are parented to it. Protected resources
 will not be deleted unless it is specifically requested using the --force flag.
 
+Several resources can be deleted at once by passing more than one URN, by passing URN globs (in which '*' matches
+any part of a URN segment and '**' matches any number of segments), or by selecting resources by type with --type
+or by property value with --where. The resources that will be deleted are listed before a single confirmation, and
+the state is saved once.
+
 Make sure that URNs are single-quoted to avoid having characters unexpectedly interpreted by the shell.
 
 Example:
 pulumi state delete 'urn:pulumi:stage::demo::eks:index:Cluster$pulumi:providers:kubernetes::eks-provider'
+pulumi state delete 'urn:pulumi:stage::demo::**::leftover-*' --where 'tags.owner=ci'
 `,
-		Args: cmdutil.ExactArgs(1),
+		Args: cobra.ArbitraryArgs,
 		Run: cmdutil.RunResultFunc(func(cmd *cobra.Command, args []string) result.Result {
 			ctx := commandContext()
 			yes = yes || skipConfirmations()
-			urn := resource.URN(args[0])
 			// Show the confirmation prompt if the user didn't pass the --yes parameter to skip it.
 			showPrompt := !yes
 
-			res := runStateEdit(ctx, stack, showPrompt, urn, func(snap *deploy.Snapshot, res *resource.State) error {
-				var handleProtected func(*resource.State) error
-				if force {
-					handleProtected = func(res *resource.State) error {
-						cmdutil.Diag().Warningf(diag.Message(res.URN,
-							"deleting protected resource %s due to presence of --force"), res.URN)
-						return edit.UnprotectResource(nil, res)
-					}
+			var handleProtected func(*resource.State) error
+			if force {
+				handleProtected = func(res *resource.State) error {
+					cmdutil.Diag().Warningf(diag.Message(res.URN,
+						"deleting protected resource %s due to presence of --force"), res.URN)
+					return edit.UnprotectResource(nil, res)
+				}
+			}
+
+			if filters.bulk(args) {
+				selector, err := filters.selector(args)
+				if err != nil {
+					return result.FromError(err)
+				}
+				count, res := runBulkStateEdit(ctx, stack, showPrompt, selector, "deleted",
+					func(snap *deploy.Snapshot, selected []*resource.State) error {
+						// Delete the resources in reverse order, so that each resource's dependents are deleted before it.
+						for i := len(selected) - 1; i >= 0; i-- {
+							if !containsResource(snap, selected[i]) {
+								// The resource was deleted as a dependent of another selected resource.
+								continue
+							}
+							if err := edit.DeleteResource(snap, selected[i], handleProtected, targetDepenedents); err != nil {
+								return err
+							}
+						}
+						return nil
+					})
+				if res != nil {
+					return deleteResult(res)
 				}
+				fmt.Printf("%d resources deleted\n", count)
+				return nil
+			}
+
+			urn := resource.URN(args[0])
+			res := runStateEdit(ctx, stack, showPrompt, urn, func(snap *deploy.Snapshot, res *resource.State) error {
 				return edit.DeleteResource(snap, res, handleProtected, targetDepenedents)
 			})
 			if res != nil {
-				switch e := res.Error().(type) {
-				case edit.ResourceHasDependenciesError:
-					message := string(e.Condemned.URN) + " can't be safely deleted because the following resources depend on it:\n"
-					for _, dependentResource := range e.Dependencies {
-						depUrn := dependentResource.URN
-						message += fmt.Sprintf(" * %-15q (%s)\n", depUrn.Name(), depUrn)
-					}
-
-					message += "\nDelete those resources first or pass --target-dependents."
-					return result.Error(message)
-				case edit.ResourceProtectedError:
-					return result.Errorf(
-						"%s can't be safely deleted because it is protected. "+
-							"Re-run this command with --force to force deletion", string(e.Condemned.URN))
-				default:
-					return res
-				}
+				return deleteResult(res)
 			}
 			fmt.Println("Resource deleted")
 			return nil
@@ -98,5 +116,37 @@ pulumi state delete 'urn:pulumi:stage::demo::eks:index:Cluster$pulumi:providers:
 	cmd.Flags().BoolVar(&force, "force", false, "Force deletion of protected resources")
 	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Skip confirmation prompts")
 	cmd.Flags().BoolVar(&targetDepenedents, "target-dependents", false, "Delete the URN and all its dependents")
+	filters.addFlags(cmd)
 	return cmd
 }
+
+// deleteResult explains the errors that edit.DeleteResource returns when a resource can't be deleted.
+func deleteResult(res result.Result) result.Result {
+	switch e := res.Error().(type) {
+	case edit.ResourceHasDependenciesError:
+		message := string(e.Condemned.URN) + " can't be safely deleted because the following resources depend on it:\n"
+		for _, dependentResource := range e.Dependencies {
+			depUrn := dependentResource.URN
+			message += fmt.Sprintf(" * %-15q (%s)\n", depUrn.Name(), depUrn)
+		}
+
+		message += "\nDelete those resources first or pass --target-dependents."
+		return result.Error(message)
+	case edit.ResourceProtectedError:
+		return result.Errorf(
+			"%s can't be safely deleted because it is protected. "+
+				"Re-run this command with --force to force deletion", string(e.Condemned.URN))
+	default:
+		return res
+	}
+}
+
+// containsResource returns true if the given resource is in the given snapshot.
+func containsResource(snap *deploy.Snapshot, res *resource.State) bool {
+	for _, r := range snap.Resources {
+		if r == res {
+			return true
+		}
+	}
+	return false
+}
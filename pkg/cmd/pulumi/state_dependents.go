@@ -0,0 +1,330 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend"
+	"github.com/pulumi/pulumi/pkg/v3/backend/display"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/v3/resource/graph"
+	"github.com/pulumi/pulumi/pkg/v3/resource/stack"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
+)
+
+// stackReferenceRelationship is the relationship of a StackReference resource in one stack to the stack that it
+// refers to.
+const stackReferenceRelationship graph.Relationship = "stack-reference"
+
+// stackReferenceType is the type of the resources that read the outputs of other stacks.
+const stackReferenceType = "pulumi:pulumi:StackReference"
+
+// An impactNode is a resource in the tree of resources that depend on, or are depended upon by, a resource.
+type impactNode struct {
+	URN resource.URN `json:"urn"`
+	// The stack that the resource belongs to, if it is not the stack that was queried.
+	Stack string `json:"stack,omitempty"`
+	// The ways in which the resource is related to the resource above it in the tree.
+	Relationships []graph.Relationship `json:"relationships,omitempty"`
+	Resources     []*impactNode        `json:"resources,omitempty"`
+}
+
+func newStateDependentsCommand() *cobra.Command {
+	var stackName string
+	var jsonOut bool
+	var stackReferences bool
+
+	cmd := &cobra.Command{
+		Use:   "dependents <resource URN>",
+		Short: "List the resources that depend on a resource",
+		Long: `List the resources that depend on a resource
+
+This command lists the resources that directly or indirectly depend on a resource through their parents,
+dependencies, property dependencies, deleted-with relationships or providers, which are the resources that
+might be affected if the resource is replaced or deleted. They are printed as a tree, in which each resource
+is listed under the resource through which it depends on the given resource.
+
+With --stack-references, the other stacks in the backend are also searched for StackReference resources
+that read this stack's outputs, and the resources that depend on them. The search is repeated for the stacks
+that are found, so stacks that read the outputs of those stacks are listed too.
+
+Make sure that URNs are single-quoted to avoid having characters unexpectedly interpreted by the shell.
+
+Example:
+pulumi state dependents 'urn:pulumi:stage::demo::aws:ec2/vpc:Vpc::main'
+`,
+		Args: cmdutil.ExactArgs(1),
+		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
+			ctx := commandContext()
+			s, snap, res, err := loadImpactResource(ctx, stackName, resource.URN(args[0]))
+			if err != nil {
+				return err
+			}
+
+			edges, err := graph.NewDependencyGraph(snap.Resources).TransitiveDependents(res)
+			if err != nil {
+				return err
+			}
+			root := impactTree(res, edges, true)
+			if stackReferences {
+				consumers, err := findStackReferenceConsumers(ctx, s)
+				if err != nil {
+					return err
+				}
+				root.Resources = append(root.Resources, consumers...)
+			}
+			return printImpactTree(root, jsonOut)
+		}),
+	}
+
+	cmd.PersistentFlags().StringVarP(
+		&stackName, "stack", "s", "",
+		"The name of the stack to operate on. Defaults to the current stack")
+	cmd.Flags().BoolVarP(&jsonOut, "json", "j", false, "Emit output as JSON")
+	cmd.Flags().BoolVar(&stackReferences, "stack-references", false,
+		"Include resources in other stacks that read this stack's outputs through a StackReference")
+	return cmd
+}
+
+func newStateDependenciesCommand() *cobra.Command {
+	var stackName string
+	var jsonOut bool
+
+	cmd := &cobra.Command{
+		Use:   "dependencies <resource URN>",
+		Short: "List the resources that a resource depends on",
+		Long: `List the resources that a resource depends on
+
+This command lists the resources that a resource directly or indirectly depends on through its parent,
+dependencies, property dependencies, deleted-with relationship or provider. They are printed as a tree, in
+which each resource is listed under the resource that depends on it.
+
+Make sure that URNs are single-quoted to avoid having characters unexpectedly interpreted by the shell.
+
+Example:
+pulumi state dependencies 'urn:pulumi:stage::demo::aws:ec2/instance:Instance::web'
+`,
+		Args: cmdutil.ExactArgs(1),
+		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
+			ctx := commandContext()
+			_, snap, res, err := loadImpactResource(ctx, stackName, resource.URN(args[0]))
+			if err != nil {
+				return err
+			}
+
+			edges, err := graph.NewDependencyGraph(snap.Resources).TransitiveDependencies(res)
+			if err != nil {
+				return err
+			}
+			root := impactTree(res, edges, false)
+			return printImpactTree(root, jsonOut)
+		}),
+	}
+
+	cmd.PersistentFlags().StringVarP(
+		&stackName, "stack", "s", "",
+		"The name of the stack to operate on. Defaults to the current stack")
+	cmd.Flags().BoolVarP(&jsonOut, "json", "j", false, "Emit output as JSON")
+	return cmd
+}
+
+// loadImpactResource loads the given stack and its snapshot, and locates the resource with the given URN in it.
+func loadImpactResource(
+	ctx context.Context, stackName string, urn resource.URN,
+) (backend.Stack, *deploy.Snapshot, *resource.State, error) {
+	if !urn.IsValid() {
+		return nil, nil, nil, errors.New("The provided input URN is not valid")
+	}
+
+	opts := display.Options{
+		Color: cmdutil.GetGlobalColorization(),
+	}
+	s, err := requireStack(ctx, stackName, stackLoadOnly, opts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	snap, err := s.Snapshot(ctx, stack.DefaultSecretsProvider)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if snap == nil {
+		return nil, nil, nil, fmt.Errorf("unable to find snapshot for stack %q", s.Ref())
+	}
+	res, err := locateStackResource(opts, snap, urn)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return s, snap, res, nil
+}
+
+// impactTree arranges the given edges into a tree rooted at res. If dependents is true, each edge's dependent is
+// placed under its dependency, and otherwise each edge's dependency is placed under its dependent.
+func impactTree(res *resource.State, edges []graph.Edge, dependents bool) *impactNode {
+	root := &impactNode{URN: res.URN}
+	nodes := map[*resource.State]*impactNode{res: root}
+	for _, edge := range edges {
+		above, below := edge.Dependent, edge.Dependency
+		if dependents {
+			above, below = edge.Dependency, edge.Dependent
+		}
+		node := &impactNode{URN: below.URN, Relationships: edge.Relationships}
+		nodes[below] = node
+		nodes[above].Resources = append(nodes[above].Resources, node)
+	}
+	return root
+}
+
+// findStackReferenceConsumers searches the other stacks in the given stack's backend for StackReference resources
+// that refer to it, and returns a tree of each such resource and the resources that depend on it. The search is
+// transitive: the consumers of each stack that is found are listed under the StackReference resource through which
+// that stack reads the outputs of the stack above it, since its own outputs may depend on them. Each stack is
+// searched for once, so stacks that refer to each other in a cycle are not listed again.
+func findStackReferenceConsumers(ctx context.Context, s backend.Stack) ([]*impactNode, error) {
+	b := s.Backend()
+
+	var summaries []backend.StackSummary
+	var token backend.ContinuationToken
+	for {
+		page, next, err := b.ListStacks(ctx, backend.ListStacksFilter{}, token)
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, page...)
+		if next == nil {
+			break
+		}
+		token = next
+	}
+
+	// Read the snapshot of every other stack once. Stacks that cannot be read are skipped with a warning.
+	type stackState struct {
+		ref  backend.StackReference
+		snap *deploy.Snapshot
+	}
+	var stacks []stackState
+	for _, summary := range summaries {
+		ref := summary.Name()
+		if ref.FullyQualifiedName() == s.Ref().FullyQualifiedName() {
+			continue
+		}
+		other, err := b.GetStack(ctx, ref)
+		if err == nil && other == nil {
+			continue
+		}
+		var snap *deploy.Snapshot
+		if err == nil {
+			snap, err = other.Snapshot(ctx, stack.DefaultSecretsProvider)
+		}
+		if err != nil {
+			cmdutil.Diag().Warningf(diag.Message("", "skipping stack %s: %v"), ref, err)
+			continue
+		}
+		if snap != nil {
+			stacks = append(stacks, stackState{ref: ref, snap: snap})
+		}
+	}
+
+	searched := map[tokens.QName]bool{s.Ref().FullyQualifiedName(): true}
+	var consumersOf func(target tokens.QName) []*impactNode
+	consumersOf = func(target tokens.QName) []*impactNode {
+		var consumers []*impactNode
+		for _, other := range stacks {
+			var dg *graph.DependencyGraph
+			for _, res := range other.snap.Resources {
+				if res.Type != stackReferenceType || res.Delete {
+					continue
+				}
+				name := res.Inputs["name"]
+				if !name.IsString() {
+					continue
+				}
+				referenced, err := b.ParseStackReference(name.StringValue())
+				if err != nil || referenced.FullyQualifiedName() != target {
+					continue
+				}
+
+				if dg == nil {
+					dg = graph.NewDependencyGraph(other.snap.Resources)
+				}
+				edges, err := dg.TransitiveDependents(res)
+				if err != nil {
+					// As with a stack that cannot be read, a stack with malformed state does not fail the search.
+					cmdutil.Diag().Warningf(diag.Message("", "skipping stack %s: %v"), other.ref, err)
+					break
+				}
+				node := impactTree(res, edges, true)
+				node.Stack, node.Relationships = other.ref.String(), []graph.Relationship{stackReferenceRelationship}
+				if fqn := other.ref.FullyQualifiedName(); !searched[fqn] {
+					searched[fqn] = true
+					node.Resources = append(node.Resources, consumersOf(fqn)...)
+				}
+				consumers = append(consumers, node)
+			}
+		}
+		return consumers
+	}
+	return consumersOf(s.Ref().FullyQualifiedName()), nil
+}
+
+// printImpactTree prints the given tree as JSON or as an indented tree of URNs.
+func printImpactTree(root *impactNode, jsonOut bool) error {
+	if jsonOut {
+		return printJSON(root)
+	}
+
+	var printNode func(node *impactNode, padding, branch string)
+	printNode = func(node *impactNode, padding, branch string) {
+		line := padding + branch
+		if node.Stack != "" {
+			line += fmt.Sprintf("[%s] ", node.Stack)
+		}
+		line += string(node.URN)
+		if len(node.Relationships) > 0 {
+			line += " ("
+			for i, rel := range node.Relationships {
+				if i > 0 {
+					line += ", "
+				}
+				line += string(rel)
+			}
+			line += ")"
+		}
+		fmt.Println(line)
+
+		switch branch {
+		case "├─ ":
+			padding += "│  "
+		case "└─ ":
+			padding += "   "
+		}
+		for i, child := range node.Resources {
+			childBranch := "├─ "
+			if i == len(node.Resources)-1 {
+				childBranch = "└─ "
+			}
+			printNode(child, padding, childBranch)
+		}
+	}
+	printNode(root, "", "")
+	return nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/v3/secrets"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+)
+
+func TestFindStackReferenceConsumers(t *testing.T) {
+	t.Parallel()
+
+	// consumer reads the outputs of the stack of the given name, and user depends on what it reads.
+	consumer := func(stackName, referenced string) []*resource.State {
+		ref := &resource.State{
+			Type:   stackReferenceType,
+			URN:    resource.NewURN(tokens.QName(stackName), "proj", "", stackReferenceType, tokens.QName(referenced)),
+			Inputs: resource.PropertyMap{"name": resource.NewStringProperty(referenced)},
+		}
+		user := &resource.State{
+			Type:         "pkgA:m:typA",
+			URN:          resource.NewURN(tokens.QName(stackName), "proj", "", "pkgA:m:typA", "user"),
+			Dependencies: []resource.URN{ref.URN},
+		}
+		return []*resource.State{ref, user}
+	}
+
+	// a reads the outputs of dev, b reads those of a, and dev reads those of b, which closes a cycle. other reads
+	// nothing.
+	snaps := map[string]*deploy.Snapshot{
+		"dev":   deploy.NewSnapshot(deploy.Manifest{}, nil, consumer("dev", "b"), nil),
+		"a":     deploy.NewSnapshot(deploy.Manifest{}, nil, consumer("a", "dev"), nil),
+		"b":     deploy.NewSnapshot(deploy.Manifest{}, nil, consumer("b", "a"), nil),
+		"other": deploy.NewSnapshot(deploy.Manifest{}, nil, nil, nil),
+	}
+
+	stackRef := func(name string) backend.StackReference {
+		return &backend.MockStackReference{
+			NameV:               tokens.Name(name),
+			FullyQualifiedNameV: tokens.QName(name),
+			StringV:             name,
+		}
+	}
+	var be *backend.MockBackend
+	stackOf := func(ref backend.StackReference) backend.Stack {
+		return &backend.MockStack{
+			RefF:     func() backend.StackReference { return ref },
+			BackendF: func() backend.Backend { return be },
+			SnapshotF: func(ctx context.Context, secretsProvider secrets.Provider) (*deploy.Snapshot, error) {
+				return snaps[ref.String()], nil
+			},
+		}
+	}
+	be = &backend.MockBackend{
+		ListStacksF: func(context.Context, backend.ListStacksFilter, backend.ContinuationToken) (
+			[]backend.StackSummary, backend.ContinuationToken, error,
+		) {
+			return []backend.StackSummary{
+				&mockStackSummary{"dev"}, &mockStackSummary{"a"}, &mockStackSummary{"b"}, &mockStackSummary{"other"},
+			}, nil, nil
+		},
+		GetStackF: func(ctx context.Context, ref backend.StackReference) (backend.Stack, error) {
+			return stackOf(ref), nil
+		},
+		ParseStackReferenceF: func(s string) (backend.StackReference, error) {
+			return stackRef(s), nil
+		},
+	}
+
+	consumers, err := findStackReferenceConsumers(context.Background(), stackOf(stackRef("dev")))
+	require.NoError(t, err)
+
+	// a is found through its reference to dev, and b, which reads a's outputs, is listed under a's reference. dev is
+	// not searched again.
+	require.Len(t, consumers, 1)
+	assert.Equal(t, "a", consumers[0].Stack)
+	assert.Equal(t, snaps["a"].Resources[0].URN, consumers[0].URN)
+	require.Len(t, consumers[0].Resources, 2)
+	assert.Equal(t, snaps["a"].Resources[1].URN, consumers[0].Resources[0].URN)
+
+	b := consumers[0].Resources[1]
+	assert.Equal(t, "b", b.Stack)
+	assert.Equal(t, snaps["b"].Resources[0].URN, b.URN)
+	require.Len(t, b.Resources, 1)
+	assert.Equal(t, snaps["b"].Resources[1].URN, b.Resources[0].URN)
+}
@@ -0,0 +1,350 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	survey "github.com/AlecAivazis/survey/v2"
+	surveycore "github.com/AlecAivazis/survey/v2/core"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend/display"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/v3/resource/edit"
+	"github.com/pulumi/pulumi/pkg/v3/resource/stack"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag/colors"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/config"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/result"
+)
+
+func newStateEditCommand() *cobra.Command {
+	var stackName string
+	var format string
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "edit [resource URN]",
+		Short: "Edit the current stack's state in a text editor",
+		Long: `Edit the current stack's state in a text editor
+
+This command opens the resources and pending operations of a stack's state in the editor named by the VISUAL or
+EDITOR environment variables. If a resource URN is given, only that resource is opened. Secret values are shown
+in plaintext while editing and are encrypted again when the state is saved.
+
+When the editor exits, the edited state is checked for integrity and the changes are shown for confirmation
+before they are saved. If the edited state is invalid, it can be edited again. The manifest and secrets provider
+of the state cannot be edited.
+
+Make sure that URNs are single-quoted to avoid having characters unexpectedly interpreted by the shell.
+
+Example:
+pulumi state edit 'urn:pulumi:stage::demo::aws:s3/bucket:Bucket::logs'
+`,
+		Args: cmdutil.MaximumNArgs(1),
+		Run: cmdutil.RunResultFunc(func(cmd *cobra.Command, args []string) result.Result {
+			ctx := commandContext()
+			yes = yes || skipConfirmations()
+			// Show the confirmation prompt if the user didn't pass the --yes parameter to skip it.
+			showPrompt := !yes
+
+			if format != "yaml" && format != "json" {
+				return result.Errorf("unknown format %q: expected yaml or json", format)
+			}
+			var urn resource.URN
+			if len(args) == 1 {
+				urn = resource.URN(args[0])
+				if !urn.IsValid() {
+					return result.Error("The provided input URN is not valid")
+				}
+			}
+
+			opts := display.Options{
+				Color: cmdutil.GetGlobalColorization(),
+			}
+			s, err := requireStack(ctx, stackName, stackLoadOnly, opts)
+			if err != nil {
+				return result.FromError(err)
+			}
+
+			changed := false
+			res := totalStateEdit(ctx, s, false, opts, func(opts display.Options, snap *deploy.Snapshot) error {
+				var target *resource.State
+				if urn != "" {
+					if target, err = locateStackResource(opts, snap, urn); err != nil {
+						return err
+					}
+				}
+
+				edited, err := editSnapshotInEditor(snap, target, format)
+				if err != nil {
+					return err
+				}
+				if edited == nil {
+					return errStateUnchanged
+				}
+				diff := edit.DiffSnapshots(snap, edited)
+				if !diff.AnyChanges() {
+					return errStateUnchanged
+				}
+
+				printSnapshotDiff(opts, diff)
+				if showPrompt && cmdutil.Interactive() && !confirmYesNo("Save these changes?", opts) {
+					return errStateEditDeclined
+				}
+				snap.Resources, snap.PendingOperations = edited.Resources, edited.PendingOperations
+				changed = true
+				return nil
+			})
+			if res != nil {
+				return res
+			}
+
+			if changed {
+				fmt.Println("State saved")
+			} else {
+				fmt.Println("No changes were made")
+			}
+			return nil
+		}),
+	}
+
+	cmd.PersistentFlags().StringVarP(
+		&stackName, "stack", "s", "",
+		"The name of the stack to operate on. Defaults to the current stack")
+	cmd.Flags().StringVar(&format, "format", "yaml", "The format in which to edit the state: yaml or json")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Skip confirmation prompts")
+	return cmd
+}
+
+// editSnapshotInEditor opens the given snapshot, or just the given resource if it is not nil, in the user's editor,
+// and returns the edited snapshot. If the edited snapshot is invalid, the user is offered the chance to edit it
+// again. A nil snapshot is returned if the user made no changes.
+func editSnapshotInEditor(snap *deploy.Snapshot, target *resource.State, format string) (*deploy.Snapshot, error) {
+	sdep, err := stack.SerializeDeployment(snap, snap.SecretsManager, true /* showSecrets */)
+	if err != nil {
+		return nil, fmt.Errorf("serializing deployment: %w", err)
+	}
+	// Only the resources and pending operations can be edited.
+	doc := &apitype.DeploymentV3{Resources: sdep.Resources, PendingOperations: sdep.PendingOperations}
+	targetIndex := -1
+	for i, res := range snap.Resources {
+		if res == target {
+			targetIndex = i
+		}
+	}
+
+	var original []byte
+	if targetIndex != -1 {
+		original, err = marshalEditable(sdep.Resources[targetIndex], format)
+	} else {
+		original, err = marshalEditable(doc, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	text := original
+	for {
+		if text, err = openInEditor(text, "."+format); err != nil {
+			return nil, err
+		}
+		if bytes.Equal(text, original) {
+			return nil, nil
+		}
+
+		edited, err := func() (*deploy.Snapshot, error) {
+			if targetIndex != -1 {
+				var res apitype.ResourceV3
+				if err := unmarshalEditable(text, format, &res); err != nil {
+					return nil, err
+				}
+				doc.Resources = append([]apitype.ResourceV3(nil), sdep.Resources...)
+				doc.Resources[targetIndex] = res
+			} else if err := unmarshalEditable(text, format, doc); err != nil {
+				return nil, err
+			}
+			edited, err := deserializeEditedSnapshot(snap, doc)
+			if err != nil {
+				return nil, err
+			}
+			if err := edited.VerifyIntegrity(); err != nil {
+				return nil, fmt.Errorf("the edited state is invalid: %w", err)
+			}
+			return edited, nil
+		}()
+		if err == nil {
+			return edited, nil
+		}
+
+		if !cmdutil.Interactive() {
+			return nil, err
+		}
+		fmt.Fprintln(os.Stderr, cmdutil.GetGlobalColorization().Colorize(colors.SpecError+"error: "+colors.Reset)+
+			err.Error())
+		if !confirmYesNo("Edit the state again?", display.Options{Color: cmdutil.GetGlobalColorization()}) {
+			return nil, errStateUnchanged
+		}
+	}
+}
+
+// deserializeEditedSnapshot deserializes the resources and pending operations of an edited deployment, encrypting
+// any secrets with the secrets manager of the original snapshot.
+func deserializeEditedSnapshot(snap *deploy.Snapshot, doc *apitype.DeploymentV3) (*deploy.Snapshot, error) {
+	dec, enc := config.Decrypter(config.NewPanicCrypter()), config.Encrypter(config.NewPanicCrypter())
+	if snap.SecretsManager != nil {
+		var err error
+		if dec, err = snap.SecretsManager.Decrypter(); err != nil {
+			return nil, err
+		}
+		if enc, err = snap.SecretsManager.Encrypter(); err != nil {
+			return nil, err
+		}
+	}
+
+	resources := make([]*resource.State, 0, len(doc.Resources))
+	for _, res := range doc.Resources {
+		state, err := stack.DeserializeResource(res, dec, enc)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, state)
+	}
+	ops := make([]resource.Operation, 0, len(doc.PendingOperations))
+	for _, op := range doc.PendingOperations {
+		operation, err := stack.DeserializeOperation(op, dec, enc)
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, operation)
+	}
+	return deploy.NewSnapshot(snap.Manifest, snap.SecretsManager, resources, ops), nil
+}
+
+func marshalEditable(v interface{}, format string) ([]byte, error) {
+	b, err := json.MarshalIndent(v, "", "    ")
+	if err != nil || format == "json" {
+		return b, err
+	}
+
+	// Round-trip through a generic value so that the YAML uses the same field names as the JSON.
+	var generic interface{}
+	if err := json.Unmarshal(b, &generic); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(generic)
+}
+
+func unmarshalEditable(text []byte, format string, v interface{}) error {
+	if format == "yaml" {
+		var generic interface{}
+		if err := yaml.Unmarshal(text, &generic); err != nil {
+			return fmt.Errorf("parsing YAML: %w", err)
+		}
+		var err error
+		if text, err = json.Marshal(generic); err != nil {
+			return err
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(text))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("parsing state: %w", err)
+	}
+	return nil
+}
+
+// openInEditor writes the given text to a temporary file, opens it in the editor named by VISUAL or EDITOR, and
+// returns the contents of the file once the editor exits.
+func openInEditor(text []byte, ext string) ([]byte, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	// The file contains plaintext secrets, so it is only readable by the current user.
+	f, err := os.CreateTemp("", "pulumi-state-*"+ext)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(text); err != nil {
+		contract.IgnoreClose(f)
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], f.Name())...) //nolint:gosec
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("running editor %q: %w", editor, err)
+	}
+	return os.ReadFile(f.Name())
+}
+
+func confirmYesNo(message string, opts display.Options) bool {
+	confirm := false
+	surveycore.DisableColor = true
+	if err := survey.AskOne(&survey.Confirm{
+		Message: opts.Color.Colorize(colors.SpecPrompt + message + colors.Reset),
+	}, &confirm, surveyIcons(opts.Color)); err != nil {
+		return false
+	}
+	return confirm
+}
+
+func printSnapshotDiff(opts display.Options, diff *edit.SnapshotDiff) {
+	for _, change := range diff.Resources {
+		switch change.Kind {
+		case edit.ChangeAdded:
+			fmt.Println(opts.Color.Colorize(colors.SpecCreate + "+ " + string(change.URN) + colors.Reset))
+		case edit.ChangeRemoved:
+			fmt.Println(opts.Color.Colorize(colors.SpecDelete + "- " + string(change.URN) + colors.Reset))
+		case edit.ChangeModified:
+			fmt.Println(opts.Color.Colorize(colors.SpecUpdate + "~ " + string(change.URN) + colors.Reset))
+			for _, field := range change.Fields {
+				fmt.Printf("    %s\n", field)
+			}
+		}
+	}
+	for _, op := range diff.Operations {
+		verb := "added"
+		if op.Kind == edit.ChangeRemoved {
+			verb = "cleared"
+		}
+		fmt.Printf("pending %s operation on %s %s\n", op.Type, op.URN, verb)
+	}
+}
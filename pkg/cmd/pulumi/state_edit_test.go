@@ -0,0 +1,82 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend"
+	"github.com/pulumi/pulumi/pkg/v3/backend/display"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/v3/secrets"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+func TestTotalStateEditUnchanged(t *testing.T) {
+	t.Parallel()
+
+	saved := false
+	s := &backend.MockStack{
+		SnapshotF: func(ctx context.Context, secretsProvider secrets.Provider) (*deploy.Snapshot, error) {
+			return deploy.NewSnapshot(deploy.Manifest{}, nil, nil, nil), nil
+		},
+		ImportDeploymentF: func(ctx context.Context, deployment *apitype.UntypedDeployment) error {
+			saved = true
+			return nil
+		},
+	}
+
+	// An edit that makes no changes does not save the state.
+	res := totalStateEdit(context.Background(), s, false, display.Options{},
+		func(opts display.Options, snap *deploy.Snapshot) error {
+			return errStateUnchanged
+		})
+	assert.Nil(t, res)
+	assert.False(t, saved)
+
+	res = totalStateEdit(context.Background(), s, false, display.Options{},
+		func(opts display.Options, snap *deploy.Snapshot) error {
+			return nil
+		})
+	assert.Nil(t, res)
+	assert.True(t, saved)
+}
+
+func TestTotalStateEditDeclined(t *testing.T) {
+	t.Parallel()
+
+	saved := false
+	s := &backend.MockStack{
+		SnapshotF: func(ctx context.Context, secretsProvider secrets.Provider) (*deploy.Snapshot, error) {
+			return deploy.NewSnapshot(deploy.Manifest{}, nil, nil, nil), nil
+		},
+		ImportDeploymentF: func(ctx context.Context, deployment *apitype.UntypedDeployment) error {
+			saved = true
+			return nil
+		},
+	}
+
+	// Declining to save an edit bails without reporting an error or saving the state.
+	res := totalStateEdit(context.Background(), s, false, display.Options{},
+		func(opts display.Options, snap *deploy.Snapshot) error {
+			return errStateEditDeclined
+		})
+	assert.NotNil(t, res)
+	assert.True(t, res.IsBail())
+	assert.False(t, saved)
+}
@@ -0,0 +1,228 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend"
+	"github.com/pulumi/pulumi/pkg/v3/backend/display"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/v3/resource/edit"
+	"github.com/pulumi/pulumi/pkg/v3/resource/stack"
+	"github.com/pulumi/pulumi/pkg/v3/version"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag/colors"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/result"
+)
+
+func newStateMoveCommand() *cobra.Command {
+	var source string
+	var dest string
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "move --dest <stack> <resource URN or glob>...",
+		Short: "Move resources from one stack to another",
+		Long: `Move resources from one stack to another
+
+This command moves resources from the state of the source stack to the state of the destination stack. The
+resources are specified by their Pulumi URNs (use ` + "`pulumi stack --show-urns`" + ` to get them), which may
+contain globs: '*' matches any run of characters other than '::' and '**' matches any run of characters.
+
+The URNs of the moved resources are rewritten for the destination stack and project. The children of a moved
+resource are moved along with it, as are its parents other than the root stack resource. The providers used by
+the moved resources are moved too, or copied if resources in the source stack still use them.
+
+Dependencies between moved resources and resources that stay in the source stack cannot be kept, since
+resources in different stacks cannot depend on each other. They are removed from both stacks and reported.
+
+The resources to move, the providers to copy and the dependencies to remove are reported before you are asked
+to confirm the move. Both stacks' states are checked before either is written. The destination stack's state is
+written first, and is restored if the source stack's state cannot be written afterwards.
+
+Make sure that URNs are single-quoted to avoid having characters unexpectedly interpreted by the shell.
+
+Example:
+pulumi state move --source dev --dest networking 'urn:pulumi:dev::app::aws:ec2/vpc:Vpc::main'
+`,
+		Args: cmdutil.MinimumNArgs(1),
+		Run: cmdutil.RunResultFunc(func(cmd *cobra.Command, args []string) result.Result {
+			ctx := commandContext()
+			yes = yes || skipConfirmations()
+			// Show the confirmation prompt if the user didn't pass the --yes parameter to skip it.
+			showPrompt := !yes
+
+			if dest == "" {
+				return result.Error("the destination stack must be specified with --dest")
+			}
+			targets := deploy.NewUrnTargets(args)
+
+			opts := display.Options{
+				Color: cmdutil.GetGlobalColorization(),
+			}
+			sourceStack, err := requireStack(ctx, source, stackLoadOnly, opts)
+			if err != nil {
+				return result.FromError(err)
+			}
+			destStack, err := requireStack(ctx, dest, stackLoadOnly, opts)
+			if err != nil {
+				return result.FromError(err)
+			}
+			if sourceStack.Ref().FullyQualifiedName() == destStack.Ref().FullyQualifiedName() {
+				return result.Error("the source and destination stacks must be different")
+			}
+
+			// The move is made as an edit of the source stack's state. The destination stack's state is saved by the
+			// edit, before the source stack's state is saved, and is restored if the source stack's state cannot be.
+			var found bool
+			var destOriginal *apitype.UntypedDeployment
+			res := totalStateEdit(ctx, sourceStack, false, opts, func(opts display.Options, sourceSnap *deploy.Snapshot) error {
+				found = true
+				destSnap, err := destStack.Snapshot(ctx, stack.DefaultSecretsProvider)
+				if err != nil {
+					return err
+				} else if destSnap == nil {
+					sm, err := getStackSecretsManager(destStack)
+					if err != nil {
+						return err
+					}
+					manifest := deploy.Manifest{Time: time.Now(), Version: version.Version}
+					manifest.Magic = manifest.NewMagic()
+					destSnap = deploy.NewSnapshot(manifest, sm, nil, nil)
+				}
+				destProject, err := stackProject(destStack, destSnap)
+				if err != nil {
+					return err
+				}
+
+				// As in totalStateEdit, a snapshot that was valid before the move must still be valid after it. Both
+				// are checked before either stack is written.
+				sourceIsAlreadyHosed := sourceSnap.VerifyIntegrity() != nil
+				destIsAlreadyHosed := destSnap.VerifyIntegrity() != nil
+
+				// The move is reported so that the user can review it before either stack is written.
+				moved, err := edit.MoveResources(sourceSnap, destSnap, targets, destStack.Ref().Name(), destProject)
+				if err != nil {
+					return err
+				}
+				printMoveResult(opts, moved)
+
+				if !sourceIsAlreadyHosed {
+					if err := sourceSnap.VerifyIntegrity(); err != nil {
+						return fmt.Errorf("moving resources produced an invalid snapshot for %s: %w", sourceStack.Ref(), err)
+					}
+				}
+				if !destIsAlreadyHosed {
+					if err := destSnap.VerifyIntegrity(); err != nil {
+						return fmt.Errorf("moving resources produced an invalid snapshot for %s: %w", destStack.Ref(), err)
+					}
+				}
+
+				if showPrompt && !confirmStateEdit(opts) {
+					return errStateEditDeclined
+				}
+
+				original, err := destStack.ExportDeployment(ctx)
+				if err != nil {
+					return fmt.Errorf("exporting the state of %s: %w", destStack.Ref(), err)
+				}
+				if err := saveSnapshot(ctx, destStack, destSnap); err != nil {
+					return fmt.Errorf("saving the state of %s: %w", destStack.Ref(), err)
+				}
+				destOriginal = original
+				return nil
+			})
+			if res != nil {
+				if destOriginal == nil || res.IsBail() {
+					return res
+				}
+				err := fmt.Errorf("saving the state of %s: %w", sourceStack.Ref(), res.Error())
+				if rerr := destStack.ImportDeployment(ctx, destOriginal); rerr != nil {
+					err = multierror.Append(err, fmt.Errorf("restoring the state of %s: %w", destStack.Ref(), rerr))
+				}
+				return result.FromError(err)
+			}
+			if !found {
+				return result.Errorf("stack %s has no resources to move", sourceStack.Ref())
+			}
+
+			fmt.Println("Resources moved")
+			return nil
+		}),
+	}
+
+	cmd.PersistentFlags().StringVarP(
+		&source, "source", "s", "",
+		"The name of the stack to move resources from. Defaults to the current stack")
+	cmd.PersistentFlags().StringVar(
+		&dest, "dest", "",
+		"The name of the stack to move resources to")
+	// The Automation API passes the stack to operate on as --stack, which is the source stack for this command.
+	cmd.PersistentFlags().StringVar(
+		&source, "stack", "",
+		"The name of the stack to move resources from. An alias for --source")
+	_ = cmd.PersistentFlags().MarkHidden("stack")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Skip confirmation prompts")
+	return cmd
+}
+
+// stackProject returns the project of the given stack. The fully qualified names of stacks that are scoped to a
+// project have the form <org>/<project>/<stack>. Legacy stacks are not scoped to a project, so their project is taken
+// from their state or, if they have none, from the current project.
+func stackProject(s backend.Stack, snap *deploy.Snapshot) (tokens.PackageName, error) {
+	if parts := strings.Split(string(s.Ref().FullyQualifiedName()), "/"); len(parts) == 3 {
+		return tokens.PackageName(parts[1]), nil
+	}
+	for _, res := range snap.Resources {
+		return res.URN.Project(), nil
+	}
+	project, _, err := readProject()
+	if err != nil {
+		return "", fmt.Errorf("determining the project of stack %s: %w", s.Ref(), err)
+	}
+	return tokens.PackageName(project.Name), nil
+}
+
+func printMoveResult(opts display.Options, moved *edit.MoveResult) {
+	fmt.Println("Resources to move:")
+	for _, res := range moved.Moved {
+		fmt.Printf("  - %s\n", res.URN)
+	}
+	if len(moved.CopiedProviders) > 0 {
+		fmt.Println("Copying providers that are used in both stacks:")
+		for _, res := range moved.CopiedProviders {
+			fmt.Printf("  - %s\n", res.URN)
+		}
+	}
+	if len(moved.BrokenDependencies) > 0 {
+		fmt.Println(opts.Color.Colorize(colors.SpecWarning +
+			"The following dependencies cross the stacks and will be removed:" + colors.Reset))
+		for _, dep := range moved.BrokenDependencies {
+			if dep.Property != "" {
+				fmt.Printf("  - %s (property %q) depended on %s\n", dep.Dependent, dep.Property, dep.Dependency)
+			} else {
+				fmt.Printf("  - %s depended on %s\n", dep.Dependent, dep.Dependency)
+			}
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+)
+
+func TestStackProject(t *testing.T) {
+	t.Parallel()
+
+	stackWithName := func(fqn tokens.QName) backend.Stack {
+		return &backend.MockStack{
+			RefF: func() backend.StackReference {
+				return &backend.MockStackReference{FullyQualifiedNameV: fqn}
+			},
+		}
+	}
+
+	// Stacks that are scoped to a project take their project from their names.
+	project, err := stackProject(stackWithName("org/networking/dev"), &deploy.Snapshot{})
+	require.NoError(t, err)
+	assert.Equal(t, tokens.PackageName("networking"), project)
+
+	// Legacy stacks take their project from their state.
+	snap := &deploy.Snapshot{
+		Resources: []*resource.State{{URN: resource.NewURN("dev", "app", "", "pulumi:pulumi:Stack", "app-dev")}},
+	}
+	project, err = stackProject(stackWithName("dev"), snap)
+	require.NoError(t, err)
+	assert.Equal(t, tokens.PackageName("app"), project)
+}
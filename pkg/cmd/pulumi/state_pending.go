@@ -0,0 +1,257 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend/display"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy/providers"
+	"github.com/pulumi/pulumi/pkg/v3/resource/edit"
+	"github.com/pulumi/pulumi/pkg/v3/resource/stack"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/plugin"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/result"
+)
+
+func newStatePendingCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pending",
+		Short: "Inspect and resolve a stack's pending operations",
+		Long: `Inspect and resolve a stack's pending operations
+
+A pending operation is recorded in a stack's state when a create, update, delete, read or import starts, and
+is removed when it finishes. If the update is interrupted, the operation is left behind, and the outcome of the
+operation is unknown. Subcommands of this command list pending operations and record their outcomes.`,
+		Args: cmdutil.NoArgs,
+	}
+
+	cmd.AddCommand(newStatePendingLsCommand())
+	cmd.AddCommand(newStatePendingResolveCommand())
+	return cmd
+}
+
+func newStatePendingLsCommand() *cobra.Command {
+	var stackName string
+
+	cmd := &cobra.Command{
+		Use:   "ls",
+		Short: "List a stack's pending operations",
+		Args:  cmdutil.NoArgs,
+		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
+			ctx := commandContext()
+			opts := display.Options{
+				Color: cmdutil.GetGlobalColorization(),
+			}
+			s, err := requireStack(ctx, stackName, stackLoadOnly, opts)
+			if err != nil {
+				return err
+			}
+			snap, err := s.Snapshot(ctx, stack.DefaultSecretsProvider)
+			if err != nil {
+				return err
+			}
+			if snap == nil || len(snap.PendingOperations) == 0 {
+				fmt.Println("There are no pending operations")
+				return nil
+			}
+
+			rows := make([]cmdutil.TableRow, 0, len(snap.PendingOperations))
+			for _, op := range snap.PendingOperations {
+				rows = append(rows, cmdutil.TableRow{Columns: []string{
+					string(op.Type), string(op.Resource.ID), string(op.Resource.URN),
+				}})
+			}
+			cmdutil.PrintTable(cmdutil.Table{
+				Headers: []string{"OPERATION", "ID", "URN"},
+				Rows:    rows,
+			})
+			return nil
+		}),
+	}
+
+	cmd.PersistentFlags().StringVarP(
+		&stackName, "stack", "s", "",
+		"The name of the stack to operate on. Defaults to the current stack")
+	return cmd
+}
+
+func newStatePendingResolveCommand() *cobra.Command {
+	var stackName string
+	var outcome string
+	var id string
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "resolve <resource URN> --as created|deleted|discard",
+		Short: "Record the outcome of a pending operation",
+		Long: `Record the outcome of a pending operation
+
+This command removes a pending operation from a stack's state and records its outcome:
+
+  - created: the resource exists. Its state is read from its provider and saved.
+  - deleted: the resource does not exist. The provider is asked to confirm this, and the resource is removed
+    from the state if it is there.
+  - discard: the operation is dropped without changing any resources.
+
+The ID of the resource is taken from the pending operation. If the operation did not record an ID, for example
+because a create was interrupted before the provider returned one, it can be given with --id. An ID is needed to
+resolve an operation as deleted if the state has more than one resource with the URN.
+
+Make sure that URNs are single-quoted to avoid having characters unexpectedly interpreted by the shell.
+
+Example:
+pulumi state pending resolve 'urn:pulumi:stage::demo::aws:s3/bucket:Bucket::logs' --as created --id logs-1234
+`,
+		Args: cmdutil.ExactArgs(1),
+		Run: cmdutil.RunResultFunc(func(cmd *cobra.Command, args []string) result.Result {
+			ctx := commandContext()
+			yes = yes || skipConfirmations()
+			// Show the confirmation prompt if the user didn't pass the --yes parameter to skip it.
+			showPrompt := !yes && cmdutil.Interactive()
+
+			urn := resource.URN(args[0])
+			if !urn.IsValid() {
+				return result.Error("The provided input URN is not valid")
+			}
+			switch edit.PendingOutcome(outcome) {
+			case edit.PendingCreated, edit.PendingDeleted, edit.PendingDiscarded:
+			default:
+				return result.Errorf("--as must be one of created, deleted, or discard")
+			}
+
+			res := runTotalStateEdit(ctx, stackName, false, func(opts display.Options, snap *deploy.Snapshot) error {
+				i := edit.LocatePendingOperation(snap, urn)
+				if i == -1 {
+					return fmt.Errorf("no pending operation exists for %s", urn)
+				}
+				op := snap.PendingOperations[i]
+				resID := op.Resource.ID
+				if id != "" {
+					resID = resource.ID(id)
+				}
+
+				var state *resource.State
+				switch edit.PendingOutcome(outcome) {
+				case edit.PendingCreated:
+					if resID == "" {
+						return errors.New("the pending operation did not record an ID; use --id to give the resource's ID")
+					}
+					read, err := readPendingResource(snap, op.Resource, resID)
+					if err != nil {
+						return err
+					}
+					if read.Outputs == nil {
+						return fmt.Errorf("the provider reports that %s with ID %s does not exist; use --as deleted",
+							urn, resID)
+					}
+					fmt.Printf("The provider reports that %s exists with ID %s\n", urn, read.ID)
+
+					copied := *op.Resource
+					state = &copied
+					state.ID, state.Outputs = read.ID, read.Outputs
+					if read.Inputs != nil {
+						state.Inputs = read.Inputs
+					}
+				case edit.PendingDeleted:
+					if resID == "" {
+						fmt.Println("The pending operation did not record an ID, so the provider cannot confirm that " +
+							"the resource does not exist")
+						break
+					}
+					read, err := readPendingResource(snap, op.Resource, resID)
+					if err != nil {
+						return err
+					}
+					if read.Outputs != nil {
+						return fmt.Errorf("the provider reports that %s with ID %s still exists; use --as created",
+							urn, resID)
+					}
+					fmt.Printf("The provider reports that %s with ID %s does not exist\n", urn, resID)
+				}
+
+				prompt := fmt.Sprintf("Resolve the pending %s operation on %s as %s?", op.Type, urn, outcome)
+				if showPrompt && !confirmYesNo(prompt, opts) {
+					return errStateEditDeclined
+				}
+				return edit.ResolvePendingOperation(snap, urn, edit.PendingOutcome(outcome), resID, state)
+			})
+			if res != nil {
+				return res
+			}
+
+			fmt.Println("Pending operation resolved")
+			return nil
+		}),
+	}
+
+	cmd.PersistentFlags().StringVarP(
+		&stackName, "stack", "s", "",
+		"The name of the stack to operate on. Defaults to the current stack")
+	cmd.Flags().StringVar(&outcome, "as", "", "The outcome of the operation: created, deleted, or discard")
+	contract.AssertNoErrorf(cmd.MarkFlagRequired("as"), `failed to mark "as" as required`)
+	cmd.Flags().StringVar(&id, "id", "", "The ID of the resource, if the pending operation did not record one")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Skip confirmation prompts")
+	return cmd
+}
+
+// readPendingResource reads the resource with the given ID from the provider of the given resource, which must be in
+// the given snapshot.
+func readPendingResource(snap *deploy.Snapshot, res *resource.State, id resource.ID) (plugin.ReadResult, error) {
+	ref, err := providers.ParseReference(res.Provider)
+	if err != nil {
+		return plugin.ReadResult{}, fmt.Errorf("parsing the provider reference of %s: %w", res.URN, err)
+	}
+	var provider *resource.State
+	for _, r := range snap.Resources {
+		if r.URN == ref.URN() && r.ID == ref.ID() {
+			provider = r
+		}
+	}
+	if provider == nil {
+		return plugin.ReadResult{}, fmt.Errorf("the provider %s of %s is not in the stack's state", ref, res.URN)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return plugin.ReadResult{}, err
+	}
+	sink := cmdutil.Diag()
+	pctx, err := plugin.NewContext(sink, sink, nil, nil, cwd, nil, true, nil)
+	if err != nil {
+		return plugin.ReadResult{}, err
+	}
+	defer contract.IgnoreClose(pctx)
+
+	reg, err := providers.NewRegistry(pctx.Host, []*resource.State{provider}, false, nil)
+	if err != nil {
+		return plugin.ReadResult{}, err
+	}
+	prov, ok := reg.GetProvider(ref)
+	contract.Assertf(ok, "provider %s was not loaded", ref)
+
+	read, _, err := prov.Read(res.URN, id, res.Inputs, res.Outputs)
+	if err != nil {
+		return plugin.ReadResult{}, fmt.Errorf("reading %s from its provider: %w", res.URN, err)
+	}
+	return read, nil
+}
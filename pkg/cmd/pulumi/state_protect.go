@@ -0,0 +1,78 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi/pkg/v3/resource/edit"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/result"
+
+	"github.com/spf13/cobra"
+)
+
+func newStateProtectCommand() *cobra.Command {
+	var stack string
+	var yes bool
+	var filters stateSelectorFlags
+
+	cmd := &cobra.Command{
+		Use:   "protect <resource URN>...",
+		Short: "Protect resources in a stack's state",
+		Long: `Protect resources in a stack's state
+
+This command sets the 'protect' bit on one or more resources, which prevents those resources from being deleted
+or replaced until they are unprotected. It can be used to freeze critical resources without running an update.
+The next update of the stack sets the 'protect' bit of each resource from the program again.
+
+Resources are selected by URN, by URN glob (in which '*' matches any part of a URN segment and '**' matches any
+number of segments), by type with --type, or by property value with --where.
+
+Make sure that URNs are single-quoted to avoid having characters unexpectedly interpreted by the shell.
+
+Example:
+pulumi state protect 'urn:pulumi:prod::demo::aws:rds/instance:Instance::db'
+pulumi state protect --type 'aws:rds/**'
+`,
+		Args: cobra.ArbitraryArgs,
+		Run: cmdutil.RunResultFunc(func(cmd *cobra.Command, args []string) result.Result {
+			ctx := commandContext()
+			yes = yes || skipConfirmations()
+			// Show the confirmation prompt if the user didn't pass the --yes parameter to skip it.
+			showPrompt := !yes
+
+			count, res := runSelectedStateEdit(ctx, stack, showPrompt, args, &filters, "protected",
+				edit.ProtectResource)
+			if res != nil {
+				return res
+			}
+			if filters.bulk(args) {
+				fmt.Printf("%d resources protected\n", count)
+			} else {
+				fmt.Println("Resource protected")
+			}
+			return nil
+		}),
+	}
+
+	cmd.PersistentFlags().StringVarP(
+		&stack, "stack", "s", "",
+		"The name of the stack to operate on. Defaults to the current stack")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Skip confirmation prompts")
+	filters.addFlags(cmd)
+
+	return cmd
+}
@@ -0,0 +1,218 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend/display"
+	"github.com/pulumi/pulumi/pkg/v3/resource/query"
+	"github.com/pulumi/pulumi/pkg/v3/resource/stack"
+	"github.com/pulumi/pulumi/pkg/v3/secrets"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/config"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
+)
+
+func newStateQueryCommand() *cobra.Command {
+	var stackName string
+	var output string
+	var jsonOut bool
+	var columns []string
+	var showSecrets bool
+
+	cmd := &cobra.Command{
+		Use:   "query <expression>",
+		Short: "List the resources in a stack's state that match an expression",
+		Long: `List the resources in a stack's state that match an expression
+
+An expression compares the fields of a resource with ==, !=, <, <=, >, >=, =~ (which matches a regular
+expression) or contains, and combines comparisons with and, or, not and parentheses. The fields are urn,
+type, name, id, parent, provider, protect, custom, delete, retainOnDelete, deletedWith and dependencies,
+and inputs and outputs followed by a property path, such as outputs.tags.env or inputs["key name"][0].
+A field on its own is true unless it is missing, null or false. A secret value is true on its own, but no
+comparison with it is unless --show-secrets is passed.
+
+The matching resources are printed as a table or as CSV, with one column for each field given by --columns,
+or as JSON, in the same format as the resources written by pulumi stack export. Secret values are not shown
+unless --show-secrets is passed.
+
+Make sure that expressions are single-quoted to avoid having characters unexpectedly interpreted by the shell.
+
+Example:
+pulumi state query 'type == "aws:s3/bucket:Bucket" and not outputs.serverSideEncryptionConfiguration'
+pulumi state query 'protect or outputs.tags.env =~ "^prod"' --columns urn,outputs.tags.env --output csv
+`,
+		Args: cmdutil.ExactArgs(1),
+		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
+			ctx := commandContext()
+			opts := display.Options{
+				Color: cmdutil.GetGlobalColorization(),
+			}
+
+			q, err := query.Parse(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid query: %w", err)
+			}
+			if jsonOut {
+				output = "json"
+			}
+			switch output {
+			case "table", "json", "csv":
+			default:
+				return fmt.Errorf("unknown output format %q; expected table, json or csv", output)
+			}
+			fields := make([]*query.Field, len(columns))
+			for i, c := range columns {
+				if fields[i], err = query.ParseField(c); err != nil {
+					return fmt.Errorf("invalid column: %w", err)
+				}
+			}
+
+			s, err := requireStack(ctx, stackName, stackLoadOnly, opts)
+			if err != nil {
+				return err
+			}
+			// Secrets are only decrypted if they are to be shown. Otherwise they are read as their ciphertexts, which
+			// are neither shown nor compared, so the query does not need the stack's secrets provider.
+			var secretsProvider secrets.Provider = ciphertextSecretsProvider{}
+			if showSecrets {
+				secretsProvider = stack.DefaultSecretsProvider
+			}
+			snap, err := s.Snapshot(ctx, secretsProvider)
+			if err != nil {
+				return err
+			}
+			var resources []*resource.State
+			if snap != nil {
+				q.ShowSecrets = showSecrets
+				resources = q.Select(snap.Resources)
+			}
+			if showSecrets {
+				log3rdPartySecretsProviderDecryptionEvent(ctx, s, "", "pulumi state query")
+			}
+
+			switch output {
+			case "json":
+				return printStateQueryJSON(resources, showSecrets)
+			case "csv":
+				return printStateQueryCSV(resources, fields, showSecrets)
+			default:
+				return printStateQueryTable(resources, fields, showSecrets)
+			}
+		}),
+	}
+
+	cmd.PersistentFlags().StringVarP(
+		&stackName, "stack", "s", "",
+		"The name of the stack to operate on. Defaults to the current stack")
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "The output format: table, json or csv")
+	cmd.Flags().BoolVarP(&jsonOut, "json", "j", false, "Emit output as JSON. Shorthand for --output json")
+	cmd.Flags().StringSliceVar(&columns, "columns", []string{"urn", "type", "id"},
+		"The fields of each resource to print in table and CSV output")
+	cmd.Flags().BoolVar(&showSecrets, "show-secrets", false, "Show secret values in plaintext")
+	return cmd
+}
+
+// printStateQueryJSON prints resources in the format of the resources in an exported deployment.
+func printStateQueryJSON(resources []*resource.State, showSecrets bool) error {
+	serialized := make([]apitype.ResourceV3, len(resources))
+	for i, res := range resources {
+		// Secrets are replaced with their values or with "[secret]" rather than encrypted, so the crypter is unused.
+		massaged := *res
+		massaged.Inputs = display.MassageSecrets(res.Inputs, showSecrets)
+		massaged.Outputs = display.MassageSecrets(res.Outputs, showSecrets)
+		r, err := stack.SerializeResource(&massaged, config.NewPanicCrypter(), showSecrets)
+		if err != nil {
+			return err
+		}
+		serialized[i] = r
+	}
+	return printJSON(serialized)
+}
+
+func printStateQueryCSV(resources []*resource.State, fields []*query.Field, showSecrets bool) error {
+	w := csv.NewWriter(os.Stdout)
+	header := make([]string, len(fields))
+	for i, f := range fields {
+		header[i] = f.String()
+	}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	for _, res := range resources {
+		row, err := stateQueryRow(res, fields, showSecrets)
+		if err != nil {
+			return err
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func printStateQueryTable(resources []*resource.State, fields []*query.Field, showSecrets bool) error {
+	if len(resources) == 0 {
+		fmt.Println("No resources match the query")
+		return nil
+	}
+	table := cmdutil.Table{Headers: make([]string, len(fields))}
+	for i, f := range fields {
+		table.Headers[i] = strings.ToUpper(f.String())
+	}
+	for _, res := range resources {
+		row, err := stateQueryRow(res, fields, showSecrets)
+		if err != nil {
+			return err
+		}
+		table.Rows = append(table.Rows, cmdutil.TableRow{Columns: row})
+	}
+	cmdutil.PrintTable(table)
+	return nil
+}
+
+// stateQueryRow formats the given fields of a resource. Strings are printed as they are, and other values as JSON.
+func stateQueryRow(res *resource.State, fields []*query.Field, showSecrets bool) ([]string, error) {
+	row := make([]string, len(fields))
+	for i, f := range fields {
+		v := display.MassageSecrets(resource.PropertyMap{"v": f.Value(res)}, showSecrets)["v"]
+		if v.IsString() {
+			row[i] = v.StringValue()
+			continue
+		}
+		serialized, err := stack.SerializePropertyValue(v, config.NewPanicCrypter(), showSecrets)
+		if err != nil {
+			return nil, err
+		}
+		if serialized == nil {
+			continue
+		}
+		b, err := json.Marshal(serialized)
+		if err != nil {
+			return nil, err
+		}
+		row[i] = string(b)
+	}
+	return row, nil
+}
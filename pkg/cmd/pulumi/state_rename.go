@@ -17,33 +17,23 @@ package main
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/pulumi/pulumi/pkg/v3/backend/display"
 	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
 	"github.com/pulumi/pulumi/pkg/v3/resource/edit"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/util/result"
 
 	"github.com/spf13/cobra"
 )
 
-func updateDependencies(dependencies []resource.URN, oldUrn resource.URN, newUrn resource.URN) []resource.URN {
-	var updatedDependencies []resource.URN
-	for _, dependency := range dependencies {
-		if dependency == oldUrn {
-			// replace old URN with new URN
-			updatedDependencies = append(updatedDependencies, newUrn)
-		} else {
-			updatedDependencies = append(updatedDependencies, dependency)
-		}
-	}
-	return updatedDependencies
-}
-
 func newStateRenameCommand() *cobra.Command {
 	var stack string
 	var yes bool
+	var filters stateSelectorFlags
 
 	cmd := &cobra.Command{
 		Use:   "rename <resource URN> <new name>",
@@ -53,10 +43,16 @@ func newStateRenameCommand() *cobra.Command {
 This command renames a resource from a stack's state. The resource is specified
 by its Pulumi URN (use ` + "`pulumi stack --show-urns`" + ` to get it) and the new name of the resource.
 
+Several resources can be renamed at once by passing a URN glob (in which '*' matches any part of a URN segment
+and '**' matches any number of segments), or by selecting resources by type with --type or by property value with
+--where. In that case, the new name must contain '{name}', which is replaced by the current name of each resource.
+The resources that will be renamed are listed before a single confirmation, and the state is saved once.
+
 Make sure that URNs are single-quoted to avoid having characters unexpectedly interpreted by the shell.
 
 Example:
 pulumi state rename 'urn:pulumi:stage::demo::eks:index:Cluster$pulumi:providers:kubernetes::eks-provider' new-name-here
+pulumi state rename 'urn:pulumi:stage::demo::aws:s3/bucket:Bucket::*' 'legacy-{name}'
 `,
 		Args: cmdutil.ExactArgs(2),
 		Run: cmdutil.RunResultFunc(func(cmd *cobra.Command, args []string) result.Result {
@@ -67,6 +63,31 @@ pulumi state rename 'urn:pulumi:stage::demo::eks:index:Cluster$pulumi:providers:
 			// Show the confirmation prompt if the user didn't pass the --yes parameter to skip it.
 			showPrompt := !yes
 
+			if filters.bulk(args[:1]) {
+				if !strings.Contains(newResourceName, "{name}") {
+					return result.Error("the new name must contain {name} when resources are selected by glob or filter")
+				}
+				selector, err := filters.selector(args[:1])
+				if err != nil {
+					return result.FromError(err)
+				}
+				count, res := runBulkStateEdit(ctx, stack, showPrompt, selector, "renamed",
+					func(snap *deploy.Snapshot, selected []*resource.State) error {
+						for _, res := range selected {
+							newName := strings.ReplaceAll(newResourceName, "{name}", string(res.URN.Name()))
+							if err := edit.RenameResource(snap, res, tokens.QName(newName)); err != nil {
+								return err
+							}
+						}
+						return nil
+					})
+				if res != nil {
+					return res
+				}
+				fmt.Printf("%d resources renamed\n", count)
+				return nil
+			}
+
 			if !urn.IsValid() {
 				return result.Error("The provided input URN is not valid")
 			}
@@ -78,32 +99,13 @@ pulumi state rename 'urn:pulumi:stage::demo::eks:index:Cluster$pulumi:providers:
 					return errors.New("The input URN does not correspond to an existing resource")
 				}
 
-				inputResource := existingResources[0]
-				oldUrn := inputResource.URN
-				// update the URN with only the name part changed
-				newUrn := oldUrn.Rename(newResourceName)
 				// Check whether the new URN _does not_ correspond to an existing resource
-				candidateResources := edit.LocateResource(snap, newUrn)
-				if len(candidateResources) > 0 {
+				if len(edit.LocateResource(snap, urn.Rename(newResourceName))) > 0 {
 					return errors.New("The chosen new name for the state corresponds to an already existing resource")
 				}
 
-				// Update the URN of the input resource
-				inputResource.URN = newUrn
-				// Update the dependants of the input resource
-				for _, existingResource := range snap.Resources {
-					// update resources other than the input resource
-					if existingResource.URN != inputResource.URN {
-						// Update dependencies
-						existingResource.Dependencies = updateDependencies(existingResource.Dependencies, oldUrn, newUrn)
-						// Update property dependencies
-						for property, dependencies := range existingResource.PropertyDependencies {
-							existingResource.PropertyDependencies[property] = updateDependencies(dependencies, oldUrn, newUrn)
-						}
-					}
-				}
-
-				return nil
+				// Update the URN of the input resource and the references to it from the other resources
+				return edit.RenameResource(snap, existingResources[0], tokens.QName(newResourceName))
 			})
 
 			if res != nil {
@@ -122,5 +124,6 @@ pulumi state rename 'urn:pulumi:stage::demo::eks:index:Cluster$pulumi:providers:
 		"The name of the stack to operate on. Defaults to the current stack")
 
 	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Skip confirmation prompts")
+	filters.addFlags(cmd)
 	return cmd
 }
@@ -0,0 +1,169 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend/display"
+	"github.com/pulumi/pulumi/pkg/v3/backend/filestate"
+	"github.com/pulumi/pulumi/pkg/v3/resource/edit"
+	"github.com/pulumi/pulumi/pkg/v3/resource/stack"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag/colors"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/result"
+)
+
+func newStateRepairCommand() *cobra.Command {
+	var stackName string
+	var yes bool
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "repair",
+		Short: "Repair a stack's state that fails its integrity checks",
+		Long: `Repair a stack's state that fails its integrity checks
+
+This command finds problems that make a stack's state invalid and proposes a fix for each of them:
+
+  - a resource whose parent does not exist is re-parented to the root stack resource;
+  - a dependency on a resource that does not exist is dropped;
+  - a resource whose provider does not exist is attached to its package's default provider;
+  - resources that come before their parents, dependencies or providers are sorted.
+
+A resource whose provider does not exist cannot be repaired if there is no default provider for its package;
+such problems are reported without a fix.
+
+Each fix is confirmed before it is applied unless --yes is passed. A report of the changes is printed once
+the state is saved. If the state still fails its integrity checks once the fixes are applied, the problems that
+remain are reported and the state is not saved unless --force is passed. Problems that cannot be fixed
+automatically can be fixed with ` + "`pulumi state edit`" + `.`,
+		Args: cmdutil.NoArgs,
+		Run: cmdutil.RunResultFunc(func(cmd *cobra.Command, args []string) result.Result {
+			ctx := commandContext()
+			yes = yes || skipConfirmations()
+			// Show the confirmation prompts if the user didn't pass the --yes parameter to skip them.
+			showPrompt := !yes && cmdutil.Interactive()
+
+			opts := display.Options{
+				Color: cmdutil.GetGlobalColorization(),
+			}
+
+			// The state is expected to fail its integrity checks, so the self-managed backend must not refuse to load it.
+			s, err := requireStack(filestate.WithoutIntegrityChecking(ctx), stackName, stackLoadOnly, opts)
+			if err != nil {
+				return result.FromError(err)
+			}
+			snap, err := s.Snapshot(ctx, stack.DefaultSecretsProvider)
+			if err != nil {
+				return result.FromError(err)
+			}
+			if snap == nil || snap.VerifyIntegrity() == nil {
+				fmt.Println("The state has no integrity problems")
+				return nil
+			}
+
+			var applied, skipped, unrepairable []*edit.Repair
+			consider := func(r *edit.Repair) {
+				if !r.CanApply() {
+					unrepairable = append(unrepairable, r)
+					return
+				}
+				if showPrompt && !confirmYesNo(repairString(r)+"?", opts) {
+					skipped = append(skipped, r)
+					return
+				}
+				r.Apply()
+				applied = append(applied, r)
+			}
+
+			for _, r := range edit.FindRepairs(snap) {
+				consider(r)
+			}
+			// Sort the resources last, since the other repairs may change what they refer to.
+			order, remaining := edit.FindOrderRepair(snap)
+			if order != nil {
+				consider(order)
+			}
+			if remaining == nil {
+				remaining = snap.VerifyIntegrity()
+			}
+
+			if len(unrepairable) > 0 {
+				fmt.Printf("Found %d problems that cannot be repaired automatically:\n", len(unrepairable))
+				for _, r := range unrepairable {
+					fmt.Printf("  - %s\n", repairString(r))
+				}
+			}
+
+			printSkipped := func() {
+				if len(skipped) > 0 {
+					fmt.Printf("Skipped %d repairs:\n", len(skipped))
+					for _, r := range skipped {
+						fmt.Printf("  - %s\n", repairString(r))
+					}
+				}
+			}
+
+			// Without any repairs the state is unchanged, so there is nothing to save, even with --force.
+			if len(applied) == 0 {
+				printSkipped()
+				fmt.Println("No repairs were applied; the state was not changed")
+				return nil
+			}
+
+			if remaining != nil && !force {
+				fmt.Println(opts.Color.Colorize(colors.SpecWarning +
+					"The state still fails its integrity checks: " + remaining.Error() + colors.Reset))
+				fmt.Println("Use `pulumi state edit` to fix the remaining problems by hand, " +
+					"or pass --force to save the repaired state anyway.")
+				return result.Errorf("the repaired state was not saved")
+			}
+
+			if err := saveSnapshot(ctx, s, snap); err != nil {
+				return result.FromError(err)
+			}
+
+			fmt.Printf("Applied %d repairs:\n", len(applied))
+			for _, r := range applied {
+				fmt.Printf("  - %s\n", repairString(r))
+			}
+			printSkipped()
+			if remaining != nil {
+				fmt.Println(opts.Color.Colorize(colors.SpecWarning +
+					"The state still fails its integrity checks: " + remaining.Error() + colors.Reset))
+				fmt.Println("Use `pulumi state edit` to fix the remaining problems by hand.")
+			}
+			return nil
+		}),
+	}
+
+	cmd.PersistentFlags().StringVarP(
+		&stackName, "stack", "s", "",
+		"The name of the stack to operate on. Defaults to the current stack")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Apply every repair without asking for confirmation")
+	cmd.Flags().BoolVarP(&force, "force", "f", false,
+		"Save the repaired state even if it still fails its integrity checks")
+	return cmd
+}
+
+func repairString(r *edit.Repair) string {
+	if r.URN == "" {
+		return r.Description
+	}
+	return fmt.Sprintf("%s: %s", r.URN, r.Description)
+}
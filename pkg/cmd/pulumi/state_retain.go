@@ -0,0 +1,83 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi/pkg/v3/resource/edit"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/result"
+
+	"github.com/spf13/cobra"
+)
+
+func newStateRetainCommand() *cobra.Command {
+	var stack string
+	var yes bool
+	var unset bool
+	var filters stateSelectorFlags
+
+	cmd := &cobra.Command{
+		Use:   "retain <resource URN>...",
+		Short: "Set or clear the retainOnDelete option of resources in a stack's state",
+		Long: `Set or clear the retainOnDelete option of resources in a stack's state
+
+This command sets the 'retainOnDelete' option on one or more resources, so that if they are deleted by an update
+or a destroy, they are removed from the stack's state but left in place by their providers. Pass --unset to clear
+the option. The next update of the stack sets the option of each resource from the program again.
+
+Resources are selected by URN, by URN glob (in which '*' matches any part of a URN segment and '**' matches any
+number of segments), by type with --type, or by property value with --where.
+
+Make sure that URNs are single-quoted to avoid having characters unexpectedly interpreted by the shell.
+
+Example:
+pulumi state retain 'urn:pulumi:prod::demo::aws:s3/bucket:Bucket::logs'
+pulumi state retain --unset --type 'aws:s3/**'
+`,
+		Args: cobra.ArbitraryArgs,
+		Run: cmdutil.RunResultFunc(func(cmd *cobra.Command, args []string) result.Result {
+			ctx := commandContext()
+			yes = yes || skipConfirmations()
+			// Show the confirmation prompt if the user didn't pass the --yes parameter to skip it.
+			showPrompt := !yes
+
+			operation, verb := edit.RetainResource, "marked as retained on delete"
+			if unset {
+				operation, verb = edit.UnretainResource, "unmarked as retained on delete"
+			}
+			count, res := runSelectedStateEdit(ctx, stack, showPrompt, args, &filters, verb, operation)
+			if res != nil {
+				return res
+			}
+			if filters.bulk(args) {
+				fmt.Printf("%d resources %s\n", count, verb)
+			} else {
+				fmt.Printf("Resource %s\n", verb)
+			}
+			return nil
+		}),
+	}
+
+	cmd.PersistentFlags().StringVarP(
+		&stack, "stack", "s", "",
+		"The name of the stack to operate on. Defaults to the current stack")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Skip confirmation prompts")
+	cmd.Flags().BoolVar(&unset, "unset", false, "Clear the retainOnDelete option instead of setting it")
+	filters.addFlags(cmd)
+
+	return cmd
+}
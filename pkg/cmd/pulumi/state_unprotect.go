@@ -21,7 +21,6 @@ import (
 	"github.com/pulumi/pulumi/pkg/v3/backend/display"
 	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
 	"github.com/pulumi/pulumi/pkg/v3/resource/edit"
-	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/util/result"
@@ -33,14 +32,19 @@ func newStateUnprotectCommand() *cobra.Command {
 	var unprotectAll bool
 	var stack string
 	var yes bool
+	var filters stateSelectorFlags
 
 	cmd := &cobra.Command{
-		Use:   "unprotect <resource URN>",
+		Use:   "unprotect <resource URN>...",
 		Short: "Unprotect resources in a stack's state",
 		Long: `Unprotect resource in a stack's state
 
-This command clears the 'protect' bit on one or more resources, allowing those resources to be deleted.`,
-		Args: cmdutil.MaximumNArgs(1),
+This command clears the 'protect' bit on one or more resources, allowing those resources to be deleted.
+
+Resources are selected by URN, by URN glob (in which '*' matches any part of a URN segment and '**' matches any
+number of segments), by type with --type, or by property value with --where. Pass --all to unprotect every
+resource.`,
+		Args: cobra.ArbitraryArgs,
 		Run: cmdutil.RunResultFunc(func(cmd *cobra.Command, args []string) result.Result {
 			ctx := commandContext()
 			yes = yes || skipConfirmations()
@@ -51,12 +55,17 @@ This command clears the 'protect' bit on one or more resources, allowing those r
 				return unprotectAllResources(ctx, stack, showPrompt)
 			}
 
-			if len(args) != 1 {
-				return result.Error("must provide a URN corresponding to a resource")
+			count, res := runSelectedStateEdit(ctx, stack, showPrompt, args, &filters, "unprotected",
+				edit.UnprotectResource)
+			if res != nil {
+				return res
 			}
-
-			urn := resource.URN(args[0])
-			return unprotectResource(ctx, stack, urn, showPrompt)
+			if filters.bulk(args) {
+				fmt.Printf("%d resources unprotected\n", count)
+			} else {
+				fmt.Println("Resource unprotected")
+			}
+			return nil
 		}),
 	}
 
@@ -65,6 +74,7 @@ This command clears the 'protect' bit on one or more resources, allowing those r
 		"The name of the stack to operate on. Defaults to the current stack")
 	cmd.Flags().BoolVar(&unprotectAll, "all", false, "Unprotect all resources in the checkpoint")
 	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Skip confirmation prompts")
+	filters.addFlags(cmd)
 
 	return cmd
 }
@@ -90,12 +100,3 @@ func unprotectAllResources(ctx context.Context, stackName string, showPrompt boo
 	fmt.Println("All resources unprotected")
 	return nil
 }
-
-func unprotectResource(ctx context.Context, stackName string, urn resource.URN, showPrompt bool) result.Result {
-	res := runStateEdit(ctx, stackName, showPrompt, urn, edit.UnprotectResource)
-	if res != nil {
-		return res
-	}
-	fmt.Println("Resource unprotected")
-	return nil
-}
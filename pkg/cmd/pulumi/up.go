@@ -81,6 +81,8 @@ func newUpCmd() *cobra.Command {
 	var targetReplaces []string
 	var targetDependents bool
 	var planFilePath string
+	var requireApproval []string
+	var approvalCallback string
 
 	// up implementation used when the source of the Pulumi program is in the current working directory.
 	upWorkingDirectory := func(ctx context.Context, opts backend.UpdateOptions) result.Result {
@@ -138,6 +140,10 @@ func newUpCmd() *cobra.Command {
 		if err != nil {
 			return result.FromError(err)
 		}
+		approvals, err := newApprovalPolicy(requireApproval, approvalCallback, &opts.Display)
+		if err != nil {
+			return result.FromError(err)
+		}
 		opts.Engine = engine.UpdateOptions{
 			LocalPolicyPacks:          engine.MakeLocalPolicyPacks(policyPackPaths, policyPackConfigPaths),
 			Parallel:                  parallel,
@@ -151,6 +157,7 @@ func newUpCmd() *cobra.Command {
 			DisableOutputValues:       disableOutputValues(),
 			UpdateTargets:             deploy.NewUrnTargets(targetURNs),
 			TargetDependents:          targetDependents,
+			Approvals:                 approvals,
 			// Trigger a plan to be generated during the preview phase which can be constrained to during the
 			// update phase.
 			GeneratePlan: true,
@@ -356,12 +363,17 @@ func newUpCmd() *cobra.Command {
 		if err != nil {
 			return result.FromError(err)
 		}
+		approvals, err := newApprovalPolicy(requireApproval, approvalCallback, &opts.Display)
+		if err != nil {
+			return result.FromError(err)
+		}
 
 		opts.Engine = engine.UpdateOptions{
 			LocalPolicyPacks: engine.MakeLocalPolicyPacks(policyPackPaths, policyPackConfigPaths),
 			Parallel:         parallel,
 			Debug:            debug,
 			Refresh:          refreshOption,
+			Approvals:        approvals,
 			// If we're in experimental mode then we trigger a plan to be generated during the preview phase
 			// which will be constrained to during the update phase.
 			GeneratePlan: hasExperimentalCommands(),
@@ -477,6 +489,12 @@ func newUpCmd() *cobra.Command {
 				if err != nil {
 					return result.FromError(err)
 				}
+				if len(requireApproval) > 0 {
+					return result.FromError(errors.New("--require-approval is not supported with --remote"))
+				}
+				if approvalCallback != "" {
+					return result.FromError(errors.New("--approval-callback is not supported with --remote"))
+				}
 
 				return runDeployment(ctx, opts.Display, apitype.Update, stackName, args[0], remoteArgs)
 			}
@@ -547,6 +565,14 @@ func newUpCmd() *cobra.Command {
 	cmd.PersistentFlags().BoolVar(
 		&targetDependents, "target-dependents", false,
 		"Allows updating of dependent targets discovered but not specified in --target list")
+	cmd.PersistentFlags().StringSliceVar(
+		&requireApproval, "require-approval", []string{},
+		"Ask for approval before deleting or replacing resources that match any of the given patterns."+
+			" Patterns that begin with `urn:` match resource URNs; all other patterns match resource types."+
+			" Wildcards (*, **) are also supported")
+	cmd.PersistentFlags().StringVar(
+		&approvalCallback, "approval-callback", "", "The URL of an approval callback to ask for approvals")
+	_ = cmd.PersistentFlags().MarkHidden("approval-callback")
 
 	// Flags for engine.UpdateOptions.
 	cmd.PersistentFlags().StringSliceVar(
@@ -60,18 +60,37 @@ func ProjectInfoContext(projinfo *Projinfo, host plugin.Host,
 		return "", "", nil, err
 	}
 
-	if logFile := env.DebugGRPC.Value(); logFile != "" {
+	// Log plugin RPCs to the gRPC debug log and to the deployment recording, if either is enabled.
+	var dis []*interceptors.DebugInterceptor
+	for _, log := range []struct {
+		file      string
+		recording bool
+	}{
+		{file: env.DebugGRPC.Value()},
+		{file: env.RecordDeployment.Value(), recording: true},
+	} {
+		if log.file == "" {
+			continue
+		}
 		di, err := interceptors.NewDebugInterceptor(interceptors.DebugInterceptorOptions{
-			LogFile: logFile,
-			Mutex:   ctx.DebugTraceMutex,
+			LogFile:   log.file,
+			Mutex:     ctx.DebugTraceMutex,
+			Recording: log.recording,
 		})
 		if err != nil {
 			return "", "", nil, err
 		}
+		dis = append(dis, di)
+	}
+	if len(dis) != 0 {
 		ctx.DialOptions = func(metadata interface{}) []grpc.DialOption {
-			return di.DialOptions(interceptors.LogOptions{
-				Metadata: metadata,
-			})
+			var opts []grpc.DialOption
+			for _, di := range dis {
+				opts = append(opts, di.DialOptions(interceptors.LogOptions{
+					Metadata: metadata,
+				})...)
+			}
+			return opts
 		}
 	}
 
@@ -149,6 +168,14 @@ type deploymentOptions struct {
 	trustDependencies bool
 }
 
+// stepTimeout returns the default deadline for each create, update and delete of the deployment.
+func (opts deploymentOptions) stepTimeout() time.Duration {
+	if opts.StepTimeout != 0 {
+		return opts.StepTimeout
+	}
+	return time.Duration(env.StepTimeout.Value()) * time.Second
+}
+
 // deploymentSourceFunc is a callback that will be used to prepare for, and evaluate, the "new" state for a stack.
 type deploymentSourceFunc func(
 	client deploy.BackendClient, opts deploymentOptions, proj *workspace.Project, pwd, main string,
@@ -288,6 +315,9 @@ func (deployment *deployment) run(cancelCtx *Context, actions runActions, policy
 			DisableResourceReferences: deployment.Options.DisableResourceReferences,
 			DisableOutputValues:       deployment.Options.DisableOutputValues,
 			GeneratePlan:              deployment.Options.UpdateOptions.GeneratePlan,
+			Approvals:                 deployment.Options.Approvals,
+			StepTimeout:               deployment.Options.stepTimeout(),
+			ContinueOnError:           deployment.Options.ContinueOnError,
 		}
 		newPlan, walkResult = deployment.Deployment.Execute(ctx, opts, preview)
 		close(done)
@@ -0,0 +1,290 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycletest
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/pulumi/pulumi/pkg/v3/engine"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy/deploytest"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/plugin"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/result"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/workspace"
+)
+
+// approvalRecorder approves every step except those whose URNs it is told to reject, and records the steps for which
+// approval was requested.
+type approvalRecorder struct {
+	m         sync.Mutex
+	reject    map[resource.URN]bool
+	requested []string
+}
+
+func (r *approvalRecorder) approve(_ context.Context, step deploy.Step) (bool, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.requested = append(r.requested, string(step.Op())+" "+string(step.URN()))
+	return !r.reject[step.URN()], nil
+}
+
+func TestApprovalRejectsReplacement(t *testing.T) {
+	t.Parallel()
+
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				DiffF: func(urn resource.URN, id resource.ID,
+					olds, news resource.PropertyMap, ignoreChanges []string,
+				) (plugin.DiffResult, error) {
+					if !olds["value"].DeepEquals(news["value"]) {
+						return plugin.DiffResult{
+							ReplaceKeys:         []resource.PropertyKey{"value"},
+							DeleteBeforeReplace: urn.Name() == "resA",
+						}, nil
+					}
+					return plugin.DiffResult{}, nil
+				},
+			}, nil
+		}),
+	}
+
+	value := "foo"
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		inputs := resource.PropertyMap{"value": resource.NewStringProperty(value)}
+		urnA, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true, deploytest.ResourceOptions{
+			Inputs: inputs,
+		})
+		if err != nil {
+			return err
+		}
+		_, _, _, err = monitor.RegisterResource("pkgA:m:typA", "resB", true, deploytest.ResourceOptions{
+			Inputs:       inputs,
+			Dependencies: []resource.URN{urnA},
+		})
+		if err != nil {
+			return err
+		}
+		_, _, _, err = monitor.RegisterResource("pkgA:m:typB", "resC", true, deploytest.ResourceOptions{
+			Inputs: inputs,
+		})
+		return err
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	p := &TestPlan{
+		Options: UpdateOptions{Host: host},
+	}
+	project := p.GetProject()
+	urnA := p.NewURN("pkgA:m:typA", "resA", "")
+	urnB := p.NewURN("pkgA:m:typA", "resB", "")
+	urnC := p.NewURN("pkgA:m:typB", "resC", "")
+
+	snap, res := TestOp(Update).Run(project, p.GetTarget(t, nil), p.Options, false, p.BackendClient, nil)
+	require.Nil(t, res)
+
+	// Require approval to replace resources of type typA and reject the delete-before-replace replacement of resA.
+	// resB is approved and resC does not need approval, so both are replaced.
+	value = "bar"
+	approvals := &approvalRecorder{reject: map[resource.URN]bool{urnA: true}}
+	p.Options.Approvals = deploy.NewApprovalPolicy([]string{"pkgA:m:typA"}, approvals.approve)
+
+	replaced := make(map[resource.URN]bool)
+	snap, res = TestOp(Update).Run(project, p.GetTarget(t, snap), p.Options, false, p.BackendClient,
+		func(_ workspace.Project, _ deploy.Target, entries JournalEntries, _ []Event, res result.Result) result.Result {
+			for _, entry := range entries {
+				if entry.Step.Op() == deploy.OpReplace {
+					replaced[entry.Step.URN()] = true
+				}
+			}
+			return res
+		})
+	require.Nil(t, res)
+
+	assert.ElementsMatch(t, []string{"replace " + string(urnA), "replace " + string(urnB)}, approvals.requested)
+	assert.Equal(t, map[resource.URN]bool{urnB: true, urnC: true}, replaced)
+
+	require.Len(t, snap.Resources, 4)
+	for _, r := range snap.Resources {
+		assert.False(t, r.Delete)
+		assert.False(t, r.PendingReplacement)
+		switch r.URN {
+		case urnA:
+			assert.Equal(t, "foo", r.Inputs["value"].StringValue())
+		case urnB, urnC:
+			assert.Equal(t, "bar", r.Inputs["value"].StringValue())
+		}
+	}
+
+	// Previews never ask for approval.
+	approvals.requested = nil
+	_, res = TestOp(Update).Run(project, p.GetTarget(t, snap), p.Options, true, p.BackendClient, nil)
+	require.Nil(t, res)
+	assert.Empty(t, approvals.requested)
+}
+
+func TestApprovalRejectedReplacementKeepsGoalMetadata(t *testing.T) {
+	t.Parallel()
+
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				DiffF: func(urn resource.URN, id resource.ID,
+					olds, news resource.PropertyMap, ignoreChanges []string,
+				) (plugin.DiffResult, error) {
+					if !olds["value"].DeepEquals(news["value"]) {
+						return plugin.DiffResult{
+							ReplaceKeys:         []resource.PropertyKey{"value"},
+							DeleteBeforeReplace: true,
+						}, nil
+					}
+					return plugin.DiffResult{}, nil
+				},
+			}, nil
+		}),
+	}
+
+	value, dependOnB := "foo", false
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		urnB, _, _, err := monitor.RegisterResource("pkgA:m:typB", "resB", true)
+		if err != nil {
+			return err
+		}
+		var deps []resource.URN
+		if dependOnB {
+			deps = []resource.URN{urnB}
+		}
+		_, _, _, err = monitor.RegisterResource("pkgA:m:typA", "resA", true, deploytest.ResourceOptions{
+			Inputs:       resource.PropertyMap{"value": resource.NewStringProperty(value)},
+			Dependencies: deps,
+		})
+		return err
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	p := &TestPlan{
+		Options: UpdateOptions{Host: host},
+	}
+	project := p.GetProject()
+	urnA := p.NewURN("pkgA:m:typA", "resA", "")
+	urnB := p.NewURN("pkgA:m:typB", "resB", "")
+
+	snap, res := TestOp(Update).Run(project, p.GetTarget(t, nil), p.Options, false, p.BackendClient, nil)
+	require.Nil(t, res)
+
+	// Reject the replacement of resA, which now also depends on resB.
+	value, dependOnB = "bar", true
+	approvals := &approvalRecorder{reject: map[resource.URN]bool{urnA: true}}
+	p.Options.Approvals = deploy.NewApprovalPolicy([]string{"pkgA:m:typA"}, approvals.approve)
+
+	target := p.GetTarget(t, snap)
+	base := target.Snapshot
+	snap, res = TestOp(Update).Run(project, target, p.Options, false, p.BackendClient, nil)
+	require.Nil(t, res)
+
+	var oldA, newA *resource.State
+	for _, r := range base.Resources {
+		if r.URN == urnA {
+			oldA = r
+		}
+	}
+	for _, r := range snap.Resources {
+		if r.URN == urnA {
+			newA = r
+		}
+	}
+	require.NotNil(t, oldA)
+	require.NotNil(t, newA)
+
+	// The base snapshot is left as it is, and the kept state takes its dependencies from the goal.
+	assert.False(t, oldA.PendingReplacement)
+	assert.Empty(t, oldA.Dependencies)
+	assert.NotSame(t, oldA, newA)
+	assert.Equal(t, "foo", newA.Inputs["value"].StringValue())
+	assert.Equal(t, oldA.ID, newA.ID)
+	assert.Equal(t, []resource.URN{urnB}, newA.Dependencies)
+
+	newA.Inputs["value"] = resource.NewStringProperty("baz")
+	assert.Equal(t, "foo", oldA.Inputs["value"].StringValue())
+}
+
+func TestApprovalRejectsDelete(t *testing.T) {
+	t.Parallel()
+
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{}, nil
+		}),
+	}
+
+	register := true
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		if !register {
+			return nil
+		}
+		urnA, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true)
+		if err != nil {
+			return err
+		}
+		_, _, _, err = monitor.RegisterResource("pkgA:m:typA", "resB", true, deploytest.ResourceOptions{
+			Dependencies: []resource.URN{urnA},
+		})
+		if err != nil {
+			return err
+		}
+		_, _, _, err = monitor.RegisterResource("pkgA:m:typA", "resC", true)
+		return err
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	p := &TestPlan{
+		Options: UpdateOptions{Host: host},
+	}
+	project := p.GetProject()
+	urnA := p.NewURN("pkgA:m:typA", "resA", "")
+	urnB := p.NewURN("pkgA:m:typA", "resB", "")
+
+	snap, res := TestOp(Update).Run(project, p.GetTarget(t, nil), p.Options, false, p.BackendClient, nil)
+	require.Nil(t, res)
+	require.Len(t, snap.Resources, 4)
+
+	// Remove every resource from the program and reject the delete of resB. resB is kept along with resA and the
+	// default provider, which it depends on, while resC is deleted.
+	register = false
+	approvals := &approvalRecorder{reject: map[resource.URN]bool{urnB: true}}
+	patterns := []string{string(p.NewURN("pkgA:m:typA", "*", ""))}
+	p.Options.Approvals = deploy.NewApprovalPolicy(patterns, approvals.approve)
+
+	snap, res = TestOp(Update).Run(project, p.GetTarget(t, snap), p.Options, false, p.BackendClient, nil)
+	require.Nil(t, res)
+	assert.Len(t, approvals.requested, 3)
+
+	var urns []resource.URN
+	for _, r := range snap.Resources {
+		urns = append(urns, r.URN)
+	}
+	assert.ElementsMatch(t, []resource.URN{
+		p.NewProviderURN("pkgA", "default", ""),
+		urnA,
+		urnB,
+	}, urns)
+}
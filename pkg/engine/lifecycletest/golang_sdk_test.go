@@ -2,7 +2,10 @@ package lifecycletest
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"reflect"
+	"sync"
 	"testing"
 
 	"github.com/blang/semver"
@@ -17,6 +20,7 @@ import (
 	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/plugin"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/util/result"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/workspace"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
@@ -928,3 +932,244 @@ func TestRemoteComponentGolang(t *testing.T) {
 	}
 	p.Run(t, nil)
 }
+
+func TestResourceHooksGolangLifecycle(t *testing.T) {
+	t.Parallel()
+
+	creates, deletes := 0, 0
+	replace := false
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				DiffF: func(urn resource.URN, id resource.ID, olds, news resource.PropertyMap,
+					ignoreChanges []string,
+				) (plugin.DiffResult, error) {
+					if replace {
+						return plugin.DiffResult{
+							Changes:     plugin.DiffSome,
+							ReplaceKeys: []resource.PropertyKey{"foo"},
+						}, nil
+					}
+					return plugin.DiffResult{}, nil
+				},
+				CreateF: func(urn resource.URN, news resource.PropertyMap, timeout float64,
+					preview bool,
+				) (resource.ID, resource.PropertyMap, resource.Status, error) {
+					if !preview {
+						creates++
+					}
+					return resource.ID(fmt.Sprintf("id-%d", creates)), news, resource.StatusOK, nil
+				},
+				UpdateF: func(urn resource.URN, id resource.ID, olds, news resource.PropertyMap, timeout float64,
+					ignoreChanges []string, preview bool,
+				) (resource.PropertyMap, resource.Status, error) {
+					return news, resource.StatusOK, nil
+				},
+				DeleteF: func(urn resource.URN, id resource.ID, olds resource.PropertyMap,
+					timeout float64,
+				) (resource.Status, error) {
+					deletes++
+					return resource.StatusOK, nil
+				},
+			}, nil
+		}),
+	}
+
+	var m sync.Mutex
+	var calls []string
+	record := func(_ context.Context, args *pulumi.ResourceHookArgs) error {
+		m.Lock()
+		defer m.Unlock()
+		inputs := args.NewInputs
+		if inputs == nil {
+			inputs = args.OldInputs
+		}
+		calls = append(calls, fmt.Sprintf("%v %v %v %v",
+			args.Kind, resource.URN(args.URN).Name(), args.ID, inputs["foo"].StringValue()))
+		return nil
+	}
+	hooks := []*pulumi.ResourceHook{
+		{Name: "beforeCreate", Kind: pulumi.BeforeCreate, Func: record},
+		{Name: "afterCreate", Kind: pulumi.AfterCreate, Func: record},
+		{Name: "beforeUpdate", Kind: pulumi.BeforeUpdate, Func: record},
+		{Name: "afterUpdate", Kind: pulumi.AfterUpdate, Func: record},
+		{Name: "beforeDelete", Kind: pulumi.BeforeDelete, Func: record},
+		{Name: "afterDelete", Kind: pulumi.AfterDelete, Func: record},
+	}
+
+	failCreate, declareA := false, true
+	foo := "bar"
+	program := deploytest.NewLanguageRuntime(func(info plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		ctx, err := pulumi.NewContext(context.Background(), pulumi.RunInfo{
+			Project:     info.Project,
+			Stack:       info.Stack,
+			Parallel:    info.Parallel,
+			DryRun:      info.DryRun,
+			MonitorAddr: info.MonitorAddress,
+		})
+		require.NoError(t, err)
+		defer contract.IgnoreClose(ctx)
+
+		return pulumi.RunWithContext(ctx, func(ctx *pulumi.Context) error {
+			if !declareA {
+				// The hooks must still be available to run for the resource that has been removed.
+				return ctx.RegisterResourceHooks(hooks...)
+			}
+
+			opts := []pulumi.ResourceOption{pulumi.Hooks(hooks...)}
+			if failCreate {
+				opts = append(opts, pulumi.Hooks(&pulumi.ResourceHook{
+					Name: "failCreate",
+					Kind: pulumi.BeforeCreate,
+					Func: func(context.Context, *pulumi.ResourceHookArgs) error {
+						return errors.New("not ready")
+					},
+				}))
+			}
+
+			var res testResource
+			err := ctx.RegisterResource("pkgA:m:typA", "resA", &testResourceInputs{
+				Foo: pulumi.String(foo),
+			}, &res, opts...)
+			if failCreate {
+				return nil
+			}
+			require.NoError(t, err)
+			return nil
+		})
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	p := &TestPlan{
+		Options: UpdateOptions{Host: host},
+		Steps:   []TestStep{{Op: Update}},
+	}
+
+	// Create the resource. The hooks should run around the create, but not during the preview.
+	snap := p.Run(t, nil)
+	assert.Equal(t, []string{
+		"BEFORE_CREATE resA  bar",
+		"AFTER_CREATE resA id-1 bar",
+	}, calls)
+
+	// Update the resource.
+	calls, foo = nil, "baz"
+	snap = p.Run(t, snap)
+	assert.Equal(t, []string{
+		"BEFORE_UPDATE resA id-1 baz",
+		"AFTER_UPDATE resA id-1 baz",
+	}, calls)
+
+	// Replace the resource. The old resource is deleted after the program has finished, which must still run its
+	// delete hooks.
+	calls, foo, replace = nil, "qux", true
+	snap = p.Run(t, snap)
+	assert.Equal(t, []string{
+		"BEFORE_CREATE resA id-1 qux",
+		"AFTER_CREATE resA id-2 qux",
+		"BEFORE_DELETE resA id-1 baz",
+		"AFTER_DELETE resA id-1 baz",
+	}, calls)
+	assert.Equal(t, 1, deletes)
+
+	// Remove the resource from the program. Its hooks are recorded in the state, so its delete hooks still run.
+	calls, replace, declareA = nil, false, false
+	snap = p.Run(t, snap)
+	assert.Equal(t, []string{
+		"BEFORE_DELETE resA id-2 qux",
+		"AFTER_DELETE resA id-2 qux",
+	}, calls)
+	assert.Equal(t, 2, deletes)
+	// Only the stack remains.
+	assert.Len(t, snap.Resources, 1)
+
+	// A failing hook should fail the step before the provider is called.
+	creates, failCreate, declareA = 0, true, true
+	p.Steps = []TestStep{{Op: Update, ExpectFailure: true, SkipPreview: true}}
+	_ = p.Run(t, nil)
+	assert.Equal(t, 0, creates)
+}
+
+func TestResourceHooksGolangFailuresContinueOnError(t *testing.T) {
+	t.Parallel()
+
+	var m sync.Mutex
+	var created []string
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				CreateF: func(urn resource.URN, news resource.PropertyMap, timeout float64,
+					preview bool,
+				) (resource.ID, resource.PropertyMap, resource.Status, error) {
+					if !preview {
+						m.Lock()
+						created = append(created, string(urn.Name()))
+						m.Unlock()
+					}
+					return resource.ID(urn.Name()), news, resource.StatusOK, nil
+				},
+			}, nil
+		}),
+	}
+
+	fail := func(context.Context, *pulumi.ResourceHookArgs) error {
+		return errors.New("not ready")
+	}
+	program := deploytest.NewLanguageRuntime(func(info plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		ctx, err := pulumi.NewContext(context.Background(), pulumi.RunInfo{
+			Project:     info.Project,
+			Stack:       info.Stack,
+			Parallel:    info.Parallel,
+			DryRun:      info.DryRun,
+			MonitorAddr: info.MonitorAddress,
+		})
+		require.NoError(t, err)
+		defer contract.IgnoreClose(ctx)
+
+		return pulumi.RunWithContext(ctx, func(ctx *pulumi.Context) error {
+			register := func(name string, hooks ...*pulumi.ResourceHook) {
+				var res testResource
+				err := ctx.RegisterResource("pkgA:m:typA", name, &testResourceInputs{
+					Foo: pulumi.String(name),
+				}, &res, pulumi.Hooks(hooks...))
+				require.NoError(t, err)
+			}
+			register("resA", &pulumi.ResourceHook{Name: "failBefore", Kind: pulumi.BeforeCreate, Func: fail})
+			register("resB", &pulumi.ResourceHook{Name: "failAfter", Kind: pulumi.AfterCreate, Func: fail})
+			register("resC")
+			return nil
+		})
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	// The registrations of the resources whose hooks fail are failed rather than left waiting, so the update finishes,
+	// and fails, once every step has run.
+	p := &TestPlan{
+		Options: UpdateOptions{Host: host, ContinueOnError: true},
+	}
+	project := p.GetProject()
+	failed := map[string]bool{}
+	snap, res := TestOp(Update).Run(project, p.GetTarget(t, nil), p.Options, false, p.BackendClient,
+		func(_ workspace.Project, _ deploy.Target, _ JournalEntries, events []Event, res result.Result) result.Result {
+			for _, e := range events {
+				if e.Type == ResourceOperationFailed {
+					failed[string(e.Payload().(ResourceOperationFailedPayload).Metadata.URN.Name())] = true
+				}
+			}
+			return res
+		})
+	require.NotNil(t, res)
+
+	// The failing "before" hook keeps resA from being created. resB is created, and kept in the state, even though
+	// its "after" hook fails. Both failures are reported.
+	assert.ElementsMatch(t, []string{"resB", "resC"}, created)
+	assert.Equal(t, map[string]bool{"resA": true, "resB": true}, failed)
+
+	var names []string
+	for _, r := range snap.Resources {
+		if r.Type == "pkgA:m:typA" {
+			names = append(names, string(r.URN.Name()))
+		}
+	}
+	assert.ElementsMatch(t, []string{"resB", "resC"}, names)
+}
@@ -0,0 +1,189 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycletest
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	. "github.com/pulumi/pulumi/pkg/v3/engine"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy/deploytest"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy/replay"
+	"github.com/pulumi/pulumi/pkg/v3/util/rpcdebug"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/plugin"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/result"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/rpcutil"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/workspace"
+	pulumirpc "github.com/pulumi/pulumi/sdk/v3/proto/go"
+)
+
+// recordProvider serves the given provider over gRPC and returns a client for it that records its RPCs to the given
+// recording, as the engine does for provider plugins.
+func recordProvider(t *testing.T, provider plugin.Provider, recording string) plugin.Provider {
+	stop := make(chan bool)
+	handle, err := rpcutil.ServeWithOptions(rpcutil.ServeOptions{
+		Cancel: stop,
+		Init: func(srv *grpc.Server) error {
+			pulumirpc.RegisterResourceProviderServer(srv, plugin.NewProviderServer(provider))
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { close(stop) })
+
+	di, err := rpcdebug.NewDebugInterceptor(rpcdebug.DebugInterceptorOptions{LogFile: recording, Recording: true})
+	require.NoError(t, err)
+	opts := append(di.DialOptions(rpcdebug.LogOptions{
+		Metadata: map[string]interface{}{"mode": "client", "kind": "resource", "name": string(provider.Pkg())},
+	}), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.Dial(fmt.Sprintf("127.0.0.1:%v", handle.Port), opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return plugin.NewProviderWithClient(nil, provider.Pkg(), pulumirpc.NewResourceProviderClient(conn), false)
+}
+
+//nolint:paralleltest // sets environment variables
+func TestReplayRecordedDeployment(t *testing.T) {
+	recording := filepath.Join(t.TempDir(), "recording.json")
+	t.Setenv("PULUMI_RECORD_DEPLOYMENT", recording)
+
+	var m sync.Mutex
+	creates, updates := 0, 0
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return recordProvider(t, &deploytest.Provider{
+				Package: "pkgA",
+				CreateF: func(urn resource.URN, news resource.PropertyMap, timeout float64,
+					preview bool,
+				) (resource.ID, resource.PropertyMap, resource.Status, error) {
+					m.Lock()
+					defer m.Unlock()
+					creates++
+					return resource.ID(fmt.Sprintf("id-%d", creates)), news, resource.StatusOK, nil
+				},
+				UpdateF: func(urn resource.URN, id resource.ID, olds, news resource.PropertyMap, timeout float64,
+					ignoreChanges []string, preview bool,
+				) (resource.PropertyMap, resource.Status, error) {
+					m.Lock()
+					defer m.Unlock()
+					updates++
+					return news, resource.StatusOK, nil
+				},
+			}, recording), nil
+		}, deploytest.WithoutGrpc),
+	}
+
+	value := "foo"
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		urnA, _, outs, err := monitor.RegisterResource("pkgA:m:typA", "resA", true, deploytest.ResourceOptions{
+			Inputs: resource.PropertyMap{"value": resource.NewStringProperty(value)},
+		})
+		if err != nil {
+			return err
+		}
+		_, _, _, err = monitor.RegisterResource("pkgA:m:typA", "resB", true, deploytest.ResourceOptions{
+			Inputs:       resource.PropertyMap{"fromA": outs["value"]},
+			Dependencies: []resource.URN{urnA},
+		})
+		return err
+	})
+
+	// collectSteps returns a validator that records the steps performed by a deployment.
+	collectSteps := func(steps *[]string) ValidateFunc {
+		return func(_ workspace.Project, _ deploy.Target, _ JournalEntries, events []Event,
+			res result.Result,
+		) result.Result {
+			for _, event := range events {
+				if event.Type == ResourcePreEvent {
+					payload := event.Payload().(ResourcePreEventPayload)
+					*steps = append(*steps, fmt.Sprintf("%v %v", payload.Metadata.Op, payload.Metadata.URN))
+				}
+			}
+			return res
+		}
+	}
+
+	p := &TestPlan{}
+	project := p.GetProject()
+
+	// Record a deployment that creates the resources and then one that updates them.
+	var recorded [2][]string
+	var snaps [2]*deploy.Snapshot
+	var snap *deploy.Snapshot
+	for i, v := range []string{"foo", "bar"} {
+		value = v
+		target := p.GetTarget(t, snap)
+		require.NoError(t, replay.WriteHeader(recording, apitype.UpdateUpdate, false, project.Name, &target))
+		host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+		s, res := TestOp(Update).Run(project, target, UpdateOptions{Host: host}, false,
+			p.BackendClient, collectSteps(&recorded[i]))
+		require.Nil(t, res)
+		snap, snaps[i] = s, s
+	}
+	assert.Equal(t, 2, creates)
+	assert.Equal(t, 2, updates)
+	assert.Contains(t, recorded[1], fmt.Sprintf("%v %v", deploy.OpUpdate, p.NewURN("pkgA:m:typA", "resA", "")))
+
+	// Replay each deployment against the recording and check that it performs the same steps and produces the same
+	// resources without calling the real provider.
+	t.Setenv("PULUMI_RECORD_DEPLOYMENT", "")
+	recordings, err := replay.Load(context.Background(), recording)
+	require.NoError(t, err)
+	require.Len(t, recordings, 2)
+	assert.Equal(t, project.Name, recordings[1].Project)
+	assert.Equal(t, p.GetTarget(t, nil).Name, recordings[1].Stack)
+	assert.Nil(t, recordings[0].Snapshot)
+	require.NotNil(t, recordings[1].Snapshot)
+	assert.Len(t, recordings[1].Snapshot.Resources, 3)
+
+	for i, rec := range recordings {
+		host, err := rec.PluginHost(nil, nil)
+		require.NoError(t, err)
+
+		target := p.GetTarget(t, nil)
+		target.Snapshot = rec.Snapshot
+
+		var replayed []string
+		s, res := TestOp(Update).Run(project, target, UpdateOptions{Host: host}, false,
+			p.BackendClient, collectSteps(&replayed))
+		require.Nil(t, res)
+		assert.ElementsMatch(t, recorded[i], replayed)
+
+		require.Len(t, s.Resources, len(snaps[i].Resources))
+		for j, r := range s.Resources {
+			expected := snaps[i].Resources[j]
+			assert.Equal(t, expected.URN, r.URN)
+			if r.Type.String() != "pulumi:providers:pkgA" {
+				assert.Equal(t, expected.ID, r.ID)
+			}
+			assert.Equal(t, expected.Outputs, r.Outputs)
+		}
+	}
+	assert.Equal(t, 2, creates)
+	assert.Equal(t, 2, updates)
+}
@@ -0,0 +1,387 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycletest
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/pulumi/pulumi/pkg/v3/engine"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy/deploytest"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/plugin"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/result"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/workspace"
+)
+
+func TestStepTimeout(t *testing.T) {
+	t.Parallel()
+
+	canceled := make(chan struct{})
+	var cancelOnce sync.Once
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				CreateF: func(urn resource.URN, news resource.PropertyMap, timeout float64,
+					preview bool,
+				) (resource.ID, resource.PropertyMap, resource.Status, error) {
+					if urn.Name() == "resB" {
+						// Hang until the engine gives up on the create and asks the provider to cancel.
+						<-canceled
+						return "", nil, resource.StatusUnknown, errors.New("canceled")
+					}
+					return resource.ID(urn.Name()), news, resource.StatusOK, nil
+				},
+				CancelF: func() error {
+					cancelOnce.Do(func() { close(canceled) })
+					return nil
+				},
+			}, nil
+		}),
+	}
+
+	var errB error
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		urnA, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true)
+		if err != nil {
+			return err
+		}
+		_, _, _, errB = monitor.RegisterResource("pkgA:m:typA", "resB", true, deploytest.ResourceOptions{
+			Dependencies: []resource.URN{urnA},
+		})
+		// resC does not depend on resB, so it is created even though resB timed out.
+		_, _, _, err = monitor.RegisterResource("pkgA:m:typA", "resC", true)
+		return err
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	p := &TestPlan{
+		Options: UpdateOptions{Host: host, StepTimeout: 100 * time.Millisecond},
+	}
+	project := p.GetProject()
+	urnB := p.NewURN("pkgA:m:typA", "resB", "")
+
+	snap, res := TestOp(Update).Run(project, p.GetTarget(t, nil), p.Options, false, p.BackendClient, nil)
+	require.NotNil(t, res)
+	require.Error(t, errB)
+
+	select {
+	case <-canceled:
+	default:
+		assert.Fail(t, "expected the provider to be asked to cancel")
+	}
+
+	var urns []resource.URN
+	for _, r := range snap.Resources {
+		urns = append(urns, r.URN)
+	}
+	assert.ElementsMatch(t, []resource.URN{
+		p.NewProviderURN("pkgA", "default", ""),
+		p.NewURN("pkgA:m:typA", "resA", ""),
+		p.NewURN("pkgA:m:typA", "resC", ""),
+	}, urns)
+
+	// The provider gave up on the create of resB, so it is not left pending.
+	assert.Empty(t, snap.PendingOperations)
+	assert.NotContains(t, urns, urnB)
+}
+
+func TestStepTimeoutAbandonsHungOperations(t *testing.T) {
+	t.Parallel()
+
+	// The create of resB ignores the request to cancel and does not return until the test is over.
+	hung := make(chan struct{})
+	t.Cleanup(func() { close(hung) })
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				CreateF: func(urn resource.URN, news resource.PropertyMap, timeout float64,
+					preview bool,
+				) (resource.ID, resource.PropertyMap, resource.Status, error) {
+					if urn.Name() == "resB" {
+						<-hung
+					}
+					return resource.ID(urn.Name()), news, resource.StatusOK, nil
+				},
+			}, nil
+		}),
+	}
+
+	var errB error
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		_, _, _, errB = monitor.RegisterResource("pkgA:m:typA", "resB", true)
+		_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resC", true)
+		return err
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	p := &TestPlan{
+		Options: UpdateOptions{Host: host, StepTimeout: 50 * time.Millisecond},
+	}
+	project := p.GetProject()
+	urnB := p.NewURN("pkgA:m:typA", "resB", "")
+
+	// The update finishes even though the provider never returns.
+	snap, res := TestOp(Update).Run(project, p.GetTarget(t, nil), p.Options, false, p.BackendClient, nil)
+	require.NotNil(t, res)
+	require.ErrorContains(t, errB, "abandoned")
+
+	var urns []resource.URN
+	for _, r := range snap.Resources {
+		urns = append(urns, r.URN)
+	}
+	assert.Contains(t, urns, p.NewURN("pkgA:m:typA", "resC", ""))
+
+	// The create of resB may still be in progress, so it is left pending for a later refresh to resolve.
+	require.Len(t, snap.PendingOperations, 1)
+	assert.Equal(t, urnB, snap.PendingOperations[0].Resource.URN)
+	assert.Equal(t, resource.OperationTypeCreating, snap.PendingOperations[0].Type)
+}
+
+func TestStepTimeoutKeepsLateSuccess(t *testing.T) {
+	t.Parallel()
+
+	// The create of resA finishes once it is asked to cancel, but succeeds anyway.
+	canceled := make(chan struct{})
+	var cancelOnce sync.Once
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				CreateF: func(urn resource.URN, news resource.PropertyMap, timeout float64,
+					preview bool,
+				) (resource.ID, resource.PropertyMap, resource.Status, error) {
+					<-canceled
+					return "created", news, resource.StatusOK, nil
+				},
+				CancelF: func() error {
+					cancelOnce.Do(func() { close(canceled) })
+					return nil
+				},
+			}, nil
+		}),
+	}
+
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true)
+		return err
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	p := &TestPlan{
+		Options: UpdateOptions{Host: host, StepTimeout: 50 * time.Millisecond},
+	}
+	project := p.GetProject()
+
+	snap, res := TestOp(Update).Run(project, p.GetTarget(t, nil), p.Options, false, p.BackendClient, nil)
+	require.Nil(t, res)
+	assert.Empty(t, snap.PendingOperations)
+	require.Len(t, snap.Resources, 2)
+	assert.Equal(t, p.NewURN("pkgA:m:typA", "resA", ""), snap.Resources[1].URN)
+	assert.Equal(t, resource.ID("created"), snap.Resources[1].ID)
+}
+
+func TestStepTimeoutKeepsDependenciesOfTimedOutDeletes(t *testing.T) {
+	t.Parallel()
+
+	canceled := make(chan struct{})
+	var cancelOnce sync.Once
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				DeleteF: func(urn resource.URN, id resource.ID, olds resource.PropertyMap,
+					timeout float64,
+				) (resource.Status, error) {
+					if urn.Name() == "resB" {
+						<-canceled
+						return resource.StatusOK, errors.New("canceled")
+					}
+					return resource.StatusOK, nil
+				},
+				CancelF: func() error {
+					cancelOnce.Do(func() { close(canceled) })
+					return nil
+				},
+			}, nil
+		}),
+	}
+
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		urnA, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true)
+		if err != nil {
+			return err
+		}
+		_, _, _, err = monitor.RegisterResource("pkgA:m:typA", "resB", true, deploytest.ResourceOptions{
+			Dependencies: []resource.URN{urnA},
+		})
+		if err != nil {
+			return err
+		}
+		_, _, _, err = monitor.RegisterResource("pkgA:m:typA", "resC", true)
+		return err
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	p := &TestPlan{
+		Options: UpdateOptions{Host: host},
+	}
+	project := p.GetProject()
+
+	snap, res := TestOp(Update).Run(project, p.GetTarget(t, nil), p.Options, false, p.BackendClient, nil)
+	require.Nil(t, res)
+
+	// The delete of resB times out, so resA, which it depends on, is kept, while resC is deleted.
+	p.Options.StepTimeout = 50 * time.Millisecond
+	snap, res = TestOp(Destroy).Run(project, p.GetTarget(t, snap), p.Options, false, p.BackendClient, nil)
+	require.NotNil(t, res)
+
+	var urns []resource.URN
+	for _, r := range snap.Resources {
+		urns = append(urns, r.URN)
+	}
+	assert.ElementsMatch(t, []resource.URN{
+		p.NewProviderURN("pkgA", "default", ""),
+		p.NewURN("pkgA:m:typA", "resA", ""),
+		p.NewURN("pkgA:m:typA", "resB", ""),
+	}, urns)
+	assert.Empty(t, snap.PendingOperations)
+}
+
+func TestStepTimeoutWithholdsDeletesThatDependOnTimedOutSteps(t *testing.T) {
+	t.Parallel()
+
+	canceled := make(chan struct{})
+	var cancelOnce sync.Once
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				UpdateF: func(urn resource.URN, id resource.ID, olds, news resource.PropertyMap, timeout float64,
+					ignoreChanges []string, preview bool,
+				) (resource.PropertyMap, resource.Status, error) {
+					<-canceled
+					return nil, resource.StatusOK, errors.New("canceled")
+				},
+				CancelF: func() error {
+					cancelOnce.Do(func() { close(canceled) })
+					return nil
+				},
+			}, nil
+		}),
+	}
+
+	inputs := resource.PropertyMap{"foo": resource.NewStringProperty("bar")}
+	registerAll := true
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		urnB, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resB", true, deploytest.ResourceOptions{
+			Inputs: inputs,
+		})
+		if !registerAll {
+			// The update of resB times out; the program carries on regardless.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		_, _, _, err = monitor.RegisterResource("pkgA:m:typA", "resA", true)
+		if err != nil {
+			return err
+		}
+		_, _, _, err = monitor.RegisterResource("pkgA:m:typA", "resC", true, deploytest.ResourceOptions{
+			Dependencies: []resource.URN{urnB},
+		})
+		return err
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	p := &TestPlan{
+		Options: UpdateOptions{Host: host},
+	}
+	project := p.GetProject()
+
+	snap, res := TestOp(Update).Run(project, p.GetTarget(t, nil), p.Options, false, p.BackendClient, nil)
+	require.Nil(t, res)
+
+	// resA and resC are no longer registered. resA does not depend on resB, so it is deleted, while the delete of resC,
+	// which depends on resB, is withheld and reported.
+	inputs = resource.PropertyMap{"foo": resource.NewStringProperty("baz")}
+	registerAll = false
+	p.Options.StepTimeout = 50 * time.Millisecond
+	validate := func(project workspace.Project, target deploy.Target, entries JournalEntries,
+		events []Event, res result.Result,
+	) result.Result {
+		var warned bool
+		for _, e := range events {
+			if e.Type != DiagEvent {
+				continue
+			}
+			payload := e.Payload().(DiagEventPayload)
+			if payload.URN == p.NewURN("pkgA:m:typA", "resC", "") && payload.Severity == diag.Warning {
+				assert.Contains(t, payload.Message, "not deleting the resource")
+				warned = true
+			}
+		}
+		assert.True(t, warned, "expected a warning for the withheld delete")
+		return res
+	}
+	snap, res = TestOp(Update).Run(project, p.GetTarget(t, snap), p.Options, false, p.BackendClient, validate)
+	require.NotNil(t, res)
+
+	var urns []resource.URN
+	for _, r := range snap.Resources {
+		urns = append(urns, r.URN)
+	}
+	assert.ElementsMatch(t, []resource.URN{
+		p.NewProviderURN("pkgA", "default", ""),
+		p.NewURN("pkgA:m:typA", "resB", ""),
+		p.NewURN("pkgA:m:typA", "resC", ""),
+	}, urns)
+}
+
+func TestStepTimeoutOnlyAppliesToProviderOperations(t *testing.T) {
+	t.Parallel()
+
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{}, nil
+		}),
+	}
+
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true)
+		return err
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	p := &TestPlan{
+		Options: UpdateOptions{Host: host},
+	}
+	project := p.GetProject()
+
+	snap, res := TestOp(Update).Run(project, p.GetTarget(t, nil), p.Options, false, p.BackendClient, nil)
+	require.Nil(t, res)
+
+	// Nothing changes, so the update only has same steps, which the default deadline does not apply to.
+	p.Options.StepTimeout = time.Nanosecond
+	snap, res = TestOp(Update).Run(project, p.GetTarget(t, snap), p.Options, false, p.BackendClient, nil)
+	require.Nil(t, res)
+	assert.Empty(t, snap.PendingOperations)
+}
@@ -23,6 +23,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	resourceanalyzer "github.com/pulumi/pulumi/pkg/v3/resource/analyzer"
 	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
@@ -156,6 +157,17 @@ type UpdateOptions struct {
 
 	// Experimental is true if the engine is in experimental mode (i.e. PULUMI_EXPERIMENTAL was set)
 	Experimental bool
+
+	// Approvals is an optional policy that requires approval for deletes and replacements of matching resources.
+	Approvals *deploy.ApprovalPolicy
+
+	// StepTimeout is the default deadline for each create, update and delete of the deployment. A resource's custom
+	// timeouts take precedence. If zero, the PULUMI_STEP_TIMEOUT environment variable is used.
+	StepTimeout time.Duration
+
+	// ContinueOnError continues the deployment when a step fails rather than canceling it, so that the steps that do
+	// not depend on the failed step still run. The deployment still fails once it is done.
+	ContinueOnError bool
 }
 
 // HasChanges returns true if there are any non-same changes in the resulting summary.
@@ -611,6 +623,16 @@ func (acts *updateActions) OnResourceStepPost(
 		}
 	}
 
+	// If a create, update or delete timed out and was abandoned, its operation may still be in progress, so we leave
+	// the operation pending in the snapshot. The user can then resolve it once the operation has finished.
+	var timeoutErr *deploy.StepTimeoutError
+	if errors.As(err, &timeoutErr) && timeoutErr.Abandoned {
+		switch step.Op() {
+		case deploy.OpCreate, deploy.OpCreateReplacement, deploy.OpUpdate, deploy.OpDelete, deploy.OpDeleteReplaced:
+			return nil
+		}
+	}
+
 	// Write out the current snapshot. Note that even if a failure has occurred, we should still have a
 	// safe checkpoint.  Note that any error that occurs when writing the checkpoint trumps the error
 	// reported above.
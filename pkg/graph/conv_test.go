@@ -0,0 +1,156 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/pkg/v3/graph"
+	"github.com/pulumi/pulumi/pkg/v3/graph/graphmlconv"
+	"github.com/pulumi/pulumi/pkg/v3/graph/jsonconv"
+	"github.com/pulumi/pulumi/pkg/v3/graph/mermaidconv"
+)
+
+type testVertex struct {
+	label string
+	outs  []graph.Edge
+}
+
+func (v *testVertex) Data() interface{}  { return nil }
+func (v *testVertex) Label() string      { return v.label }
+func (v *testVertex) Ins() []graph.Edge  { return nil }
+func (v *testVertex) Outs() []graph.Edge { return v.outs }
+func (v *testVertex) Attributes() map[string]interface{} {
+	return map[string]interface{}{"protect": v.label == "b"}
+}
+
+type testEdge struct {
+	from, to *testVertex
+	label    string
+}
+
+func (e *testEdge) Data() interface{}  { return nil }
+func (e *testEdge) Label() string      { return e.label }
+func (e *testEdge) To() graph.Vertex   { return e.to }
+func (e *testEdge) From() graph.Vertex { return e.from }
+func (e *testEdge) Color() string      { return "#123456" }
+
+type testGraph struct {
+	roots []graph.Edge
+}
+
+func (g *testGraph) Roots() []graph.Edge { return g.roots }
+
+// newTestGraph returns the graph a -> b -> c, a -> c, whose only root is a.
+func newTestGraph() graph.Graph {
+	a, b, c := &testVertex{label: "a"}, &testVertex{label: "b"}, &testVertex{label: "c \"quoted\""}
+	a.outs = []graph.Edge{&testEdge{from: a, to: b, label: "x"}, &testEdge{from: a, to: c}}
+	b.outs = []graph.Edge{&testEdge{from: b, to: c}}
+	return &testGraph{roots: []graph.Edge{&testEdge{to: a}}}
+}
+
+func TestVertices(t *testing.T) {
+	t.Parallel()
+
+	var labels []string
+	for _, v := range graph.Vertices(newTestGraph()) {
+		labels = append(labels, v.Label())
+	}
+	assert.Equal(t, []string{"a", "b", "c \"quoted\""}, labels)
+}
+
+func TestMermaid(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	require.NoError(t, mermaidconv.Print(newTestGraph(), &buf))
+	assert.Equal(t, `flowchart LR
+    Resource0["a"]
+    Resource1["b"]
+    Resource2["c #quot;quoted#quot;"]
+    Resource0 -->|"x"| Resource1
+    Resource0 --> Resource2
+    Resource1 --> Resource2
+    linkStyle 0 stroke:#123456
+    linkStyle 1 stroke:#123456
+    linkStyle 2 stroke:#123456
+`, buf.String())
+}
+
+func TestGraphML(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	require.NoError(t, graphmlconv.Print(newTestGraph(), &buf))
+
+	var doc struct {
+		Keys []struct {
+			ID   string `xml:"id,attr"`
+			Type string `xml:"attr.type,attr"`
+		} `xml:"key"`
+		Nodes []struct {
+			ID   string `xml:"id,attr"`
+			Data []struct {
+				Key   string `xml:"key,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"data"`
+		} `xml:"graph>node"`
+		Edges []struct {
+			Source string `xml:"source,attr"`
+			Target string `xml:"target,attr"`
+		} `xml:"graph>edge"`
+	}
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+
+	require.Len(t, doc.Keys, 4)
+	assert.Equal(t, "node_label", doc.Keys[0].ID)
+	assert.Equal(t, "node_protect", doc.Keys[1].ID)
+	assert.Equal(t, "boolean", doc.Keys[1].Type)
+	require.Len(t, doc.Nodes, 3)
+	assert.Equal(t, "c \"quoted\"", doc.Nodes[2].Data[0].Value)
+	assert.Equal(t, "true", doc.Nodes[1].Data[1].Value)
+	require.Len(t, doc.Edges, 3)
+	assert.Equal(t, "Resource1", doc.Edges[2].Source)
+	assert.Equal(t, "Resource2", doc.Edges[2].Target)
+}
+
+func TestJSON(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	require.NoError(t, jsonconv.Print(newTestGraph(), &buf))
+
+	var doc struct {
+		Nodes []map[string]interface{} `json:"nodes"`
+		Edges []map[string]interface{} `json:"edges"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(t, []map[string]interface{}{
+		{"id": "Resource0", "label": "a", "protect": false},
+		{"id": "Resource1", "label": "b", "protect": true},
+		{"id": "Resource2", "label": "c \"quoted\"", "protect": false},
+	}, doc.Nodes)
+	assert.Equal(t, []map[string]interface{}{
+		{"from": "Resource0", "to": "Resource1", "label": "x", "color": "#123456"},
+		{"from": "Resource0", "to": "Resource2", "color": "#123456"},
+		{"from": "Resource1", "to": "Resource2", "color": "#123456"},
+	}, doc.Edges)
+}
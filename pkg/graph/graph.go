@@ -46,3 +46,18 @@ type Edge interface {
 	From() Vertex      // the vertex this edge connects from.
 	Color() string     // an optional color for this edge, for when this graph is displayed.
 }
+
+// Attributed is implemented by vertices and edges that carry attributes, such as the type of the resource that a
+// vertex represents, that should be included when the graph is printed in formats that support them. Attribute
+// values are strings, booleans, or numbers.
+type Attributed interface {
+	Attributes() map[string]interface{}
+}
+
+// AttributesOf returns the attributes of the given vertex or edge, or nil if it does not implement Attributed.
+func AttributesOf(x interface{}) map[string]interface{} {
+	if a, ok := x.(Attributed); ok {
+		return a.Attributes()
+	}
+	return nil
+}
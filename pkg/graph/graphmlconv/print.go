@@ -0,0 +1,162 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package graphmlconv converts a resource graph into a GraphML document.  GraphML is an XML format that is understood
+// by many graph tools, such as yEd, Gephi and NetworkX.  Please see http://graphml.graphdrawing.org for a
+// specification of the format.
+package graphmlconv
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/pulumi/pulumi/pkg/v3/graph"
+)
+
+type document struct {
+	XMLName xml.Name `xml:"graphml"`
+	XMLNS   string   `xml:"xmlns,attr"`
+	Keys    []key    `xml:"key"`
+	Graph   body     `xml:"graph"`
+}
+
+type key struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type body struct {
+	ID          string `xml:"id,attr"`
+	EdgeDefault string `xml:"edgedefault,attr"`
+	Nodes       []node `xml:"node"`
+	Edges       []edge `xml:"edge"`
+}
+
+type node struct {
+	ID   string `xml:"id,attr"`
+	Data []data `xml:"data"`
+}
+
+type edge struct {
+	Source string `xml:"source,attr"`
+	Target string `xml:"target,attr"`
+	Data   []data `xml:"data"`
+}
+
+type data struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// keys records the GraphML keys that are used by either nodes or edges.
+type keys struct {
+	domain string
+	types  map[string]string
+}
+
+// add returns the data for the given attribute, declaring its key if this is the first time it has been seen.
+func (ks *keys) add(name string, value interface{}) data {
+	typ, str := "string", fmt.Sprintf("%v", value)
+	switch value.(type) {
+	case bool:
+		typ = "boolean"
+	case int, int32, int64:
+		typ = "long"
+	case float32, float64:
+		typ = "double"
+	}
+	if _, has := ks.types[name]; !has {
+		ks.types[name] = typ
+	}
+	return data{Key: ks.domain + "_" + name, Value: str}
+}
+
+// declare appends the declarations of the keys to the given list, sorted by name.
+func (ks *keys) declare(decls []key) []key {
+	names := make([]string, 0, len(ks.types))
+	for name := range ks.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		decls = append(decls, key{ID: ks.domain + "_" + name, For: ks.domain, AttrName: name, AttrType: ks.types[name]})
+	}
+	return decls
+}
+
+// attributes returns the data for the given attributes, sorted by name.
+func (ks *keys) attributes(attrs map[string]interface{}) []data {
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := make([]data, 0, len(names))
+	for _, name := range names {
+		result = append(result, ks.add(name, attrs[name]))
+	}
+	return result
+}
+
+// Print prints a resource graph.  The label of each vertex and edge, the color of each edge, and the attributes of the
+// vertices and edges that implement graph.Attributed are written as GraphML data.
+func Print(g graph.Graph, w io.Writer) error {
+	nodeKeys := &keys{domain: "node", types: map[string]string{}}
+	edgeKeys := &keys{domain: "edge", types: map[string]string{}}
+
+	vertices := graph.Vertices(g)
+	ids := make(map[graph.Vertex]string, len(vertices))
+	for i, v := range vertices {
+		ids[v] = "Resource" + strconv.Itoa(i)
+	}
+
+	doc := document{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Graph: body{ID: "G", EdgeDefault: "directed"},
+	}
+	for _, v := range vertices {
+		n := node{ID: ids[v], Data: []data{nodeKeys.add("label", v.Label())}}
+		n.Data = append(n.Data, nodeKeys.attributes(graph.AttributesOf(v))...)
+		doc.Graph.Nodes = append(doc.Graph.Nodes, n)
+
+		for _, out := range v.Outs() {
+			e := edge{Source: ids[v], Target: ids[out.To()]}
+			if label := out.Label(); label != "" {
+				e.Data = append(e.Data, edgeKeys.add("label", label))
+			}
+			if color := out.Color(); color != "" {
+				e.Data = append(e.Data, edgeKeys.add("color", color))
+			}
+			e.Data = append(e.Data, edgeKeys.attributes(graph.AttributesOf(out))...)
+			doc.Graph.Edges = append(doc.Graph.Edges, e)
+		}
+	}
+	doc.Keys = edgeKeys.declare(nodeKeys.declare(nil))
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "    ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,72 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jsonconv converts a resource graph into a JSON document listing its nodes and edges.  This is useful for
+// processing the graph with other tools.
+package jsonconv
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/pulumi/pulumi/pkg/v3/graph"
+)
+
+// Print prints a resource graph as a JSON object with "nodes" and "edges" arrays.  Each node has an "id" and a
+// "label", and each edge has a "from" and a "to" node ID and an optional "label" and "color".  The attributes of
+// vertices and edges that implement graph.Attributed are included alongside these fields.
+func Print(g graph.Graph, w io.Writer) error {
+	vertices := graph.Vertices(g)
+	ids := make(map[graph.Vertex]string, len(vertices))
+	for i, v := range vertices {
+		ids[v] = "Resource" + strconv.Itoa(i)
+	}
+
+	nodes := make([]map[string]interface{}, 0, len(vertices))
+	edges := []map[string]interface{}{}
+	for _, v := range vertices {
+		node := object(graph.AttributesOf(v))
+		node["id"], node["label"] = ids[v], v.Label()
+		nodes = append(nodes, node)
+
+		for _, out := range v.Outs() {
+			edge := object(graph.AttributesOf(out))
+			edge["from"], edge["to"] = ids[v], ids[out.To()]
+			if label := out.Label(); label != "" {
+				edge["label"] = label
+			}
+			if color := out.Color(); color != "" {
+				edge["color"] = color
+			}
+			edges = append(edges, edge)
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	return enc.Encode(map[string]interface{}{
+		"nodes": nodes,
+		"edges": edges,
+	})
+}
+
+// object copies the given attributes into a new map, so that the fields of the node or edge can be added to it.
+func object(attrs map[string]interface{}) map[string]interface{} {
+	obj := make(map[string]interface{}, len(attrs)+4)
+	for k, v := range attrs {
+		obj[k] = v
+	}
+	return obj
+}
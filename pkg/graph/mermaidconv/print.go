@@ -0,0 +1,80 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mermaidconv converts a resource graph into a Mermaid flowchart.  Mermaid diagrams can be embedded in
+// Markdown documents and are rendered by many tools, including GitHub.  Please see https://mermaid.js.org for a
+// specification of the syntax.
+package mermaidconv
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pulumi/pulumi/pkg/v3/graph"
+)
+
+// Print prints a resource graph.
+func Print(g graph.Graph, w io.Writer) error {
+	b := bufio.NewWriter(w)
+	indent := "    "
+
+	vertices := graph.Vertices(g)
+	ids := make(map[graph.Vertex]string, len(vertices))
+	for i, v := range vertices {
+		ids[v] = "Resource" + strconv.Itoa(i)
+	}
+
+	if _, err := b.WriteString("flowchart LR\n"); err != nil {
+		return err
+	}
+	for _, v := range vertices {
+		if _, err := fmt.Fprintf(b, "%s%s[\"%s\"]\n", indent, ids[v], escape(v.Label())); err != nil {
+			return err
+		}
+	}
+
+	// Mermaid styles links by their index in the order in which they are declared, so we remember the color of each.
+	var styles []string
+	link := 0
+	for _, v := range vertices {
+		for _, out := range v.Outs() {
+			arrow := "-->"
+			if label := out.Label(); label != "" {
+				arrow = fmt.Sprintf("-->|\"%s\"|", escape(label))
+			}
+			if _, err := fmt.Fprintf(b, "%s%s %s %s\n", indent, ids[v], arrow, ids[out.To()]); err != nil {
+				return err
+			}
+			if color := out.Color(); color != "" {
+				styles = append(styles, fmt.Sprintf("%slinkStyle %d stroke:%s", indent, link, color))
+			}
+			link++
+		}
+	}
+	for _, style := range styles {
+		if _, err := fmt.Fprintln(b, style); err != nil {
+			return err
+		}
+	}
+
+	return b.Flush()
+}
+
+// escape replaces the characters that cannot appear in a quoted Mermaid label with their entity codes.
+func escape(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "\n", " ").Replace(s)
+}
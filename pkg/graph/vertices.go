@@ -0,0 +1,37 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+// Vertices returns the vertices that are reachable from the roots of the graph by following outgoing edges, in
+// breadth-first order starting with the targets of the root edges.
+func Vertices(g Graph) []Vertex {
+	var vertices []Vertex
+	queued := make(map[Vertex]bool)
+	enqueue := func(v Vertex) {
+		if v != nil && !queued[v] {
+			queued[v] = true
+			vertices = append(vertices, v)
+		}
+	}
+	for _, root := range g.Roots() {
+		enqueue(root.To())
+	}
+	for i := 0; i < len(vertices); i++ {
+		for _, out := range vertices[i].Outs() {
+			enqueue(out.To())
+		}
+	}
+	return vertices
+}
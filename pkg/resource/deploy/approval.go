@@ -0,0 +1,79 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+// StepApprovalFunc is called to approve a delete or replacement before it is executed. It returns false to reject the
+// step, in which case the resource is left as it is and the rest of the deployment continues. Returning an error
+// fails the deployment.
+type StepApprovalFunc func(ctx context.Context, step Step) (bool, error)
+
+// ApprovalPolicy gates the deletes and replacements of matching resources on explicit approval. Approval is requested
+// by the deployment executor as the steps are about to be scheduled, so the deployment pauses only for the steps that
+// need approval. Previews never request approval.
+type ApprovalPolicy struct {
+	urns    UrnTargets
+	types   UrnTargets
+	approve StepApprovalFunc
+}
+
+// NewApprovalPolicy creates a policy that requires approval for the deletes and replacements of resources that match
+// any of the given patterns. Patterns that begin with "urn:" are matched against resource URNs; all other patterns are
+// matched against resource types. Both kinds of pattern may use the same globs as UrnTargets. If no patterns are
+// given, every delete and replacement requires approval.
+func NewApprovalPolicy(patterns []string, approve StepApprovalFunc) *ApprovalPolicy {
+	var urns, types []string
+	for _, p := range patterns {
+		if strings.HasPrefix(p, "urn:") {
+			urns = append(urns, p)
+		} else {
+			types = append(types, p)
+		}
+	}
+	return &ApprovalPolicy{
+		urns:    NewUrnTargets(urns),
+		types:   NewUrnTargets(types),
+		approve: approve,
+	}
+}
+
+// RequiresApproval returns true if the given step must be approved before it is executed.
+func (p *ApprovalPolicy) RequiresApproval(step Step) bool {
+	if p == nil {
+		return false
+	}
+	switch step.Op() {
+	case OpDelete, OpReplace:
+	default:
+		return false
+	}
+
+	if !p.urns.IsConstrained() && !p.types.IsConstrained() {
+		return true
+	}
+	return p.urns.IsConstrained() && p.urns.Contains(step.URN()) ||
+		p.types.IsConstrained() && p.types.Contains(resource.URN(step.Type()))
+}
+
+// Approve asks for approval of the given step.
+func (p *ApprovalPolicy) Approve(ctx context.Context, step Step) (bool, error) {
+	return p.approve(ctx, step)
+}
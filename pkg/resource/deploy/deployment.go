@@ -21,6 +21,7 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"time"
 
 	uuid "github.com/gofrs/uuid"
 
@@ -52,20 +53,23 @@ type BackendClient interface {
 
 // Options controls the deployment process.
 type Options struct {
-	Events                    Events     // an optional events callback interface.
-	Parallel                  int        // the degree of parallelism for resource operations (<=1 for serial).
-	Refresh                   bool       // whether or not to refresh before executing the deployment.
-	RefreshOnly               bool       // whether or not to exit after refreshing.
-	RefreshTargets            UrnTargets // The specific resources to refresh during a refresh op.
-	ReplaceTargets            UrnTargets // Specific resources to replace.
-	DestroyTargets            UrnTargets // Specific resources to destroy.
-	UpdateTargets             UrnTargets // Specific resources to update.
-	TargetDependents          bool       // true if we're allowing things to proceed, even with unspecified targets
-	TrustDependencies         bool       // whether or not to trust the resource dependency graph.
-	UseLegacyDiff             bool       // whether or not to use legacy diffing behavior.
-	DisableResourceReferences bool       // true to disable resource reference support.
-	DisableOutputValues       bool       // true to disable output value support.
-	GeneratePlan              bool       // true to enable plan generation.
+	Events                    Events          // an optional events callback interface.
+	Parallel                  int             // the degree of parallelism for resource operations (<=1 for serial).
+	Refresh                   bool            // whether or not to refresh before executing the deployment.
+	RefreshOnly               bool            // whether or not to exit after refreshing.
+	RefreshTargets            UrnTargets      // The specific resources to refresh during a refresh op.
+	ReplaceTargets            UrnTargets      // Specific resources to replace.
+	DestroyTargets            UrnTargets      // Specific resources to destroy.
+	UpdateTargets             UrnTargets      // Specific resources to update.
+	TargetDependents          bool            // true if we're allowing things to proceed, even with unspecified targets
+	TrustDependencies         bool            // whether or not to trust the resource dependency graph.
+	UseLegacyDiff             bool            // whether or not to use legacy diffing behavior.
+	DisableResourceReferences bool            // true to disable resource reference support.
+	DisableOutputValues       bool            // true to disable output value support.
+	GeneratePlan              bool            // true to enable plan generation.
+	Approvals                 *ApprovalPolicy // an optional policy that requires approval for deletes and replacements.
+	StepTimeout               time.Duration   // the default deadline for creates, updates and deletes.
+	ContinueOnError           bool            // true to continue the deployment when a step fails.
 }
 
 // DegreeOfParallelism returns the degree of parallelism that should be used during the
@@ -182,6 +182,10 @@ func (ex *deploymentExecutor) Execute(callerCtx context.Context, opts Options, p
 	if res != nil {
 		return nil, res
 	}
+	// Release a program that is waiting for the deployment to finish so that it can serve resource hooks.
+	if waiter, ok := src.(programWaiter); ok {
+		defer waiter.releaseProgram()
+	}
 
 	// Set up a step generator for this deployment.
 	ex.stepGen = newStepGenerator(ex.deployment, opts, updateTargetsOpt, replaceTargetsOpt)
@@ -191,7 +195,7 @@ func (ex *deploymentExecutor) Execute(callerCtx context.Context, opts Options, p
 	ctx, cancel := context.WithCancel(callerCtx)
 
 	// Set up a step generator and executor for this deployment.
-	ex.stepExec = newStepExecutor(ctx, cancel, ex.deployment, opts, preview, false)
+	ex.stepExec = newStepExecutor(ctx, cancel, ex.deployment, opts, preview, opts.ContinueOnError)
 
 	// We iterate the source in its own goroutine because iteration is blocking and we want the main loop to be able to
 	// respond to cancellation requests promptly.
@@ -242,7 +246,7 @@ func (ex *deploymentExecutor) Execute(callerCtx context.Context, opts Options, p
 				}
 
 				if event.Event == nil {
-					res := ex.performDeletes(ctx, updateTargetsOpt, destroyTargetsOpt)
+					res := ex.performDeletes(ctx, opts, updateTargetsOpt, destroyTargetsOpt)
 					if res != nil {
 						if resErr := res.Error(); resErr != nil {
 							logging.V(4).Infof("deploymentExecutor.Execute(...): error performing deletes: %v", resErr)
@@ -253,7 +257,7 @@ func (ex *deploymentExecutor) Execute(callerCtx context.Context, opts Options, p
 					return false, res
 				}
 
-				if res := ex.handleSingleEvent(event.Event); res != nil {
+				if res := ex.handleSingleEvent(ctx, opts, event.Event); res != nil {
 					if resErr := res.Error(); resErr != nil {
 						logging.V(4).Infof("deploymentExecutor.Execute(...): error handling event: %v", resErr)
 						ex.reportError(ex.deployment.generateEventURN(event.Event), resErr)
@@ -346,7 +350,7 @@ func (ex *deploymentExecutor) Execute(callerCtx context.Context, opts Options, p
 }
 
 func (ex *deploymentExecutor) performDeletes(
-	ctx context.Context, updateTargetsOpt, destroyTargetsOpt UrnTargets,
+	ctx context.Context, opts Options, updateTargetsOpt, destroyTargetsOpt UrnTargets,
 ) result.Result {
 	defer func() {
 		// We're done here - signal completion so that the step executor knows to terminate.
@@ -376,6 +380,17 @@ func (ex *deploymentExecutor) performDeletes(
 		return res
 	}
 
+	deleteSteps, res = ex.approveDeletes(ctx, opts.Approvals, deleteSteps)
+	if res != nil {
+		return res
+	}
+
+	// If a step failed without canceling the deployment, e.g. because it timed out, its resource may not have been
+	// replaced or updated, so the deletes that replace it or that depend on it are withheld.
+	if failed := ex.stepExec.Failed(); len(failed) > 0 {
+		deleteSteps = ex.withholdDeletes(deleteSteps, failed)
+	}
+
 	deletes := ex.stepGen.ScheduleDeletes(deleteSteps)
 
 	// ScheduleDeletes gives us a list of lists of steps. Each list of steps can safely be executed
@@ -385,11 +400,29 @@ func (ex *deploymentExecutor) performDeletes(
 	// This is not "true" delete parallelism, since there may be resources that could safely begin
 	// deleting but we won't until the previous set of deletes fully completes. This approximation
 	// is conservative, but correct.
+	//
+	// If a delete times out, its resource may still exist, so the resources that it depends on are kept.
+	kept := make(map[*resource.State]bool)
 	for _, antichain := range deletes {
+		if len(kept) > 0 {
+			antichain = ex.keepDeletes(antichain, kept)
+		}
+
 		logging.V(4).Infof("deploymentExecutor.Execute(...): beginning delete antichain")
 		tok := ex.stepExec.ExecuteParallel(antichain)
 		tok.Wait(ctx)
 		logging.V(4).Infof("deploymentExecutor.Execute(...): antichain complete")
+
+		for _, step := range ex.stepExec.TimedOut() {
+			if (step.Op() == OpDelete || step.Op() == OpDeleteReplaced) && !kept[step.Old()] {
+				kept[step.Old()] = true
+				if ex.deployment.depGraph != nil {
+					for dep := range ex.deployment.depGraph.TransitiveDependenciesOf(step.Old()) {
+						kept[dep] = true
+					}
+				}
+			}
+		}
 	}
 
 	// After executing targeted deletes, we may now have resources that depend on the resource that
@@ -406,9 +439,135 @@ func (ex *deploymentExecutor) performDeletes(
 	return nil
 }
 
+// keepDeletes returns the steps in the given antichain that do not delete a kept resource. The deletes of the kept
+// resources are not executed.
+func (ex *deploymentExecutor) keepDeletes(steps antichain, kept map[*resource.State]bool) antichain {
+	remaining := make(antichain, 0, len(steps))
+	for _, step := range steps {
+		if kept[step.Old()] {
+			ex.deployment.Diag().Warningf(diag.RawMessage(step.URN(),
+				"not deleting the resource because the delete of a resource that depends on it timed out"))
+			ex.stepGen.keepDeleted(step)
+			continue
+		}
+		remaining = append(remaining, step)
+	}
+	return remaining
+}
+
+// withholdDeletes returns the given deletes less those that replace a resource whose step failed or that delete a
+// resource that depends on one. The withheld deletes are not executed.
+func (ex *deploymentExecutor) withholdDeletes(deleteSteps []Step, failed []resource.URN) []Step {
+	withheld := make(map[resource.URN]resource.URN)
+	for _, urn := range failed {
+		if _, has := withheld[urn]; !has {
+			withheld[urn] = urn
+		}
+		old, has := ex.deployment.olds[urn]
+		if !has || ex.deployment.depGraph == nil {
+			continue
+		}
+		for _, dependent := range ex.deployment.depGraph.DependingOn(old, nil, true) {
+			if _, has := withheld[dependent.URN]; !has {
+				withheld[dependent.URN] = urn
+			}
+		}
+	}
+
+	remaining := make([]Step, 0, len(deleteSteps))
+	for _, step := range deleteSteps {
+		cause, has := withheld[step.URN()]
+		if !has {
+			remaining = append(remaining, step)
+			continue
+		}
+
+		if cause == step.URN() {
+			ex.deployment.Diag().Warningf(diag.RawMessage(step.URN(),
+				"not deleting the resource because the step that replaces it failed"))
+		} else {
+			ex.deployment.Diag().Warningf(diag.RawMessage(step.URN(),
+				fmt.Sprintf("not deleting the resource because it depends on %v, whose step failed", cause)))
+		}
+		ex.stepGen.keepDeleted(step)
+	}
+	return remaining
+}
+
+// approveDeletes asks for approval of each delete that requires it and returns the deletes that may proceed. A
+// rejected delete leaves its resource in the stack, along with every resource that the rejected resource depends on.
+func (ex *deploymentExecutor) approveDeletes(
+	ctx context.Context, policy *ApprovalPolicy, deleteSteps []Step,
+) ([]Step, result.Result) {
+	if ex.deployment.preview {
+		return deleteSteps, nil
+	}
+
+	kept := make(map[*resource.State]bool)
+	for _, step := range deleteSteps {
+		if !policy.RequiresApproval(step) {
+			continue
+		}
+
+		approved, err := policy.Approve(ctx, step)
+		if err != nil {
+			return nil, result.FromError(fmt.Errorf("requesting approval to delete %v: %w", step.URN(), err))
+		}
+		if !approved {
+			ex.deployment.Diag().Warningf(diag.RawMessage(step.URN(), "delete was rejected; leaving the resource as is"))
+			kept[step.Old()] = true
+			if ex.deployment.depGraph != nil {
+				for dep := range ex.deployment.depGraph.TransitiveDependenciesOf(step.Old()) {
+					kept[dep] = true
+				}
+			}
+		}
+	}
+	if len(kept) == 0 {
+		return deleteSteps, nil
+	}
+
+	approved := make([]Step, 0, len(deleteSteps))
+	for _, step := range deleteSteps {
+		if kept[step.Old()] {
+			ex.stepGen.keepDeleted(step)
+			continue
+		}
+		approved = append(approved, step)
+	}
+	return approved, nil
+}
+
+// approveReplacement asks for approval of the replacement in the given chain of steps, if it requires it. If the
+// replacement is rejected, the chain is replaced with a single step that leaves the resource as it is.
+func (ex *deploymentExecutor) approveReplacement(
+	ctx context.Context, policy *ApprovalPolicy, event RegisterResourceEvent, chain []Step,
+) ([]Step, result.Result) {
+	if ex.deployment.preview {
+		return chain, nil
+	}
+
+	for _, step := range chain {
+		if step.Op() != OpReplace || !policy.RequiresApproval(step) {
+			continue
+		}
+
+		approved, err := policy.Approve(ctx, step)
+		if err != nil {
+			return nil, result.FromError(fmt.Errorf("requesting approval to replace %v: %w", step.URN(), err))
+		}
+		if !approved {
+			ex.deployment.Diag().Warningf(diag.RawMessage(step.URN(),
+				"replacement was rejected; leaving the resource as is"))
+			return []Step{ex.stepGen.keepReplaced(event, chain, step)}, nil
+		}
+	}
+	return chain, nil
+}
+
 // handleSingleEvent handles a single source event. For all incoming events, it produces a chain that needs
 // to be executed and schedules the chain for execution.
-func (ex *deploymentExecutor) handleSingleEvent(event SourceEvent) result.Result {
+func (ex *deploymentExecutor) handleSingleEvent(ctx context.Context, opts Options, event SourceEvent) result.Result {
 	contract.Requiref(event != nil, "event", "must not be nil")
 
 	var steps []Step
@@ -417,6 +576,9 @@ func (ex *deploymentExecutor) handleSingleEvent(event SourceEvent) result.Result
 	case RegisterResourceEvent:
 		logging.V(4).Infof("deploymentExecutor.handleSingleEvent(...): received RegisterResourceEvent")
 		steps, res = ex.stepGen.GenerateSteps(e)
+		if res == nil {
+			steps, res = ex.approveReplacement(ctx, opts.Approvals, e, steps)
+		}
 	case ReadResourceEvent:
 		logging.V(4).Infof("deploymentExecutor.handleSingleEvent(...): received ReadResourceEvent")
 		steps, res = ex.stepGen.GenerateReadSteps(e)
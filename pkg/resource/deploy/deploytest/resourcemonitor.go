@@ -107,6 +107,7 @@ type ResourceOptions struct {
 	Remote                  bool
 	Providers               map[string]string
 	AdditionalSecretOutputs []resource.PropertyKey
+	Hooks                   []*pulumirpc.RegisterResourceRequest_ResourceHook
 
 	DisableSecrets            bool
 	DisableResourceReferences bool
@@ -225,6 +226,7 @@ func (rm *ResourceMonitor) RegisterResource(t tokens.Type, name string, custom b
 		AdditionalSecretOutputs:    additionalSecretOutputs,
 		Aliases:                    aliasObjects,
 		DeletedWith:                string(opts.DeletedWith),
+		Hooks:                      opts.Hooks,
 	}
 
 	// submit request
@@ -0,0 +1,158 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// recordedConn is a gRPC client connection that answers each call with the response recorded for it.
+//
+// Calls are matched to recorded entries by method and by the URN or token in the request, if any. Calls with the same
+// key are answered in the order in which they were recorded; once the recorded entries for a key are exhausted the
+// last one is reused, which covers calls such as GetPluginInfo that the engine may make more often on replay.
+type recordedConn struct {
+	m       sync.Mutex
+	entries map[string][]Entry
+	next    map[string]int
+}
+
+var _ grpc.ClientConnInterface = (*recordedConn)(nil)
+
+func newRecordedConn(entries []Entry) (*recordedConn, error) {
+	conn := &recordedConn{
+		entries: make(map[string][]Entry),
+		next:    make(map[string]int),
+	}
+	for _, e := range entries {
+		key, err := callKey(e.Method, e.Request)
+		if err != nil {
+			return nil, fmt.Errorf("decoding recorded %v request: %w", e.Method, err)
+		}
+		conn.entries[key] = append(conn.entries[key], e)
+	}
+	return conn, nil
+}
+
+// callKey returns the key used to match a call to its recorded entries.
+func callKey(method string, request json.RawMessage) (string, error) {
+	if len(request) == 0 || bytes.Equal(request, []byte("null")) {
+		return method, nil
+	}
+
+	var fields struct {
+		URN   string `json:"urn"`
+		Token string `json:"tok"`
+	}
+	if err := json.Unmarshal(request, &fields); err != nil {
+		return "", err
+	}
+	switch {
+	case fields.URN != "":
+		return method + " " + fields.URN, nil
+	case fields.Token != "":
+		return method + " " + fields.Token, nil
+	default:
+		return method, nil
+	}
+}
+
+// lookup returns the recorded entry for the next call to the given method with the given request.
+func (c *recordedConn) lookup(method string, args interface{}) (Entry, error) {
+	req, err := marshalMessage(args)
+	if err != nil {
+		return Entry{}, err
+	}
+	key, err := callKey(method, req)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	entries := c.entries[key]
+	if len(entries) == 0 {
+		return Entry{}, status.Errorf(codes.Unimplemented, "no recorded response for %v", key)
+	}
+	i := c.next[key]
+	if i < len(entries)-1 {
+		c.next[key] = i + 1
+	}
+	return entries[i], nil
+}
+
+func (c *recordedConn) Invoke(ctx context.Context, method string, args interface{}, reply interface{},
+	opts ...grpc.CallOption,
+) error {
+	entry, err := c.lookup(method, args)
+	if err != nil {
+		return err
+	}
+	if len(entry.Status) != 0 {
+		var st spb.Status
+		if err := unmarshalMessage(entry.Status, &st); err != nil {
+			return fmt.Errorf("decoding recorded %v status: %w", method, err)
+		}
+		return status.ErrorProto(&st)
+	}
+	if len(entry.Errors) != 0 {
+		// The recording does not hold the call's status, so its code is unknown.
+		return status.Error(codes.Unknown, strings.Join(entry.Errors, "; "))
+	}
+	return unmarshalMessage(entry.Response, reply)
+}
+
+func (c *recordedConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string,
+	opts ...grpc.CallOption,
+) (grpc.ClientStream, error) {
+	return nil, status.Errorf(codes.Unimplemented, "streaming call %v cannot be replayed", method)
+}
+
+func marshalMessage(m interface{}) (json.RawMessage, error) {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("expected a proto.Message, got %T", m)
+	}
+	var buf bytes.Buffer
+	if err := (&jsonpb.Marshaler{}).Marshal(&buf, msg); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func unmarshalMessage(data json.RawMessage, m interface{}) error {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return fmt.Errorf("expected a proto.Message, got %T", m)
+	}
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	u := jsonpb.Unmarshaler{AllowUnknownFields: true}
+	return u.Unmarshal(bytes.NewReader(data), msg)
+}
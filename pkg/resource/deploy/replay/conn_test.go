@@ -0,0 +1,127 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	pbempty "github.com/golang/protobuf/ptypes/empty"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pulumirpc "github.com/pulumi/pulumi/sdk/v3/proto/go"
+)
+
+// recordedEntry returns an entry that records a call to the given provider method on the given connection.
+func recordedEntry(t *testing.T, conn int, method string, req, resp proto.Message) Entry {
+	request, err := marshalMessage(req)
+	require.NoError(t, err)
+	response, err := marshalMessage(resp)
+	require.NoError(t, err)
+	return Entry{
+		Method:   "/pulumirpc.ResourceProvider/" + method,
+		Request:  request,
+		Response: response,
+		Metadata: map[string]interface{}{"mode": "client", "kind": "resource", "name": "pkgA", "conn": float64(conn)},
+	}
+}
+
+func TestCallKey(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		request string
+		want    string
+	}{
+		{``, "/m"},
+		{`null`, "/m"},
+		{`{}`, "/m"},
+		{`{"urn":"urn:pulumi:stack::proj::pkgA:m:typA::resA","tok":"pkgA:index:fn"}`,
+			"/m urn:pulumi:stack::proj::pkgA:m:typA::resA"},
+		{`{"tok":"pkgA:index:fn"}`, "/m pkgA:index:fn"},
+	}
+	for _, tt := range tests {
+		key, err := callKey("/m", json.RawMessage(tt.request))
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, key, tt.request)
+	}
+}
+
+func TestRecordedConn(t *testing.T) {
+	t.Parallel()
+
+	urn := "urn:pulumi:stack::proj::pkgA:m:typA::resA"
+	failed := recordedEntry(t, 1, "Delete", &pulumirpc.DeleteRequest{Urn: urn}, &pbempty.Empty{})
+	failed.Errors = []string{"delete failed"}
+
+	// A failure whose status was recorded is replayed with its code and details.
+	initFailed, err := status.New(codes.Unknown, "create failed").WithDetails(&pulumirpc.ErrorResourceInitFailed{
+		Id:      "id-3",
+		Reasons: []string{"not ready"},
+	})
+	require.NoError(t, err)
+	failedCreate := recordedEntry(t, 1, "Create", &pulumirpc.CreateRequest{Urn: urn}, &pulumirpc.CreateResponse{})
+	failedCreate.Errors = []string{initFailed.Err().Error()}
+	failedCreate.Status, err = marshalMessage(initFailed.Proto())
+	require.NoError(t, err)
+	notFound := recordedEntry(t, 1, "Invoke", &pulumirpc.InvokeRequest{Tok: "pkgA:index:fn"}, &pulumirpc.InvokeResponse{})
+	notFound.Errors = []string{"rpc error: code = NotFound desc = no such thing"}
+	notFound.Status, err = marshalMessage(status.New(codes.NotFound, "no such thing").Proto())
+	require.NoError(t, err)
+
+	conn, err := newRecordedConn([]Entry{
+		recordedEntry(t, 1, "Read", &pulumirpc.ReadRequest{Urn: urn}, &pulumirpc.ReadResponse{Id: "id-1"}),
+		recordedEntry(t, 1, "Read", &pulumirpc.ReadRequest{Urn: urn}, &pulumirpc.ReadResponse{Id: "id-2"}),
+		failed,
+		failedCreate,
+		notFound,
+	})
+	require.NoError(t, err)
+	client := pulumirpc.NewResourceProviderClient(conn)
+	ctx := context.Background()
+
+	// Calls with the same key are answered in order, and the last answer is reused.
+	for _, id := range []string{"id-1", "id-2", "id-2"} {
+		resp, err := client.Read(ctx, &pulumirpc.ReadRequest{Urn: urn})
+		require.NoError(t, err)
+		assert.Equal(t, id, resp.GetId())
+	}
+
+	_, err = client.Delete(ctx, &pulumirpc.DeleteRequest{Urn: urn})
+	assert.ErrorContains(t, err, "delete failed")
+	assert.Equal(t, codes.Unknown, status.Code(err))
+
+	_, err = client.Create(ctx, &pulumirpc.CreateRequest{Urn: urn})
+	st := status.Convert(err)
+	assert.Equal(t, codes.Unknown, st.Code())
+	assert.Equal(t, "create failed", st.Message())
+	require.Len(t, st.Details(), 1)
+	detail, ok := st.Details()[0].(*pulumirpc.ErrorResourceInitFailed)
+	require.True(t, ok)
+	assert.Equal(t, "id-3", detail.GetId())
+	assert.Equal(t, []string{"not ready"}, detail.GetReasons())
+
+	_, err = client.Invoke(ctx, &pulumirpc.InvokeRequest{Tok: "pkgA:index:fn"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, "no such thing", status.Convert(err).Message())
+
+	_, err = client.Read(ctx, &pulumirpc.ReadRequest{Urn: "urn:pulumi:stack::proj::pkgA:m:typA::resB"})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
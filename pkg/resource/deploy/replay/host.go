@@ -0,0 +1,269 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/blang/semver"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy/deploytest"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy/providers"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/plugin"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+	pulumirpc "github.com/pulumi/pulumi/sdk/v3/proto/go"
+)
+
+const (
+	checkConfigMethod = "/pulumirpc.ResourceProvider/CheckConfig"
+	configureMethod   = "/pulumirpc.ResourceProvider/Configure"
+)
+
+// PluginHost returns a plugin host that serves the recorded deployment: its providers answer each RPC with the
+// recorded response and its language runtime replays the recorded resource monitor requests.
+//
+// The host is intended to be passed as the Host of the engine's UpdateOptions, with the recording's Snapshot as the
+// base snapshot of the deployment's target.
+func (r *Recording) PluginHost(sink, statusSink diag.Sink) (plugin.Host, error) {
+	recorded, err := r.providers()
+	if err != nil {
+		return nil, err
+	}
+
+	loaders := make([]*deploytest.ProviderLoader, 0, len(recorded.packages))
+	for _, pkg := range recorded.packages {
+		pkg := pkg
+		loaders = append(loaders, deploytest.NewProviderLoader(pkg, semver.Version{}, func() (plugin.Provider, error) {
+			client := pulumirpc.NewResourceProviderClient(&providerConn{providers: recorded, pkg: pkg})
+			return plugin.NewProviderWithClient(nil, pkg, client, false /* disableProviderPreview */), nil
+		}, deploytest.WithoutGrpc))
+	}
+
+	runtime, err := r.LanguageRuntime()
+	if err != nil {
+		return nil, err
+	}
+	return &host{Host: deploytest.NewPluginHost(sink, statusSink, runtime, loaders...)}, nil
+}
+
+// host is a plugin host that ignores the versions of the providers requested by the engine: the recording holds a
+// single set of responses for each provider package.
+type host struct {
+	plugin.Host
+}
+
+func (h *host) Provider(pkg tokens.Package, version *semver.Version) (plugin.Provider, error) {
+	return h.Host.Provider(pkg, nil)
+}
+
+// recordedProviders holds the recorded connections to provider plugins, keyed by the provider resource that each
+// connection served.
+//
+// The engine loads a new plugin for each provider resource, but it does not tell the plugin host which resource a
+// plugin is for. Instead, each replayed provider is bound to its recorded connection by the first call that
+// identifies it: providers from the base snapshot are loaded and configured in the order in which they appear in the
+// snapshot before any other provider is loaded, and every other provider has its configuration checked with its URN
+// before it is used.
+type recordedProviders struct {
+	m sync.Mutex
+
+	// packages lists the packages of the recorded providers.
+	packages []tokens.Package
+	// byRef maps the reference of each provider in the base snapshot and the URN of each provider registered during
+	// the deployment to the connections recorded for it, in the order in which they were opened. Providers that are
+	// registered during the deployment are keyed by URN because the engine assigns them new IDs on replay.
+	byRef map[string][]*recordedConn
+	// snapshot lists the references of each package's providers in the base snapshot, in the order in which they are
+	// loaded, and loaded counts the ones that have been bound.
+	snapshot map[tokens.Package][]string
+	loaded   map[tokens.Package]int
+	// unbound holds a connection for each package that answers the calls made before a provider is bound, such as
+	// GetPluginInfo.
+	unbound map[tokens.Package]*recordedConn
+}
+
+// providers groups the recorded provider RPCs by the provider resource on whose connection they were made.
+func (r *Recording) providers() (*recordedProviders, error) {
+	var ids []int64
+	byConn := make(map[int64][]Entry)
+	for _, e := range r.Entries {
+		if e.kind() != "resource" || e.name() == "" {
+			continue
+		}
+		id := e.conn()
+		if _, ok := byConn[id]; !ok {
+			ids = append(ids, id)
+		}
+		byConn[id] = append(byConn[id], e)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	recorded := &recordedProviders{
+		byRef:    make(map[string][]*recordedConn),
+		snapshot: make(map[tokens.Package][]string),
+		loaded:   make(map[tokens.Package]int),
+		unbound:  make(map[tokens.Package]*recordedConn),
+	}
+	if r.Snapshot != nil {
+		for _, res := range r.Snapshot.Resources {
+			if !providers.IsProviderType(res.URN.Type()) {
+				continue
+			}
+			ref, err := providers.NewReference(res.URN, res.ID)
+			if err != nil {
+				return nil, err
+			}
+			pkg := providers.GetProviderPackage(res.URN.Type())
+			recorded.snapshot[pkg] = append(recorded.snapshot[pkg], ref.String())
+		}
+	}
+
+	loaded := make(map[tokens.Package]int)
+	for _, id := range ids {
+		entries := byConn[id]
+		pkg := tokens.Package(entries[0].name())
+		conn, err := newRecordedConn(entries)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := recorded.unbound[pkg]; !ok {
+			recorded.packages = append(recorded.packages, pkg)
+			recorded.unbound[pkg] = conn
+		}
+
+		var key string
+		method, request := firstConfigCall(entries)
+		switch method {
+		case configureMethod:
+			i := loaded[pkg]
+			if i >= len(recorded.snapshot[pkg]) {
+				return nil, fmt.Errorf("recorded %v provider was configured but is not in the deployment's snapshot", pkg)
+			}
+			key, loaded[pkg] = recorded.snapshot[pkg][i], i+1
+		case checkConfigMethod:
+			var fields struct {
+				URN string `json:"urn"`
+			}
+			if err := json.Unmarshal(request, &fields); err != nil {
+				return nil, fmt.Errorf("decoding recorded %v request: %w", method, err)
+			}
+			key = fields.URN
+		default:
+			// The plugin was loaded but never served a provider resource, e.g. to ensure that it is installed.
+			continue
+		}
+		recorded.byRef[key] = append(recorded.byRef[key], conn)
+	}
+	return recorded, nil
+}
+
+// firstConfigCall returns the method and request of the first call on a provider connection that configured the
+// provider or checked its configuration, if any.
+func firstConfigCall(entries []Entry) (string, json.RawMessage) {
+	for _, e := range entries {
+		if e.Method == configureMethod || e.Method == checkConfigMethod {
+			return e.Method, e.Request
+		}
+	}
+	return "", nil
+}
+
+// bind returns the recorded connection for the provider resource identified by the given call, or nil if the call
+// does not identify one. A call that identifies a provider that was not recorded fails with FailedPrecondition rather
+// than Unimplemented, which the engine would treat as a provider that does not support the call.
+func (p *recordedProviders) bind(pkg tokens.Package, method string, args interface{}) (*recordedConn, error) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	var key string
+	switch method {
+	case configureMethod:
+		i := p.loaded[pkg]
+		if i >= len(p.snapshot[pkg]) {
+			return nil, status.Errorf(codes.FailedPrecondition, "no recorded %v provider to configure", pkg)
+		}
+		key, p.loaded[pkg] = p.snapshot[pkg][i], i+1
+	case checkConfigMethod:
+		req, ok := args.(*pulumirpc.CheckRequest)
+		if !ok {
+			return nil, fmt.Errorf("expected a CheckRequest, got %T", args)
+		}
+		key = req.GetUrn()
+	default:
+		return nil, nil
+	}
+
+	conns := p.byRef[key]
+	if len(conns) == 0 {
+		return nil, status.Errorf(codes.FailedPrecondition, "no recorded provider for %v", key)
+	}
+	p.byRef[key] = conns[1:]
+	return conns[0], nil
+}
+
+// providerConn is a gRPC client connection for a replayed provider. It answers each call with the responses recorded
+// for the provider resource that it is bound to.
+type providerConn struct {
+	providers *recordedProviders
+	pkg       tokens.Package
+
+	m     sync.Mutex
+	bound *recordedConn
+}
+
+var _ grpc.ClientConnInterface = (*providerConn)(nil)
+
+// conn returns the recorded connection that answers the given call.
+func (c *providerConn) conn(method string, args interface{}) (*recordedConn, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if c.bound != nil {
+		return c.bound, nil
+	}
+	bound, err := c.providers.bind(c.pkg, method, args)
+	if err != nil {
+		return nil, err
+	}
+	if bound == nil {
+		return c.providers.unbound[c.pkg], nil
+	}
+	c.bound = bound
+	return bound, nil
+}
+
+func (c *providerConn) Invoke(ctx context.Context, method string, args interface{}, reply interface{},
+	opts ...grpc.CallOption,
+) error {
+	conn, err := c.conn(method, args)
+	if err != nil {
+		return err
+	}
+	return conn.Invoke(ctx, method, args, reply, opts...)
+}
+
+func (c *providerConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string,
+	opts ...grpc.CallOption,
+) (grpc.ClientStream, error) {
+	return nil, status.Errorf(codes.Unimplemented, "streaming call %v cannot be replayed", method)
+}
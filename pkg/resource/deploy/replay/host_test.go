@@ -0,0 +1,113 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replay
+
+import (
+	"testing"
+
+	pbempty "github.com/golang/protobuf/ptypes/empty"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/plugin"
+	pulumirpc "github.com/pulumi/pulumi/sdk/v3/proto/go"
+)
+
+func TestPluginHostProviders(t *testing.T) {
+	t.Parallel()
+
+	const (
+		oldA = "urn:pulumi:stack::proj::pulumi:providers:pkgA::default"
+		oldB = "urn:pulumi:stack::proj::pulumi:providers:pkgA::west"
+		newC = "urn:pulumi:stack::proj::pulumi:providers:pkgA::east"
+		newD = "urn:pulumi:stack::proj::pulumi:providers:pkgA::north"
+	)
+
+	// Each recorded provider answers the same invoke with its own region.
+	region := func(conn int, name string) Entry {
+		ret, err := plugin.MarshalProperties(resource.PropertyMap{"region": resource.NewStringProperty(name)},
+			plugin.MarshalOptions{})
+		require.NoError(t, err)
+		return recordedEntry(t, conn, "Invoke",
+			&pulumirpc.InvokeRequest{Tok: "pkgA:index:getRegion"}, &pulumirpc.InvokeResponse{Return: ret})
+	}
+	configure := func(conn int) Entry {
+		return recordedEntry(t, conn, "Configure", &pulumirpc.ConfigureRequest{}, &pulumirpc.ConfigureResponse{})
+	}
+	checkConfig := func(conn int, urn string) Entry {
+		return recordedEntry(t, conn, "CheckConfig", &pulumirpc.CheckRequest{Urn: urn}, &pulumirpc.CheckResponse{})
+	}
+	pluginInfo := recordedEntry(t, 1, "GetPluginInfo", &pbempty.Empty{}, &pulumirpc.PluginInfo{Version: "1.0.0"})
+
+	rec := &Recording{
+		Snapshot: &deploy.Snapshot{Resources: []*resource.State{
+			{URN: oldA, Type: "pulumi:providers:pkgA", ID: "id-a", Custom: true},
+			{URN: oldB, Type: "pulumi:providers:pkgA", ID: "id-b", Custom: true},
+		}},
+		Entries: []Entry{
+			pluginInfo,
+			configure(1),
+			configure(2),
+			region(1, "us-east-1"),
+			region(2, "us-west-2"),
+			checkConfig(3, newC),
+			checkConfig(4, newD),
+			configure(3),
+			configure(4),
+			region(4, "us-north-1"),
+			region(3, "eu-east-1"),
+		},
+	}
+	host, err := rec.PluginHost(nil, nil)
+	require.NoError(t, err)
+
+	load := func() plugin.Provider {
+		provider, err := host.Provider("pkgA", nil)
+		require.NoError(t, err)
+		info, err := provider.GetPluginInfo()
+		require.NoError(t, err)
+		assert.Equal(t, "1.0.0", info.Version.String())
+		return provider
+	}
+	assertRegion := func(provider plugin.Provider, expected string) {
+		ret, _, err := provider.Invoke("pkgA:index:getRegion", nil)
+		require.NoError(t, err)
+		assert.Equal(t, expected, ret["region"].StringValue())
+	}
+
+	// The providers from the snapshot are configured in order, and the others are identified by URN, regardless of
+	// the order in which they are loaded.
+	a, b := load(), load()
+	require.NoError(t, a.Configure(nil))
+	require.NoError(t, b.Configure(nil))
+	d, c := load(), load()
+	_, _, err = d.CheckConfig(newD, nil, nil, false)
+	require.NoError(t, err)
+	_, _, err = c.CheckConfig(newC, nil, nil, false)
+	require.NoError(t, err)
+	require.NoError(t, d.Configure(nil))
+	require.NoError(t, c.Configure(nil))
+
+	assertRegion(a, "us-east-1")
+	assertRegion(b, "us-west-2")
+	assertRegion(c, "eu-east-1")
+	assertRegion(d, "us-north-1")
+
+	// There are no more recorded providers to bind.
+	_, _, err = load().CheckConfig(newC, nil, nil, false)
+	assert.ErrorContains(t, err, "no recorded provider")
+}
@@ -0,0 +1,231 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/blang/semver"
+	pbempty "github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy/providers"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/plugin"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/logging"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/rpcutil"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/workspace"
+	pulumirpc "github.com/pulumi/pulumi/sdk/v3/proto/go"
+)
+
+// monitorCalls maps the resource monitor methods that are replayed to constructors for their request and response
+// messages. Streaming methods are not replayed.
+var monitorCalls = map[string]func() (interface{}, interface{}){
+	"/pulumirpc.ResourceMonitor/SupportsFeature": func() (interface{}, interface{}) {
+		return &pulumirpc.SupportsFeatureRequest{}, &pulumirpc.SupportsFeatureResponse{}
+	},
+	"/pulumirpc.ResourceMonitor/Invoke": func() (interface{}, interface{}) {
+		return &pulumirpc.ResourceInvokeRequest{}, &pulumirpc.InvokeResponse{}
+	},
+	"/pulumirpc.ResourceMonitor/Call": func() (interface{}, interface{}) {
+		return &pulumirpc.CallRequest{}, &pulumirpc.CallResponse{}
+	},
+	"/pulumirpc.ResourceMonitor/ReadResource": func() (interface{}, interface{}) {
+		return &pulumirpc.ReadResourceRequest{}, &pulumirpc.ReadResourceResponse{}
+	},
+	"/pulumirpc.ResourceMonitor/RegisterResource": func() (interface{}, interface{}) {
+		return &pulumirpc.RegisterResourceRequest{}, &pulumirpc.RegisterResourceResponse{}
+	},
+	"/pulumirpc.ResourceMonitor/RegisterResourceOutputs": func() (interface{}, interface{}) {
+		return &pulumirpc.RegisterResourceOutputsRequest{}, &pbempty.Empty{}
+	},
+}
+
+// LanguageRuntime returns a language runtime that replays the recorded program. Running the program sends the recorded
+// resource monitor requests to the engine one at a time, in the order in which the original requests completed.
+func (r *Recording) LanguageRuntime() (plugin.LanguageRuntime, error) {
+	var language, monitor []Entry
+	for _, e := range r.Entries {
+		switch e.kind() {
+		case "language":
+			language = append(language, e)
+		case "monitor":
+			if _, ok := monitorCalls[e.Method]; ok {
+				monitor = append(monitor, e)
+			}
+		}
+	}
+
+	conn, err := newRecordedConn(language)
+	if err != nil {
+		return nil, err
+	}
+	return &languageRuntime{
+		client:  pulumirpc.NewLanguageRuntimeClient(conn),
+		monitor: monitor,
+	}, nil
+}
+
+type languageRuntime struct {
+	client  pulumirpc.LanguageRuntimeClient
+	monitor []Entry
+}
+
+func (p *languageRuntime) Close() error {
+	return nil
+}
+
+func (p *languageRuntime) GetRequiredPlugins(info plugin.ProgInfo) ([]workspace.PluginSpec, error) {
+	resp, err := p.client.GetRequiredPlugins(context.Background(), &pulumirpc.GetRequiredPluginsRequest{})
+	if err != nil {
+		// The language host calls may not have been recorded, e.g. if the program was hosted in-process.
+		if status.Code(err) == codes.Unimplemented {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	results := make([]workspace.PluginSpec, 0, len(resp.GetPlugins()))
+	for _, info := range resp.GetPlugins() {
+		var version *semver.Version
+		if v := info.GetVersion(); v != "" {
+			sv, err := semver.ParseTolerant(v)
+			if err != nil {
+				return nil, fmt.Errorf("illegal semver recorded for %s@%s: %w", info.GetName(), v, err)
+			}
+			version = &sv
+		}
+		results = append(results, workspace.PluginSpec{
+			Name:              info.GetName(),
+			Kind:              workspace.PluginKind(info.GetKind()),
+			Version:           version,
+			PluginDownloadURL: info.GetServer(),
+		})
+	}
+	return results, nil
+}
+
+func (p *languageRuntime) Run(info plugin.RunInfo) (string, bool, error) {
+	conn, err := grpc.Dial(
+		info.MonitorAddress,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		rpcutil.GrpcChannelOptions(),
+	)
+	if err != nil {
+		return "", false, fmt.Errorf("could not connect to resource monitor: %w", err)
+	}
+	defer contract.IgnoreClose(conn)
+
+	if err := p.replay(context.Background(), conn); err != nil {
+		return err.Error(), false, nil
+	}
+
+	// Report the same result as the recorded program.
+	resp, err := p.client.Run(context.Background(), &pulumirpc.RunRequest{})
+	if err != nil {
+		if status.Code(err) == codes.Unimplemented {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return resp.GetError(), resp.GetBail(), nil
+}
+
+// replay sends the recorded resource monitor requests to the monitor at the other end of the given connection.
+func (p *languageRuntime) replay(ctx context.Context, conn *grpc.ClientConn) error {
+	// The engine assigns new IDs to provider resources, so references to providers in the recorded requests are
+	// rewritten to refer to the IDs assigned during the replay.
+	refs := make(map[string]string)
+	rewrite := func(ref string) string {
+		if replayed, ok := refs[ref]; ok {
+			return replayed
+		}
+		return ref
+	}
+
+	for _, e := range p.monitor {
+		req, resp := monitorCalls[e.Method]()
+		if err := unmarshalMessage(e.Request, req); err != nil {
+			return fmt.Errorf("decoding recorded %v request: %w", e.Method, err)
+		}
+
+		switch req := req.(type) {
+		case *pulumirpc.RegisterResourceRequest:
+			req.Provider = rewrite(req.Provider)
+			for pkg, ref := range req.Providers {
+				req.Providers[pkg] = rewrite(ref)
+			}
+			// The program's resource hooks are not running, so they cannot be replayed.
+			req.Hooks = nil
+		case *pulumirpc.ReadResourceRequest:
+			req.Provider = rewrite(req.Provider)
+		case *pulumirpc.ResourceInvokeRequest:
+			req.Provider = rewrite(req.Provider)
+		case *pulumirpc.CallRequest:
+			req.Provider = rewrite(req.Provider)
+		}
+
+		logging.V(7).Infof("replaying %v", e.Method)
+		err := conn.Invoke(ctx, e.Method, req, resp)
+		if err != nil {
+			if len(e.Errors) != 0 {
+				// The recorded call failed too.
+				continue
+			}
+			return fmt.Errorf("replaying %v: %w", e.Method, err)
+		}
+
+		if resp, ok := resp.(*pulumirpc.RegisterResourceResponse); ok {
+			recorded := &pulumirpc.RegisterResourceResponse{}
+			if err := unmarshalMessage(e.Response, recorded); err != nil {
+				return fmt.Errorf("decoding recorded %v response: %w", e.Method, err)
+			}
+			urn := resource.URN(resp.GetUrn())
+			if providers.IsProviderType(urn.Type()) && recorded.GetId() != resp.GetId() {
+				refs[string(urn)+"::"+recorded.GetId()] = string(urn) + "::" + resp.GetId()
+			}
+		}
+	}
+	return nil
+}
+
+func (p *languageRuntime) GetPluginInfo() (workspace.PluginInfo, error) {
+	return workspace.PluginInfo{Name: "replay"}, nil
+}
+
+func (p *languageRuntime) InstallDependencies(directory string) error {
+	return nil
+}
+
+func (p *languageRuntime) About() (plugin.AboutInfo, error) {
+	return plugin.AboutInfo{}, nil
+}
+
+func (p *languageRuntime) GetProgramDependencies(
+	info plugin.ProgInfo, transitiveDependencies bool,
+) ([]plugin.DependencyInfo, error) {
+	return nil, nil
+}
+
+func (p *languageRuntime) RunPlugin(info plugin.RunPluginInfo) (io.Reader, io.Reader, context.CancelFunc, error) {
+	return nil, nil, nil, errors.New("recorded deployments cannot run plugins")
+}
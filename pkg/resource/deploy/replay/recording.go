@@ -0,0 +1,184 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package replay implements deterministic replay of recorded deployments.
+//
+// A recording is a file of JSON lines. Each deployment in the recording begins with a header entry that holds the
+// stack's state before the deployment and is followed by one entry per RPC made between the engine and its providers,
+// its language host, and its resource monitor. The RPC entries use the same format as the gRPC debug logs written by
+// PULUMI_DEBUG_GRPC, but also hold the errors and gRPC statuses returned by each call and number the connections to
+// plugins so that the calls made to different providers from the same plugin can be told apart.
+//
+// Replaying a recorded deployment runs the engine against stub providers that answer each RPC with its recorded
+// response and a stub language host that sends the recorded resource monitor requests in their original order.
+// `pulumi replay-deployment` replays the deployments in a recording.
+package replay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/v3/resource/stack"
+	"github.com/pulumi/pulumi/pkg/v3/secrets/b64"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+)
+
+// Entry is a single entry in a recording.
+type Entry struct {
+	// Header is set if this entry begins a new deployment.
+	Header *Header `json:"header,omitempty"`
+
+	// Method is the full name of the gRPC method that was called.
+	Method string `json:"method,omitempty"`
+	// Request and Response hold the JSON-encoded request and response messages.
+	Request  json.RawMessage `json:"request,omitempty"`
+	Response json.RawMessage `json:"response,omitempty"`
+	// Errors holds any errors returned by the call.
+	Errors []string `json:"errors,omitempty"`
+	// Status holds the JSON-encoded gRPC status returned by the call, if it failed.
+	Status json.RawMessage `json:"status,omitempty"`
+	// Metadata describes the connection on which the call was made.
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Header describes a recorded deployment.
+type Header struct {
+	// Kind is the kind of the deployment, e.g. an update or a refresh.
+	Kind apitype.UpdateKind `json:"kind,omitempty"`
+	// Preview is true if the deployment was a preview.
+	Preview bool `json:"preview,omitempty"`
+	// Project and Stack name the project and stack that were deployed.
+	Project tokens.PackageName `json:"project,omitempty"`
+	Stack   tokens.Name        `json:"stack,omitempty"`
+	// Snapshot is the stack's state before the deployment, if any.
+	Snapshot *apitype.DeploymentV3 `json:"snapshot,omitempty"`
+}
+
+// WriteHeader appends a header for a new deployment of the given target to the recording at the given path. The
+// header must be written before the deployment starts so that it precedes the deployment's RPCs.
+func WriteHeader(path string, kind apitype.UpdateKind, preview bool, project tokens.PackageName,
+	target *deploy.Target,
+) error {
+	header := &Header{Kind: kind, Preview: preview, Project: project, Stack: target.Name}
+	if snap := target.Snapshot; snap != nil {
+		// Secrets are recorded in plaintext, just as they are in the RPC entries, so that the recording can be
+		// replayed without access to the stack's secrets provider.
+		dep, err := stack.SerializeDeployment(snap, b64.NewBase64SecretsManager(), false /* showSecrets */)
+		if err != nil {
+			return fmt.Errorf("serializing snapshot: %w", err)
+		}
+		header.Snapshot = dep
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening recording %s: %w", path, err)
+	}
+	defer f.Close()
+
+	if err := json.NewEncoder(f).Encode(Entry{Header: header}); err != nil {
+		return fmt.Errorf("writing recording %s: %w", path, err)
+	}
+	return nil
+}
+
+// Recording is a single recorded deployment.
+type Recording struct {
+	// Kind is the kind of the deployment, e.g. an update or a refresh.
+	Kind apitype.UpdateKind
+	// Preview is true if the deployment was a preview.
+	Preview bool
+	// Project and Stack name the project and stack that were deployed.
+	Project tokens.PackageName
+	Stack   tokens.Name
+	// Snapshot is the stack's state before the deployment, if any.
+	Snapshot *deploy.Snapshot
+	// Entries are the RPCs made during the deployment, in the order in which they completed.
+	Entries []Entry
+}
+
+// Load reads the deployments recorded in the file at the given path.
+func Load(ctx context.Context, path string) ([]*Recording, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var recordings []*Recording
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+
+		if entry.Header != nil {
+			rec := &Recording{
+				Kind:    entry.Header.Kind,
+				Preview: entry.Header.Preview,
+				Project: entry.Header.Project,
+				Stack:   entry.Header.Stack,
+			}
+			if entry.Header.Snapshot != nil {
+				snap, err := stack.DeserializeDeploymentV3(ctx, *entry.Header.Snapshot, stack.DefaultSecretsProvider)
+				if err != nil {
+					return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+				}
+				rec.Snapshot = snap
+			}
+			recordings = append(recordings, rec)
+			continue
+		}
+
+		if len(recordings) == 0 {
+			return nil, fmt.Errorf("%s:%d: recorded RPC does not belong to a deployment", path, line)
+		}
+		rec := recordings[len(recordings)-1]
+		rec.Entries = append(rec.Entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return recordings, nil
+}
+
+// kind returns the kind of connection on which the entry was recorded, or "monitor" for calls served by the
+// engine's resource monitor.
+func (e Entry) kind() string {
+	if mode, _ := e.Metadata["mode"].(string); mode == "server" {
+		return "monitor"
+	}
+	kind, _ := e.Metadata["kind"].(string)
+	return kind
+}
+
+// name returns the name of the plugin on which the entry was recorded, if any.
+func (e Entry) name() string {
+	name, _ := e.Metadata["name"].(string)
+	return name
+}
+
+// conn returns the number of the client connection on which the entry was recorded.
+func (e Entry) conn() int64 {
+	// Numbers in the metadata are decoded as float64s.
+	conn, _ := e.Metadata["conn"].(float64)
+	return int64(conn)
+}
@@ -0,0 +1,99 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+)
+
+// appendEntries appends the given RPC entries to the recording at the given path.
+func appendEntries(t *testing.T, path string, entries ...Entry) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	defer f.Close()
+
+	for _, e := range entries {
+		require.NoError(t, json.NewEncoder(f).Encode(e))
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "recording.json")
+	check := Entry{Method: "/pulumirpc.ResourceProvider/Check", Metadata: map[string]interface{}{"kind": "resource"}}
+	register := Entry{Method: "/pulumirpc.ResourceMonitor/RegisterResource", Metadata: map[string]interface{}{
+		"mode": "server",
+	}}
+
+	target := &deploy.Target{Name: "stack"}
+	require.NoError(t, WriteHeader(path, apitype.PreviewUpdate, true, "proj", target))
+	appendEntries(t, path, check, register)
+
+	snap := &deploy.Snapshot{Resources: []*resource.State{{
+		URN:     "urn:pulumi:stack::proj::pkgA:m:typA::resA",
+		Type:    "pkgA:m:typA",
+		ID:      "id-1",
+		Custom:  true,
+		Outputs: resource.PropertyMap{"secret": resource.MakeSecret(resource.NewStringProperty("shh"))},
+	}}}
+	target.Snapshot = snap
+	require.NoError(t, WriteHeader(path, apitype.RefreshUpdate, false, "proj", target))
+	appendEntries(t, path, check)
+
+	recordings, err := Load(context.Background(), path)
+	require.NoError(t, err)
+	require.Len(t, recordings, 2)
+
+	assert.Equal(t, apitype.PreviewUpdate, recordings[0].Kind)
+	assert.True(t, recordings[0].Preview)
+	assert.Equal(t, tokens.PackageName("proj"), recordings[0].Project)
+	assert.Equal(t, tokens.Name("stack"), recordings[0].Stack)
+	assert.Nil(t, recordings[0].Snapshot)
+	require.Len(t, recordings[0].Entries, 2)
+	assert.Equal(t, "resource", recordings[0].Entries[0].kind())
+	assert.Equal(t, "monitor", recordings[0].Entries[1].kind())
+
+	assert.Equal(t, apitype.RefreshUpdate, recordings[1].Kind)
+	assert.False(t, recordings[1].Preview)
+	require.NotNil(t, recordings[1].Snapshot)
+	require.Len(t, recordings[1].Snapshot.Resources, 1)
+	res := recordings[1].Snapshot.Resources[0]
+	assert.Equal(t, snap.Resources[0].URN, res.URN)
+	assert.Equal(t, snap.Resources[0].Outputs, res.Outputs)
+	assert.Len(t, recordings[1].Entries, 1)
+}
+
+func TestLoadWithoutHeader(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "recording.json")
+	appendEntries(t, path, Entry{Method: "/pulumirpc.ResourceProvider/Check"})
+
+	_, err := Load(context.Background(), path)
+	assert.ErrorContains(t, err, "recorded RPC does not belong to a deployment")
+}
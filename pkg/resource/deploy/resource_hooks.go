@@ -0,0 +1,288 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/display"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/plugin"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/logging"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/rpcutil"
+	pulumirpc "github.com/pulumi/pulumi/sdk/v3/proto/go"
+)
+
+var hookKindsFromRPC = map[pulumirpc.ResourceHookKind]resource.HookKind{
+	pulumirpc.ResourceHookKind_BEFORE_CREATE: resource.HookBeforeCreate,
+	pulumirpc.ResourceHookKind_AFTER_CREATE:  resource.HookAfterCreate,
+	pulumirpc.ResourceHookKind_BEFORE_UPDATE: resource.HookBeforeUpdate,
+	pulumirpc.ResourceHookKind_AFTER_UPDATE:  resource.HookAfterUpdate,
+	pulumirpc.ResourceHookKind_BEFORE_DELETE: resource.HookBeforeDelete,
+	pulumirpc.ResourceHookKind_AFTER_DELETE:  resource.HookAfterDelete,
+}
+
+var hookKindsToRPC = map[resource.HookKind]pulumirpc.ResourceHookKind{
+	resource.HookBeforeCreate: pulumirpc.ResourceHookKind_BEFORE_CREATE,
+	resource.HookAfterCreate:  pulumirpc.ResourceHookKind_AFTER_CREATE,
+	resource.HookBeforeUpdate: pulumirpc.ResourceHookKind_BEFORE_UPDATE,
+	resource.HookAfterUpdate:  pulumirpc.ResourceHookKind_AFTER_UPDATE,
+	resource.HookBeforeDelete: pulumirpc.ResourceHookKind_BEFORE_DELETE,
+	resource.HookAfterDelete:  pulumirpc.ResourceHookKind_AFTER_DELETE,
+}
+
+// parseResourceHooks converts the lifecycle hooks sent with a RegisterResourceRequest into their engine
+// representation.
+func parseResourceHooks(rpcHooks []*pulumirpc.RegisterResourceRequest_ResourceHook) ([]resource.Hook, error) {
+	if len(rpcHooks) == 0 {
+		return nil, nil
+	}
+
+	hooks := make([]resource.Hook, len(rpcHooks))
+	for i, h := range rpcHooks {
+		if h.GetKind() == pulumirpc.ResourceHookKind_RESOURCE_HOOK_KIND_UNSPECIFIED {
+			return nil, fmt.Errorf("resource hook '%v' must have a kind", h.GetName())
+		}
+		kind, ok := hookKindsFromRPC[h.GetKind()]
+		if !ok {
+			return nil, fmt.Errorf("resource hook '%v' has unknown kind %v", h.GetName(), h.GetKind())
+		}
+		if h.GetName() == "" {
+			return nil, fmt.Errorf("resource hook of kind %v must have a name", kind)
+		}
+		if h.GetTarget() == "" {
+			return nil, fmt.Errorf("resource hook '%v' must have a target", h.GetName())
+		}
+		hooks[i] = resource.Hook{Name: h.GetName(), Kind: kind, Target: h.GetTarget()}
+	}
+	return hooks, nil
+}
+
+// stepHookKinds returns the kinds of hooks that run before and after a step with the given operation. Operations that
+// do not correspond to a create, update, or delete of the resource do not run hooks.
+func stepHookKinds(op display.StepOp) (resource.HookKind, resource.HookKind, bool) {
+	switch op {
+	case OpCreate, OpCreateReplacement:
+		return resource.HookBeforeCreate, resource.HookAfterCreate, true
+	case OpUpdate:
+		return resource.HookBeforeUpdate, resource.HookAfterUpdate, true
+	case OpDelete, OpDeleteReplaced:
+		return resource.HookBeforeDelete, resource.HookAfterDelete, true
+	default:
+		return "", "", false
+	}
+}
+
+// resourceHookTargets records the address of the ResourceHooks server that implements each hook the program has made
+// available during the current deployment. Hooks recorded in the state of a resource the program no longer declares
+// are resolved through it.
+type resourceHookTargets struct {
+	m       sync.Mutex
+	targets map[string]string
+}
+
+func newResourceHookTargets() *resourceHookTargets {
+	return &resourceHookTargets{targets: make(map[string]string)}
+}
+
+func (t *resourceHookTargets) register(name, target string) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	t.targets[name] = target
+}
+
+func (t *resourceHookTargets) get(name string) (string, bool) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	target, ok := t.targets[name]
+	return target, ok
+}
+
+// programWaiter is implemented by source iterators whose program may wait for the deployment to finish before it
+// exits, so that it can serve the resource hooks of steps that run after the program is done.
+type programWaiter interface {
+	releaseProgram()
+}
+
+// resourceHookSource is implemented by sources that run a program, and can therefore resolve the hooks recorded in the
+// state of resources that the program no longer declares.
+type resourceHookSource interface {
+	resourceHookTarget(name string) (string, bool)
+}
+
+// stepHooks returns the lifecycle hooks that apply to the given step. Hooks are taken from the resource's goal if the
+// program registered the resource during this deployment. Otherwise, e.g. when deleting a resource that was removed
+// from the program, they are taken from the resource's state and resolved against the hooks the program has made
+// available.
+func (se *stepExecutor) stepHooks(step Step) ([]resource.Hook, error) {
+	if goal, ok := se.deployment.goals.get(step.URN()); ok {
+		return goal.Hooks, nil
+	}
+
+	old := step.Old()
+	if old == nil || len(old.Hooks) == 0 {
+		return nil, nil
+	}
+
+	source, ok := se.deployment.source.(resourceHookSource)
+	if !ok {
+		// No program is running, e.g. during a destroy, so there is nothing that could run the hooks.
+		se.deployment.Diag().Warningf(diag.RawMessage(step.URN(), fmt.Sprintf(
+			"skipping the resource hooks of %v because no program is running to serve them", step.URN())))
+		return nil, nil
+	}
+
+	hooks := make([]resource.Hook, len(old.Hooks))
+	for i, hook := range old.Hooks {
+		target, ok := source.resourceHookTarget(hook.Name)
+		if !ok {
+			return nil, fmt.Errorf("resource hook '%v' of %v is not registered by the program; attach it to another "+
+				"resource or register it with the program so that it can run for resources that are no longer declared",
+				hook.Name, step.URN())
+		}
+		hook.Target = target
+		hooks[i] = hook
+	}
+	return hooks, nil
+}
+
+// resourceHooks runs the lifecycle hooks registered by a program by calling back into the program's ResourceHooks
+// server. Connections to the servers are cached for the lifetime of the deployment.
+type resourceHooks struct {
+	m     sync.Mutex
+	conns map[string]*grpc.ClientConn
+}
+
+func newResourceHooks() *resourceHooks {
+	return &resourceHooks{conns: make(map[string]*grpc.ClientConn)}
+}
+
+// client returns a client for the ResourceHooks server at the given address.
+func (h *resourceHooks) client(target string) (pulumirpc.ResourceHooksClient, error) {
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	conn, ok := h.conns[target]
+	if !ok {
+		c, err := grpc.Dial(
+			target,
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			rpcutil.GrpcChannelOptions(),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could not connect to resource hooks server at %v: %w", target, err)
+		}
+		h.conns[target] = c
+		conn = c
+	}
+	return pulumirpc.NewResourceHooksClient(conn), nil
+}
+
+// run runs each hook of the given kind in order, stopping at the first hook that fails.
+func (h *resourceHooks) run(ctx context.Context, step Step, hooks []resource.Hook, kind resource.HookKind) error {
+	for _, hook := range hooks {
+		if hook.Kind != kind {
+			continue
+		}
+
+		logging.V(7).Infof("running %v hook '%v' for %v", kind, hook.Name, step.URN())
+		req, err := newResourceHookRequest(step, hook)
+		if err != nil {
+			return err
+		}
+
+		client, err := h.client(hook.Target)
+		if err != nil {
+			return err
+		}
+		resp, err := client.InvokeResourceHook(ctx, req)
+		if err != nil {
+			if status.Code(err) == codes.Unavailable {
+				return fmt.Errorf("%v hook '%v' could not be run because the program is no longer running: %w",
+					kind, hook.Name, err)
+			}
+			return fmt.Errorf("%v hook '%v' failed: %w", kind, hook.Name, err)
+		}
+		if msg := resp.GetError(); msg != "" {
+			return fmt.Errorf("%v hook '%v' failed: %v", kind, hook.Name, msg)
+		}
+	}
+	return nil
+}
+
+// close closes any connections opened to ResourceHooks servers.
+func (h *resourceHooks) close() {
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	for target, conn := range h.conns {
+		contract.IgnoreError(conn.Close())
+		delete(h.conns, target)
+	}
+}
+
+func newResourceHookRequest(step Step, hook resource.Hook) (*pulumirpc.ResourceHookRequest, error) {
+	marshal := func(props resource.PropertyMap) (*structpb.Struct, error) {
+		if props == nil {
+			return nil, nil
+		}
+		return plugin.MarshalProperties(props, plugin.MarshalOptions{
+			Label:         fmt.Sprintf("%v.hook(%v)", step.URN(), hook.Name),
+			KeepUnknowns:  true,
+			KeepSecrets:   true,
+			KeepResources: true,
+		})
+	}
+
+	req := &pulumirpc.ResourceHookRequest{
+		Name: hook.Name,
+		Kind: hookKindsToRPC[hook.Kind],
+		Urn:  string(step.URN()),
+		Type: string(step.Type()),
+	}
+
+	var err error
+	if oldState := step.Old(); oldState != nil {
+		req.Id = string(oldState.ID)
+		if req.OldInputs, err = marshal(oldState.Inputs); err != nil {
+			return nil, err
+		}
+		if req.OldOutputs, err = marshal(oldState.Outputs); err != nil {
+			return nil, err
+		}
+	}
+	if newState := step.New(); newState != nil {
+		if newState.ID != "" {
+			req.Id = string(newState.ID)
+		}
+		if req.NewInputs, err = marshal(newState.Inputs); err != nil {
+			return nil, err
+		}
+		if req.NewOutputs, err = marshal(newState.Outputs); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	pulumirpc "github.com/pulumi/pulumi/sdk/v3/proto/go"
+)
+
+func TestParseResourceHooks(t *testing.T) {
+	t.Parallel()
+
+	hooks, err := parseResourceHooks([]*pulumirpc.RegisterResourceRequest_ResourceHook{
+		{Name: "drain", Kind: pulumirpc.ResourceHookKind_BEFORE_DELETE, Target: "127.0.0.1:1234"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []resource.Hook{{Name: "drain", Kind: resource.HookBeforeDelete, Target: "127.0.0.1:1234"}}, hooks)
+
+	tests := []struct {
+		name    string
+		hook    *pulumirpc.RegisterResourceRequest_ResourceHook
+		wantErr string
+	}{
+		{
+			name:    "unspecified kind",
+			hook:    &pulumirpc.RegisterResourceRequest_ResourceHook{Name: "drain", Target: "127.0.0.1:1234"},
+			wantErr: "resource hook 'drain' must have a kind",
+		},
+		{
+			name: "unknown kind",
+			hook: &pulumirpc.RegisterResourceRequest_ResourceHook{
+				Name: "drain", Kind: pulumirpc.ResourceHookKind(42), Target: "127.0.0.1:1234",
+			},
+			wantErr: "resource hook 'drain' has unknown kind 42",
+		},
+		{
+			name: "no name",
+			hook: &pulumirpc.RegisterResourceRequest_ResourceHook{
+				Kind: pulumirpc.ResourceHookKind_AFTER_CREATE, Target: "127.0.0.1:1234",
+			},
+			wantErr: "must have a name",
+		},
+		{
+			name: "no target",
+			hook: &pulumirpc.RegisterResourceRequest_ResourceHook{
+				Name: "drain", Kind: pulumirpc.ResourceHookKind_AFTER_CREATE,
+			},
+			wantErr: "resource hook 'drain' must have a target",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := parseResourceHooks([]*pulumirpc.RegisterResourceRequest_ResourceHook{tt.hook})
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
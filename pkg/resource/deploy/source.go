@@ -90,6 +90,7 @@ type RegisterResourceEvent interface {
 // RegisterResult is the state of the resource after it has been registered.
 type RegisterResult struct {
 	State *resource.State // the resource state.
+	Err   error           // non-nil if the resource could not be registered.
 }
 
 // RegisterResourceOutputsEvent is an event that asks the engine to complete the provisioning of a resource.
@@ -68,6 +68,7 @@ func NewEvalSource(plugctx *plugin.Context, runinfo *EvalRunInfo,
 		runinfo:             runinfo,
 		defaultProviderInfo: defaultProviderInfo,
 		dryRun:              dryRun,
+		hooks:               newResourceHookTargets(),
 	}
 }
 
@@ -76,12 +77,19 @@ type evalSource struct {
 	runinfo             *EvalRunInfo                            // the directives to use when running the program.
 	defaultProviderInfo map[tokens.Package]workspace.PluginSpec // the default provider versions for this source.
 	dryRun              bool                                    // true if this is a dry-run operation only.
+	hooks               *resourceHookTargets                    // the resource hooks made available by the program.
 }
 
 func (src *evalSource) Close() error {
 	return nil
 }
 
+// resourceHookTarget returns the address of the server that implements the named resource hook, if the program has
+// made the hook available during this deployment.
+func (src *evalSource) resourceHookTarget(name string) (string, bool) {
+	return src.hooks.get(name)
+}
+
 // Project is the name of the project being run by this evaluation source.
 func (src *evalSource) Project() tokens.PackageName {
 	return src.runinfo.Proj.Name
@@ -126,7 +134,10 @@ func (src *evalSource) Iterate(
 		regChan:     regChan,
 		regOutChan:  regOutChan,
 		regReadChan: regReadChan,
-		finChan:     make(chan result.Result),
+		// The program may exit after it has signaled that it is done, at which point nothing receives from finChan.
+		finChan:     make(chan result.Result, 1),
+		programDone: mon.programDone,
+		shutdown:    mon.shutdown,
 	}
 
 	// Now invoke Run in a goroutine.  All subsequent resource creation events will come in over the gRPC channel,
@@ -138,13 +149,16 @@ func (src *evalSource) Iterate(
 }
 
 type evalSourceIterator struct {
-	mon         SourceResourceMonitor              // the resource monitor, per iterator.
-	src         *evalSource                        // the owning eval source object.
-	regChan     chan *registerResourceEvent        // the channel that contains resource registrations.
-	regOutChan  chan *registerResourceOutputsEvent // the channel that contains resource completions.
-	regReadChan chan *readResourceEvent            // the channel that contains read resource requests.
-	finChan     chan result.Result                 // the channel that communicates completion.
-	done        bool                               // set to true when the evaluation is done.
+	mon          SourceResourceMonitor              // the resource monitor, per iterator.
+	src          *evalSource                        // the owning eval source object.
+	regChan      chan *registerResourceEvent        // the channel that contains resource registrations.
+	regOutChan   chan *registerResourceOutputsEvent // the channel that contains resource completions.
+	regReadChan  chan *readResourceEvent            // the channel that contains read resource requests.
+	finChan      chan result.Result                 // the channel that communicates completion.
+	programDone  <-chan struct{}                    // closed when the program signals that it is done.
+	shutdown     chan struct{}                      // closed to release a program waiting for the deployment to finish.
+	shutdownOnce sync.Once                          // ensures that shutdown is closed only once.
+	done         bool                               // set to true when the evaluation is done.
 }
 
 func (iter *evalSourceIterator) Close() error {
@@ -152,6 +166,11 @@ func (iter *evalSourceIterator) Close() error {
 	return iter.mon.Cancel()
 }
 
+// releaseProgram releases a program that is waiting for the deployment to finish.
+func (iter *evalSourceIterator) releaseProgram() {
+	iter.shutdownOnce.Do(func() { close(iter.shutdown) })
+}
+
 func (iter *evalSourceIterator) ResourceMonitor() SourceResourceMonitor {
 	return iter.mon
 }
@@ -191,6 +210,12 @@ func (iter *evalSourceIterator) Next() (SourceEvent, result.Result) {
 			}
 		}
 		return nil, res
+	case <-iter.programDone:
+		// The program has finished registering resources, but is waiting for the deployment to finish so that it can
+		// continue to serve resource hooks. It exits once the program is released.
+		iter.done = true
+		logging.V(5).Infof("EvalSourceIterator ended with the program waiting for shutdown.")
+		return nil, nil
 	}
 }
 
@@ -494,6 +519,10 @@ type resmon struct {
 	regReadChan               chan *readResourceEvent            // the channel to send resource reads to.
 	cancel                    chan bool                          // a channel that can cancel the server.
 	done                      <-chan error                       // a channel that resolves when the server completes.
+	hooks                     *resourceHookTargets               // the resource hooks made available by the program.
+	programDone               chan struct{}                      // closed when the program signals that it is done.
+	programDoneOnce           sync.Once                          // ensures that programDone is closed only once.
+	shutdown                  chan struct{}                      // closed when the deployment has finished.
 	disableResourceReferences bool                               // true if resource references are disabled.
 	disableOutputValues       bool                               // true if output values are disabled.
 }
@@ -528,6 +557,9 @@ func newResourceMonitor(src *evalSource, provs ProviderSource, regChan chan *reg
 		regOutChan:                regOutChan,
 		regReadChan:               regReadChan,
 		cancel:                    cancel,
+		hooks:                     src.hooks,
+		programDone:               make(chan struct{}),
+		shutdown:                  make(chan struct{}),
 		disableResourceReferences: opts.DisableResourceReferences,
 		disableOutputValues:       opts.DisableOutputValues,
 	}
@@ -577,10 +609,21 @@ func sourceEvalServeOptions(ctx *plugin.Context, tracingSpan opentracing.Span) [
 		tracingSpan,
 		otgrpc.SpanDecorator(decorateResourceSpans),
 	)
-	if logFile := env.DebugGRPC.Value(); logFile != "" {
+	// Log monitor RPCs to the gRPC debug log and to the deployment recording, if either is enabled.
+	for _, log := range []struct {
+		file      string
+		recording bool
+	}{
+		{file: env.DebugGRPC.Value()},
+		{file: env.RecordDeployment.Value(), recording: true},
+	} {
+		if log.file == "" {
+			continue
+		}
 		di, err := interceptors.NewDebugInterceptor(interceptors.DebugInterceptorOptions{
-			LogFile: logFile,
-			Mutex:   ctx.DebugTraceMutex,
+			LogFile:   log.file,
+			Mutex:     ctx.DebugTraceMutex,
+			Recording: log.recording,
 		})
 		if err != nil {
 			// ignoring
@@ -1011,9 +1054,16 @@ func (rm *resmon) RegisterResource(ctx context.Context,
 	retainOnDelete := req.GetRetainOnDelete()
 	deletedWith := resource.URN(req.GetDeletedWith())
 
+	hooks, err := parseResourceHooks(req.GetHooks())
+	if err != nil {
+		return nil, rpcerror.New(codes.InvalidArgument, err.Error())
+	}
+	for _, hook := range hooks {
+		rm.hooks.register(hook.Name, hook.Target)
+	}
+
 	// Custom resources must have a three-part type so that we can 1) identify if they are providers and 2) retrieve the
 	// provider responsible for managing a particular resource (based on the type's Package).
-	var err error
 	var t tokens.Type
 	if custom || remote {
 		t, err = tokens.ParseTypeToken(req.GetType())
@@ -1195,9 +1245,9 @@ func (rm *resmon) RegisterResource(ctx context.Context,
 	logging.V(5).Infof(
 		"ResourceMonitor.RegisterResource received: t=%v, name=%v, custom=%v, #props=%v, parent=%v, protect=%v, "+
 			"provider=%v, deps=%v, deleteBeforeReplace=%v, ignoreChanges=%v, aliases=%v, customTimeouts=%v, "+
-			"providers=%v, replaceOnChanges=%v, retainOnDelete=%v, deletedWith=%v",
+			"providers=%v, replaceOnChanges=%v, retainOnDelete=%v, deletedWith=%v, hooks=%v",
 		t, name, custom, len(props), parent, protect, providerRef, dependencies, deleteBeforeReplace, ignoreChanges,
-		aliases, timeouts, providerRefs, replaceOnChanges, retainOnDelete, deletedWith)
+		aliases, timeouts, providerRefs, replaceOnChanges, retainOnDelete, deletedWith, hooks)
 
 	// If this is a remote component, fetch its provider and issue the construct call. Otherwise, register the resource.
 	var result *RegisterResult
@@ -1240,10 +1290,12 @@ func (rm *resmon) RegisterResource(ctx context.Context,
 		}
 	} else {
 		// Send the goal state to the engine.
+		goal := resource.NewGoal(t, name, custom, props, parent, protect, dependencies,
+			providerRef.String(), nil, propertyDependencies, deleteBeforeReplace, ignoreChanges,
+			additionalSecretOutputs, aliases, id, &timeouts, replaceOnChanges, retainOnDelete, deletedWith)
+		goal.Hooks = hooks
 		step := &registerResourceEvent{
-			goal: resource.NewGoal(t, name, custom, props, parent, protect, dependencies,
-				providerRef.String(), nil, propertyDependencies, deleteBeforeReplace, ignoreChanges,
-				additionalSecretOutputs, aliases, id, &timeouts, replaceOnChanges, retainOnDelete, deletedWith),
+			goal: goal,
 			done: make(chan *RegisterResult),
 		}
 
@@ -1261,6 +1313,9 @@ func (rm *resmon) RegisterResource(ctx context.Context,
 			logging.V(5).Infof("ResourceMonitor.RegisterResource operation canceled, name=%s", name)
 			return nil, rpcerror.New(codes.Unavailable, "resource monitor shut down while waiting on step's done channel")
 		}
+		if result.Err != nil {
+			return nil, rpcerror.New(codes.Aborted, result.Err.Error())
+		}
 	}
 
 	if !custom && result != nil && result.State != nil && result.State.URN != "" {
@@ -1423,6 +1478,7 @@ func (rm *resmon) RegisterResourceOutputs(ctx context.Context,
 type registerResourceEvent struct {
 	goal *resource.Goal       // the resource goal state produced by the iterator.
 	done chan *RegisterResult // the channel to communicate with after the resource state is available.
+	once sync.Once            // ensures that only the first result is communicated.
 }
 
 var _ RegisterResourceEvent = (*registerResourceEvent)(nil)
@@ -1434,8 +1490,10 @@ func (g *registerResourceEvent) Goal() *resource.Goal {
 }
 
 func (g *registerResourceEvent) Done(result *RegisterResult) {
-	// Communicate the resulting state back to the RPC thread, which is parked awaiting our reply.
-	g.done <- result
+	// Communicate the resulting state back to the RPC thread, which is parked awaiting our reply. The step executor
+	// may fail a registration before the step that completes it is done, in which case the failure is what the RPC
+	// thread sees.
+	g.once.Do(func() { g.done <- result })
 }
 
 type registerResourceOutputsEvent struct {
@@ -1515,3 +1573,35 @@ func decorateResourceSpans(span opentracing.Span, method string, req, resp inter
 		span.SetTag("pulumi-decorator", req.(*pulumirpc.RegisterResourceRequest).Type)
 	}
 }
+
+// RegisterResourceHook makes a resource hook available to the engine without attaching it to a resource, so that it
+// can run for resources that are no longer declared by the program.
+func (rm *resmon) RegisterResourceHook(ctx context.Context,
+	req *pulumirpc.RegisterResourceHookRequest,
+) (*pbempty.Empty, error) {
+	if req.GetName() == "" {
+		return nil, rpcerror.New(codes.InvalidArgument, "resource hooks must have a name")
+	}
+	if req.GetTarget() == "" {
+		return nil, rpcerror.New(codes.InvalidArgument,
+			fmt.Sprintf("resource hook '%v' must have a target", req.GetName()))
+	}
+	logging.V(5).Infof("ResourceMonitor.RegisterResourceHook received: name=%v", req.GetName())
+	rm.hooks.register(req.GetName(), req.GetTarget())
+	return &pbempty.Empty{}, nil
+}
+
+// SignalAndWaitForShutdown marks the program as done and blocks until the deployment has finished, so that the program
+// can serve the resource hooks of steps that run after it is done, such as deletes.
+func (rm *resmon) SignalAndWaitForShutdown(ctx context.Context, req *pbempty.Empty) (*pbempty.Empty, error) {
+	logging.V(5).Infof("ResourceMonitor.SignalAndWaitForShutdown received")
+	rm.programDoneOnce.Do(func() { close(rm.programDone) })
+
+	select {
+	case <-rm.shutdown:
+	case <-rm.cancel:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return &pbempty.Empty{}, nil
+}
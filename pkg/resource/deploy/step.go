@@ -291,6 +291,7 @@ type DeleteStep struct {
 	deployment     *Deployment           // the current deployment.
 	old            *resource.State       // the state of the existing resource.
 	replacing      bool                  // true if part of a replacement.
+	pendingReplace bool                  // true if the resource remains pending replacement once it is deleted.
 	otherDeletions map[resource.URN]bool // other resources that are planned to delete
 }
 
@@ -331,19 +332,20 @@ func NewDeleteReplacementStep(
 	//
 	// In the former case, the persistence layer may require that the resource remain in the
 	// checkpoint file for purposes of checkpoint integrity. We communicate this case by means
-	// of the `PendingReplacement` field on `resource.State`, which we set here.
+	// of the `PendingReplacement` field on `resource.State`, which we set when the step is applied, so that the
+	// resource is left as it is if the step is never executed, e.g. because its replacement was rejected.
 	//
 	// In the latter case, the resource must be deleted, but the deletion may not occur if an earlier step fails.
 	// The engine requires that the fact that the old resource must be deleted is persisted in the checkpoint so
 	// that it can issue a deletion of this resource on the next update to this stack.
 	contract.Assertf(pendingReplace != old.Delete,
 		"resource %v cannot be pending replacement and deletion at the same time", old.URN)
-	old.PendingReplacement = pendingReplace
 	return &DeleteStep{
 		deployment:     deployment,
 		otherDeletions: otherDeletions,
 		old:            old,
 		replacing:      true,
+		pendingReplace: pendingReplace,
 	}
 }
 
@@ -398,6 +400,9 @@ func (s *DeleteStep) Apply(preview bool) (resource.Status, StepCompleteFunc, err
 	if !s.replacing && s.old.Protect {
 		return resource.StatusOK, nil, deleteProtectedError{urn: s.old.URN}
 	}
+	if s.replacing {
+		s.old.PendingReplacement = s.pendingReplace
+	}
 
 	if preview {
 		// Do nothing in preview
@@ -875,6 +880,7 @@ func (s *RefreshStep) Apply(preview bool) (resource.Status, StepCompleteFunc, er
 			s.old.Parent, s.old.Protect, s.old.External, s.old.Dependencies, initErrors, s.old.Provider,
 			s.old.PropertyDependencies, s.old.PendingReplacement, s.old.AdditionalSecretOutputs, s.old.Aliases,
 			&s.old.CustomTimeouts, s.old.ImportID, s.old.RetainOnDelete, s.old.DeletedWith, s.old.Created, s.old.Modified)
+		s.new.Hooks = s.old.Hooks
 		complete = func() {
 			var inputsChange, outputsChange bool
 			if s.old != nil {
@@ -20,9 +20,12 @@ import (
 	"fmt"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/pulumi/pulumi/sdk/v3/go/common/diag"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/display"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/plugin"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/util/logging"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/util/result"
@@ -96,6 +99,13 @@ type stepExecutor struct {
 	ctx      context.Context    // cancellation context for the current deployment.
 	cancel   context.CancelFunc // CancelFunc that cancels the above context.
 	sawError atomic.Value       // atomic boolean indicating whether or not the step excecutor saw that there was an error.
+
+	hooks *resourceHooks // the runner for resource lifecycle hooks registered by the program.
+
+	m        sync.Mutex
+	inflight map[plugin.Provider]int // the number of steps being applied by each provider.
+	timedOut []Step                  // the steps that did not finish before their deadlines.
+	failed   []resource.URN          // the resources whose steps failed without canceling the deployment.
 }
 
 //
@@ -211,6 +221,21 @@ func (se *stepExecutor) ExecuteRegisterResourceOutputs(e RegisterResourceOutputs
 	return nil
 }
 
+// TimedOut returns the steps that did not finish before their deadlines.
+func (se *stepExecutor) TimedOut() []Step {
+	se.m.Lock()
+	defer se.m.Unlock()
+	return append([]Step(nil), se.timedOut...)
+}
+
+// Failed returns the URNs of the resources whose steps failed without canceling the deployment, either because they
+// timed out or because the deployment continues on error.
+func (se *stepExecutor) Failed() []resource.URN {
+	se.m.Lock()
+	defer se.m.Unlock()
+	return append([]resource.URN(nil), se.failed...)
+}
+
 // Errored returns whether or not this step executor saw a step whose execution ended in failure.
 func (se *stepExecutor) Errored() bool {
 	return se.sawError.Load().(bool)
@@ -228,6 +253,7 @@ func (se *stepExecutor) WaitForCompletion() {
 	se.log(synchronousWorkerID, "StepExecutor.waitForCompletion(): waiting for worker threads to exit")
 	se.workers.Wait()
 	se.log(synchronousWorkerID, "StepExecutor.waitForCompletion(): worker threads all exited")
+	se.hooks.close()
 }
 
 //
@@ -248,7 +274,38 @@ func (se *stepExecutor) executeChain(workerID int, chain chain) {
 		}
 
 		if err := se.executeStep(workerID, step); err != nil {
+			// A step whose resource hook failed fails the resource's registration, so that the program does not
+			// wait for the resource if the deployment goes on.
+			var hookErr *resourceHookError
+			if errors.As(err, &hookErr) {
+				failRegistration(chain, hookErr.err)
+				err = hookErr.err
+				if hookErr.reported {
+					err = errStepApplyFailed
+				}
+			}
+
+			// A step that timed out fails the resource's registration, if the chain has one, rather than the whole
+			// deployment, so that the steps that do not depend on the resource can go on. The deployment executor
+			// keeps the dependencies of a resource whose delete timed out.
+			var timeoutErr *StepTimeoutError
+			if errors.As(err, &timeoutErr) {
+				se.log(workerID, "step %v on %v timed out", step.Op(), step.URN())
+				failRegistration(chain, timeoutErr)
+				se.m.Lock()
+				se.timedOut = append(se.timedOut, step)
+				se.failed = append(se.failed, step.URN())
+				se.m.Unlock()
+				se.sawError.Store(true)
+				return
+			}
+
 			se.log(workerID, "step %v on %v failed, signalling cancellation", step.Op(), step.URN())
+			if se.continueOnError {
+				se.m.Lock()
+				se.failed = append(se.failed, step.URN())
+				se.m.Unlock()
+			}
 			se.cancelDueToError()
 			if err != errStepApplyFailed {
 				// Step application errors are recorded by the OnResourceStepPost callback. This is confusing,
@@ -274,9 +331,11 @@ func (se *stepExecutor) cancelDueToError() {
 //
 // The next few functions are responsible for executing individual steps. The basic flow of step
 // execution is
-//   1. The pre-step event is raised, if there are any attached callbacks to the engine
-//   2. If successful, the step is executed (if not a preview)
-//   3. The post-step event is raised, if there are any attached callbacks to the engine
+//   1. Any "before" lifecycle hooks registered by the program are run (if not a preview)
+//   2. The pre-step event is raised, if there are any attached callbacks to the engine
+//   3. If successful, the step is executed (if not a preview)
+//   4. If successful, any "after" lifecycle hooks registered by the program are run (if not a preview)
+//   5. The post-step event is raised, if there are any attached callbacks to the engine
 //
 // The pre-step event returns an interface{}, which is some arbitrary context that must be passed
 // verbatim to the post-step event.
@@ -285,8 +344,34 @@ func (se *stepExecutor) cancelDueToError() {
 // executeStep executes a single step, returning true if the step execution was successful and
 // false if it was not.
 func (se *stepExecutor) executeStep(workerID int, step Step) error {
-	var payload interface{}
+	var hooks []resource.Hook
+	before, after, hasHooks := stepHookKinds(step.Op())
+	if hasHooks && !se.preview {
+		var err error
+		if hooks, err = se.stepHooks(step); err != nil {
+			se.log(workerID, "step %v on %v could not resolve hooks: %v", step.Op(), step.URN(), err)
+			return err
+		}
+	}
 	events := se.opts.Events
+	if len(hooks) > 0 {
+		if hookErr := se.hooks.run(se.ctx, step, hooks, before); hookErr != nil {
+			se.log(workerID, "step %v on %v failed %v hook: %v", step.Op(), step.URN(), before, hookErr)
+			// The step is not applied, but its pre- and post-step events are raised so that it is reported as failed.
+			if events != nil {
+				payload, err := events.OnResourceStepPre(step)
+				if err != nil {
+					return fmt.Errorf("pre-step event returned an error: %w", err)
+				}
+				if err = events.OnResourceStepPost(payload, step, resource.StatusOK, hookErr); err != nil {
+					return fmt.Errorf("post-step event returned an error: %w", err)
+				}
+			}
+			return &resourceHookError{err: hookErr, reported: events != nil}
+		}
+	}
+
+	var payload interface{}
 	if events != nil {
 		var err error
 		payload, err = events.OnResourceStepPre(step)
@@ -297,7 +382,7 @@ func (se *stepExecutor) executeStep(workerID int, step Step) error {
 	}
 
 	se.log(workerID, "applying step %v on %v (preview %v)", step.Op(), step.URN(), se.preview)
-	status, stepComplete, err := step.Apply(se.preview)
+	status, stepComplete, err := se.applyStep(workerID, step)
 
 	if err == nil {
 		// If we have a state object, and this is a create or update, remember it, as we may need to update it later.
@@ -358,13 +443,35 @@ func (se *stepExecutor) executeStep(workerID int, step Step) error {
 		}
 	}
 
+	// A failing "after" hook fails the step, but the step was applied, so it is reported as a partial failure and its
+	// results are still saved without losing track of the resource.
+	var hookErr error
+	if err == nil && len(hooks) > 0 {
+		if hookErr = se.hooks.run(se.ctx, step, hooks, after); hookErr != nil {
+			se.log(workerID, "step %v on %v failed %v hook: %v", step.Op(), step.URN(), after, hookErr)
+			status = resource.StatusPartialFailure
+		}
+	}
+
 	if events != nil {
-		if postErr := events.OnResourceStepPost(payload, step, status, err); postErr != nil {
+		stepErr := err
+		if hookErr != nil {
+			stepErr = hookErr
+		}
+		if postErr := events.OnResourceStepPost(payload, step, status, stepErr); postErr != nil {
 			se.log(workerID, "step %v on %v failed post-resource step: %v", step.Op(), step.URN(), postErr)
 			return fmt.Errorf("post-step event returned an error: %w", postErr)
 		}
 	}
 
+	// The registration of a resource whose "after" hook failed fails with the hook's error rather than completing
+	// with the resource's state.
+	if hookErr != nil {
+		if reg := stepRegistration(step); reg != nil {
+			reg.Done(&RegisterResult{Err: hookErr})
+		}
+	}
+
 	// Calling stepComplete allows steps that depend on this step to continue. OnResourceStepPost saved the results
 	// of the step in the snapshot, so we are ready to go.
 	if stepComplete != nil {
@@ -372,14 +479,202 @@ func (se *stepExecutor) executeStep(workerID int, step Step) error {
 		stepComplete()
 	}
 
+	if hookErr != nil {
+		return &resourceHookError{err: hookErr, reported: events != nil}
+	}
+
 	if err != nil {
 		se.log(workerID, "step %v on %v failed with an error: %v", step.Op(), step.URN(), err)
+		if timeoutErr, ok := err.(*StepTimeoutError); ok {
+			return timeoutErr
+		}
 		return errStepApplyFailed
 	}
 
 	return nil
 }
 
+// resourceHookError is the error with which a step fails if one of its resource hooks fails. reported is set if the
+// failure was reported through the step's events.
+type resourceHookError struct {
+	err      error
+	reported bool
+}
+
+func (e *resourceHookError) Error() string {
+	return e.err.Error()
+}
+
+func (e *resourceHookError) Unwrap() error {
+	return e.err
+}
+
+// StepTimeoutError is the error with which a step fails if it does not finish before its deadline.
+type StepTimeoutError struct {
+	URN     resource.URN
+	Op      display.StepOp
+	Timeout time.Duration
+	// Abandoned is set if the provider had not given up on the operation by the end of the grace period that followed
+	// the deadline, in which case the operation may still be in progress.
+	Abandoned bool
+}
+
+func (e *StepTimeoutError) Error() string {
+	msg := fmt.Sprintf("%v of %v did not finish within %v", e.Op, e.URN, e.Timeout)
+	if e.Abandoned {
+		msg += "; the operation was abandoned and may still be in progress"
+	}
+	return msg
+}
+
+// maxStepTimeoutGracePeriod bounds the time that a provider is given to give up on an operation once its step's
+// deadline has passed.
+const maxStepTimeoutGracePeriod = time.Minute
+
+// stepTimeout returns the deadline that the engine enforces for the given step, or zero if there is none. Only
+// creates, updates and deletes have deadlines. The resource's custom timeout for the step's operation takes precedence
+// over the deployment's default.
+func (se *stepExecutor) stepTimeout(step Step) time.Duration {
+	var seconds float64
+	switch step.Op() {
+	case OpCreate, OpCreateReplacement:
+		seconds = step.New().CustomTimeouts.Create
+	case OpUpdate:
+		seconds = step.New().CustomTimeouts.Update
+	case OpDelete, OpDeleteReplaced:
+		seconds = step.Old().CustomTimeouts.Delete
+	default:
+		return 0
+	}
+	if seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	return se.opts.StepTimeout
+}
+
+// applyStep applies the given step. If the step does not finish before its deadline, the step's provider is asked to
+// cancel its in-flight operations, and the provider is given as long again as the deadline, up to
+// maxStepTimeoutGracePeriod, to finish. If it succeeds in that time its results are kept; otherwise the step fails
+// with a StepTimeoutError, and if the provider still has not returned, the operation is abandoned.
+//
+// Providers can only be asked to cancel all of their in-flight operations, so the provider is only asked to cancel if
+// the timed-out step is the only step that it is applying. Otherwise the other steps are left to finish, and the
+// timed-out step is abandoned at the end of the grace period.
+func (se *stepExecutor) applyStep(workerID int, step Step) (resource.Status, StepCompleteFunc, error) {
+	prov, err := getProvider(step)
+	if err != nil {
+		prov = nil
+	}
+
+	timeout := se.stepTimeout(step)
+	if timeout <= 0 {
+		return se.apply(prov, step)
+	}
+
+	type applyResult struct {
+		status   resource.Status
+		complete StepCompleteFunc
+		err      error
+	}
+	// The channel is buffered so that the result of an abandoned step can be sent once the step is done.
+	done := make(chan applyResult, 1)
+	go func() {
+		status, complete, err := se.apply(prov, step)
+		done <- applyResult{status, complete, err}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		return r.status, r.complete, r.err
+	case <-timer.C:
+	}
+
+	se.log(workerID, "step %v on %v timed out after %v", step.Op(), step.URN(), timeout)
+	if prov != nil {
+		if se.applying(prov) == 1 {
+			if err := prov.SignalCancellation(); err != nil {
+				se.log(workerID, "failed to signal cancellation to the provider of %v: %v", step.URN(), err)
+			}
+		} else {
+			se.log(workerID, "not signalling cancellation to the provider of %v, which is applying other steps",
+				step.URN())
+		}
+	}
+
+	grace := timeout
+	if grace > maxStepTimeoutGracePeriod {
+		grace = maxStepTimeoutGracePeriod
+	}
+	timer.Reset(grace)
+	select {
+	case r := <-done:
+		if r.err == nil {
+			se.log(workerID, "step %v on %v finished after its deadline", step.Op(), step.URN())
+			return r.status, r.complete, nil
+		}
+		se.log(workerID, "step %v on %v was given up by its provider: %v", step.Op(), step.URN(), r.err)
+		return r.status, r.complete, &StepTimeoutError{URN: step.URN(), Op: step.Op(), Timeout: timeout}
+	case <-timer.C:
+		se.log(workerID, "step %v on %v was abandoned", step.Op(), step.URN())
+		return resource.StatusUnknown, nil, &StepTimeoutError{
+			URN: step.URN(), Op: step.Op(), Timeout: timeout, Abandoned: true,
+		}
+	}
+}
+
+// apply applies the given step, counting it among the steps that its provider, if any, is applying.
+func (se *stepExecutor) apply(prov plugin.Provider, step Step) (resource.Status, StepCompleteFunc, error) {
+	if prov != nil {
+		se.m.Lock()
+		se.inflight[prov]++
+		se.m.Unlock()
+		defer func() {
+			se.m.Lock()
+			if se.inflight[prov]--; se.inflight[prov] == 0 {
+				delete(se.inflight, prov)
+			}
+			se.m.Unlock()
+		}()
+	}
+	return step.Apply(se.preview)
+}
+
+// applying returns the number of steps that the given provider is applying.
+func (se *stepExecutor) applying(prov plugin.Provider) int {
+	se.m.Lock()
+	defer se.m.Unlock()
+	return se.inflight[prov]
+}
+
+// failRegistration fails the resource registration completed by the given chain, if any, so that the program stops
+// waiting for the resource.
+func failRegistration(chain chain, err error) {
+	for _, step := range chain {
+		if reg := stepRegistration(step); reg != nil {
+			reg.Done(&RegisterResult{Err: err})
+			return
+		}
+	}
+}
+
+// stepRegistration returns the resource registration that the given step completes, if any.
+func stepRegistration(step Step) RegisterResourceEvent {
+	switch step := step.(type) {
+	case *SameStep:
+		return step.reg
+	case *CreateStep:
+		return step.reg
+	case *UpdateStep:
+		return step.reg
+	case *ImportStep:
+		return step.reg
+	default:
+		return nil
+	}
+}
+
 // log is a simple logging helper for the step executor.
 func (se *stepExecutor) log(workerID int, msg string, args ...interface{}) {
 	if logging.V(stepExecutorLogLevel) {
@@ -457,6 +752,8 @@ func newStepExecutor(ctx context.Context, cancel context.CancelFunc, deployment
 		incomingChains:  make(chan incomingChain),
 		ctx:             ctx,
 		cancel:          cancel,
+		hooks:           newResourceHooks(),
+		inflight:        make(map[plugin.Provider]int),
 	}
 
 	exec.sawError.Store(false)
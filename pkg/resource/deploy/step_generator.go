@@ -533,6 +533,7 @@ func (sg *stepGenerator) generateSteps(event RegisterResourceEvent) ([]Step, res
 		goal.Dependencies, goal.InitErrors, goal.Provider, goal.PropertyDependencies, false,
 		goal.AdditionalSecretOutputs, aliasUrns, &goal.CustomTimeouts, "", goal.RetainOnDelete, goal.DeletedWith,
 		createdAt, modifiedAt)
+	new.Hooks = goal.Hooks
 
 	// Mark the URN/resource as having been seen. So we can run analyzers on all resources seen, as well as
 	// lookup providers for calculating replacement of resources that use the provider.
@@ -1196,6 +1197,42 @@ func (sg *stepGenerator) GenerateDeletes(targetsOpt UrnTargets) ([]Step, result.
 	return dels, nil
 }
 
+// keepReplaced is called when the replacement of a resource has been rejected. It undoes the bookkeeping for the
+// replacement chain that was generated for the resource and returns a step that leaves the resource as it is.
+func (sg *stepGenerator) keepReplaced(event RegisterResourceEvent, chain []Step, replace Step) Step {
+	urn := replace.URN()
+	for _, s := range chain {
+		if s.Op() != OpDelete && s.Op() != OpDeleteReplaced {
+			continue
+		}
+
+		// Any dependents condemned along with the resource are no longer deleted.
+		if s.URN() != urn {
+			delete(sg.deletes, s.URN())
+			delete(sg.pendingDeletes, s.Old())
+			delete(sg.dependentReplaceKeys, s.URN())
+		}
+	}
+	delete(sg.replaces, urn)
+	sg.sames[urn] = true
+
+	// The resource keeps its inputs and provider, since it is not replaced, but takes the rest of its metadata from
+	// the goal, as it would for any other same step.
+	old, goal := replace.Old(), event.Goal()
+	new := resource.NewState(old.Type, urn, old.Custom, false, "", old.Inputs.Copy(), nil, goal.Parent, goal.Protect,
+		old.External, goal.Dependencies, goal.InitErrors, old.Provider, goal.PropertyDependencies, false,
+		goal.AdditionalSecretOutputs, replace.New().Aliases, &goal.CustomTimeouts, old.ImportID, goal.RetainOnDelete,
+		goal.DeletedWith, old.Created, old.Modified)
+	new.Hooks = goal.Hooks
+	return NewSameStep(sg.deployment, event, old, new)
+}
+
+// keepDeleted is called when the delete of a resource will not be executed, either because the delete was rejected
+// or because a resource that depends on it is being kept.
+func (sg *stepGenerator) keepDeleted(step Step) {
+	delete(sg.deletes, step.URN())
+}
+
 // getTargetDependents returns the (transitive) set of dependents on the target resources.
 // This includes both implicit and explicit dependents in the DAG itself, as well as children.
 func (sg *stepGenerator) getTargetDependents(targetsOpt UrnTargets) map[resource.URN]bool {
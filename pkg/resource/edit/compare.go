@@ -0,0 +1,129 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edit
+
+import (
+	"strings"
+
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+// A ResourceDelta describes how a resource differs between two snapshots of a stack, such as two versions from the
+// stack's history.
+type ResourceDelta struct {
+	Kind ChangeKind
+	// Old is the state of the resource in the older snapshot, or nil if the resource was added.
+	Old *resource.State
+	// New is the state of the resource in the newer snapshot, or nil if the resource was removed.
+	New *resource.State
+	// Fields are the names of the fields of a modified resource other than its inputs and outputs that changed, as
+	// they appear in the serialized state.
+	Fields []string
+	// Inputs and Outputs are the differences between the inputs and outputs of a modified resource, or nil if they
+	// did not change.
+	Inputs, Outputs *resource.ObjectDiff
+}
+
+// URN returns the URN of the resource in the newer snapshot, or in the older snapshot if it was removed.
+func (d ResourceDelta) URN() resource.URN {
+	if d.New != nil {
+		return d.New.URN
+	}
+	return d.Old.URN
+}
+
+// Renamed returns true if the resource has a different URN in each snapshot.
+func (d ResourceDelta) Renamed() bool {
+	return d.Old != nil && d.New != nil && d.Old.URN != d.New.URN
+}
+
+// CompareSnapshots compares the resources of two snapshots of a stack. Resources are aligned by URN, or, if a
+// resource in the newer snapshot has an alias that is the URN of an otherwise unmatched resource in the older
+// snapshot, by alias. Resources that are pending deletion are only aligned with each other. The deltas of removed
+// resources come first, in the order of the older snapshot, followed by the deltas of added and modified resources
+// in the order of the newer snapshot. Resources that did not change are omitted.
+func CompareSnapshots(old, new *deploy.Snapshot) []ResourceDelta {
+	type key struct {
+		urn    resource.URN
+		delete bool
+	}
+	olds := make(map[key]*resource.State)
+	if old != nil {
+		for _, res := range old.Resources {
+			k := key{res.URN, res.Delete}
+			if _, has := olds[k]; !has {
+				olds[k] = res
+			}
+		}
+	}
+	var newResources []*resource.State
+	if new != nil {
+		newResources = new.Resources
+	}
+
+	// Match each new resource to an old resource, first by URN and then by alias.
+	matches := make(map[*resource.State]*resource.State)
+	matched := make(map[*resource.State]bool)
+	for _, res := range newResources {
+		if o, has := olds[key{res.URN, res.Delete}]; has && !matched[o] {
+			matches[res], matched[o] = o, true
+		}
+	}
+	for _, res := range newResources {
+		if _, has := matches[res]; has {
+			continue
+		}
+		for _, alias := range res.Aliases {
+			if o, has := olds[key{alias, res.Delete}]; has && !matched[o] {
+				matches[res], matched[o] = o, true
+				break
+			}
+		}
+	}
+
+	var deltas []ResourceDelta
+	if old != nil {
+		for _, res := range old.Resources {
+			if !matched[res] {
+				deltas = append(deltas, ResourceDelta{Kind: ChangeRemoved, Old: res})
+			}
+		}
+	}
+	for _, res := range newResources {
+		o, has := matches[res]
+		if !has {
+			deltas = append(deltas, ResourceDelta{Kind: ChangeAdded, New: res})
+			continue
+		}
+
+		delta := ResourceDelta{
+			Kind:    ChangeModified,
+			Old:     o,
+			New:     res,
+			Inputs:  o.Inputs.Diff(res.Inputs, resource.IsInternalPropertyKey),
+			Outputs: o.Outputs.Diff(res.Outputs, resource.IsInternalPropertyKey),
+		}
+		for _, field := range diffResource(o, res) {
+			if !strings.HasPrefix(field, "inputs.") && !strings.HasPrefix(field, "outputs.") {
+				delta.Fields = append(delta.Fields, field)
+			}
+		}
+		if delta.Inputs != nil || delta.Outputs != nil || len(delta.Fields) > 0 {
+			deltas = append(deltas, delta)
+		}
+	}
+	return deltas
+}
@@ -0,0 +1,67 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+func TestCompareSnapshots(t *testing.T) {
+	t.Parallel()
+
+	pA := NewProviderResource("a", "p1", "0")
+	a := NewResource("a", pA)
+	a.Inputs = resource.PropertyMap{"size": resource.NewNumberProperty(1)}
+	b := NewResource("b", pA)
+	removed := NewResource("removed", pA)
+	old := NewSnapshot([]*resource.State{pA, a, b, removed})
+
+	a2 := *a
+	a2.Inputs = resource.PropertyMap{"size": resource.NewNumberProperty(2)}
+	// b is renamed to c, which lists b's old URN as an alias.
+	c := NewResource("c", pA)
+	c.Aliases = []resource.URN{b.URN}
+	c.Protect = true
+	added := NewResource("added", pA)
+	new := NewSnapshot([]*resource.State{pA, &a2, c, added})
+
+	deltas := CompareSnapshots(old, new)
+	require.Len(t, deltas, 4)
+
+	assert.Equal(t, ChangeRemoved, deltas[0].Kind)
+	assert.Equal(t, removed.URN, deltas[0].URN())
+
+	assert.Equal(t, ChangeModified, deltas[1].Kind)
+	assert.Equal(t, a.URN, deltas[1].URN())
+	require.NotNil(t, deltas[1].Inputs)
+	assert.Equal(t, []resource.PropertyKey{"size"}, deltas[1].Inputs.ChangedKeys())
+	assert.Nil(t, deltas[1].Outputs)
+	assert.Empty(t, deltas[1].Fields)
+
+	assert.Equal(t, ChangeModified, deltas[2].Kind)
+	assert.True(t, deltas[2].Renamed())
+	assert.Equal(t, b, deltas[2].Old)
+	assert.Equal(t, []string{"urn", "protect", "aliases"}, deltas[2].Fields)
+
+	assert.Equal(t, ChangeAdded, deltas[3].Kind)
+	assert.Equal(t, added.URN, deltas[3].URN())
+
+	assert.Empty(t, CompareSnapshots(old, old))
+}
@@ -0,0 +1,167 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edit
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+// ChangeKind is the kind of change made to a resource or pending operation in a snapshot.
+type ChangeKind string
+
+const (
+	ChangeAdded    ChangeKind = "added"
+	ChangeRemoved  ChangeKind = "removed"
+	ChangeModified ChangeKind = "modified"
+)
+
+// ResourceChange is a change made to a resource in a snapshot.
+type ResourceChange struct {
+	URN  resource.URN
+	Kind ChangeKind
+	// Fields are the names of the fields of a modified resource that changed, as they appear in the serialized state.
+	// Changes to inputs and outputs are reported for each top-level property, e.g. "inputs.name".
+	Fields []string
+}
+
+// OperationChange is a pending operation that was added to or removed from a snapshot.
+type OperationChange struct {
+	URN  resource.URN
+	Type resource.OperationType
+	Kind ChangeKind
+}
+
+// SnapshotDiff describes the changes between two snapshots.
+type SnapshotDiff struct {
+	Resources  []ResourceChange
+	Operations []OperationChange
+}
+
+// AnyChanges returns true if the snapshots differ.
+func (d *SnapshotDiff) AnyChanges() bool {
+	return len(d.Resources) > 0 || len(d.Operations) > 0
+}
+
+// DiffSnapshots compares the resources and pending operations of two snapshots. Resources are matched by URN; if
+// several resources share a URN, they are matched in the order in which they appear. Property values are compared
+// without being shown, so the result is safe to display even if the snapshots contain secrets.
+func DiffSnapshots(old, new *deploy.Snapshot) *SnapshotDiff {
+	type key struct {
+		urn resource.URN
+		n   int
+	}
+	index := func(resources []*resource.State) ([]key, map[key]*resource.State) {
+		keys := make([]key, 0, len(resources))
+		states := make(map[key]*resource.State, len(resources))
+		seen := make(map[resource.URN]int)
+		for _, res := range resources {
+			k := key{res.URN, seen[res.URN]}
+			seen[res.URN]++
+			keys = append(keys, k)
+			states[k] = res
+		}
+		return keys, states
+	}
+	oldKeys, olds := index(old.Resources)
+	newKeys, news := index(new.Resources)
+
+	diff := &SnapshotDiff{}
+	for _, k := range oldKeys {
+		if _, has := news[k]; !has {
+			diff.Resources = append(diff.Resources, ResourceChange{URN: k.urn, Kind: ChangeRemoved})
+		}
+	}
+	for _, k := range newKeys {
+		o, has := olds[k]
+		if !has {
+			diff.Resources = append(diff.Resources, ResourceChange{URN: k.urn, Kind: ChangeAdded})
+			continue
+		}
+		if fields := diffResource(o, news[k]); len(fields) > 0 {
+			diff.Resources = append(diff.Resources, ResourceChange{URN: k.urn, Kind: ChangeModified, Fields: fields})
+		}
+	}
+
+	type opKey struct {
+		urn resource.URN
+		typ resource.OperationType
+	}
+	oldOps := make(map[opKey]int)
+	for _, op := range old.PendingOperations {
+		oldOps[opKey{op.Resource.URN, op.Type}]++
+	}
+	for _, op := range new.PendingOperations {
+		k := opKey{op.Resource.URN, op.Type}
+		if oldOps[k] > 0 {
+			oldOps[k]--
+			continue
+		}
+		diff.Operations = append(diff.Operations, OperationChange{URN: k.urn, Type: k.typ, Kind: ChangeAdded})
+	}
+	for _, op := range old.PendingOperations {
+		k := opKey{op.Resource.URN, op.Type}
+		if oldOps[k] > 0 {
+			oldOps[k]--
+			diff.Operations = append(diff.Operations, OperationChange{URN: k.urn, Type: k.typ, Kind: ChangeRemoved})
+		}
+	}
+	return diff
+}
+
+// diffResource returns the names of the fields that differ between two states of a resource.
+func diffResource(old, new *resource.State) []string {
+	var fields []string
+	oldv, newv := reflect.ValueOf(old).Elem(), reflect.ValueOf(new).Elem()
+	serialized := reflect.TypeOf(apitype.ResourceV3{})
+	for i := 0; i < oldv.NumField(); i++ {
+		field := oldv.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := field.Name
+		if sf, ok := serialized.FieldByName(field.Name); ok {
+			name = strings.Split(sf.Tag.Get("json"), ",")[0]
+		}
+
+		switch o, n := oldv.Field(i).Interface(), newv.Field(i).Interface(); o := o.(type) {
+		case resource.PropertyMap:
+			for _, k := range o.Diff(n.(resource.PropertyMap)).ChangedKeys() {
+				fields = append(fields, name+"."+string(k))
+			}
+		default:
+			if !equalField(oldv.Field(i), newv.Field(i)) {
+				fields = append(fields, name)
+			}
+		}
+	}
+	return fields
+}
+
+// equalField compares two field values, treating nil and empty slices and maps as equal, since the distinction is
+// lost when a snapshot is serialized.
+func equalField(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Slice, reflect.Map:
+		if a.Len() == 0 && b.Len() == 0 {
+			return true
+		}
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
@@ -0,0 +1,51 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	t.Parallel()
+
+	a, b, c := NewResource("a", nil), NewResource("b", nil), NewResource("c", nil)
+	a.Inputs["secret"] = resource.MakeSecret(resource.NewStringProperty("foo"))
+	old := NewSnapshot([]*resource.State{a, b})
+	old.PendingOperations = []resource.Operation{resource.NewOperation(b, resource.OperationTypeCreating)}
+
+	a2 := *a
+	a2.ID = "new-id"
+	a2.Inputs = resource.PropertyMap{"secret": resource.MakeSecret(resource.NewStringProperty("bar"))}
+	a2.Dependencies = []resource.URN{}
+	new := NewSnapshot([]*resource.State{&a2, c})
+
+	diff := DiffSnapshots(old, new)
+	assert.True(t, diff.AnyChanges())
+	assert.Equal(t, []ResourceChange{
+		{URN: b.URN, Kind: ChangeRemoved},
+		{URN: a.URN, Kind: ChangeModified, Fields: []string{"id", "inputs.secret"}},
+		{URN: c.URN, Kind: ChangeAdded},
+	}, diff.Resources)
+	assert.Equal(t, []OperationChange{
+		{URN: b.URN, Type: resource.OperationTypeCreating, Kind: ChangeRemoved},
+	}, diff.Operations)
+
+	assert.False(t, DiffSnapshots(old, old).AnyChanges())
+}
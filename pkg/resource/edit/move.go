@@ -0,0 +1,265 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edit
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy/providers"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
+)
+
+// BrokenDependency is a dependency between a resource that was moved to another stack and a resource that was not.
+// Both URNs are the URNs of the resources in the source stack.
+type BrokenDependency struct {
+	Dependent  resource.URN
+	Dependency resource.URN
+	// Property is the property of the dependent that depended on the dependency, or empty if the dependency was not
+	// specific to a property.
+	Property resource.PropertyKey
+}
+
+// MoveResult describes the changes made by MoveResources.
+type MoveResult struct {
+	// Moved are the resources that were removed from the source stack and added to the destination stack.
+	Moved []*resource.State
+	// CopiedProviders are the providers that were copied to the destination stack because resources in both stacks
+	// use them.
+	CopiedProviders []*resource.State
+	// BrokenDependencies are the dependencies between moved and unmoved resources. These dependencies are removed
+	// from both stacks.
+	BrokenDependencies []BrokenDependency
+}
+
+// MoveResources moves the resources in the source snapshot whose URNs match the given targets to the destination
+// snapshot, rewriting their URNs for the given destination stack and project.
+//
+// The children of a moved resource are always moved along with it, as are its parents other than the root stack
+// resource. Resources that were children of the source's root stack become children of the destination's root stack.
+// The providers used by moved resources are moved too, or copied if resources that remain in the source stack still
+// use them. Dependencies between moved and unmoved resources cannot be kept across stacks; they are removed from both
+// snapshots and reported in the result.
+//
+// Nothing is changed if an error is returned.
+func MoveResources(
+	source, dest *deploy.Snapshot, targets deploy.UrnTargets,
+	destStack tokens.Name, destProject tokens.PackageName,
+) (*MoveResult, error) {
+	contract.Requiref(source != nil, "source", "must not be nil")
+	contract.Requiref(dest != nil, "dest", "must not be nil")
+
+	byURN := make(map[resource.URN]*resource.State)
+	for _, res := range source.Resources {
+		byURN[res.URN] = res
+	}
+
+	// Select the matching resources and their parents, then their children. Parents come before their children in a
+	// snapshot, so a single pass in order finds every descendant.
+	moving := make(map[resource.URN]bool)
+	for _, res := range source.Resources {
+		if !targets.Contains(res.URN) {
+			continue
+		}
+		if res.Type == resource.RootStackType {
+			return nil, fmt.Errorf("cannot move the root stack resource %s", res.URN)
+		}
+		for r := res; r != nil && r.Type != resource.RootStackType && !moving[r.URN]; r = byURN[r.Parent] {
+			moving[r.URN] = true
+		}
+	}
+	for _, res := range source.Resources {
+		if res.Parent != "" && moving[res.Parent] {
+			moving[res.URN] = true
+		}
+	}
+	if len(moving) == 0 {
+		return nil, fmt.Errorf("no resources match %v", targets.Literals())
+	}
+
+	for _, op := range source.PendingOperations {
+		if moving[op.Resource.URN] {
+			return nil, fmt.Errorf("resource %s has a pending %s operation; run `pulumi refresh` to resolve it first",
+				op.Resource.URN, op.Type)
+		}
+	}
+
+	// Providers that are still used by resources that stay behind are copied rather than moved.
+	usedByMoved, usedByUnmoved := make(map[resource.URN]bool), make(map[resource.URN]bool)
+	for _, res := range source.Resources {
+		if res.Provider == "" {
+			continue
+		}
+		ref, err := providers.ParseReference(res.Provider)
+		if err != nil {
+			return nil, fmt.Errorf("parsing provider reference of %s: %w", res.URN, err)
+		}
+		if moving[res.URN] {
+			usedByMoved[ref.URN()] = true
+		} else {
+			usedByUnmoved[ref.URN()] = true
+		}
+	}
+	copying := make(map[resource.URN]bool)
+	for urn := range usedByMoved {
+		if !moving[urn] || usedByUnmoved[urn] {
+			if moving[byURN[urn].Parent] {
+				return nil, fmt.Errorf("provider %s is used by resources that are not being moved, "+
+					"but its parent %s is being moved", urn, byURN[urn].Parent)
+			}
+			delete(moving, urn)
+			copying[urn] = true
+		}
+	}
+
+	// Check the destination for resources that would conflict with the ones being added. A provider that is already
+	// in the destination with the same ID is shared rather than added again.
+	rewrite := func(urn resource.URN) resource.URN {
+		return resource.NewURN(destStack.Q(), destProject, "", urn.QualifiedType(), urn.Name())
+	}
+	var destRoot resource.URN
+	destByURN := make(map[resource.URN]*resource.State)
+	for _, res := range dest.Resources {
+		if res.Type == resource.RootStackType && res.Parent == "" {
+			destRoot = res.URN
+		}
+		destByURN[res.URN] = res
+	}
+	shared := make(map[resource.URN]bool)
+	for _, res := range source.Resources {
+		if !moving[res.URN] && !copying[res.URN] {
+			continue
+		}
+		existing, has := destByURN[rewrite(res.URN)]
+		switch {
+		case !has:
+		case providers.IsProviderType(res.Type) && existing.ID == res.ID:
+			shared[res.URN] = true
+		default:
+			return nil, fmt.Errorf("a resource named %s already exists in the destination stack", existing.URN)
+		}
+	}
+
+	// Everything has been checked, so the snapshots can now be changed. Dependencies that cross the stacks are broken.
+	result := &MoveResult{}
+	keep := func(dependent, dep resource.URN, key resource.PropertyKey, moved bool) bool {
+		if moved && (moving[dep] || copying[dep]) || !moved && !moving[dep] {
+			return true
+		}
+		result.BrokenDependencies = append(result.BrokenDependencies, BrokenDependency{
+			Dependent:  dependent,
+			Dependency: dep,
+			Property:   key,
+		})
+		return false
+	}
+	filter := func(res *resource.State, moved bool) {
+		var deps []resource.URN
+		for _, dep := range res.Dependencies {
+			if keep(res.URN, dep, "", moved) {
+				deps = append(deps, dep)
+			}
+		}
+		res.Dependencies = deps
+
+		var propDeps map[resource.PropertyKey][]resource.URN
+		if res.PropertyDependencies != nil {
+			propDeps = make(map[resource.PropertyKey][]resource.URN, len(res.PropertyDependencies))
+		}
+		keys := make([]resource.PropertyKey, 0, len(res.PropertyDependencies))
+		for key := range res.PropertyDependencies {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+		for _, key := range keys {
+			var deps []resource.URN
+			for _, dep := range res.PropertyDependencies[key] {
+				if keep(res.URN, dep, key, moved) {
+					deps = append(deps, dep)
+				}
+			}
+			propDeps[key] = deps
+		}
+		res.PropertyDependencies = propDeps
+
+		if res.DeletedWith != "" && !keep(res.URN, res.DeletedWith, "", moved) {
+			res.DeletedWith = ""
+		}
+	}
+	rewriteState := func(res *resource.State) {
+		res.URN = rewrite(res.URN)
+		switch {
+		case res.Parent == "":
+		case moving[res.Parent]:
+			res.Parent = rewrite(res.Parent)
+		default:
+			// Only the root stack resource can be the parent of a moved resource without being moved itself.
+			res.Parent = destRoot
+		}
+		for i, dep := range res.Dependencies {
+			res.Dependencies[i] = rewrite(dep)
+		}
+		for _, deps := range res.PropertyDependencies {
+			for i, dep := range deps {
+				deps[i] = rewrite(dep)
+			}
+		}
+		if res.DeletedWith != "" {
+			res.DeletedWith = rewrite(res.DeletedWith)
+		}
+		if res.Provider != "" {
+			ref, err := providers.ParseReference(res.Provider)
+			contract.AssertNoErrorf(err, "failed to parse provider reference from validated checkpoint")
+			ref, err = providers.NewReference(rewrite(ref.URN()), ref.ID())
+			contract.AssertNoErrorf(err, "failed to generate provider reference from valid reference")
+			res.Provider = ref.String()
+		}
+		// Aliases refer to the resource's previous names in the source stack, which mean nothing in the destination.
+		res.Aliases = nil
+	}
+
+	remaining := make([]*resource.State, 0, len(source.Resources))
+	var added []*resource.State
+	for _, res := range source.Resources {
+		if copying[res.URN] && !shared[res.URN] {
+			// filter replaces the dependency slices and maps, so the copy does not share them with the original.
+			c := *res
+			filter(&c, true)
+			result.CopiedProviders = append(result.CopiedProviders, &c)
+			added = append(added, &c)
+		}
+
+		if !moving[res.URN] {
+			filter(res, false)
+			remaining = append(remaining, res)
+			continue
+		}
+		filter(res, true)
+		result.Moved = append(result.Moved, res)
+		if !shared[res.URN] {
+			added = append(added, res)
+		}
+	}
+	source.Resources = remaining
+
+	for _, res := range added {
+		rewriteState(res)
+	}
+	dest.Resources = append(dest.Resources, added...)
+	return result, nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy/providers"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+)
+
+func newRootStack(stack, project string) *resource.State {
+	return &resource.State{
+		Type: resource.RootStackType,
+		URN:  resource.DefaultRootStackURN(tokens.QName(stack), tokens.PackageName(project)),
+	}
+}
+
+func TestMoveResources(t *testing.T) {
+	t.Parallel()
+
+	root := newRootStack("test", "test")
+	pA := NewProviderResource("a", "p1", "0")
+	comp := NewResource("comp", nil)
+	comp.Parent = root.URN
+	a := NewResource("a", pA)
+	a.Parent = comp.URN
+	b := NewResource("b", pA, a.URN)
+	b.Parent = root.URN
+	b.PropertyDependencies = map[resource.PropertyKey][]resource.URN{"x": {a.URN}}
+	source := NewSnapshot([]*resource.State{root, pA, comp, a, b})
+
+	destRoot := newRootStack("prod", "other")
+	dest := NewSnapshot([]*resource.State{destRoot})
+
+	oldA := a.URN
+
+	// Moving a pulls in its parent component, and copies the provider, which b still uses.
+	result, err := MoveResources(source, dest, deploy.NewUrnTargetsFromUrns([]resource.URN{oldA}), "prod", "other")
+	require.NoError(t, err)
+	require.NoError(t, source.VerifyIntegrity())
+	require.NoError(t, dest.VerifyIntegrity())
+
+	assert.Equal(t, []*resource.State{root, pA, b}, source.Resources)
+	assert.Empty(t, b.Dependencies)
+	assert.Empty(t, b.PropertyDependencies["x"])
+	assert.Equal(t, []BrokenDependency{
+		{Dependent: b.URN, Dependency: oldA},
+		{Dependent: b.URN, Dependency: oldA, Property: "x"},
+	}, result.BrokenDependencies)
+
+	require.Len(t, dest.Resources, 4)
+	require.Len(t, result.CopiedProviders, 1)
+	assert.Equal(t, []*resource.State{comp, a}, result.Moved)
+	newProvider := dest.Resources[1]
+	assert.Equal(t, result.CopiedProviders[0], newProvider)
+	assert.Equal(t, resource.URN("urn:pulumi:prod::other::pulumi:providers:a::p1"), newProvider.URN)
+	assert.Equal(t, resource.URN("urn:pulumi:test::test::pulumi:providers:a::p1"), pA.URN)
+
+	assert.Equal(t, resource.URN("urn:pulumi:prod::other::a:b:c::comp"), comp.URN)
+	assert.Equal(t, destRoot.URN, comp.Parent)
+	assert.Equal(t, resource.URN("urn:pulumi:prod::other::a:b:c::a"), a.URN)
+	assert.Equal(t, comp.URN, a.Parent)
+	ref, err := providers.ParseReference(a.Provider)
+	require.NoError(t, err)
+	assert.Equal(t, newProvider.URN, ref.URN())
+}
+
+func TestMoveResourcesErrors(t *testing.T) {
+	t.Parallel()
+
+	root := newRootStack("test", "test")
+	a := NewResource("a", nil)
+	source := NewSnapshot([]*resource.State{root, a})
+
+	existing := NewResource("a", nil)
+	existing.URN = "urn:pulumi:prod::test::a:b:c::a"
+	dest := NewSnapshot([]*resource.State{existing})
+
+	_, err := MoveResources(source, dest, deploy.NewUrnTargets([]string{"**"}), "prod", "test")
+	assert.ErrorContains(t, err, "cannot move the root stack resource")
+
+	_, err = MoveResources(source, dest, deploy.NewUrnTargetsFromUrns([]resource.URN{a.URN}), "prod", "test")
+	assert.ErrorContains(t, err, "already exists in the destination stack")
+	assert.Equal(t, []*resource.State{root, a}, source.Resources)
+	assert.Equal(t, []*resource.State{existing}, dest.Resources)
+
+	_, err = MoveResources(source, dest, deploy.NewUrnTargets([]string{"urn:pulumi:test::test::a:b:c::missing"}),
+		"prod", "test")
+	assert.ErrorContains(t, err, "no resources match")
+}
@@ -135,6 +135,84 @@ func UnprotectResource(_ *deploy.Snapshot, res *resource.State) error {
 	return nil
 }
 
+// ProtectResource protects a resource.
+func ProtectResource(_ *deploy.Snapshot, res *resource.State) error {
+	res.Protect = true
+	return nil
+}
+
+// RetainResource sets the retainOnDelete option of a resource, so that it is removed from the state but not deleted
+// by its provider when it is deleted.
+func RetainResource(_ *deploy.Snapshot, res *resource.State) error {
+	res.RetainOnDelete = true
+	return nil
+}
+
+// UnretainResource clears the retainOnDelete option of a resource.
+func UnretainResource(_ *deploy.Snapshot, res *resource.State) error {
+	res.RetainOnDelete = false
+	return nil
+}
+
+// RenameResource changes the name of the given resource, which must be in the given snapshot, and updates the
+// references to it from the other resources in the snapshot. An error is returned if a resource with the new name
+// already exists.
+func RenameResource(snap *deploy.Snapshot, res *resource.State, newName tokens.QName) error {
+	contract.Requiref(snap != nil, "snap", "must not be nil")
+	contract.Requiref(res != nil, "res", "must not be nil")
+
+	oldURN := res.URN
+	newURN := oldURN.Rename(string(newName))
+	if len(LocateResource(snap, newURN)) > 0 {
+		return fmt.Errorf("cannot rename %s to %q: a resource with that name already exists", oldURN, newName)
+	}
+
+	// If the resource is a provider, the references to it from the resources that it manages must change too.
+	var oldRef, newRef string
+	if providers.IsProviderType(res.Type) && res.ID != "" {
+		old, err := providers.NewReference(oldURN, res.ID)
+		contract.AssertNoErrorf(err, "failed to create provider reference")
+		renamed, err := providers.NewReference(newURN, res.ID)
+		contract.AssertNoErrorf(err, "failed to create provider reference")
+		oldRef, newRef = old.String(), renamed.String()
+	}
+
+	rewrite := func(urn resource.URN) resource.URN {
+		if urn == oldURN {
+			return newURN
+		}
+		return urn
+	}
+	rewriteState := func(other *resource.State) {
+		other.Parent = rewrite(other.Parent)
+		other.DeletedWith = rewrite(other.DeletedWith)
+		for i, dep := range other.Dependencies {
+			other.Dependencies[i] = rewrite(dep)
+		}
+		for _, deps := range other.PropertyDependencies {
+			for i, dep := range deps {
+				deps[i] = rewrite(dep)
+			}
+		}
+		if oldRef != "" && other.Provider == oldRef {
+			other.Provider = newRef
+		}
+	}
+
+	res.URN = newURN
+	for _, other := range snap.Resources {
+		rewriteState(other)
+	}
+
+	// A pending operation on the resource must refer to it by its new URN, or it would no longer be resolved against
+	// the resource.
+	for _, op := range snap.PendingOperations {
+		op.Resource.URN = rewrite(op.Resource.URN)
+		rewriteState(op.Resource)
+	}
+	return nil
+}
+
 // LocateResource returns all resources in the given snapshot that have the given URN.
 func LocateResource(snap *deploy.Snapshot, urn resource.URN) []*resource.State {
 	// If there is no snapshot then return no resources
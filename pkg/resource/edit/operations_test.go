@@ -400,6 +400,58 @@ func TestUnprotectResource(t *testing.T) {
 	assert.False(t, a.Protect)
 }
 
+func TestProtectAndRetainResource(t *testing.T) {
+	t.Parallel()
+
+	pA := NewProviderResource("a", "p1", "0")
+	a := NewResource("a", pA)
+	snap := NewSnapshot([]*resource.State{pA, a})
+
+	assert.NoError(t, ProtectResource(snap, a))
+	assert.True(t, a.Protect)
+	assert.NoError(t, RetainResource(snap, a))
+	assert.True(t, a.RetainOnDelete)
+	assert.NoError(t, UnretainResource(snap, a))
+	assert.False(t, a.RetainOnDelete)
+	assert.True(t, a.Protect)
+}
+
+func TestRenameResource(t *testing.T) {
+	t.Parallel()
+
+	pA := NewProviderResource("a", "p1", "0")
+	a := NewResource("a", pA)
+	b := NewResource("b", pA, a.URN)
+	b.Parent = a.URN
+	b.DeletedWith = a.URN
+	b.PropertyDependencies = map[resource.PropertyKey][]resource.URN{"x": {a.URN}}
+	snap := NewSnapshot([]*resource.State{pA, a, b})
+	pendingA := NewResource("a", pA)
+	pendingB := NewResource("c", pA, a.URN)
+	pendingB.Parent = a.URN
+	snap.PendingOperations = []resource.Operation{
+		resource.NewOperation(pendingA, resource.OperationTypeUpdating),
+		resource.NewOperation(pendingB, resource.OperationTypeCreating),
+	}
+
+	require.NoError(t, RenameResource(snap, a, "renamed"))
+	assert.Equal(t, tokens.QName("renamed"), a.URN.Name())
+	assert.Equal(t, a.URN, pendingA.URN)
+	assert.Equal(t, a.URN, pendingB.Parent)
+	assert.Equal(t, []resource.URN{a.URN}, pendingB.Dependencies)
+	assert.Equal(t, a.URN, b.Parent)
+	assert.Equal(t, a.URN, b.DeletedWith)
+	assert.Equal(t, []resource.URN{a.URN}, b.Dependencies)
+	assert.Equal(t, []resource.URN{a.URN}, b.PropertyDependencies["x"])
+
+	// Renaming a provider updates the references to it.
+	require.NoError(t, RenameResource(snap, pA, "p2"))
+	assert.Equal(t, string(pA.URN)+"::0", a.Provider)
+	assert.NoError(t, snap.VerifyIntegrity())
+
+	assert.ErrorContains(t, RenameResource(snap, b, "renamed"), "already exists")
+}
+
 func TestLocateResourceNotFound(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,108 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edit
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
+)
+
+// PendingOutcome is the outcome of an interrupted operation, as chosen by the user resolving it.
+type PendingOutcome string
+
+const (
+	// PendingCreated records that the resource exists, with the state read from its provider.
+	PendingCreated PendingOutcome = "created"
+	// PendingDeleted records that the resource does not exist.
+	PendingDeleted PendingOutcome = "deleted"
+	// PendingDiscarded drops the pending operation without changing any resources.
+	PendingDiscarded PendingOutcome = "discard"
+)
+
+// LocatePendingOperation returns the index of the first pending operation on the resource with the given URN in the
+// given snapshot, or -1 if there is none.
+func LocatePendingOperation(snap *deploy.Snapshot, urn resource.URN) int {
+	for i, op := range snap.PendingOperations {
+		if op.Resource.URN == urn {
+			return i
+		}
+	}
+	return -1
+}
+
+// ResolvePendingOperation removes the first pending operation on the resource with the given URN from the snapshot
+// and records the given outcome of the operation:
+//
+//   - if the resource was created, its state is replaced by, or the snapshot is extended with, the given state;
+//   - if the resource was deleted, the resource with the operation's URN and the given ID, or the operation's ID if no
+//     ID is given, is deleted from the snapshot. If no ID is known, the URN must identify a single resource;
+//   - if the operation is discarded, no resources are changed.
+//
+// The state must be non-nil if and only if the outcome is PendingCreated.
+func ResolvePendingOperation(
+	snap *deploy.Snapshot, urn resource.URN, outcome PendingOutcome, id resource.ID, state *resource.State,
+) error {
+	contract.Requiref(snap != nil, "snap", "must not be nil")
+	contract.Requiref((outcome == PendingCreated) == (state != nil), "state", "must be given only for created")
+
+	i := LocatePendingOperation(snap, urn)
+	if i == -1 {
+		return fmt.Errorf("no pending operation exists for %s", urn)
+	}
+	op := snap.PendingOperations[i]
+
+	switch outcome {
+	case PendingCreated:
+		replaced := false
+		for j, res := range snap.Resources {
+			if res.URN == urn && !res.Delete {
+				snap.Resources[j], replaced = state, true
+				break
+			}
+		}
+		if !replaced {
+			snap.Resources = append(snap.Resources, state)
+		}
+	case PendingDeleted:
+		if id == "" {
+			id = op.Resource.ID
+		}
+		var deleted []*resource.State
+		for _, res := range snap.Resources {
+			if res.URN == urn && (id == "" || res.ID == id) {
+				deleted = append(deleted, res)
+			}
+		}
+		if len(deleted) > 1 {
+			return fmt.Errorf("%d resources with URN %s are in the snapshot; the ID of the deleted one must be given",
+				len(deleted), urn)
+		}
+		if len(deleted) == 1 {
+			if err := DeleteResource(snap, deleted[0], nil, false); err != nil {
+				return err
+			}
+		}
+	case PendingDiscarded:
+	default:
+		return fmt.Errorf("unknown outcome %q: expected one of %s, %s, or %s",
+			outcome, PendingCreated, PendingDeleted, PendingDiscarded)
+	}
+
+	snap.PendingOperations = append(snap.PendingOperations[:i:i], snap.PendingOperations[i+1:]...)
+	return nil
+}
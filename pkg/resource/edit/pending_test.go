@@ -0,0 +1,83 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+func TestResolvePendingOperation(t *testing.T) {
+	t.Parallel()
+
+	pA := NewProviderResource("a", "p1", "0")
+	a := NewResource("a", pA)
+	a.ID = "a-id"
+	b := NewResource("b", pA)
+	c := NewResource("c", pA)
+	c.ID = "c-id"
+	snap := NewSnapshot([]*resource.State{pA, a, c})
+	snap.PendingOperations = []resource.Operation{
+		resource.NewOperation(a, resource.OperationTypeUpdating),
+		resource.NewOperation(b, resource.OperationTypeCreating),
+		resource.NewOperation(c, resource.OperationTypeDeleting),
+	}
+
+	// The create of b succeeded.
+	created := *b
+	created.ID = "b-id"
+	require.NoError(t, ResolvePendingOperation(snap, b.URN, PendingCreated, "", &created))
+	assert.Equal(t, []*resource.State{pA, a, c, &created}, snap.Resources)
+	assert.Len(t, snap.PendingOperations, 2)
+
+	// The delete of c succeeded.
+	require.NoError(t, ResolvePendingOperation(snap, c.URN, PendingDeleted, "", nil))
+	assert.Equal(t, []*resource.State{pA, a, &created}, snap.Resources)
+
+	// The update of a is dropped.
+	require.NoError(t, ResolvePendingOperation(snap, a.URN, PendingDiscarded, "", nil))
+	assert.Equal(t, []*resource.State{pA, a, &created}, snap.Resources)
+	assert.Empty(t, snap.PendingOperations)
+	assert.NoError(t, snap.VerifyIntegrity())
+
+	assert.ErrorContains(t, ResolvePendingOperation(snap, a.URN, PendingDiscarded, "", nil), "no pending operation")
+}
+
+func TestResolvePendingDeleteWithID(t *testing.T) {
+	t.Parallel()
+
+	pA := NewProviderResource("a", "p1", "0")
+	old := NewResource("a", pA)
+	old.ID, old.Delete = "a-1", true
+	a := NewResource("a", pA)
+	a.ID = "a-2"
+	snap := NewSnapshot([]*resource.State{pA, old, a})
+
+	// The delete did not record an ID, so it is not known which of the resources was deleted.
+	pending := *old
+	pending.ID = ""
+	snap.PendingOperations = []resource.Operation{resource.NewOperation(&pending, resource.OperationTypeDeleting)}
+	assert.ErrorContains(t, ResolvePendingOperation(snap, a.URN, PendingDeleted, "", nil), "must be given")
+	assert.Equal(t, []*resource.State{pA, old, a}, snap.Resources)
+	assert.Len(t, snap.PendingOperations, 1)
+
+	require.NoError(t, ResolvePendingOperation(snap, a.URN, PendingDeleted, "a-1", nil))
+	assert.Equal(t, []*resource.State{pA, a}, snap.Resources)
+	assert.Empty(t, snap.PendingOperations)
+}
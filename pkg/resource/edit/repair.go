@@ -0,0 +1,236 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edit
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy/providers"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
+)
+
+// Repair is a proposed fix for an integrity violation in a snapshot. A repair that cannot be applied only reports a
+// violation that cannot be fixed automatically.
+type Repair struct {
+	// URN is the URN of the resource that the repair changes, or empty if the repair changes the whole snapshot.
+	URN resource.URN
+	// Description describes the violation and how the repair fixes it.
+	Description string
+
+	apply func()
+}
+
+// CanApply returns true if the repair fixes the violation that it reports.
+func (r *Repair) CanApply() bool {
+	return r.apply != nil
+}
+
+// Apply makes the repair to the snapshot for which it was proposed.
+func (r *Repair) Apply() {
+	contract.Requiref(r.CanApply(), "r", "must fix the violation that it reports")
+	r.apply()
+}
+
+// FindRepairs proposes fixes for the references in the given snapshot that refer to resources that do not exist:
+//
+//   - a resource whose parent does not exist is re-parented to the root stack resource;
+//   - a dependency on a resource that does not exist is dropped;
+//   - a resource whose provider does not exist is attached to its package's default provider.
+//
+// A resource whose provider does not exist is reported by a repair that cannot be applied if the snapshot has no
+// default provider for its package. Repairs are independent of each other, so any subset of them can be applied. The order of the resources in the
+// snapshot is not considered; see FindOrderRepair.
+func FindRepairs(snap *deploy.Snapshot) []*Repair {
+	contract.Requiref(snap != nil, "snap", "must not be nil")
+
+	urns := make(map[resource.URN]bool)
+	provs := make(map[providers.Reference]bool)
+	defaultProviders := make(map[string]*resource.State)
+	var root *resource.State
+	for _, res := range snap.Resources {
+		urns[res.URN] = true
+		if res.Type == resource.RootStackType && res.Parent == "" && root == nil {
+			root = res
+		}
+		if providers.IsProviderType(res.Type) {
+			if ref, err := providers.NewReference(res.URN, res.ID); err == nil {
+				provs[ref] = true
+				if providers.IsDefaultProvider(res.URN) && !res.Delete {
+					defaultProviders[string(providers.GetProviderPackage(res.Type))] = res
+				}
+			}
+		}
+	}
+
+	var repairs []*Repair
+	for _, res := range snap.Resources {
+		res := res
+
+		if res.Parent != "" && !urns[res.Parent] {
+			var parent resource.URN
+			target := "no parent"
+			if root != nil && root != res {
+				parent, target = root.URN, "the root stack resource"
+			}
+			repairs = append(repairs, &Repair{
+				URN:         res.URN,
+				Description: fmt.Sprintf("parent %s does not exist; re-parent to %s", res.Parent, target),
+				apply:       func() { res.Parent = parent },
+			})
+		}
+
+		for _, dep := range res.Dependencies {
+			if !urns[dep] {
+				dep := dep
+				repairs = append(repairs, &Repair{
+					URN:         res.URN,
+					Description: fmt.Sprintf("dependency %s does not exist; drop it", dep),
+					apply:       func() { res.Dependencies = removeURN(res.Dependencies, dep) },
+				})
+			}
+		}
+		keys := make([]resource.PropertyKey, 0, len(res.PropertyDependencies))
+		for key := range res.PropertyDependencies {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+		for _, key := range keys {
+			for _, dep := range res.PropertyDependencies[key] {
+				if !urns[dep] {
+					key, dep := key, dep
+					repairs = append(repairs, &Repair{
+						URN:         res.URN,
+						Description: fmt.Sprintf("dependency %s of property %q does not exist; drop it", dep, key),
+						apply:       func() { res.PropertyDependencies[key] = removeURN(res.PropertyDependencies[key], dep) },
+					})
+				}
+			}
+		}
+		if res.DeletedWith != "" && !urns[res.DeletedWith] {
+			repairs = append(repairs, &Repair{
+				URN:         res.URN,
+				Description: fmt.Sprintf("deletedWith resource %s does not exist; drop it", res.DeletedWith),
+				apply:       func() { res.DeletedWith = "" },
+			})
+		}
+
+		if res.Provider != "" {
+			ref, err := providers.ParseReference(res.Provider)
+			if err == nil && provs[ref] {
+				continue
+			}
+			pkg := string(res.Type.Package())
+			if def, ok := defaultProviders[pkg]; ok {
+				newRef, err := providers.NewReference(def.URN, def.ID)
+				contract.AssertNoErrorf(err, "failed to create a reference to a provider in the snapshot")
+				repairs = append(repairs, &Repair{
+					URN: res.URN,
+					Description: fmt.Sprintf("provider %s does not exist; attach to the default %s provider %s",
+						res.Provider, pkg, newRef),
+					apply: func() { res.Provider = newRef.String() },
+				})
+			} else {
+				repairs = append(repairs, &Repair{
+					URN: res.URN,
+					Description: fmt.Sprintf("provider %s does not exist and there is no default %s provider to attach to",
+						res.Provider, pkg),
+				})
+			}
+		}
+	}
+	return repairs
+}
+
+// FindOrderRepair proposes a fix for resources that come before their parents, dependencies, or providers in the
+// given snapshot. The repair sorts the resources so that each comes after the resources it refers to, otherwise
+// keeping their current order. It returns nil if the resources are already in order, and an error if they refer to
+// each other in a cycle.
+func FindOrderRepair(snap *deploy.Snapshot) (*Repair, error) {
+	contract.Requiref(snap != nil, "snap", "must not be nil")
+
+	byURN := make(map[resource.URN][]*resource.State)
+	for _, res := range snap.Resources {
+		byURN[res.URN] = append(byURN[res.URN], res)
+	}
+	referenced := func(res *resource.State) []resource.URN {
+		refs := append([]resource.URN{res.Parent}, res.Dependencies...)
+		if ref, err := providers.ParseReference(res.Provider); err == nil {
+			refs = append(refs, ref.URN())
+		}
+		return refs
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[*resource.State]int)
+	sorted := make([]*resource.State, 0, len(snap.Resources))
+	var visit func(res *resource.State) error
+	visit = func(res *resource.State) error {
+		switch state[res] {
+		case visiting:
+			return fmt.Errorf("resource %s refers to itself through its parents, dependencies, or provider", res.URN)
+		case visited:
+			return nil
+		}
+		state[res] = visiting
+		for _, urn := range referenced(res) {
+			for _, ref := range byURN[urn] {
+				if ref == res {
+					continue
+				}
+				if err := visit(ref); err != nil {
+					return err
+				}
+			}
+		}
+		state[res] = visited
+		sorted = append(sorted, res)
+		return nil
+	}
+	for _, res := range snap.Resources {
+		if err := visit(res); err != nil {
+			return nil, err
+		}
+	}
+
+	moved := 0
+	for i, res := range sorted {
+		if snap.Resources[i] != res {
+			moved++
+		}
+	}
+	if moved == 0 {
+		return nil, nil
+	}
+	return &Repair{
+		Description: fmt.Sprintf("%d resources come before the resources they refer to; sort them", moved),
+		apply:       func() { snap.Resources = sorted },
+	}, nil
+}
+
+func removeURN(urns []resource.URN, urn resource.URN) []resource.URN {
+	result := urns[:0]
+	for _, u := range urns {
+		if u != urn {
+			result = append(result, u)
+		}
+	}
+	return result
+}
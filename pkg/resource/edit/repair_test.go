@@ -0,0 +1,79 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+func TestRepairs(t *testing.T) {
+	t.Parallel()
+
+	root := newRootStack("test", "test")
+	pA := NewProviderResource("a", "default", "0")
+	missing := NewResource("missing", nil)
+	gone := NewProviderResource("a", "gone", "1")
+
+	// b refers to a missing parent, dependency and provider, and comes before c, which it depends on.
+	c := NewResource("c", pA)
+	b := NewResource("b", gone, missing.URN, c.URN)
+	b.Parent = missing.URN
+	snap := NewSnapshot([]*resource.State{root, pA, b, c})
+	require.Error(t, snap.VerifyIntegrity())
+
+	repairs := FindRepairs(snap)
+	require.Len(t, repairs, 3)
+	for _, r := range repairs {
+		assert.Equal(t, b.URN, r.URN)
+		r.Apply()
+	}
+	assert.Equal(t, root.URN, b.Parent)
+	assert.Equal(t, []resource.URN{c.URN}, b.Dependencies)
+	assert.Equal(t, c.Provider, b.Provider)
+	assert.Empty(t, FindRepairs(snap))
+
+	order, err := FindOrderRepair(snap)
+	require.NoError(t, err)
+	require.NotNil(t, order)
+	order.Apply()
+	assert.Equal(t, []*resource.State{root, pA, c, b}, snap.Resources)
+	assert.NoError(t, snap.VerifyIntegrity())
+
+	order, err = FindOrderRepair(snap)
+	assert.NoError(t, err)
+	assert.Nil(t, order)
+
+	// A resource whose provider does not exist cannot be repaired if there is no default provider for its package.
+	other := NewProviderResource("b", "gone", "2")
+	d := NewResource("d", other)
+	d.Type = "b:b:d"
+	snap.Resources = append(snap.Resources, d)
+	repairs = FindRepairs(snap)
+	require.Len(t, repairs, 1)
+	assert.Equal(t, d.URN, repairs[0].URN)
+	assert.False(t, repairs[0].CanApply())
+	assert.Contains(t, repairs[0].Description, "no default b provider")
+	snap.Resources = snap.Resources[:len(snap.Resources)-1]
+
+	// Resources that depend on each other cannot be sorted.
+	c.Dependencies = []resource.URN{b.URN}
+	_, err = FindOrderRepair(snap)
+	assert.ErrorContains(t, err, "refers to itself")
+}
@@ -0,0 +1,159 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edit
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+// A Selector selects the resources in a snapshot that match all of its criteria. The zero value selects every
+// resource.
+type Selector struct {
+	// URNs matches the URNs of the selected resources, with the URN and glob syntax of deploy.UrnTargets.
+	URNs deploy.UrnTargets
+	// Types matches the types of the selected resources, with the same glob syntax.
+	Types deploy.UrnTargets
+	// Where holds the predicates on properties that the selected resources must satisfy.
+	Where []Predicate
+}
+
+// Select returns the resources in the snapshot that the selector matches, in the order in which they appear.
+func (s Selector) Select(snap *deploy.Snapshot) []*resource.State {
+	if snap == nil {
+		return nil
+	}
+
+	var selected []*resource.State
+	for _, res := range snap.Resources {
+		if s.Matches(res) {
+			selected = append(selected, res)
+		}
+	}
+	return selected
+}
+
+// Matches returns true if the selector matches the given resource.
+func (s Selector) Matches(res *resource.State) bool {
+	if !s.URNs.Contains(res.URN) || !s.Types.Contains(resource.URN(res.Type)) {
+		return false
+	}
+	for _, p := range s.Where {
+		if !p.Matches(res) {
+			return false
+		}
+	}
+	return true
+}
+
+// A Predicate tests the value of a property of a resource. Properties are looked up in the resource's outputs, and
+// in its inputs if they are not among its outputs.
+//
+// Secret values are opaque unless the predicate is told to show secrets: a secret is present, but it is neither equal
+// nor unequal to any value.
+type Predicate struct {
+	// Path is the path of the property.
+	Path resource.PropertyPath
+	// Op is "=" or "!=" to compare the property with Value, or "" to test that the property is present.
+	Op string
+	// Value is the value to compare the property with. A '*' in the value matches any sequence of characters.
+	Value string
+	// ShowSecrets makes the predicate compare the plaintext values of secrets.
+	ShowSecrets bool
+}
+
+// ParsePredicate parses a predicate of the form `path`, `path=value` or `path!=value`, where path is a property path
+// such as `tags.env` or `ingress[0].port`.
+func ParsePredicate(s string) (Predicate, error) {
+	var p Predicate
+	path := s
+	if i := strings.Index(s, "="); i != -1 {
+		path, p.Op, p.Value = s[:i], "=", s[i+1:]
+		if strings.HasSuffix(path, "!") {
+			path, p.Op = path[:len(path)-1], "!="
+		}
+	}
+	if path == "" {
+		return Predicate{}, fmt.Errorf("invalid predicate %q: missing property path", s)
+	}
+	parsed, err := resource.ParsePropertyPath(path)
+	if err != nil {
+		return Predicate{}, fmt.Errorf("invalid predicate %q: %w", s, err)
+	}
+	p.Path = parsed
+	return p, nil
+}
+
+// Matches returns true if the given resource satisfies the predicate. A property that is not present is not equal
+// to any value.
+func (p Predicate) Matches(res *resource.State) bool {
+	v, ok := p.Path.Get(resource.NewObjectProperty(res.Outputs))
+	if !ok {
+		v, ok = p.Path.Get(resource.NewObjectProperty(res.Inputs))
+	}
+	if ok && p.Op != "" && !p.ShowSecrets && v.ContainsSecrets() {
+		// Nothing may be learned about an opaque secret.
+		return false
+	}
+	switch p.Op {
+	case "":
+		return ok
+	case "=":
+		return ok && p.matchesValue(v)
+	case "!=":
+		return !ok || !p.matchesValue(v)
+	default:
+		return false
+	}
+}
+
+func (p Predicate) matchesValue(v resource.PropertyValue) bool {
+	for v.IsSecret() {
+		v = v.SecretValue().Element
+	}
+
+	var s string
+	switch {
+	case v.IsString():
+		s = v.StringValue()
+	case v.IsBool():
+		s = strconv.FormatBool(v.BoolValue())
+	case v.IsNumber():
+		s = strconv.FormatFloat(v.NumberValue(), 'f', -1, 64)
+	case v.IsNull():
+		s = "null"
+	default:
+		bytes, err := json.Marshal(v.Mappable())
+		if err != nil {
+			return false
+		}
+		s = string(bytes)
+	}
+
+	if !strings.ContainsRune(p.Value, '*') {
+		return s == p.Value
+	}
+	parts := strings.Split(p.Value, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$").MatchString(s)
+}
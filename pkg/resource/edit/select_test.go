@@ -0,0 +1,84 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+func TestSelector(t *testing.T) {
+	t.Parallel()
+
+	pA := NewProviderResource("a", "p1", "0")
+	a := NewResource("a", pA)
+	a.Outputs = resource.NewPropertyMapFromMap(map[string]interface{}{
+		"tags": map[string]interface{}{"env": "dev", "owner": "ops-team"},
+		"size": 3,
+	})
+	b := NewResource("b", pA)
+	b.Inputs = resource.NewPropertyMapFromMap(map[string]interface{}{
+		"tags": map[string]interface{}{"env": "prod"},
+	})
+	leftover := NewResource("leftover-1", pA)
+	snap := NewSnapshot([]*resource.State{pA, a, b, leftover})
+
+	where := func(predicates ...string) []Predicate {
+		var ps []Predicate
+		for _, s := range predicates {
+			p, err := ParsePredicate(s)
+			require.NoError(t, err)
+			ps = append(ps, p)
+		}
+		return ps
+	}
+
+	assert.Equal(t, []*resource.State{pA, a, b, leftover}, Selector{}.Select(snap))
+	assert.Equal(t, []*resource.State{leftover}, Selector{
+		URNs: deploy.NewUrnTargets([]string{"**::leftover-*"}),
+	}.Select(snap))
+	assert.Equal(t, []*resource.State{a, b, leftover}, Selector{
+		Types: deploy.NewUrnTargets([]string{"a:*:c"}),
+	}.Select(snap))
+	assert.Equal(t, []*resource.State{a}, Selector{Where: where("tags.env=dev", "size=3")}.Select(snap))
+	assert.Equal(t, []*resource.State{b}, Selector{Where: where("tags.env=p*")}.Select(snap))
+	assert.Equal(t, []*resource.State{a, b}, Selector{Where: where("tags")}.Select(snap))
+	assert.Equal(t, []*resource.State{pA, b, leftover}, Selector{Where: where("tags.owner!=ops-*")}.Select(snap))
+
+	// Secrets are present but opaque unless the predicates show secrets.
+	b.Outputs = resource.PropertyMap{"password": resource.MakeSecret(resource.NewStringProperty("hunter2"))}
+	assert.Equal(t, []*resource.State{b}, Selector{Where: where("password")}.Select(snap))
+	assert.Empty(t, Selector{Where: where("password=hunter2")}.Select(snap))
+	assert.Equal(t, []*resource.State{pA, a, leftover}, Selector{Where: where("password!=hunter2")}.Select(snap))
+	showSecrets := func(ps []Predicate) []Predicate {
+		for i := range ps {
+			ps[i].ShowSecrets = true
+		}
+		return ps
+	}
+	assert.Equal(t, []*resource.State{b}, Selector{Where: showSecrets(where("password=hunter2"))}.Select(snap))
+	assert.Equal(t, []*resource.State{pA, a, leftover},
+		Selector{Where: showSecrets(where("password!=hunter2"))}.Select(snap))
+	assert.Equal(t, []*resource.State{pA, a, b, leftover},
+		Selector{Where: showSecrets(where("password!=x*"))}.Select(snap))
+
+	_, err := ParsePredicate("=x")
+	assert.ErrorContains(t, err, "missing property path")
+}
@@ -0,0 +1,210 @@
+// Copyright 2016-2023, Pulumi Corporation.  All rights reserved.
+
+package graph
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy/providers"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
+)
+
+// Relationship is a way in which one resource depends on another.
+type Relationship string
+
+const (
+	// ParentRelationship means that the resource is a child of the other resource.
+	ParentRelationship Relationship = "parent"
+	// DependencyRelationship means that the other resource is in the resource's dependencies.
+	DependencyRelationship Relationship = "dependency"
+	// PropertyDependencyRelationship means that the other resource is a dependency of one of the resource's
+	// properties.
+	PropertyDependencyRelationship Relationship = "property-dependency"
+	// DeletedWithRelationship means that the resource is deleted with the other resource.
+	DeletedWithRelationship Relationship = "deleted-with"
+	// ProviderRelationship means that the other resource is the resource's provider.
+	ProviderRelationship Relationship = "provider"
+)
+
+// RelationshipsTo returns the ways in which res depends on the resource with the given URN, or nil if it does not.
+// It returns an error if res has a malformed provider reference.
+func RelationshipsTo(res *resource.State, urn resource.URN) ([]Relationship, error) {
+	refs, err := references(res)
+	if err != nil {
+		return nil, err
+	}
+	return relationshipsTo(refs, urn), nil
+}
+
+// relationshipsTo returns the relationships implied by the given references to the given URN.
+func relationshipsTo(refs []reference, urn resource.URN) []Relationship {
+	var rels []Relationship
+	for _, ref := range refs {
+		if ref.urn == urn && (len(rels) == 0 || rels[len(rels)-1] != ref.rel) {
+			rels = append(rels, ref.rel)
+		}
+	}
+	return rels
+}
+
+// DependencyURNs returns the URNs of the resources that res depends on through any of the relationships listed above.
+// A URN is listed once for each way in which res depends on it. It returns an error if res has a malformed provider
+// reference.
+func DependencyURNs(res *resource.State) ([]resource.URN, error) {
+	refs, err := references(res)
+	if err != nil {
+		return nil, err
+	}
+	urns := make([]resource.URN, len(refs))
+	for i, ref := range refs {
+		urns[i] = ref.urn
+	}
+	return urns, nil
+}
+
+// An Edge records that a resource depends on another resource.
+type Edge struct {
+	Dependent     *resource.State // the resource that depends on the other.
+	Dependency    *resource.State // the resource that is depended upon.
+	Relationships []Relationship  // the ways in which Dependent depends on Dependency.
+}
+
+// TransitiveDependents returns the resources that depend on res, directly or indirectly, through any of the
+// relationships listed above. Each dependent is returned as the Dependent of one edge, whose Dependency is the first
+// resource that the dependent refers to that is either res or one of its other dependents, so that the edges form a
+// tree rooted at res. The edges are in the topological order of their dependents.
+//
+// A reference to a URN refers to the last resource with that URN before the referring resource, so resources that
+// share a URN, such as a resource that is pending deletion and its replacement, are told apart.
+//
+// This function is linear in the number of resources in the `DependencyGraph`. It returns an error if a resource
+// after res has a malformed provider reference.
+func (dg *DependencyGraph) TransitiveDependents(res *resource.State) ([]Edge, error) {
+	cursorIndex, ok := dg.index[res]
+	contract.Assertf(ok, "could not determine index for resource %s", res.URN)
+
+	// latest maps each URN to the last resource with that URN before the candidate.
+	latest := make(map[resource.URN]*resource.State)
+	for _, r := range dg.resources[:cursorIndex+1] {
+		latest[r.URN] = r
+	}
+
+	set := map[*resource.State]bool{res: true}
+	var edges []Edge
+	for i := cursorIndex + 1; i < len(dg.resources); i++ {
+		candidate := dg.resources[i]
+		refs, err := references(candidate)
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range refs {
+			if dependency := latest[ref.urn]; dependency != nil && set[dependency] {
+				edges = append(edges, Edge{
+					Dependent:     candidate,
+					Dependency:    dependency,
+					Relationships: relationshipsTo(refs, ref.urn),
+				})
+				set[candidate] = true
+				break
+			}
+		}
+		latest[candidate.URN] = candidate
+	}
+	return edges, nil
+}
+
+// TransitiveDependencies returns the resources that res depends on, directly or indirectly, through any of the
+// relationships listed above. Each dependency is returned as the Dependency of one edge, whose Dependent is the first
+// resource that refers to it that is either res or one of its other dependencies, so that the edges form a tree
+// rooted at res. The edges are in reverse topological order of their dependencies.
+//
+// As with TransitiveDependents, a reference to a URN refers to the last resource with that URN before the referring
+// resource.
+//
+// This function is linear in the number of resources in the `DependencyGraph`. It returns an error if res or one of
+// its dependencies has a malformed provider reference.
+func (dg *DependencyGraph) TransitiveDependencies(res *resource.State) ([]Edge, error) {
+	cursorIndex, ok := dg.index[res]
+	contract.Assertf(ok, "could not determine index for resource %s", res.URN)
+
+	// wanted maps each URN that is referred to by a resource in the set, and that has not yet been resolved to a
+	// resource, to the first resource that refers to it. Since the resources in the set all come after the candidate,
+	// the candidate is the resource that a reference to its URN refers to.
+	wanted := make(map[resource.URN]*resource.State)
+	// refs holds the references of each resource in the set.
+	refs := make(map[*resource.State][]reference)
+	add := func(r *resource.State) error {
+		rs, err := references(r)
+		if err != nil {
+			return err
+		}
+		refs[r] = rs
+		for _, ref := range rs {
+			if _, has := wanted[ref.urn]; !has {
+				wanted[ref.urn] = r
+			}
+		}
+		return nil
+	}
+	if err := add(res); err != nil {
+		return nil, err
+	}
+
+	var edges []Edge
+	for i := cursorIndex - 1; i >= 0; i-- {
+		candidate := dg.resources[i]
+		if dependent, has := wanted[candidate.URN]; has {
+			edges = append(edges, Edge{
+				Dependent:     dependent,
+				Dependency:    candidate,
+				Relationships: relationshipsTo(refs[dependent], candidate.URN),
+			})
+			delete(wanted, candidate.URN)
+			if err := add(candidate); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return edges, nil
+}
+
+// A reference is a URN that a resource refers to, along with the relationship that the reference implies.
+type reference struct {
+	urn resource.URN
+	rel Relationship
+}
+
+// references returns the URNs that the given resource refers to, in a fixed order of relationships. It returns an
+// error if the resource has a malformed provider reference.
+func references(res *resource.State) ([]reference, error) {
+	var refs []reference
+	if res.Parent != "" {
+		refs = append(refs, reference{res.Parent, ParentRelationship})
+	}
+	for _, dep := range res.Dependencies {
+		refs = append(refs, reference{dep, DependencyRelationship})
+	}
+	keys := make([]string, 0, len(res.PropertyDependencies))
+	for k := range res.PropertyDependencies {
+		keys = append(keys, string(k))
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, dep := range res.PropertyDependencies[resource.PropertyKey(k)] {
+			refs = append(refs, reference{dep, PropertyDependencyRelationship})
+		}
+	}
+	if res.DeletedWith != "" {
+		refs = append(refs, reference{res.DeletedWith, DeletedWithRelationship})
+	}
+	if res.Provider != "" {
+		ref, err := providers.ParseReference(res.Provider)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse provider reference %q of %v: %w", res.Provider, res.URN, err)
+		}
+		refs = append(refs, reference{ref.URN(), ProviderRelationship})
+	}
+	return refs, nil
+}
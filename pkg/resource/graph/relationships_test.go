@@ -0,0 +1,117 @@
+// Copyright 2016-2023, Pulumi Corporation.  All rights reserved.
+
+package graph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+func TestTransitiveDependentsAndDependencies(t *testing.T) {
+	t.Parallel()
+
+	pA := NewProviderResource("test", "pA", "0")
+	a := NewResource("a", pA)
+	b := NewResource("b", pA)
+	b.PropertyDependencies = map[resource.PropertyKey][]resource.URN{"x": {a.URN}, "y": {a.URN}}
+	c := NewResource("c", nil)
+	c.Parent = b.URN
+	d := NewResource("d", nil)
+	d.DeletedWith = c.URN
+	e := NewResource("e", nil, a.URN)
+	e.PropertyDependencies = map[resource.PropertyKey][]resource.URN{"z": {a.URN}}
+	unrelated := NewResource("unrelated", nil)
+
+	dg := NewDependencyGraph([]*resource.State{pA, a, b, c, d, e, unrelated})
+
+	edges, err := dg.TransitiveDependents(a)
+	require.NoError(t, err)
+	assert.Equal(t, []Edge{
+		{Dependent: b, Dependency: a, Relationships: []Relationship{PropertyDependencyRelationship}},
+		{Dependent: c, Dependency: b, Relationships: []Relationship{ParentRelationship}},
+		{Dependent: d, Dependency: c, Relationships: []Relationship{DeletedWithRelationship}},
+		{Dependent: e, Dependency: a, Relationships: []Relationship{
+			DependencyRelationship, PropertyDependencyRelationship,
+		}},
+	}, edges)
+
+	edges, err = dg.TransitiveDependencies(d)
+	require.NoError(t, err)
+	assert.Equal(t, []Edge{
+		{Dependent: d, Dependency: c, Relationships: []Relationship{DeletedWithRelationship}},
+		{Dependent: c, Dependency: b, Relationships: []Relationship{ParentRelationship}},
+		{Dependent: b, Dependency: a, Relationships: []Relationship{PropertyDependencyRelationship}},
+		{Dependent: b, Dependency: pA, Relationships: []Relationship{ProviderRelationship}},
+	}, edges)
+
+	urns, err := DependencyURNs(b)
+	require.NoError(t, err)
+	assert.Equal(t, []resource.URN{a.URN, a.URN, pA.URN}, urns)
+	urns, err = DependencyURNs(d)
+	require.NoError(t, err)
+	assert.Equal(t, []resource.URN{c.URN}, urns)
+
+	edges, err = dg.TransitiveDependents(unrelated)
+	require.NoError(t, err)
+	assert.Empty(t, edges)
+	edges, err = dg.TransitiveDependencies(unrelated)
+	require.NoError(t, err)
+	assert.Empty(t, edges)
+}
+
+func TestTransitiveDependentsAndDependenciesWithSharedURNs(t *testing.T) {
+	t.Parallel()
+
+	// a is being replaced: oldA is pending deletion and b still refers to it, while c refers to its replacement.
+	oldA := NewResource("a", nil)
+	oldA.Delete = true
+	b := NewResource("b", nil, oldA.URN)
+	a := NewResource("a", nil)
+	c := NewResource("c", nil, a.URN)
+
+	dg := NewDependencyGraph([]*resource.State{oldA, b, a, c})
+
+	edges, err := dg.TransitiveDependents(oldA)
+	require.NoError(t, err)
+	assert.Equal(t, []Edge{
+		{Dependent: b, Dependency: oldA, Relationships: []Relationship{DependencyRelationship}},
+	}, edges)
+	edges, err = dg.TransitiveDependents(a)
+	require.NoError(t, err)
+	assert.Equal(t, []Edge{
+		{Dependent: c, Dependency: a, Relationships: []Relationship{DependencyRelationship}},
+	}, edges)
+
+	edges, err = dg.TransitiveDependencies(b)
+	require.NoError(t, err)
+	assert.Equal(t, []Edge{
+		{Dependent: b, Dependency: oldA, Relationships: []Relationship{DependencyRelationship}},
+	}, edges)
+	edges, err = dg.TransitiveDependencies(c)
+	require.NoError(t, err)
+	assert.Equal(t, []Edge{
+		{Dependent: c, Dependency: a, Relationships: []Relationship{DependencyRelationship}},
+	}, edges)
+}
+
+func TestRelationshipsWithMalformedProviderReference(t *testing.T) {
+	t.Parallel()
+
+	a := NewResource("a", nil)
+	b := NewResource("b", nil, a.URN)
+	b.Provider = "not-a-reference"
+	dg := NewDependencyGraph([]*resource.State{a, b})
+
+	_, err := DependencyURNs(b)
+	assert.ErrorContains(t, err, `cannot parse provider reference "not-a-reference"`)
+	_, err = RelationshipsTo(b, a.URN)
+	assert.Error(t, err)
+	_, err = dg.TransitiveDependents(a)
+	assert.Error(t, err)
+	_, err = dg.TransitiveDependencies(b)
+	assert.Error(t, err)
+}
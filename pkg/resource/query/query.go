@@ -0,0 +1,605 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package query implements a small expression language for selecting resources from a stack's state.
+//
+// An expression is made of comparisons joined by `and`, `or` and `not`, with parentheses for grouping:
+//
+//	type == 'aws:s3/bucket:Bucket' and not outputs.serverSideEncryptionConfiguration
+//	protect or outputs.tags.env =~ '^prod'
+//	inputs["instance type"] >= 't3' and dependencies contains 'urn:pulumi:prod::app::aws:ec2/vpc:Vpc::main'
+//
+// The operands of a comparison are literals (single- or double-quoted strings, numbers, `true`, `false` and
+// `null`) and fields of a resource: `urn`, `type`, `name`, `id`, `parent`, `provider`, `protect`, `custom`,
+// `delete`, `retainOnDelete`, `deletedWith` and `dependencies`, and `inputs` and `outputs` followed by a property
+// path such as `.tags.env` or `["key with spaces"][0]`. A property that does not exist is `null`.
+//
+// The comparison operators are `==`, `!=`, `<`, `<=`, `>`, `>=`, `=~`, which matches a string against a regular
+// expression, and `contains`, which tests whether a string contains a substring, an array contains an element, or an
+// object contains a key. An operand on its own is true unless it is `null`, `false`, or missing.
+//
+// Secret values are opaque unless a query is told to show secrets: a secret on its own is true, but no comparison
+// with it is.
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+// A Query is a parsed query expression.
+type Query struct {
+	// ShowSecrets makes the query compare the plaintext values of secrets.
+	ShowSecrets bool
+
+	source string
+	root   node
+}
+
+// Parse parses a query expression.
+func Parse(expr string) (*Query, error) {
+	p, err := newParser(expr)
+	if err != nil {
+		return nil, err
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.errorf(tok, "unexpected %q", tok.text)
+	}
+	return &Query{source: expr, root: root}, nil
+}
+
+// String returns the source of the query.
+func (q *Query) String() string {
+	return q.source
+}
+
+// Matches returns true if the given resource satisfies the query.
+func (q *Query) Matches(res *resource.State) bool {
+	return truthy(q.root.eval(res, q.ShowSecrets), q.ShowSecrets)
+}
+
+// Select returns the resources that satisfy the query, in the order in which they are given.
+func (q *Query) Select(resources []*resource.State) []*resource.State {
+	var selected []*resource.State
+	for _, res := range resources {
+		if q.Matches(res) {
+			selected = append(selected, res)
+		}
+	}
+	return selected
+}
+
+// A Field is a field of a resource, such as `type` or `outputs.arn`, that can be read from a resource.
+type Field struct {
+	source string
+	field  *fieldNode
+}
+
+// ParseField parses a field in the syntax of query expressions.
+func ParseField(s string) (*Field, error) {
+	p, err := newParser(s)
+	if err != nil {
+		return nil, err
+	}
+	tok := p.next()
+	if tok.kind != tokenPath {
+		return nil, p.errorf(tok, "expected a field, found %q", tok.text)
+	}
+	f, err := p.parseField(tok)
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.errorf(tok, "unexpected %q", tok.text)
+	}
+	return &Field{source: s, field: f}, nil
+}
+
+// String returns the source of the field.
+func (f *Field) String() string {
+	return f.source
+}
+
+// Value returns the value of the field of the given resource, or a null value if it does not exist.
+func (f *Field) Value(res *resource.State) resource.PropertyValue {
+	return f.field.eval(res, true)
+}
+
+type node interface {
+	// eval evaluates the node for the given resource. Secrets are opaque to comparisons unless showSecrets is set.
+	eval(res *resource.State, showSecrets bool) resource.PropertyValue
+}
+
+type literalNode struct {
+	value resource.PropertyValue
+}
+
+func (n *literalNode) eval(*resource.State, bool) resource.PropertyValue {
+	return n.value
+}
+
+// fields are the fields of a resource that can be used in queries, other than inputs and outputs.
+var fields = map[string]func(res *resource.State) resource.PropertyValue{
+	"urn":  func(res *resource.State) resource.PropertyValue { return stringValue(string(res.URN)) },
+	"type": func(res *resource.State) resource.PropertyValue { return stringValue(string(res.Type)) },
+	"name": func(res *resource.State) resource.PropertyValue { return stringValue(string(res.URN.Name())) },
+	"id":   func(res *resource.State) resource.PropertyValue { return stringValue(string(res.ID)) },
+	"parent": func(res *resource.State) resource.PropertyValue {
+		return stringValue(string(res.Parent))
+	},
+	"provider": func(res *resource.State) resource.PropertyValue { return stringValue(res.Provider) },
+	"protect":  func(res *resource.State) resource.PropertyValue { return resource.NewBoolProperty(res.Protect) },
+	"custom":   func(res *resource.State) resource.PropertyValue { return resource.NewBoolProperty(res.Custom) },
+	"delete":   func(res *resource.State) resource.PropertyValue { return resource.NewBoolProperty(res.Delete) },
+	"retainOnDelete": func(res *resource.State) resource.PropertyValue {
+		return resource.NewBoolProperty(res.RetainOnDelete)
+	},
+	"deletedWith": func(res *resource.State) resource.PropertyValue {
+		return stringValue(string(res.DeletedWith))
+	},
+	"dependencies": func(res *resource.State) resource.PropertyValue {
+		deps := make([]resource.PropertyValue, len(res.Dependencies))
+		for i, dep := range res.Dependencies {
+			deps[i] = resource.NewStringProperty(string(dep))
+		}
+		return resource.NewArrayProperty(deps)
+	},
+}
+
+// stringValue returns a string value, or null if the string is empty.
+func stringValue(s string) resource.PropertyValue {
+	if s == "" {
+		return resource.NewNullProperty()
+	}
+	return resource.NewStringProperty(s)
+}
+
+type fieldNode struct {
+	name string
+	// path is the path of the property within the inputs or outputs, for those fields.
+	path resource.PropertyPath
+}
+
+func (n *fieldNode) eval(res *resource.State, _ bool) resource.PropertyValue {
+	var props resource.PropertyMap
+	switch n.name {
+	case "inputs":
+		props = res.Inputs
+	case "outputs":
+		props = res.Outputs
+	default:
+		return fields[n.name](res)
+	}
+	v, ok := n.path.Get(resource.NewObjectProperty(props))
+	if !ok {
+		return resource.NewNullProperty()
+	}
+	return v
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n *notNode) eval(res *resource.State, showSecrets bool) resource.PropertyValue {
+	return resource.NewBoolProperty(!truthy(n.operand.eval(res, showSecrets), showSecrets))
+}
+
+type logicalNode struct {
+	and         bool
+	left, right node
+}
+
+func (n *logicalNode) eval(res *resource.State, showSecrets bool) resource.PropertyValue {
+	left := truthy(n.left.eval(res, showSecrets), showSecrets)
+	if n.and != left {
+		// The result is decided by the left operand: false for `and`, true for `or`.
+		return resource.NewBoolProperty(left)
+	}
+	return resource.NewBoolProperty(truthy(n.right.eval(res, showSecrets), showSecrets))
+}
+
+type comparisonNode struct {
+	op          string
+	left, right node
+	// re is the compiled regular expression of a `=~` comparison.
+	re *regexp.Regexp
+}
+
+func (n *comparisonNode) eval(res *resource.State, showSecrets bool) resource.PropertyValue {
+	left, right := unwrap(n.left.eval(res, showSecrets), showSecrets), unwrap(n.right.eval(res, showSecrets), showSecrets)
+	if left.IsSecret() || right.IsSecret() {
+		// unwrap only leaves secrets in place when they are opaque, and nothing may be learned about them.
+		return resource.NewBoolProperty(false)
+	}
+
+	var result bool
+	switch n.op {
+	case "==":
+		result = equal(left, right, showSecrets)
+	case "!=":
+		result = !equal(left, right, showSecrets)
+	case "<", "<=", ">", ">=":
+		if c, ok := compare(left, right); ok {
+			switch n.op {
+			case "<":
+				result = c < 0
+			case "<=":
+				result = c <= 0
+			case ">":
+				result = c > 0
+			case ">=":
+				result = c >= 0
+			}
+		}
+	case "=~":
+		result = left.IsString() && n.re.MatchString(left.StringValue())
+	case "contains":
+		result = contains(left, right, showSecrets)
+	}
+	return resource.NewBoolProperty(result)
+}
+
+// unwrap returns the value of a known output, and the plaintext value of a secret if showSecrets is set.
+func unwrap(v resource.PropertyValue, showSecrets bool) resource.PropertyValue {
+	for {
+		switch {
+		case v.IsSecret() && showSecrets:
+			v = v.SecretValue().Element
+		case v.IsOutput() && v.OutputValue().Known:
+			v = v.OutputValue().Element
+		default:
+			return v
+		}
+	}
+}
+
+func truthy(v resource.PropertyValue, showSecrets bool) bool {
+	v = unwrap(v, showSecrets)
+	return !v.IsNull() && !(v.IsBool() && !v.BoolValue())
+}
+
+// equal tests two values for equality. Values that contain opaque secrets are never equal to anything.
+func equal(a, b resource.PropertyValue, showSecrets bool) bool {
+	if !showSecrets && (a.ContainsSecrets() || b.ContainsSecrets()) {
+		return false
+	}
+	if a.IsNumber() && b.IsNumber() {
+		return a.NumberValue() == b.NumberValue()
+	}
+	return a.DeepEquals(b)
+}
+
+// compare orders two numbers or two strings, and returns false if the values are not of those types.
+func compare(a, b resource.PropertyValue) (int, bool) {
+	switch {
+	case a.IsNumber() && b.IsNumber():
+		x, y := a.NumberValue(), b.NumberValue()
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	case a.IsString() && b.IsString():
+		return strings.Compare(a.StringValue(), b.StringValue()), true
+	}
+	return 0, false
+}
+
+func contains(container, element resource.PropertyValue, showSecrets bool) bool {
+	switch {
+	case container.IsString() && element.IsString():
+		return strings.Contains(container.StringValue(), element.StringValue())
+	case container.IsArray():
+		for _, e := range container.ArrayValue() {
+			if equal(unwrap(e, showSecrets), element, showSecrets) {
+				return true
+			}
+		}
+	case container.IsObject() && element.IsString():
+		_, has := container.ObjectValue()[resource.PropertyKey(element.StringValue())]
+		return has
+	}
+	return false
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPath
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// value is the unquoted value of a string token.
+	value string
+	pos   int
+}
+
+type parser struct {
+	source string
+	tokens []token
+}
+
+func newParser(source string) (*parser, error) {
+	p := &parser{source: source}
+	for i := 0; ; {
+		for i < len(source) && unicode.IsSpace(rune(source[i])) {
+			i++
+		}
+		if i == len(source) {
+			p.tokens = append(p.tokens, token{kind: tokenEOF, text: "end of expression", pos: i})
+			return p, nil
+		}
+
+		start, c := i, source[i]
+		switch {
+		case c == '(':
+			p.tokens, i = append(p.tokens, token{kind: tokenLParen, text: "(", pos: i}), i+1
+		case c == ')':
+			p.tokens, i = append(p.tokens, token{kind: tokenRParen, text: ")", pos: i}), i+1
+		case c == '\'' || c == '"':
+			value, end, err := scanString(source, i)
+			if err != nil {
+				return nil, err
+			}
+			p.tokens = append(p.tokens, token{kind: tokenString, text: source[i:end], value: value, pos: i})
+			i = end
+		case c >= '0' && c <= '9' || c == '-' || c == '.':
+			for i++; i < len(source) && strings.ContainsRune("0123456789.eE+-", rune(source[i])); i++ {
+			}
+			p.tokens = append(p.tokens, token{kind: tokenNumber, text: source[start:i], pos: start})
+		case strings.ContainsRune("=!<>", rune(c)):
+			op := source[i : i+1]
+			if i+1 < len(source) && strings.ContainsRune("=~", rune(source[i+1])) {
+				op = source[i : i+2]
+			}
+			switch op {
+			case "==", "!=", "<", "<=", ">", ">=", "=~":
+			default:
+				return nil, fmt.Errorf("invalid operator %q at position %d", op, i+1)
+			}
+			p.tokens, i = append(p.tokens, token{kind: tokenOperator, text: op, pos: i}), i+len(op)
+		case c == '_' || c == '$' || c == '[' || unicode.IsLetter(rune(c)):
+			end, err := scanPath(source, i)
+			if err != nil {
+				return nil, err
+			}
+			p.tokens, i = append(p.tokens, token{kind: tokenPath, text: source[i:end], pos: i}), end
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", c, i+1)
+		}
+	}
+}
+
+// scanString scans the quoted string that starts at the given position, and returns its value and the position
+// after its closing quote. A backslash escapes the character that follows it.
+func scanString(source string, start int) (string, int, error) {
+	quote := source[start]
+	var value strings.Builder
+	for i := start + 1; i < len(source); i++ {
+		switch source[i] {
+		case '\\':
+			if i+1 < len(source) {
+				i++
+				value.WriteByte(source[i])
+			}
+		case quote:
+			return value.String(), i + 1, nil
+		default:
+			value.WriteByte(source[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string at position %d", start+1)
+}
+
+// scanPath scans the field or property path that starts at the given position, and returns the position after it.
+// Brackets in the path may contain double-quoted strings.
+func scanPath(source string, i int) (int, error) {
+	for i < len(source) {
+		c := source[i]
+		switch {
+		case c == '[':
+			start := i
+			for i++; i < len(source) && source[i] != ']'; i++ {
+				if source[i] == '"' {
+					_, end, err := scanString(source, i)
+					if err != nil {
+						return 0, err
+					}
+					i = end - 1
+				}
+			}
+			if i == len(source) {
+				return 0, fmt.Errorf("missing closing bracket for the bracket at position %d", start+1)
+			}
+			i++
+		case c == '_' || c == '$' || c == '.' || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)):
+			i++
+		default:
+			return i, nil
+		}
+	}
+	return i, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[0]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[0]
+	if tok.kind != tokenEOF {
+		p.tokens = p.tokens[1:]
+	}
+	return tok
+}
+
+// keyword returns true if the next token is the given keyword.
+func (p *parser) keyword(word string) bool {
+	tok := p.peek()
+	return tok.kind == tokenPath && tok.text == word
+}
+
+func (p *parser) errorf(tok token, format string, args ...interface{}) error {
+	return fmt.Errorf("%s at position %d", fmt.Sprintf(format, args...), tok.pos+1)
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{and: false, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.keyword("not") {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	if p.peek().kind == tokenLParen {
+		p.next()
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if tok := p.next(); tok.kind != tokenRParen {
+			return nil, p.errorf(tok, "expected \")\", found %q", tok.text)
+		}
+		return n, nil
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	op := p.peek()
+	if op.kind != tokenOperator && !p.keyword("contains") {
+		return left, nil
+	}
+	p.next()
+
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	n := &comparisonNode{op: op.text, left: left, right: right}
+	if op.text == "=~" {
+		lit, ok := right.(*literalNode)
+		if !ok || !lit.value.IsString() {
+			return nil, p.errorf(op, "the right side of =~ must be a string")
+		}
+		if n.re, err = regexp.Compile(lit.value.StringValue()); err != nil {
+			return nil, p.errorf(op, "invalid regular expression: %v", err)
+		}
+	}
+	return n, nil
+}
+
+func (p *parser) parseOperand() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenString:
+		return &literalNode{resource.NewStringProperty(tok.value)}, nil
+	case tokenNumber:
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, p.errorf(tok, "invalid number %q", tok.text)
+		}
+		return &literalNode{resource.NewNumberProperty(f)}, nil
+	case tokenPath:
+		switch tok.text {
+		case "true", "false":
+			return &literalNode{resource.NewBoolProperty(tok.text == "true")}, nil
+		case "null":
+			return &literalNode{resource.NewNullProperty()}, nil
+		case "and", "or", "not", "contains":
+			return nil, p.errorf(tok, "expected an operand, found %q", tok.text)
+		}
+		return p.parseField(tok)
+	default:
+		return nil, p.errorf(tok, "expected an operand, found %q", tok.text)
+	}
+}
+
+func (p *parser) parseField(tok token) (*fieldNode, error) {
+	name, rest := tok.text, ""
+	if i := strings.IndexAny(name, ".["); i != -1 {
+		name, rest = name[:i], name[i:]
+	}
+	switch name {
+	case "inputs", "outputs":
+		path, err := resource.ParsePropertyPath(rest)
+		if err != nil {
+			return nil, p.errorf(tok, "invalid property path %q: %v", tok.text, err)
+		}
+		return &fieldNode{name: name, path: path}, nil
+	default:
+		if _, ok := fields[name]; !ok || rest != "" {
+			return nil, p.errorf(tok, "unknown field %q", tok.text)
+		}
+		return &fieldNode{name: name}, nil
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+func TestQuery(t *testing.T) {
+	t.Parallel()
+
+	bucket := &resource.State{
+		URN:     "urn:pulumi:prod::app::aws:s3/bucket:Bucket::logs",
+		Type:    "aws:s3/bucket:Bucket",
+		ID:      "logs-1234",
+		Custom:  true,
+		Protect: true,
+		Inputs: resource.NewPropertyMapFromMap(map[string]interface{}{
+			"acl": "private",
+		}),
+		Outputs: resource.PropertyMap{
+			"tags": resource.NewObjectProperty(resource.NewPropertyMapFromMap(map[string]interface{}{
+				"env":         "production",
+				"cost center": 42,
+			})),
+			"arn":     resource.MakeSecret(resource.NewStringProperty("arn:aws:s3:::logs")),
+			"grants":  resource.NewArrayProperty([]resource.PropertyValue{resource.NewStringProperty("read")}),
+			"website": resource.NewNullProperty(),
+		},
+	}
+	vpc := &resource.State{
+		URN:          "urn:pulumi:prod::app::aws:ec2/vpc:Vpc::main",
+		Type:         "aws:ec2/vpc:Vpc",
+		Custom:       true,
+		Dependencies: []resource.URN{bucket.URN},
+	}
+
+	tests := []struct {
+		expr        string
+		showSecrets bool
+		want        []*resource.State
+	}{
+		{`type == 'aws:s3/bucket:Bucket'`, false, []*resource.State{bucket}},
+		{`type != "aws:s3/bucket:Bucket"`, false, []*resource.State{vpc}},
+		{`name == 'main' or protect`, false, []*resource.State{bucket, vpc}},
+		{`custom and not protect`, false, []*resource.State{vpc}},
+		{`not (protect or id)`, false, []*resource.State{vpc}},
+		{`id`, false, []*resource.State{bucket}},
+		{`outputs.tags.env =~ '^prod'`, false, []*resource.State{bucket}},
+		{`outputs.tags["cost center"] >= 40`, false, []*resource.State{bucket}},
+		{`outputs.tags["cost center"] < 40`, false, nil},
+		{`outputs.arn contains ':logs'`, true, []*resource.State{bucket}},
+		{`outputs.arn contains ':logs'`, false, nil},
+		{`outputs.arn == 'arn:aws:s3:::logs'`, false, nil},
+		{`outputs.arn != 'arn:aws:s3:::logs'`, false, []*resource.State{vpc}},
+		{`outputs.arn`, false, []*resource.State{bucket}},
+		{`outputs.grants contains 'read'`, false, []*resource.State{bucket}},
+		{`outputs.grants[0] == 'read'`, false, []*resource.State{bucket}},
+		{`outputs.tags contains 'env'`, false, []*resource.State{bucket}},
+		{`outputs.website`, false, nil},
+		{`outputs.website == null and inputs.acl == 'private'`, false, []*resource.State{bucket}},
+		{`inputs.missing == null`, false, []*resource.State{bucket, vpc}},
+		{`dependencies contains 'urn:pulumi:prod::app::aws:s3/bucket:Bucket::logs'`, false, []*resource.State{vpc}},
+		{`urn > 'urn:pulumi:prod::app::aws:s'`, false, []*resource.State{bucket}},
+		{`protect == true`, false, []*resource.State{bucket}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		name := tt.expr
+		if tt.showSecrets {
+			name += " (show secrets)"
+		}
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			q, err := Parse(tt.expr)
+			require.NoError(t, err)
+			q.ShowSecrets = tt.showSecrets
+			assert.Equal(t, tt.want, q.Select([]*resource.State{bucket, vpc}))
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{``, `expected an operand, found "end of expression" at position 1`},
+		{`type ==`, `expected an operand, found "end of expression" at position 8`},
+		{`type = 'a'`, `invalid operator "=" at position 6`},
+		{`colour == 'red'`, `unknown field "colour" at position 1`},
+		{`type.name`, `unknown field "type.name" at position 1`},
+		{`(protect`, `expected ")", found "end of expression" at position 9`},
+		{`protect custom`, `unexpected "custom" at position 9`},
+		{`type =~ name`, `the right side of =~ must be a string at position 6`},
+		{`type =~ '('`, "invalid regular expression: error parsing regexp: missing closing ): `(` at position 6"},
+		{`name == 'main`, `unterminated string at position 9`},
+		{`outputs.tags["env"`, `missing closing bracket for the bracket at position 13`},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.expr, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Parse(tt.expr)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestField(t *testing.T) {
+	t.Parallel()
+
+	res := &resource.State{
+		Type:    "pulumi:pulumi:Stack",
+		Outputs: resource.NewPropertyMapFromMap(map[string]interface{}{"url": "https://example.com"}),
+	}
+
+	f, err := ParseField("outputs.url")
+	require.NoError(t, err)
+	assert.Equal(t, resource.NewStringProperty("https://example.com"), f.Value(res))
+
+	f, err = ParseField("id")
+	require.NoError(t, err)
+	assert.True(t, f.Value(res).IsNull())
+
+	_, err = ParseField("type == 'a'")
+	assert.EqualError(t, err, `unexpected "==" at position 6`)
+}
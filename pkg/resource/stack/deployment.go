@@ -337,6 +337,10 @@ func SerializeResource(res *resource.State, enc config.Encrypter, showSecrets bo
 		v3Resource.CustomTimeouts = &res.CustomTimeouts
 	}
 
+	for _, hook := range res.Hooks {
+		v3Resource.Hooks = append(v3Resource.Hooks, apitype.ResourceHookV1{Name: hook.Name, Kind: string(hook.Kind)})
+	}
+
 	return v3Resource, nil
 }
 
@@ -513,11 +517,15 @@ func DeserializeResource(res apitype.ResourceV3, dec config.Decrypter, enc confi
 		return nil, fmt.Errorf("resource '%s' has 'custom' false but non-empty ID", res.URN)
 	}
 
-	return resource.NewState(
+	state := resource.NewState(
 		res.Type, res.URN, res.Custom, res.Delete, res.ID,
 		inputs, outputs, res.Parent, res.Protect, res.External, res.Dependencies, res.InitErrors, res.Provider,
 		res.PropertyDependencies, res.PendingReplacement, res.AdditionalSecretOutputs, res.Aliases, res.CustomTimeouts,
-		res.ImportID, res.RetainOnDelete, res.DeletedWith, res.Created, res.Modified), nil
+		res.ImportID, res.RetainOnDelete, res.DeletedWith, res.Created, res.Modified)
+	for _, hook := range res.Hooks {
+		state.Hooks = append(state.Hooks, resource.Hook{Name: hook.Name, Kind: resource.HookKind(hook.Kind)})
+	}
+	return state, nil
 }
 
 // DeserializeOperation hydrates a pending resource/operation pair.
@@ -23,24 +23,35 @@ import (
 	"os"
 	"reflect"
 	"sync"
+	"sync/atomic"
 
 	"google.golang.org/grpc"
 
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/proto"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 type DebugInterceptor struct {
-	logFile string
-	mutex   *sync.Mutex
+	logFile   string
+	mutex     *sync.Mutex
+	recording bool
 }
 
 type DebugInterceptorOptions struct {
 	LogFile string
 	Mutex   *sync.Mutex
+
+	// Recording is set if the log is a deployment recording rather than a debug log. Recordings also log the errors
+	// and gRPC statuses returned by unary calls, and number each client connection in its metadata so that the calls
+	// made on different connections to the same plugin can be told apart.
+	Recording bool
 }
 
+// connections numbers the client connections that are logged to recordings.
+var connections int64
+
 type LogOptions struct {
 	Metadata interface{}
 }
@@ -50,7 +61,7 @@ func NewDebugInterceptor(opts DebugInterceptorOptions) (*DebugInterceptor, error
 	if opts.LogFile == "" {
 		return nil, fmt.Errorf("logFile cannot be empty")
 	}
-	i := &DebugInterceptor{logFile: opts.LogFile}
+	i := &DebugInterceptor{logFile: opts.LogFile, recording: opts.Recording}
 
 	if opts.Mutex != nil {
 		i.mutex = opts.Mutex
@@ -69,6 +80,15 @@ func (i *DebugInterceptor) ServerOptions(opts LogOptions) []grpc.ServerOption {
 }
 
 func (i *DebugInterceptor) DialOptions(opts LogOptions) []grpc.DialOption {
+	if md, ok := opts.Metadata.(map[string]interface{}); ok && i.recording {
+		numbered := make(map[string]interface{}, len(md)+1)
+		for k, v := range md {
+			numbered[k] = v
+		}
+		numbered["conn"] = atomic.AddInt64(&connections, 1)
+		opts.Metadata = numbered
+	}
+
 	return []grpc.DialOption{
 		grpc.WithChainUnaryInterceptor(i.DebugClientInterceptor(opts)),
 		grpc.WithChainStreamInterceptor(i.DebugStreamClientInterceptor(opts)),
@@ -90,6 +110,10 @@ func (i *DebugInterceptor) DebugServerInterceptor(opts LogOptions) grpc.UnarySer
 		i.trackRequest(&log, req)
 		resp, err := handler(ctx, req)
 		i.trackResponse(&log, resp)
+		if err != nil && i.recording {
+			i.track(&log, err)
+			i.trackStatus(&log, err)
+		}
 		if e := i.record(log); e != nil {
 			return resp, e
 		}
@@ -128,6 +152,10 @@ func (i *DebugInterceptor) DebugClientInterceptor(opts LogOptions) grpc.UnaryCli
 		i.trackRequest(&log, req)
 		err := invoker(ctx, method, req, reply, cc, gopts...)
 		i.trackResponse(&log, reply)
+		if err != nil && i.recording {
+			i.track(&log, err)
+			i.trackStatus(&log, err)
+		}
 		if e := i.record(log); e != nil {
 			return e
 		}
@@ -173,6 +201,17 @@ func (*DebugInterceptor) track(log *debugInterceptorLogEntry, err error) {
 	log.Errors = append(log.Errors, err.Error())
 }
 
+// trackStatus logs the gRPC status of a failed call, including its code and details, so that the failure can be
+// replayed.
+func (i *DebugInterceptor) trackStatus(log *debugInterceptorLogEntry, err error) {
+	j, err := i.transcode(status.Convert(err).Proto())
+	if err != nil {
+		i.track(log, err)
+	} else {
+		log.Status = j
+	}
+}
+
 func (i *DebugInterceptor) trackRequest(log *debugInterceptorLogEntry, req interface{}) {
 	j, err := i.transcode(req)
 	if err != nil {
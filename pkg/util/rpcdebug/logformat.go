@@ -26,5 +26,6 @@ type debugInterceptorLogEntry struct {
 	Request  json.RawMessage `json:"request,omitempty"`
 	Response json.RawMessage `json:"response,omitempty"`
 	Errors   []string        `json:"errors,omitempty"`
+	Status   json.RawMessage `json:"status,omitempty"`
 	Metadata interface{}     `json:"metadata,omitempty"`
 }
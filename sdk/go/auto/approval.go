@@ -0,0 +1,69 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auto
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// approvalServer serves the approval callback that the CLI calls to ask for approval of deletes and replacements.
+type approvalServer struct {
+	server *http.Server
+	url    string
+}
+
+func startApprovalServer(ctx context.Context, approver optup.ApprovalFunc) (*approvalServer, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("starting approval server: %w", err)
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req apitype.StepApprovalRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(apitype.StepApprovalResponse{Approved: approver(ctx, req)})
+	})
+
+	s := &approvalServer{
+		server: &http.Server{Handler: handler, ReadHeaderTimeout: time.Minute},
+		url:    "http://" + l.Addr().String(),
+	}
+	go func() {
+		_ = s.server.Serve(l)
+	}()
+	return s, nil
+}
+
+func (s *approvalServer) Close() error {
+	return s.server.Close()
+}
@@ -0,0 +1,374 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auto
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/display"
+)
+
+// ChangeSet describes the changes that an operation made, or would make, to the resources of a stack.
+type ChangeSet struct {
+	// Resources lists the resources that were changed, in the order that the operation began to change them.
+	// Resources that were left as they were are not listed.
+	Resources []ResourceChange
+	// Summary counts the resources by the operation performed on them, including those left as they were.
+	Summary map[apitype.OpType]int
+	// Duration is how long the operation took.
+	Duration time.Duration
+}
+
+// ResourceChange describes the change that an operation made, or would make, to a single resource.
+type ResourceChange struct {
+	// URN is the URN of the resource.
+	URN string
+	// Type is the type of the resource.
+	Type string
+	// Op is the operation performed on the resource. For replacements this is display.StepOp("replace") rather
+	// than the create and delete steps that make up the replacement.
+	Op display.StepOp
+	// OldInputs are the resource's inputs before the change, if it existed. Secret values are "[secret]".
+	OldInputs map[string]interface{}
+	// NewInputs are the resource's inputs after the change, unless it was deleted. Secret values are "[secret]".
+	NewInputs map[string]interface{}
+	// DiffKeys are the top-level properties that changed.
+	DiffKeys []string
+	// ReplaceKeys are the properties whose changes caused the resource to be replaced.
+	ReplaceKeys []string
+	// DetailedDiff maps the paths of the changed properties to the kind of each change, if the provider reported
+	// a detailed diff.
+	DetailedDiff map[string]PropertyDiff
+	// Duration is how long the change took, as measured by the timestamps of the operation's events, which are
+	// whole seconds. It is zero for previews.
+	Duration time.Duration
+	// Error holds the errors reported for the resource if the change failed, or is empty.
+	Error string
+}
+
+// Failed returns the changes that failed.
+func (c ChangeSet) Failed() []ResourceChange {
+	var failed []ResourceChange
+	for _, r := range c.Resources {
+		if r.Error != "" {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// ChangeSetRecorder builds a ChangeSet from the engine events of an operation. It may be used by StackEngine
+// implementations to fill in the ChangeSet of their results. Its methods are safe for concurrent use.
+type ChangeSetRecorder struct {
+	m         sync.Mutex
+	planning  bool
+	resources map[string]*recordedChange
+	order     []string
+	errors    map[string][]string
+	summary   *apitype.SummaryEvent
+}
+
+type recordedChange struct {
+	change  ResourceChange
+	started time.Time
+	logical bool
+}
+
+// NewChangeSetRecorder creates a recorder for the events of an operation.
+func NewChangeSetRecorder() *ChangeSetRecorder {
+	return &ChangeSetRecorder{
+		resources: map[string]*recordedChange{},
+		errors:    map[string][]string{},
+	}
+}
+
+// Record adds an engine event to the change set. The changes are timed by the events' timestamps, so events may be
+// recorded after the fact, e.g. when they are read from an event log.
+func (r *ChangeSetRecorder) Record(e events.EngineEvent) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	emitted := time.Unix(int64(e.Timestamp), 0)
+	switch {
+	case e.ResourcePreEvent != nil:
+		r.planning = r.planning || e.ResourcePreEvent.Planning
+		r.recordStep(e.ResourcePreEvent.Metadata, emitted)
+	case e.ResOutputsEvent != nil:
+		r.planning = r.planning || e.ResOutputsEvent.Planning
+		r.finishStep(e.ResOutputsEvent.Metadata, emitted)
+	case e.ResOpFailedEvent != nil:
+		r.finishStep(e.ResOpFailedEvent.Metadata, emitted)
+		if c, has := r.resources[e.ResOpFailedEvent.Metadata.URN]; has && c.change.Error == "" {
+			c.change.Error = "the operation failed"
+		}
+	case e.DiagnosticEvent != nil:
+		if d := e.DiagnosticEvent; d.Severity == "error" && d.URN != "" {
+			r.errors[d.URN] = append(r.errors[d.URN], strings.TrimSpace(d.Message))
+		}
+	case e.SummaryEvent != nil:
+		r.summary = e.SummaryEvent
+	}
+}
+
+func (r *ChangeSetRecorder) recordStep(step apitype.StepEventMetadata, emitted time.Time) {
+	if step.Op == apitype.OpSame {
+		return
+	}
+
+	c, has := r.resources[step.URN]
+	if !has {
+		c = &recordedChange{started: emitted}
+		r.resources[step.URN] = c
+		r.order = append(r.order, step.URN)
+	}
+	// A replacement is made up of several steps, of which only the logical step describes the whole change.
+	if has && c.logical && !step.Logical {
+		return
+	}
+	c.logical = c.logical || step.Logical
+
+	change := &c.change
+	change.URN, change.Type, change.Op = step.URN, step.Type, display.StepOp(step.Op)
+	if step.Old != nil {
+		change.OldInputs = step.Old.Inputs
+	}
+	if step.New != nil {
+		change.NewInputs = step.New.Inputs
+	}
+	change.DiffKeys, change.ReplaceKeys = step.Diffs, step.Keys
+	change.DetailedDiff = nil
+	if len(step.DetailedDiff) > 0 {
+		change.DetailedDiff = make(map[string]PropertyDiff, len(step.DetailedDiff))
+		for k, d := range step.DetailedDiff {
+			change.DetailedDiff[k] = PropertyDiff{Kind: string(d.Kind), InputDiff: d.InputDiff}
+		}
+	}
+}
+
+func (r *ChangeSetRecorder) finishStep(step apitype.StepEventMetadata, emitted time.Time) {
+	if c, has := r.resources[step.URN]; has {
+		c.change.Duration = emitted.Sub(c.started)
+	}
+}
+
+// ChangeSet returns the changes recorded so far.
+func (r *ChangeSetRecorder) ChangeSet() ChangeSet {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	var cs ChangeSet
+	for _, urn := range r.order {
+		change := r.resources[urn].change
+		if errs := r.errors[urn]; len(errs) > 0 && change.Error != "" {
+			change.Error = strings.Join(errs, "\n")
+		}
+		if r.planning {
+			change.Duration = 0
+		}
+		cs.Resources = append(cs.Resources, change)
+	}
+	if r.summary != nil {
+		cs.Summary = r.summary.ResourceChanges
+		cs.Duration = time.Duration(r.summary.DurationSeconds) * time.Second
+	}
+	return cs
+}
+
+// MarkdownOptions controls how a ChangeSet is rendered as markdown.
+type MarkdownOptions struct {
+	// Title is the heading of the rendered change set. It defaults to "Resource changes".
+	Title string
+	// ShowInputs adds a collapsible section to each changed resource with the old and new values of its changed
+	// inputs.
+	ShowInputs bool
+}
+
+// Markdown renders the change set as GitHub-flavored markdown, for example to post as a comment on a pull request.
+// The changes are listed in a table, followed by a summary of the counts of each operation and by the errors of any
+// changes that failed.
+func (c ChangeSet) Markdown(opts MarkdownOptions) string {
+	title := opts.Title
+	if title == "" {
+		title = "Resource changes"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "### %s\n\n", title)
+
+	if len(c.Resources) == 0 {
+		b.WriteString("No changes.\n")
+	} else {
+		b.WriteString("| | Resource | Type | Operation | Changed properties |\n")
+		b.WriteString("|---|---|---|---|---|\n")
+		for _, r := range c.Resources {
+			fmt.Fprintf(&b, "| %s | `%s` | `%s` | %s | %s |\n",
+				markdownOpSymbol(r.Op), markdownCell(resourceName(r.URN)), markdownCell(r.Type), r.Op,
+				markdownCell(changedProperties(r)))
+		}
+	}
+
+	if summary := markdownSummary(c.Summary); summary != "" {
+		fmt.Fprintf(&b, "\n**%s**\n", summary)
+	}
+
+	if opts.ShowInputs {
+		for _, r := range c.Resources {
+			if details := markdownInputs(r); details != "" {
+				fmt.Fprintf(&b, "\n<details>\n<summary><code>%s</code></summary>\n\n%s</details>\n",
+					resourceName(r.URN), details)
+			}
+		}
+	}
+
+	if failed := c.Failed(); len(failed) > 0 {
+		b.WriteString("\n#### Errors\n")
+		for _, r := range failed {
+			fmt.Fprintf(&b, "\n`%s`:\n```\n%s\n```\n", resourceName(r.URN), r.Error)
+		}
+	}
+	return b.String()
+}
+
+// markdownOpSymbol returns the symbol that the CLI shows for the given operation.
+func markdownOpSymbol(op display.StepOp) string {
+	switch apitype.OpType(op) {
+	case apitype.OpCreate, apitype.OpImport:
+		return "+"
+	case apitype.OpUpdate:
+		return "~"
+	case apitype.OpDelete:
+		return "-"
+	case apitype.OpReplace, apitype.OpCreateReplacement, apitype.OpDeleteReplaced:
+		return "+-"
+	case apitype.OpRead, apitype.OpRefresh:
+		return ">"
+	default:
+		return ""
+	}
+}
+
+// resourceName returns the name of the resource with the given URN.
+func resourceName(urn string) string {
+	if i := strings.LastIndex(urn, "::"); i >= 0 {
+		return urn[i+2:]
+	}
+	return urn
+}
+
+func changedProperties(r ResourceChange) string {
+	keys := r.DiffKeys
+	if len(keys) == 0 && len(r.DetailedDiff) > 0 {
+		for k := range r.DetailedDiff {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+	}
+	replace := map[string]bool{}
+	for _, k := range r.ReplaceKeys {
+		replace[k] = true
+	}
+
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		names[i] = k
+		if replace[k] {
+			names[i] += " (replace)"
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+func markdownSummary(summary map[apitype.OpType]int) string {
+	ops := []struct {
+		op    apitype.OpType
+		label string
+	}{
+		{apitype.OpCreate, "to create"},
+		{apitype.OpUpdate, "to update"},
+		{apitype.OpReplace, "to replace"},
+		{apitype.OpDelete, "to delete"},
+		{apitype.OpImport, "to import"},
+		{apitype.OpSame, "unchanged"},
+	}
+	var parts []string
+	for _, o := range ops {
+		if n := summary[o.op]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, o.label))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// markdownInputs renders the old and new values of the resource's changed inputs.
+func markdownInputs(r ResourceChange) string {
+	keys := r.DiffKeys
+	if len(keys) == 0 {
+		switch {
+		case r.OldInputs == nil:
+			keys = sortedKeys(r.NewInputs)
+		case r.NewInputs == nil:
+			keys = sortedKeys(r.OldInputs)
+		}
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("| Property | Old | New |\n|---|---|---|\n")
+	for _, k := range keys {
+		fmt.Fprintf(&b, "| `%s` | %s | %s |\n", markdownCell(k),
+			markdownValue(r.OldInputs, k), markdownValue(r.NewInputs, k))
+	}
+	return b.String()
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		// Internal properties such as __defaults are not shown.
+		if !strings.HasPrefix(k, "__") {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func markdownValue(inputs map[string]interface{}, key string) string {
+	v, has := inputs[key]
+	if !has {
+		return ""
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return "`" + markdownCell(string(b)) + "`"
+}
+
+// markdownCell escapes the characters that would end a markdown table cell or code span.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	s = strings.ReplaceAll(s, "`", "'")
+	return strings.ReplaceAll(s, "\n", " ")
+}
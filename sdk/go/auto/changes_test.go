@@ -0,0 +1,142 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/display"
+)
+
+const (
+	testStackURN  = "urn:pulumi:dev::proj::pulumi:pulumi:Stack::proj-dev"
+	testBucketURN = "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::my|bucket"
+	testQueueURN  = "urn:pulumi:dev::proj::aws:sqs/queue:Queue::queue"
+)
+
+func stepEvent(op apitype.OpType, urn, typ string, logical bool, diffs ...string) events.EngineEvent {
+	return events.EngineEvent{EngineEvent: apitype.EngineEvent{
+		ResourcePreEvent: &apitype.ResourcePreEvent{Metadata: apitype.StepEventMetadata{
+			Op:      op,
+			URN:     urn,
+			Type:    typ,
+			Logical: logical,
+			Old:     &apitype.StepEventStateMetadata{Inputs: map[string]interface{}{"name": "old"}},
+			New:     &apitype.StepEventStateMetadata{Inputs: map[string]interface{}{"name": "new"}},
+			Diffs:   diffs,
+			Keys:    diffs,
+		}},
+	}}
+}
+
+func TestChangeSetRecorder(t *testing.T) {
+	t.Parallel()
+
+	r := NewChangeSetRecorder()
+	r.Record(stepEvent(apitype.OpSame, testStackURN, "pulumi:pulumi:Stack", true))
+	r.Record(stepEvent(apitype.OpReplace, testBucketURN, "aws:s3/bucket:Bucket", true, "name"))
+	r.Record(stepEvent(apitype.OpCreateReplacement, testBucketURN, "aws:s3/bucket:Bucket", false, "name"))
+	// The changes are timed by the events' timestamps, not by when they are recorded.
+	queueStep := stepEvent(apitype.OpUpdate, testQueueURN, "aws:sqs/queue:Queue", true)
+	queueStep.Timestamp = 1700000000
+	r.Record(queueStep)
+	r.Record(events.EngineEvent{EngineEvent: apitype.EngineEvent{
+		DiagnosticEvent: &apitype.DiagnosticEvent{URN: testQueueURN, Severity: "error", Message: "access denied\n"},
+	}})
+	r.Record(events.EngineEvent{EngineEvent: apitype.EngineEvent{
+		Timestamp:        1700000002,
+		ResOpFailedEvent: &apitype.ResOpFailedEvent{Metadata: apitype.StepEventMetadata{URN: testQueueURN}},
+	}})
+	r.Record(events.EngineEvent{EngineEvent: apitype.EngineEvent{
+		SummaryEvent: &apitype.SummaryEvent{
+			DurationSeconds: 3,
+			ResourceChanges: map[apitype.OpType]int{apitype.OpSame: 1, apitype.OpReplace: 1, apitype.OpUpdate: 1},
+		},
+	}})
+
+	cs := r.ChangeSet()
+	require.Len(t, cs.Resources, 2)
+
+	bucket := cs.Resources[0]
+	assert.Equal(t, testBucketURN, bucket.URN)
+	assert.Equal(t, display.StepOp("replace"), bucket.Op)
+	assert.Equal(t, []string{"name"}, bucket.DiffKeys)
+	assert.Equal(t, "old", bucket.OldInputs["name"])
+	assert.Empty(t, bucket.Error)
+
+	queue := cs.Resources[1]
+	assert.Equal(t, display.StepOp("update"), queue.Op)
+	assert.Equal(t, "access denied", queue.Error)
+	assert.Equal(t, "2s", queue.Duration.String())
+
+	assert.Equal(t, []ResourceChange{queue}, cs.Failed())
+	assert.Equal(t, 1, cs.Summary[apitype.OpReplace])
+	assert.Equal(t, "3s", cs.Duration.String())
+}
+
+func TestChangeSetMarkdown(t *testing.T) {
+	t.Parallel()
+
+	cs := ChangeSet{
+		Resources: []ResourceChange{{
+			URN:         testBucketURN,
+			Type:        "aws:s3/bucket:Bucket",
+			Op:          display.StepOp("replace"),
+			OldInputs:   map[string]interface{}{"name": "old"},
+			NewInputs:   map[string]interface{}{"name": "new"},
+			DiffKeys:    []string{"name", "tags"},
+			ReplaceKeys: []string{"name"},
+		}, {
+			URN:   testQueueURN,
+			Type:  "aws:sqs/queue:Queue",
+			Op:    display.StepOp("update"),
+			Error: "access denied",
+		}},
+		Summary: map[apitype.OpType]int{apitype.OpReplace: 1, apitype.OpUpdate: 1, apitype.OpSame: 2},
+	}
+
+	expected := "### Changes to dev\n" +
+		"\n" +
+		"| | Resource | Type | Operation | Changed properties |\n" +
+		"|---|---|---|---|---|\n" +
+		"| +- | `my\\|bucket` | `aws:s3/bucket:Bucket` | replace | name (replace), tags |\n" +
+		"| ~ | `queue` | `aws:sqs/queue:Queue` | update |  |\n" +
+		"\n" +
+		"**1 to update, 1 to replace, 2 unchanged**\n" +
+		"\n" +
+		"<details>\n" +
+		"<summary><code>my|bucket</code></summary>\n" +
+		"\n" +
+		"| Property | Old | New |\n" +
+		"|---|---|---|\n" +
+		"| `name` | `\"old\"` | `\"new\"` |\n" +
+		"| `tags` |  |  |\n" +
+		"</details>\n" +
+		"\n" +
+		"#### Errors\n" +
+		"\n" +
+		"`queue`:\n" +
+		"```\n" +
+		"access denied\n" +
+		"```\n"
+	assert.Equal(t, expected, cs.Markdown(MarkdownOptions{Title: "Changes to dev", ShowInputs: true}))
+
+	assert.Equal(t, "### Resource changes\n\nNo changes.\n", ChangeSet{}.Markdown(MarkdownOptions{}))
+}
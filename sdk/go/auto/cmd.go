@@ -58,16 +58,29 @@ func runPulumiCommandSync(
 }
 
 func withNonInteractiveArg(args []string) []string {
-	out := make([]string, 0, len(args))
-	seen := false
 	for _, a := range args {
-		out = append(out, a)
+		if a == "--" {
+			break
+		}
 		if a == "--non-interactive" {
-			seen = true
+			return append([]string(nil), args...)
 		}
 	}
-	if !seen {
-		out = append(out, "--non-interactive")
+	return withFlags(args, "--non-interactive")
+}
+
+// withFlags returns a copy of the given arguments with the given flags added before the "--" that ends the flags, if
+// there is one, and at the end otherwise.
+func withFlags(args []string, flags ...string) []string {
+	end := len(args)
+	for i, a := range args {
+		if a == "--" {
+			end = i
+			break
+		}
 	}
-	return out
+	out := make([]string, 0, len(args)+len(flags))
+	out = append(out, args[:end]...)
+	out = append(out, flags...)
+	return append(out, args[end:]...)
 }
@@ -0,0 +1,70 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auto
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optdestroy"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/opthistory"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optrefresh"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// ErrUnsupportedByEngine is returned by the stack methods that run the Pulumi CLI, such as ImportResources,
+// QueryState, Cancel and the State methods, when the stack's workspace is a StackEngine, which runs operations
+// without the CLI.
+var ErrUnsupportedByEngine = errors.New("the operation is not supported by the workspace's engine")
+
+// StackEngine is implemented by workspaces that run stack lifecycle operations themselves rather than through the
+// Pulumi CLI, such as the in-process workspace in github.com/pulumi/pulumi/pkg/v3/automation. If a stack's workspace
+// implements StackEngine, the stack's Preview, PreviewPlan, Up, Refresh, Destroy and History methods call it with
+// their options. The stack methods that would run the Pulumi CLI return an error that wraps ErrUnsupportedByEngine.
+type StackEngine interface {
+	// PreviewStack performs a dry-run update of the given stack.
+	PreviewStack(ctx context.Context, stackName string, opts *optpreview.Options) (PreviewResult, error)
+	// UpStack creates or updates the resources of the given stack.
+	UpStack(ctx context.Context, stackName string, opts *optup.Options) (UpResult, error)
+	// RefreshStack refreshes the resources of the given stack from their providers.
+	RefreshStack(ctx context.Context, stackName string, opts *optrefresh.Options) (RefreshResult, error)
+	// DestroyStack deletes the resources of the given stack.
+	DestroyStack(ctx context.Context, stackName string, opts *optdestroy.Options) (DestroyResult, error)
+	// StackHistory returns a page of the history of the given stack, most recent first.
+	StackHistory(
+		ctx context.Context, stackName string, pageSize, page int, opts *opthistory.Options,
+	) ([]UpdateSummary, error)
+}
+
+// ServeProgram starts a language runtime server that runs the given inline program, so that a StackEngine can run
+// the program by using a project runtime named "client" whose "address" option is the returned address. The returned
+// function stops the server once the operation has finished. If the program is still running, for example because
+// the operation failed while the program was waiting on the engine, it is cancelled.
+func ServeProgram(program pulumi.RunFunc) (string, func() error, error) {
+	server, err := startLanguageRuntimeServer(program)
+	if err != nil {
+		return "", nil, err
+	}
+	return server.address, server.Abort, nil
+}
+
+// NewEngineError returns the error of a failed StackEngine operation, given the operation's progress output and error
+// output. Predicates such as IsRuntimeError classify the error by this output in the same way as they classify the
+// errors of operations run by the Pulumi CLI. Since no process was run, the error's exit code is -1.
+func NewEngineError(err error, stdout, stderr string) error {
+	return newAutoError(err, stdout, stderr, -1)
+}
@@ -15,6 +15,7 @@
 package auto
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
@@ -40,8 +41,49 @@ func (ae autoError) Error() string {
 	return fmt.Sprintf("%s\ncode: %d\nstdout: %s\nstderr: %s\n", ae.err.Error(), ae.code, ae.stdout, ae.stderr)
 }
 
+func (ae autoError) Unwrap() error {
+	return ae.err
+}
+
+// StackNotFoundError is returned by workspaces that do not run the Pulumi CLI when a stack does not exist.
+type StackNotFoundError struct {
+	StackName string
+}
+
+func (e StackNotFoundError) Error() string {
+	return fmt.Sprintf("no stack named '%s' found", e.StackName)
+}
+
+// StackAlreadyExistsError is returned by workspaces that do not run the Pulumi CLI when a stack that is being
+// created already exists.
+type StackAlreadyExistsError struct {
+	StackName string
+}
+
+func (e StackAlreadyExistsError) Error() string {
+	return fmt.Sprintf("stack '%s' already exists", e.StackName)
+}
+
+// ConcurrentUpdateError is returned by workspaces that do not run the Pulumi CLI when a stack operation fails because
+// another update is in progress.
+type ConcurrentUpdateError struct {
+	Err error
+}
+
+func (e ConcurrentUpdateError) Error() string {
+	return e.Err.Error()
+}
+
+func (e ConcurrentUpdateError) Unwrap() error {
+	return e.Err
+}
+
 // IsConcurrentUpdateError returns true if the error was a result of a conflicting update locking the stack.
 func IsConcurrentUpdateError(e error) bool {
+	if errors.As(e, &ConcurrentUpdateError{}) {
+		return true
+	}
+
 	ae, ok := e.(autoError)
 	if !ok {
 		return false
@@ -54,6 +96,10 @@ func IsConcurrentUpdateError(e error) bool {
 
 // IsSelectStack404Error returns true if the error was a result of selecting a stack that does not exist.
 func IsSelectStack404Error(e error) bool {
+	if errors.As(e, &StackNotFoundError{}) {
+		return true
+	}
+
 	ae, ok := e.(autoError)
 	if !ok {
 		return false
@@ -65,6 +111,10 @@ func IsSelectStack404Error(e error) bool {
 
 // IsCreateStack409Error returns true if the error was a result of creating a stack that already exists.
 func IsCreateStack409Error(e error) bool {
+	if errors.As(e, &StackAlreadyExistsError{}) {
+		return true
+	}
+
 	ae, ok := e.(autoError)
 	if !ok {
 		return false
@@ -145,3 +195,36 @@ func IsUnexpectedEngineError(e error) bool {
 
 	return strings.Contains(as.stdout, "The Pulumi CLI encountered a fatal error. This is a bug!")
 }
+
+// IsResourceProtectedError returns true if a state operation failed because a resource is protected.
+func IsResourceProtectedError(e error) bool {
+	ae, ok := e.(autoError)
+	if !ok {
+		return false
+	}
+
+	return strings.Contains(ae.stderr, "can't be safely deleted because it is protected")
+}
+
+// IsResourceHasDependentsError returns true if a state operation failed because other resources depend on a
+// resource that it would delete.
+func IsResourceHasDependentsError(e error) bool {
+	ae, ok := e.(autoError)
+	if !ok {
+		return false
+	}
+
+	return strings.Contains(ae.stderr, "can't be safely deleted because the following resources depend on it")
+}
+
+// IsResourceNotFoundError returns true if a state operation failed because no resource matched the given URN.
+func IsResourceNotFoundError(e error) bool {
+	ae, ok := e.(autoError)
+	if !ok {
+		return false
+	}
+
+	regex := regexp.MustCompile(`No such resource .* exists in the current state|no resources match |` +
+		`The input URN does not correspond to an existing resource`)
+	return regex.MatchString(ae.stderr)
+}
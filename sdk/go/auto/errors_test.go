@@ -17,6 +17,7 @@ package auto
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os/exec"
 	"path/filepath"
@@ -580,3 +581,23 @@ func TestRuntimeErrorDotnet(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func TestStateErrors(t *testing.T) {
+	t.Parallel()
+
+	protected := newAutoError(errors.New("failed to delete resources"), "",
+		"error: urn:pulumi:dev::app::aws:s3/bucket:Bucket::b can't be safely deleted because it is protected. "+
+			"Re-run this command with --force to force deletion", 255)
+	dependents := newAutoError(errors.New("failed to delete resources"), "",
+		"error: urn:pulumi:dev::app::aws:ec2/vpc:Vpc::v can't be safely deleted because the following resources "+
+			"depend on it:\n * \"sn\" (urn:pulumi:dev::app::aws:ec2/subnet:Subnet::sn)", 255)
+	notFound := newAutoError(errors.New("failed to rename resources"), "",
+		"error: No such resource \"urn:pulumi:dev::app::aws:ec2/vpc:Vpc::v\" exists in the current state", 255)
+
+	assert.True(t, IsResourceProtectedError(protected))
+	assert.False(t, IsResourceProtectedError(dependents))
+	assert.True(t, IsResourceHasDependentsError(dependents))
+	assert.False(t, IsResourceHasDependentsError(notFound))
+	assert.True(t, IsResourceNotFoundError(notFound))
+	assert.False(t, IsResourceNotFoundError(errors.New("no resources match the given URNs")))
+}
@@ -0,0 +1,238 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
+)
+
+// Sink receives the engine events of an operation as they occur, for example to persist them. Sinks are given to
+// operations with the EventSinks option of each operation.
+type Sink interface {
+	// WriteEvent writes an event to the sink. If it returns an error, no further events are written to the sink, and
+	// the operation returns the error, along with its result, once it completes.
+	WriteEvent(e EngineEvent) error
+}
+
+// SinkStream returns a channel that writes the events sent on it to the given sinks, for use as one of the event
+// streams of an operation, and a function that waits for the channel to be closed and returns the first error
+// returned by the sinks.
+func SinkStream(sinks ...Sink) (chan<- EngineEvent, func() error) {
+	stream, done := make(chan EngineEvent), make(chan struct{})
+
+	var sinkErr error
+	go func() {
+		defer close(done)
+
+		failed := make([]bool, len(sinks))
+		for e := range stream {
+			for i, s := range sinks {
+				if failed[i] {
+					continue
+				}
+				if err := s.WriteEvent(e); err != nil {
+					failed[i] = true
+					if sinkErr == nil {
+						sinkErr = err
+					}
+				}
+			}
+		}
+	}()
+
+	return stream, func() error {
+		<-done
+		return sinkErr
+	}
+}
+
+// LogWriter is a Sink that writes events to an io.Writer as lines of JSON, in the format of the event log of the
+// Pulumi CLI. The engine numbers the events of each operation from zero, so the writer renumbers them in the order
+// they are written, which lets a LogReader resume reading a log of several operations from any event. Events that
+// could not be decoded from the engine are not written.
+type LogWriter struct {
+	m    sync.Mutex
+	w    io.Writer
+	next int
+}
+
+// NewLogWriter creates a LogWriter that writes to the given writer, numbering the events it writes from zero.
+func NewLogWriter(w io.Writer) *LogWriter {
+	return &LogWriter{w: w}
+}
+
+// WriteEvent writes an event as a single line of JSON, with the next sequence number of the log.
+func (l *LogWriter) WriteEvent(e EngineEvent) error {
+	if e.Error != nil {
+		return nil
+	}
+
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	event := e.EngineEvent
+	event.Sequence = l.next
+	b, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event %d: %w", event.Sequence, err)
+	}
+	if _, err := l.w.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("failed to write event %d: %w", event.Sequence, err)
+	}
+	l.next++
+	return nil
+}
+
+// LogFile is a LogWriter that appends events to a file.
+type LogFile struct {
+	*LogWriter
+	f *os.File
+}
+
+// OpenLogFile opens the event log at the given path for appending, creating it if it does not exist. The events
+// appended to the log are numbered after the last event already in it. If the log ends with an event that was only
+// partially written, for example because the process writing it crashed, the partial event is removed.
+func OpenLogFile(path string) (*LogFile, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	data, err := truncatePartialEvent(f)
+	if err != nil {
+		contract.IgnoreError(f.Close())
+		return nil, fmt.Errorf("failed to repair event log: %w", err)
+	}
+	next, err := nextSequence(data)
+	if err != nil {
+		contract.IgnoreError(f.Close())
+		return nil, err
+	}
+
+	w := NewLogWriter(f)
+	w.next = next
+	return &LogFile{LogWriter: w, f: f}, nil
+}
+
+// truncatePartialEvent removes the trailing bytes of the file after its last newline, and leaves the file's offset
+// at its end. It returns the remaining contents of the file.
+func truncatePartialEvent(f *os.File) ([]byte, error) {
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	end := int64(bytes.LastIndexByte(data, '\n') + 1)
+	if end != int64(len(data)) {
+		if err := f.Truncate(end); err != nil {
+			return nil, err
+		}
+	}
+	if _, err = f.Seek(end, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return data[:end], nil
+}
+
+// nextSequence returns the sequence number that follows that of the last event in the given log.
+func nextSequence(log []byte) (int, error) {
+	log = bytes.TrimSuffix(log, []byte{'\n'})
+	if len(log) == 0 {
+		return 0, nil
+	}
+	var last struct {
+		Sequence int `json:"sequence"`
+	}
+	if err := json.Unmarshal(log[bytes.LastIndexByte(log, '\n')+1:], &last); err != nil {
+		return 0, fmt.Errorf("failed to decode the last event of the log: %w", err)
+	}
+	return last.Sequence + 1, nil
+}
+
+// Sync commits the events written so far to stable storage.
+func (l *LogFile) Sync() error {
+	return l.f.Sync()
+}
+
+// Close syncs and closes the file.
+func (l *LogFile) Close() error {
+	if err := l.f.Sync(); err != nil {
+		contract.IgnoreError(l.f.Close())
+		return err
+	}
+	return l.f.Close()
+}
+
+// LogReader reads the events written by a LogWriter.
+type LogReader struct {
+	r    *bufio.Reader
+	from int
+}
+
+// NewLogReader creates a reader for the event log read from r, which skips the events before the given sequence
+// number. Use a sequence number of 0 to read every event, or one more than that of the last event read to resume
+// reading the log.
+func NewLogReader(r io.Reader, fromSequence int) *LogReader {
+	return &LogReader{r: bufio.NewReader(r), from: fromSequence}
+}
+
+// Next returns the next event in the log. It returns io.EOF at the end of the log. A final event that was only
+// partially written is treated as the end of the log.
+func (r *LogReader) Next() (EngineEvent, error) {
+	for {
+		line, err := r.r.ReadBytes('\n')
+		if err == io.EOF {
+			return EngineEvent{}, io.EOF
+		} else if err != nil {
+			return EngineEvent{}, err
+		}
+
+		var e EngineEvent
+		if err := json.Unmarshal(line, &e.EngineEvent); err != nil {
+			return EngineEvent{}, fmt.Errorf("failed to decode event: %w", err)
+		}
+		if e.Sequence >= r.from {
+			return e, nil
+		}
+	}
+}
+
+// ReadLogFile reads the events in the event log at the given path, starting with the event with the given sequence
+// number.
+func ReadLogFile(path string, fromSequence int) ([]EngineEvent, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer contract.IgnoreClose(f)
+
+	var events []EngineEvent
+	r := NewLogReader(f, fromSequence)
+	for {
+		e, err := r.Next()
+		if err == io.EOF {
+			return events, nil
+		} else if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+func testEvent(sequence int, message string) EngineEvent {
+	return EngineEvent{EngineEvent: apitype.EngineEvent{
+		Sequence:        sequence,
+		DiagnosticEvent: &apitype.DiagnosticEvent{Message: message, Severity: "info"},
+	}}
+}
+
+func TestLogFileResume(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	log, err := OpenLogFile(path)
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, log.WriteEvent(testEvent(i, "event")))
+	}
+	require.NoError(t, log.WriteEvent(EngineEvent{Error: errors.New("undecodable event")}))
+	require.NoError(t, log.Close())
+
+	// Simulate a crash while writing an event.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"sequence":3,"diagnost`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	events, err := ReadLogFile(path, 1)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, 1, events[0].Sequence)
+	assert.Equal(t, "event", events[1].DiagnosticEvent.Message)
+
+	// Reopening the log removes the partial event, so that appending to it keeps it readable.
+	log, err = OpenLogFile(path)
+	require.NoError(t, err)
+	require.NoError(t, log.WriteEvent(testEvent(3, "resumed")))
+	require.NoError(t, log.Close())
+
+	events, err = ReadLogFile(path, 3)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "resumed", events[0].DiagnosticEvent.Message)
+}
+
+func TestLogFileSequences(t *testing.T) {
+	t.Parallel()
+
+	// The engine numbers the events of each operation from zero, but the log numbers them in the order they are
+	// written, including when it is reopened for a later operation.
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	for _, operation := range []string{"preview", "up"} {
+		log, err := OpenLogFile(path)
+		require.NoError(t, err)
+		for i := 0; i < 2; i++ {
+			require.NoError(t, log.WriteEvent(testEvent(i, operation)))
+		}
+		require.NoError(t, log.Close())
+	}
+
+	events, err := ReadLogFile(path, 0)
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+	for i, e := range events {
+		assert.Equal(t, i, e.Sequence)
+	}
+
+	// Reading can resume after the last event that was read, even if it was in an earlier operation.
+	events, err = ReadLogFile(path, 2)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "up", events[0].DiagnosticEvent.Message)
+}
+
+type failingSink struct {
+	writes int
+}
+
+func (s *failingSink) WriteEvent(EngineEvent) error {
+	s.writes++
+	return errors.New("disk full")
+}
+
+func TestSinkStream(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	log, err := OpenLogFile(path)
+	require.NoError(t, err)
+	failing := &failingSink{}
+
+	stream, wait := SinkStream(failing, log)
+	stream <- testEvent(0, "first")
+	stream <- testEvent(1, "second")
+	close(stream)
+
+	assert.EqualError(t, wait(), "disk full")
+	assert.Equal(t, 1, failing.writes, "a failed sink should not be written to again")
+	require.NoError(t, log.Close())
+
+	events, err := ReadLogFile(path, 0)
+	require.NoError(t, err)
+	assert.Len(t, events, 2)
+}
@@ -18,11 +18,13 @@ package auto
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optdestroy"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
@@ -1109,6 +1111,31 @@ func ExampleStack_SetAllConfig() {
 	_ = stack.SetAllConfig(ctx, cfg)
 }
 
+func ExampleStack_Use() {
+	ctx := context.Background()
+	stackName := FullyQualifiedStackName("org", "project", "stack")
+	stack, _ := NewStackLocalSource(ctx, stackName, filepath.Join(".", "program"))
+	// refuse to destroy production stacks
+	stack.Use(BeforeOperation(func(ctx context.Context, op *Operation) error {
+		if strings.HasSuffix(op.Stack.Name(), "/prod") {
+			return errors.New("production stacks can't be destroyed")
+		}
+		return nil
+	}, OperationDestroy))
+	// report the outcome of every update
+	stack.Use(AfterOperation(func(ctx context.Context, op *Operation) {
+		switch {
+		case op.Canceled:
+			fmt.Printf("update of %s was canceled\n", op.Stack.Name())
+		case op.Err != nil:
+			fmt.Printf("update of %s failed: %v\n", op.Stack.Name(), op.Err)
+		default:
+			fmt.Printf("update of %s succeeded with %d outputs\n", op.Stack.Name(), len(op.Outputs))
+		}
+	}, OperationUp))
+	stack.Up(ctx)
+}
+
 func ExampleStack_Cancel() {
 	ctx := context.Background()
 	stackName := FullyQualifiedStackName("org", "project", "stack")
@@ -16,74 +16,53 @@ package auto
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
+	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	git "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
 	"github.com/go-git/go-git/v5/plumbing/protocol/packp/capability"
 	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/go-git/go-git/v5/storage/memory"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/fsutil"
 )
 
 func setupGitRepo(ctx context.Context, workDir string, repoArgs *GitRepo) (string, error) {
+	if repoArgs.Shallow && repoArgs.CommitHash != "" {
+		return "", errors.New("a shallow clone cannot check out a specific commit hash")
+	}
+	if repoArgs.SparseCheckout && repoArgs.ProjectPath == "" {
+		return "", errors.New("a sparse checkout requires a project path")
+	}
+
+	auth, err := gitAuthMethod(ctx, repoArgs)
+	if err != nil {
+		return "", err
+	}
+
 	cloneOptions := &git.CloneOptions{
 		RemoteName: "origin", // be explicit so we can require it in remote refs
 		URL:        repoArgs.URL,
+		Auth:       auth,
+		NoCheckout: repoArgs.SparseCheckout,
 	}
-
-	if repoArgs.Auth != nil {
-		authDetails := repoArgs.Auth
-		// Each of the authentication options are mutually exclusive so let's check that only 1 is specified
-		if authDetails.SSHPrivateKeyPath != "" && authDetails.Username != "" ||
-			authDetails.PersonalAccessToken != "" && authDetails.Username != "" ||
-			authDetails.PersonalAccessToken != "" && authDetails.SSHPrivateKeyPath != "" ||
-			authDetails.Username != "" && authDetails.SSHPrivateKey != "" {
-			return "", errors.New("please specify one authentication option of `Personal Access Token`, " +
-				"`Username\\Password`, `SSH Private Key Path` or `SSH Private Key`")
-		}
-
-		// Firstly we will try to check that an SSH Private Key Path has been specified
-		if authDetails.SSHPrivateKeyPath != "" {
-			publicKeys, err := ssh.NewPublicKeysFromFile("git", repoArgs.Auth.SSHPrivateKeyPath, repoArgs.Auth.Password)
-			if err != nil {
-				return "", fmt.Errorf("unable to use SSH Private Key Path: %w", err)
-			}
-
-			cloneOptions.Auth = publicKeys
-		}
-
-		// Then we check if the details of a SSH Private Key as passed
-		if authDetails.SSHPrivateKey != "" {
-			publicKeys, err := ssh.NewPublicKeys("git", []byte(repoArgs.Auth.SSHPrivateKey), repoArgs.Auth.Password)
-			if err != nil {
-				return "", fmt.Errorf("unable to use SSH Private Key: %w", err)
-			}
-
-			cloneOptions.Auth = publicKeys
-		}
-
-		// Then we check to see if a Personal Access Token has been specified
-		// the username for use with a PAT can be *anything* but an empty string
-		// so we are setting this to `git`
-		if authDetails.PersonalAccessToken != "" {
-			cloneOptions.Auth = &http.BasicAuth{
-				Username: "git",
-				Password: repoArgs.Auth.PersonalAccessToken,
-			}
-		}
-
-		// then we check to see if a username and a password has been specified
-		if authDetails.Password != "" && authDetails.Username != "" {
-			cloneOptions.Auth = &http.BasicAuth{
-				Username: repoArgs.Auth.Username,
-				Password: repoArgs.Auth.Password,
-			}
-		}
+	if repoArgs.Shallow {
+		cloneOptions.Depth = 1
+		cloneOptions.SingleBranch = true
 	}
 
 	// *Repository.Clone() will do appropriate fetching given a branch name. We must deal with
@@ -125,14 +104,36 @@ func setupGitRepo(ctx context.Context, workDir string, repoArgs *GitRepo) (strin
 		}
 	}
 
+	// When there's a cache, bring its mirror up to date and clone from that instead. The lock is
+	// held until the clone is complete, so the mirror can't change underneath it.
+	if repoArgs.CacheDir != "" {
+		mirrorDir, unlock, err := updateGitMirror(ctx, repoArgs.CacheDir, repoArgs.URL, auth)
+		if err != nil {
+			transport.UnsupportedCapabilities = oldUnsupportedCaps
+			return "", fmt.Errorf("unable to update git cache: %w", err)
+		}
+		defer unlock()
+		cloneOptions.URL = mirrorDir
+		cloneOptions.Auth = nil
+	}
+
+	// A single branch clone of HEAD assumes the default branch is master, so find out what it is.
+	if repoArgs.Shallow && cloneOptions.ReferenceName == "" {
+		branch, err := defaultBranch(ctx, cloneOptions.URL, cloneOptions.Auth)
+		if err != nil {
+			transport.UnsupportedCapabilities = oldUnsupportedCaps
+			return "", fmt.Errorf("unable to list remote refs: %w", err)
+		}
+		cloneOptions.ReferenceName = branch
+	}
+
 	// clone
 	repo, err := git.PlainCloneContext(ctx, workDir, false, cloneOptions)
+	transport.UnsupportedCapabilities = oldUnsupportedCaps
 	if err != nil {
 		return "", fmt.Errorf("unable to clone repo: %w", err)
 	}
 
-	transport.UnsupportedCapabilities = oldUnsupportedCaps
-
 	if repoArgs.CommitHash != "" {
 		// ensure that the commit has been fetched
 		err = repo.FetchContext(ctx, &git.FetchOptions{
@@ -153,13 +154,34 @@ func setupGitRepo(ctx context.Context, workDir string, repoArgs *GitRepo) (strin
 		hash := repoArgs.CommitHash
 		err = w.Checkout(&git.CheckoutOptions{
 			Hash:  plumbing.NewHash(hash),
-			Force: true,
+			Force: !repoArgs.SparseCheckout,
+			Keep:  repoArgs.SparseCheckout, // only move HEAD; the sparse checkout follows
 		})
 		if err != nil {
 			return "", fmt.Errorf("unable to checkout commit: %w", err)
 		}
 	}
 
+	if repoArgs.SparseCheckout {
+		if err := sparseCheckout(repo, workDir, repoArgs.ProjectPath); err != nil {
+			return "", fmt.Errorf("unable to checkout project path: %w", err)
+		}
+	}
+
+	// A clone from the cache has the mirror as its origin; point it back at the real remote.
+	if repoArgs.CacheDir != "" {
+		if err := repo.DeleteRemote("origin"); err != nil {
+			return "", err
+		}
+		_, err = repo.CreateRemote(&config.RemoteConfig{
+			Name: "origin",
+			URLs: []string{repoArgs.URL},
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
 	var relPath string
 	if repoArgs.ProjectPath != "" {
 		relPath = repoArgs.ProjectPath
@@ -168,3 +190,281 @@ func setupGitRepo(ctx context.Context, workDir string, repoArgs *GitRepo) (strin
 	workDir = filepath.Join(workDir, relPath)
 	return workDir, nil
 }
+
+// sparseCheckout checks out HEAD into the worktree at workDir, limited to the files under
+// projectPath.
+func sparseCheckout(repo *git.Repository, workDir, projectPath string) error {
+	head, err := repo.Head()
+	if err != nil {
+		return err
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+
+	// go-git's own sparse checkout expects the skipped files to be on disk already. Instead, fill
+	// the index, mark everything outside the project as skipped, and write the rest directly.
+	if err := w.Reset(&git.ResetOptions{Commit: head.Hash(), Mode: git.MixedReset}); err != nil {
+		return err
+	}
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return err
+	}
+	idx.SkipUnless([]string{filepath.ToSlash(filepath.Clean(projectPath)) + "/"})
+	if err := repo.Storer.SetIndex(idx); err != nil {
+		return err
+	}
+
+	for _, e := range idx.Entries {
+		if e.SkipWorktree || e.Mode == filemode.Submodule {
+			continue
+		}
+		if err := checkoutIndexEntry(repo, workDir, e); err != nil {
+			return fmt.Errorf("%s: %w", e.Name, err)
+		}
+	}
+	return nil
+}
+
+// checkoutIndexEntry writes the file for an index entry into the worktree at workDir.
+func checkoutIndexEntry(repo *git.Repository, workDir string, e *index.Entry) error {
+	blob, err := repo.BlobObject(e.Hash)
+	if err != nil {
+		return err
+	}
+	r, err := blob.Reader()
+	if err != nil {
+		return err
+	}
+	defer contract.IgnoreClose(r)
+
+	path := filepath.Join(workDir, filepath.FromSlash(e.Name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	if e.Mode == filemode.Symlink {
+		target, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(string(target), path)
+	}
+
+	mode, err := e.Mode.ToOSFileMode()
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode.Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		contract.IgnoreClose(f)
+		return err
+	}
+	return f.Close()
+}
+
+// gitAuthMethod returns the authentication method configured for the repo, if any.
+func gitAuthMethod(ctx context.Context, repoArgs *GitRepo) (transport.AuthMethod, error) {
+	if repoArgs.AuthProvider != nil {
+		if repoArgs.Auth != nil {
+			return nil, errors.New("please specify only one of `Auth` or `AuthProvider`")
+		}
+		auth, err := repoArgs.AuthProvider.GitAuth(ctx, repoArgs.URL)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get git credentials: %w", err)
+		}
+		return auth, nil
+	}
+
+	if repoArgs.Auth == nil {
+		return nil, nil
+	}
+
+	authDetails := repoArgs.Auth
+	// Each of the authentication options are mutually exclusive so let's check that only 1 is specified
+	if authDetails.SSHPrivateKeyPath != "" && authDetails.Username != "" ||
+		authDetails.PersonalAccessToken != "" && authDetails.Username != "" ||
+		authDetails.PersonalAccessToken != "" && authDetails.SSHPrivateKeyPath != "" ||
+		authDetails.Username != "" && authDetails.SSHPrivateKey != "" {
+		return nil, errors.New("please specify one authentication option of `Personal Access Token`, " +
+			"`Username\\Password`, `SSH Private Key Path` or `SSH Private Key`")
+	}
+
+	var auth transport.AuthMethod
+
+	// Firstly we will try to check that an SSH Private Key Path has been specified
+	if authDetails.SSHPrivateKeyPath != "" {
+		publicKeys, err := ssh.NewPublicKeysFromFile("git", authDetails.SSHPrivateKeyPath, authDetails.Password)
+		if err != nil {
+			return nil, fmt.Errorf("unable to use SSH Private Key Path: %w", err)
+		}
+
+		auth = publicKeys
+	}
+
+	// Then we check if the details of a SSH Private Key as passed
+	if authDetails.SSHPrivateKey != "" {
+		publicKeys, err := ssh.NewPublicKeys("git", []byte(authDetails.SSHPrivateKey), authDetails.Password)
+		if err != nil {
+			return nil, fmt.Errorf("unable to use SSH Private Key: %w", err)
+		}
+
+		auth = publicKeys
+	}
+
+	// Then we check to see if a Personal Access Token has been specified
+	// the username for use with a PAT can be *anything* but an empty string
+	// so we are setting this to `git`
+	if authDetails.PersonalAccessToken != "" {
+		auth = &http.BasicAuth{
+			Username: "git",
+			Password: authDetails.PersonalAccessToken,
+		}
+	}
+
+	// then we check to see if a username and a password has been specified
+	if authDetails.Password != "" && authDetails.Username != "" {
+		auth = &http.BasicAuth{
+			Username: authDetails.Username,
+			Password: authDetails.Password,
+		}
+	}
+
+	return auth, nil
+}
+
+// GitAuthProvider supplies the credentials for cloning a git repository when the clone happens,
+// rather than ahead of time as with GitAuth. This allows using an SSH agent, or a token that is
+// minted on demand.
+type GitAuthProvider interface {
+	// GitAuth returns the authentication method to use for the repository at url.
+	GitAuth(ctx context.Context, url string) (transport.AuthMethod, error)
+}
+
+// GitAuthFunc adapts an ordinary function to a GitAuthProvider.
+type GitAuthFunc func(ctx context.Context, url string) (transport.AuthMethod, error)
+
+// GitAuth calls f(ctx, url).
+func (f GitAuthFunc) GitAuth(ctx context.Context, url string) (transport.AuthMethod, error) {
+	return f(ctx, url)
+}
+
+// GitSSHAgentAuth returns a GitAuthProvider that authenticates as user with the keys held by the
+// SSH agent listening on SSH_AUTH_SOCK. The URL of the repository must use SSH, e.g.
+// git@github.com:org/repository.git.
+func GitSSHAgentAuth(user string) GitAuthProvider {
+	return GitAuthFunc(func(context.Context, string) (transport.AuthMethod, error) {
+		return ssh.NewSSHAgentAuth(user)
+	})
+}
+
+// GitTokenAuth returns a GitAuthProvider that calls token for a personal access token each time
+// a repository is cloned, e.g. to fetch a short-lived token from a secrets manager.
+func GitTokenAuth(token func(ctx context.Context, url string) (string, error)) GitAuthProvider {
+	return GitAuthFunc(func(ctx context.Context, url string) (transport.AuthMethod, error) {
+		t, err := token(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+		// as with GitAuth.PersonalAccessToken, the username can be anything but empty
+		return &http.BasicAuth{Username: "git", Password: t}, nil
+	})
+}
+
+// gitMirrorLocks serializes access to each mirror within this process; the file lock held
+// alongside serializes access across processes.
+var gitMirrorLocks sync.Map // map[string]*fsutil.FileMutex
+
+// updateGitMirror creates or updates a bare mirror of the repository at url within cacheDir. It
+// returns the directory of the mirror, locked, and a function to unlock it.
+func updateGitMirror(
+	ctx context.Context, cacheDir, url string, auth transport.AuthMethod,
+) (string, func(), error) {
+	if err := os.MkdirAll(cacheDir, 0o700); err != nil {
+		return "", nil, err
+	}
+
+	sum := sha256.Sum256([]byte(url))
+	mirrorDir, err := filepath.Abs(filepath.Join(cacheDir, hex.EncodeToString(sum[:])[:16]+".git"))
+	if err != nil {
+		return "", nil, err
+	}
+
+	mu, _ := gitMirrorLocks.LoadOrStore(mirrorDir, fsutil.NewFileMutex(mirrorDir+".lock"))
+	lock := mu.(*fsutil.FileMutex)
+	if err := lock.Lock(); err != nil {
+		return "", nil, err
+	}
+	unlock := func() { contract.IgnoreError(lock.Unlock()) }
+
+	if err := fetchGitMirror(ctx, mirrorDir, url, auth); err != nil {
+		unlock()
+		return "", nil, err
+	}
+	return mirrorDir, unlock, nil
+}
+
+// fetchGitMirror fetches all branches and tags from url into the bare repository at mirrorDir,
+// creating it if necessary, and points its HEAD at the remote's default branch.
+func fetchGitMirror(ctx context.Context, mirrorDir, url string, auth transport.AuthMethod) error {
+	mirror, err := git.PlainOpen(mirrorDir)
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		mirror, err = git.PlainInit(mirrorDir, true)
+		if err == nil {
+			_, err = mirror.CreateRemote(&config.RemoteConfig{
+				Name: "origin",
+				URLs: []string{url},
+				Fetch: []config.RefSpec{
+					"+refs/heads/*:refs/heads/*",
+					"+refs/tags/*:refs/tags/*",
+				},
+			})
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	remote, err := mirror.Remote("origin")
+	if err != nil {
+		return err
+	}
+	err = remote.FetchContext(ctx, &git.FetchOptions{
+		Auth:  auth,
+		Tags:  git.NoTags, // the refspecs already include tags
+		Force: true,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return err
+	}
+
+	branch, err := defaultBranch(ctx, url, auth)
+	if err != nil || branch == "" {
+		return err
+	}
+	return mirror.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, branch))
+}
+
+// defaultBranch returns the branch that HEAD refers to in the repository at url, or "" if the
+// remote doesn't say.
+func defaultBranch(ctx context.Context, url string, auth transport.AuthMethod) (plumbing.ReferenceName, error) {
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{url},
+	})
+	refs, err := remote.ListContext(ctx, &git.ListOptions{Auth: auth})
+	if err != nil {
+		return "", err
+	}
+	for _, ref := range refs {
+		if ref.Name() == plumbing.HEAD && ref.Type() == plumbing.SymbolicReference {
+			return ref.Target(), nil
+		}
+	}
+	return "", nil
+}
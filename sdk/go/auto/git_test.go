@@ -2,13 +2,16 @@ package auto
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	git "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -149,3 +152,165 @@ func TestGitClone(t *testing.T) {
 		})
 	}
 }
+
+func TestGitCloneOptions(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	originDir := filepath.Join(tmpDir, "origin")
+
+	origin, err := git.PlainInit(originDir, false)
+	assert.NoError(t, err)
+	w, err := origin.Worktree()
+	assert.NoError(t, err)
+	// use a default branch other than master, since go-git can assume that
+	main := plumbing.NewBranchReferenceName("main")
+	assert.NoError(t, origin.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, main)))
+
+	// commit writes the given files to the origin and commits them
+	commit := func(files ...string) plumbing.Hash {
+		for _, f := range files {
+			path := filepath.Join(originDir, f)
+			assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+			assert.NoError(t, os.WriteFile(path, []byte(f), 0o600))
+			_, err := w.Add(f)
+			assert.NoError(t, err)
+		}
+		hash, err := w.Commit("add "+strings.Join(files, ", "), &git.CommitOptions{
+			Author: &object.Signature{
+				Name:  "testo",
+				Email: "testo@example.com",
+			},
+		})
+		assert.NoError(t, err)
+		return hash
+	}
+	commit("README.md")
+	head := commit("project/Pulumi.yaml", "other/Pulumi.yaml")
+
+	clone := func(t *testing.T, repo *GitRepo) (string, *git.Repository) {
+		dir, err := os.MkdirTemp(tmpDir, "testcase")
+		assert.NoError(t, err)
+		_, err = setupGitRepo(context.Background(), dir, repo)
+		assert.NoError(t, err)
+		r, err := git.PlainOpen(dir)
+		assert.NoError(t, err)
+		return dir, r
+	}
+
+	// these share the origin, so run them in a group that completes before the origin changes below
+	t.Run("group", func(t *testing.T) {
+		t.Run("shallow", func(t *testing.T) {
+			t.Parallel()
+			_, r := clone(t, &GitRepo{URL: originDir, Shallow: true})
+
+			ref, err := r.Head()
+			assert.NoError(t, err)
+			assert.Equal(t, head, ref.Hash())
+			assert.Equal(t, main, ref.Name())
+			shallow, err := r.Storer.Shallow()
+			assert.NoError(t, err)
+			assert.Equal(t, []plumbing.Hash{head}, shallow)
+		})
+
+		t.Run("sparse checkout", func(t *testing.T) {
+			t.Parallel()
+			dir, r := clone(t, &GitRepo{URL: originDir, ProjectPath: "project", SparseCheckout: true})
+
+			ref, err := r.Head()
+			assert.NoError(t, err)
+			assert.Equal(t, head, ref.Hash())
+			assert.FileExists(t, filepath.Join(dir, "project", "Pulumi.yaml"))
+			assert.NoFileExists(t, filepath.Join(dir, "other", "Pulumi.yaml"))
+			assert.NoFileExists(t, filepath.Join(dir, "README.md"))
+		})
+
+		t.Run("sparse checkout of commit", func(t *testing.T) {
+			t.Parallel()
+			dir, r := clone(t, &GitRepo{
+				URL:            originDir,
+				ProjectPath:    "project",
+				CommitHash:     head.String(),
+				SparseCheckout: true,
+			})
+
+			ref, err := r.Head()
+			assert.NoError(t, err)
+			assert.Equal(t, head, ref.Hash())
+			assert.FileExists(t, filepath.Join(dir, "project", "Pulumi.yaml"))
+			assert.NoFileExists(t, filepath.Join(dir, "other", "Pulumi.yaml"))
+		})
+
+		t.Run("auth provider", func(t *testing.T) {
+			t.Parallel()
+			var calledWith string
+			_, r := clone(t, &GitRepo{
+				URL: originDir,
+				AuthProvider: GitAuthFunc(func(_ context.Context, url string) (transport.AuthMethod, error) {
+					calledWith = url
+					return nil, nil
+				}),
+			})
+			assert.Equal(t, originDir, calledWith)
+			ref, err := r.Head()
+			assert.NoError(t, err)
+			assert.Equal(t, head, ref.Hash())
+		})
+
+	})
+
+	t.Run("cache", func(t *testing.T) {
+		cacheDir := filepath.Join(tmpDir, "cache")
+		repo := &GitRepo{URL: originDir, CacheDir: cacheDir}
+
+		_, r := clone(t, repo)
+		ref, err := r.Head()
+		assert.NoError(t, err)
+		assert.Equal(t, head, ref.Hash())
+		assert.Equal(t, main, ref.Name())
+		remote, err := r.Remote("origin")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{originDir}, remote.Config().URLs)
+
+		mirrors, err := filepath.Glob(filepath.Join(cacheDir, "*.git"))
+		assert.NoError(t, err)
+		assert.Len(t, mirrors, 1)
+
+		// a second clone picks up new commits through the same mirror
+		newHead := commit("project/index.ts")
+		_, r = clone(t, repo)
+		ref, err = r.Head()
+		assert.NoError(t, err)
+		assert.Equal(t, newHead, ref.Hash())
+
+		mirrors, err = filepath.Glob(filepath.Join(cacheDir, "*.git"))
+		assert.NoError(t, err)
+		assert.Len(t, mirrors, 1)
+	})
+
+	// test that these result in errors
+	for name, repo := range map[string]*GitRepo{
+		"shallow with commit hash": {URL: originDir, Shallow: true, CommitHash: head.String()},
+		"sparse without project":   {URL: originDir, SparseCheckout: true},
+		"auth and auth provider": {
+			URL:          originDir,
+			Auth:         &GitAuth{PersonalAccessToken: "token"},
+			AuthProvider: GitSSHAgentAuth("git"),
+		},
+		"auth provider error": {
+			URL: originDir,
+			AuthProvider: GitTokenAuth(func(context.Context, string) (string, error) {
+				return "", errors.New("no token")
+			}),
+		},
+	} {
+		repo := repo
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			dir, err := os.MkdirTemp(tmpDir, "testcase")
+			assert.NoError(t, err)
+			_, err = setupGitRepo(context.Background(), dir, repo)
+			assert.Error(t, err)
+		})
+	}
+}
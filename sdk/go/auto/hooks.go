@@ -0,0 +1,136 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auto
+
+import (
+	"context"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// OperationKind identifies the kind of a stack operation.
+type OperationKind string
+
+const (
+	// OperationPreview is Stack.Preview, which Stack.PreviewPlan also runs.
+	OperationPreview OperationKind = "preview"
+	// OperationUp is Stack.Up.
+	OperationUp OperationKind = "up"
+	// OperationRefresh is Stack.Refresh.
+	OperationRefresh OperationKind = "refresh"
+	// OperationDestroy is Stack.Destroy.
+	OperationDestroy OperationKind = "destroy"
+	// OperationImport is Stack.ImportResources.
+	OperationImport OperationKind = "import"
+	// OperationCancel is Stack.Cancel.
+	OperationCancel OperationKind = "cancel"
+)
+
+// Operation describes a stack operation to the hooks registered with Stack.Use. The fields after Kind are set once
+// the operation has run, as far as the kind of operation and its outcome allow.
+type Operation struct {
+	// Stack is the stack being operated on.
+	Stack *Stack
+	// Kind is the kind of the operation.
+	Kind OperationKind
+
+	// Summary is the summary of a successful up, refresh, destroy or import.
+	Summary *UpdateSummary
+	// ChangeSummary counts the changes that a preview found, by kind of change.
+	ChangeSummary map[apitype.OpType]int
+	// ChangeSet describes the changes of a preview or up. It is set even if the operation failed, in which case it
+	// includes the resources that failed.
+	ChangeSet *ChangeSet
+	// Outputs are the stack's outputs after a successful up.
+	Outputs OutputMap
+	// Err is the error of the operation, or of a hook that stopped it.
+	Err error
+	// Canceled is true if the operation failed because its context was canceled.
+	Canceled bool
+}
+
+// StackHook is middleware around the operations of a Stack. It is called with the operation about to run and must
+// call next to run it, after which the operation's result fields are set; the error it returns is the error of the
+// operation. A hook can stop an operation by returning an error without calling next. See BeforeOperation and
+// AfterOperation for the common cases.
+type StackHook func(ctx context.Context, op *Operation, next func(context.Context) error) error
+
+// Use registers hooks to be called around the stack's operations: Preview, PreviewPlan, Up, Refresh, Destroy,
+// ImportResources and Cancel. Hooks run in the order they are registered, so the first hook is the outermost.
+// Hooks are not shared with copies of the Stack made before Use is called.
+func (s *Stack) Use(hooks ...StackHook) {
+	s.hooks = append(s.hooks[:len(s.hooks):len(s.hooks)], hooks...)
+}
+
+// BeforeOperation returns a StackHook that calls fn before each operation of the given kinds, or of any kind if none
+// are given. If fn returns an error, the operation is not run and fails with that error.
+func BeforeOperation(fn func(ctx context.Context, op *Operation) error, kinds ...OperationKind) StackHook {
+	return func(ctx context.Context, op *Operation, next func(context.Context) error) error {
+		if matchesKinds(op.Kind, kinds) {
+			if err := fn(ctx, op); err != nil {
+				return err
+			}
+		}
+		return next(ctx)
+	}
+}
+
+// AfterOperation returns a StackHook that calls fn after each operation of the given kinds, or of any kind if none
+// are given, whether the operation succeeded, failed or was canceled.
+func AfterOperation(fn func(ctx context.Context, op *Operation), kinds ...OperationKind) StackHook {
+	return func(ctx context.Context, op *Operation, next func(context.Context) error) error {
+		err := next(ctx)
+		if matchesKinds(op.Kind, kinds) {
+			fn(ctx, op)
+		}
+		return err
+	}
+}
+
+func matchesKinds(kind OperationKind, kinds []OperationKind) bool {
+	if len(kinds) == 0 {
+		return true
+	}
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// runHooks runs an operation of the given kind through the stack's hooks. run performs the operation and sets the
+// result fields of op.
+func (s *Stack) runHooks(ctx context.Context, kind OperationKind, run func(context.Context, *Operation) error) error {
+	op := &Operation{Stack: s, Kind: kind}
+
+	// Each layer records its error on op, so that a hook sees the outcome of everything inside it, including a
+	// hook that stopped the operation.
+	layer := func(ctx context.Context, err error) error {
+		op.Err = err
+		op.Canceled = err != nil && ctx.Err() != nil
+		return err
+	}
+	next := func(ctx context.Context) error {
+		return layer(ctx, run(ctx, op))
+	}
+	for i := len(s.hooks) - 1; i >= 0; i-- {
+		hook, inner := s.hooks[i], next
+		next = func(ctx context.Context) error {
+			return layer(ctx, hook(ctx, op, inner))
+		}
+	}
+	return next(ctx)
+}
@@ -0,0 +1,230 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auto
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optdestroy"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/opthistory"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optrefresh"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// hookTestEngine is a workspace whose operations return canned results, so that hooks can be tested without running
+// the Pulumi CLI.
+type hookTestEngine struct {
+	Workspace
+
+	upErr error
+	ops   []string
+}
+
+func (e *hookTestEngine) PreviewStack(
+	ctx context.Context, stackName string, opts *optpreview.Options,
+) (PreviewResult, error) {
+	e.ops = append(e.ops, "preview")
+	return PreviewResult{ChangeSummary: map[apitype.OpType]int{apitype.OpCreate: 2}}, nil
+}
+
+func (e *hookTestEngine) UpStack(ctx context.Context, stackName string, opts *optup.Options) (UpResult, error) {
+	e.ops = append(e.ops, "up")
+	if err := ctx.Err(); err != nil {
+		return UpResult{}, err
+	}
+	if e.upErr != nil {
+		return UpResult{ChangeSet: ChangeSet{Resources: []ResourceChange{{Error: e.upErr.Error()}}}}, e.upErr
+	}
+	return UpResult{
+		Outputs: OutputMap{"url": {Value: "https://example.com"}},
+		Summary: UpdateSummary{Kind: "update", Result: "succeeded"},
+	}, nil
+}
+
+func (e *hookTestEngine) RefreshStack(
+	ctx context.Context, stackName string, opts *optrefresh.Options,
+) (RefreshResult, error) {
+	e.ops = append(e.ops, "refresh")
+	return RefreshResult{Summary: UpdateSummary{Kind: "refresh"}}, nil
+}
+
+func (e *hookTestEngine) DestroyStack(
+	ctx context.Context, stackName string, opts *optdestroy.Options,
+) (DestroyResult, error) {
+	e.ops = append(e.ops, "destroy")
+	return DestroyResult{Summary: UpdateSummary{Kind: "destroy"}}, nil
+}
+
+func (e *hookTestEngine) StackHistory(
+	ctx context.Context, stackName string, pageSize, page int, opts *opthistory.Options,
+) ([]UpdateSummary, error) {
+	return nil, nil
+}
+
+func TestStackHooksOrder(t *testing.T) {
+	t.Parallel()
+
+	engine := &hookTestEngine{}
+	s := Stack{workspace: engine, stackName: "dev"}
+
+	// each hook records when it's entered and left
+	trace := func(name string) StackHook {
+		return func(ctx context.Context, op *Operation, next func(context.Context) error) error {
+			engine.ops = append(engine.ops, name+" before "+string(op.Kind))
+			err := next(ctx)
+			engine.ops = append(engine.ops, name+" after "+string(op.Kind))
+			return err
+		}
+	}
+	s.Use(trace("a"), trace("b"))
+	s.Use(trace("c"))
+
+	_, err := s.Preview(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"a before preview", "b before preview", "c before preview",
+		"preview",
+		"c after preview", "b after preview", "a after preview",
+	}, engine.ops)
+}
+
+func TestStackHooksResults(t *testing.T) {
+	t.Parallel()
+
+	engine := &hookTestEngine{}
+	s := Stack{workspace: engine, stackName: "dev"}
+
+	var ops []Operation
+	s.Use(AfterOperation(func(ctx context.Context, op *Operation) {
+		assert.Same(t, &s, op.Stack)
+		ops = append(ops, *op)
+	}))
+
+	_, err := s.Preview(context.Background())
+	require.NoError(t, err)
+	_, err = s.Up(context.Background())
+	require.NoError(t, err)
+	_, err = s.Refresh(context.Background())
+	require.NoError(t, err)
+	_, err = s.Destroy(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, ops, 4)
+	assert.Equal(t, OperationPreview, ops[0].Kind)
+	assert.Equal(t, map[apitype.OpType]int{apitype.OpCreate: 2}, ops[0].ChangeSummary)
+	assert.NotNil(t, ops[0].ChangeSet)
+	assert.Nil(t, ops[0].Summary)
+
+	assert.Equal(t, OperationUp, ops[1].Kind)
+	assert.Equal(t, "succeeded", ops[1].Summary.Result)
+	assert.Equal(t, "https://example.com", ops[1].Outputs["url"].Value)
+
+	assert.Equal(t, OperationRefresh, ops[2].Kind)
+	assert.Equal(t, "refresh", ops[2].Summary.Kind)
+	assert.Equal(t, OperationDestroy, ops[3].Kind)
+	assert.Equal(t, "destroy", ops[3].Summary.Kind)
+	for _, op := range ops {
+		assert.NoError(t, op.Err)
+		assert.False(t, op.Canceled)
+	}
+}
+
+func TestStackHooksFailure(t *testing.T) {
+	t.Parallel()
+
+	failed := errors.New("resource failed")
+	engine := &hookTestEngine{upErr: failed}
+	s := Stack{workspace: engine, stackName: "dev"}
+
+	var after *Operation
+	s.Use(AfterOperation(func(ctx context.Context, op *Operation) {
+		after = op
+	}, OperationUp))
+
+	_, err := s.Up(context.Background())
+	assert.ErrorIs(t, err, failed)
+	require.NotNil(t, after)
+	assert.ErrorIs(t, after.Err, failed)
+	assert.False(t, after.Canceled)
+	assert.Nil(t, after.Summary)
+	assert.Nil(t, after.Outputs)
+	require.NotNil(t, after.ChangeSet)
+	assert.Len(t, after.ChangeSet.Failed(), 1)
+
+	// the hook only observes ups
+	after = nil
+	_, err = s.Preview(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, after)
+}
+
+func TestStackHooksCanceled(t *testing.T) {
+	t.Parallel()
+
+	engine := &hookTestEngine{}
+	s := Stack{workspace: engine, stackName: "dev"}
+
+	var after *Operation
+	s.Use(AfterOperation(func(ctx context.Context, op *Operation) {
+		after = op
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := s.Up(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	require.NotNil(t, after)
+	assert.True(t, after.Canceled)
+}
+
+func TestStackHooksStop(t *testing.T) {
+	t.Parallel()
+
+	engine := &hookTestEngine{}
+	s := Stack{workspace: engine, stackName: "prod"}
+
+	denied := errors.New("denied")
+	var after *Operation
+	s.Use(
+		AfterOperation(func(ctx context.Context, op *Operation) {
+			after = op
+		}),
+		BeforeOperation(func(ctx context.Context, op *Operation) error {
+			return denied
+		}, OperationDestroy, OperationCancel),
+	)
+
+	_, err := s.Destroy(context.Background())
+	assert.ErrorIs(t, err, denied)
+	require.NotNil(t, after)
+	assert.Equal(t, OperationDestroy, after.Kind)
+	assert.ErrorIs(t, after.Err, denied)
+
+	// Cancel would run the CLI, so this also checks that it isn't run
+	err = s.Cancel(context.Background())
+	assert.ErrorIs(t, err, denied)
+	assert.Equal(t, OperationCancel, after.Kind)
+
+	_, err = s.Refresh(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"refresh"}, engine.ops)
+}
@@ -926,6 +926,19 @@ type GitRepo struct {
 	Setup SetupFn
 	// GitAuth is the different Authentication options for the Git repository
 	Auth *GitAuth
+	// Optional provider to fetch credentials on demand, e.g. from an SSH agent or a token service.
+	// It cannot be combined with Auth, and is only supported by local workspaces.
+	AuthProvider GitAuthProvider
+	// Shallow clones only the most recent commit of the requested branch, rather than the full history.
+	// It cannot be combined with CommitHash, and is only supported by local workspaces.
+	Shallow bool
+	// SparseCheckout checks out only the files under ProjectPath, which must be set.
+	// It is only supported by local workspaces.
+	SparseCheckout bool
+	// Optional directory holding mirrors of cloned repositories. Workspaces sharing a CacheDir fetch only
+	// what changed upstream since the last clone, and clone locally from the mirror.
+	// It is only supported by local workspaces.
+	CacheDir string
 }
 
 // GitAuth is the authentication details that can be specified for a private Git repo.
@@ -0,0 +1,172 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package optimport contains functional options to be used with stack import operations
+// github.com/sdk/v3/go/auto Stack.ImportResources(...optimport.Option)
+package optimport
+
+import (
+	"io"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/debug"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+)
+
+// NameTable maps the names used for parents and providers in the resources to import to their URNs.
+// These names are used in the generated code, and should match the corresponding declarations in the program.
+func NameTable(names map[string]string) Option {
+	return optionFunc(func(opts *Options) {
+		opts.NameTable = names
+	})
+}
+
+// Protect sets whether the imported resources are protected from deletion. Defaults to true.
+func Protect(protect bool) Option {
+	return optionFunc(func(opts *Options) {
+		opts.Protect = &protect
+	})
+}
+
+// GenerateCode sets whether code is generated for the imported resources. Defaults to true.
+func GenerateCode(generate bool) Option {
+	return optionFunc(func(opts *Options) {
+		opts.GenerateCode = &generate
+	})
+}
+
+// Languages specifies the languages to generate code in. Defaults to the language of the project.
+func Languages(languages ...string) Option {
+	return optionFunc(func(opts *Options) {
+		opts.Languages = languages
+	})
+}
+
+// Parallel is the number of resource operations to run in parallel at once during the import
+// (1 for no parallelism). Defaults to unbounded. (default 2147483647)
+func Parallel(n int) Option {
+	return optionFunc(func(opts *Options) {
+		opts.Parallel = n
+	})
+}
+
+// Message (optional) to associate with the import operation
+func Message(message string) Option {
+	return optionFunc(func(opts *Options) {
+		opts.Message = message
+	})
+}
+
+// ProgressStreams allows specifying one or more io.Writers to redirect incremental import stdout
+func ProgressStreams(writers ...io.Writer) Option {
+	return optionFunc(func(opts *Options) {
+		opts.ProgressStreams = writers
+	})
+}
+
+// ErrorProgressStreams allows specifying one or more io.Writers to redirect incremental import stderr
+func ErrorProgressStreams(writers ...io.Writer) Option {
+	return optionFunc(func(opts *Options) {
+		opts.ErrorProgressStreams = writers
+	})
+}
+
+// EventStreams allows specifying one or more channels to receive the Pulumi event stream
+func EventStreams(channels ...chan<- events.EngineEvent) Option {
+	return optionFunc(func(opts *Options) {
+		opts.EventStreams = channels
+	})
+}
+
+// EventSinks allows specifying one or more sinks to persist the Pulumi event stream, such as an events.LogFile.
+// The operation returns once every event has been written to the sinks.
+func EventSinks(sinks ...events.Sink) Option {
+	return optionFunc(func(opts *Options) {
+		opts.EventSinks = sinks
+	})
+}
+
+// DebugLogging provides options for verbose logging to standard error, and enabling plugin logs.
+func DebugLogging(debugOpts debug.LoggingOptions) Option {
+	return optionFunc(func(opts *Options) {
+		opts.DebugLogOpts = debugOpts
+	})
+}
+
+// UserAgent specifies the agent responsible for the update, stored in backends as "environment.exec.agent"
+func UserAgent(agent string) Option {
+	return optionFunc(func(opts *Options) {
+		opts.UserAgent = agent
+	})
+}
+
+// Color allows specifying whether to colorize output. Choices are: always, never, raw, auto (default "auto")
+func Color(color string) Option {
+	return optionFunc(func(opts *Options) {
+		opts.Color = color
+	})
+}
+
+// ShowSecrets configures whether to show config secrets when they appear in the config.
+func ShowSecrets(show bool) Option {
+	return optionFunc(func(opts *Options) {
+		opts.ShowSecrets = &show
+	})
+}
+
+// Option is a parameter to be applied to a Stack.ImportResources() operation
+type Option interface {
+	ApplyOption(*Options)
+}
+
+// ---------------------------------- implementation details ----------------------------------
+
+// Options is an implementation detail
+type Options struct {
+	// NameTable maps the names of parents and providers to their URNs
+	NameTable map[string]string
+	// Protect the imported resources from deletion. Defaults to true.
+	Protect *bool
+	// Generate code for the imported resources. Defaults to true.
+	GenerateCode *bool
+	// The languages to generate code in. Defaults to the language of the project.
+	Languages []string
+	// Parallel is the number of resource operations to run in parallel at once
+	// (1 for no parallelism). Defaults to unbounded. (default 2147483647)
+	Parallel int
+	// Message (optional) to associate with the import operation
+	Message string
+	// ProgressStreams allows specifying one or more io.Writers to redirect incremental import stdout
+	ProgressStreams []io.Writer
+	// ErrorProgressStreams allows specifying one or more io.Writers to redirect incremental import stderr
+	ErrorProgressStreams []io.Writer
+	// EventStreams allows specifying one or more channels to receive the Pulumi event stream
+	EventStreams []chan<- events.EngineEvent
+	// EventSinks allows specifying one or more sinks to persist the Pulumi event stream
+	EventSinks []events.Sink
+	// DebugLogOpts specifies additional settings for debug logging
+	DebugLogOpts debug.LoggingOptions
+	// UserAgent specifies the agent responsible for the update, stored in backends as "environment.exec.agent"
+	UserAgent string
+	// Colorize output. Choices are: always, never, raw, auto (default "auto")
+	Color string
+	// Show config secrets when they appear.
+	ShowSecrets *bool
+}
+
+type optionFunc func(*Options)
+
+// ApplyOption is an implementation detail
+func (o optionFunc) ApplyOption(opts *Options) {
+	o(opts)
+}
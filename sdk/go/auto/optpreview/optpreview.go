@@ -101,6 +101,14 @@ func EventStreams(channels ...chan<- events.EngineEvent) Option {
 	})
 }
 
+// EventSinks allows specifying one or more sinks to persist the Pulumi event stream, such as an events.LogFile.
+// The operation returns once every event has been written to the sinks.
+func EventSinks(sinks ...events.Sink) Option {
+	return optionFunc(func(opts *Options) {
+		opts.EventSinks = sinks
+	})
+}
+
 // UserAgent specifies the agent responsible for the update, stored in backends as "environment.exec.agent"
 func UserAgent(agent string) Option {
 	return optionFunc(func(opts *Options) {
@@ -147,6 +155,8 @@ type Options struct {
 	ErrorProgressStreams []io.Writer
 	// EventStreams allows specifying one or more channels to receive the Pulumi event stream
 	EventStreams []chan<- events.EngineEvent
+	// EventSinks allows specifying one or more sinks to persist the Pulumi event stream
+	EventSinks []events.Sink
 	// UserAgent specifies the agent responsible for the update, stored in backends as "environment.exec.agent"
 	UserAgent string
 	// Colorize output. Choices are: always, never, raw, auto (default "auto")
@@ -73,6 +73,14 @@ func EventStreams(channels ...chan<- events.EngineEvent) Option {
 	})
 }
 
+// EventSinks allows specifying one or more sinks to persist the Pulumi event stream, such as an events.LogFile.
+// The operation returns once every event has been written to the sinks.
+func EventSinks(sinks ...events.Sink) Option {
+	return optionFunc(func(opts *Options) {
+		opts.EventSinks = sinks
+	})
+}
+
 // DebugLogging provides options for verbose logging to standard error, and enabling plugin logs.
 func DebugLogging(debugOpts debug.LoggingOptions) Option {
 	return optionFunc(func(opts *Options) {
@@ -118,6 +126,8 @@ type Options struct {
 	ErrorProgressStreams []io.Writer
 	// EventStreams allows specifying one or more channels to receive the Pulumi event stream
 	EventStreams []chan<- events.EngineEvent
+	// EventSinks allows specifying one or more sinks to persist the Pulumi event stream
+	EventSinks []events.Sink
 	// DebugLogOpts specifies additional settings for debug logging
 	DebugLogOpts debug.LoggingOptions
 	// UserAgent specifies the agent responsible for the update, stored in backends as "environment.exec.agent"
@@ -0,0 +1,83 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package optstate contains functional options to be used with stack state operations
+// github.com/sdk/v3/go/auto Stack.StateDelete(...optstate.Option), Stack.StateUnprotect(...optstate.Option), etc.
+package optstate
+
+// Types only selects resources whose type matches one of the given globs, such as "aws:s3/**".
+// Used by StateDelete, StateUnprotect and StateRename.
+func Types(globs ...string) Option {
+	return optionFunc(func(opts *Options) {
+		opts.Types = globs
+	})
+}
+
+// Where only selects resources with properties that satisfy all the given predicates, of the form
+// "path", "path=value" or "path!=value". Used by StateDelete, StateUnprotect and StateRename.
+func Where(predicates ...string) Option {
+	return optionFunc(func(opts *Options) {
+		opts.Where = predicates
+	})
+}
+
+// Force deletes protected resources rather than failing. Used by StateDelete.
+func Force() Option {
+	return optionFunc(func(opts *Options) {
+		opts.Force = true
+	})
+}
+
+// TargetDependents deletes the resources that depend on the deleted resources too, rather than failing.
+// Used by StateDelete.
+func TargetDependents() Option {
+	return optionFunc(func(opts *Options) {
+		opts.TargetDependents = true
+	})
+}
+
+// All selects every resource in the stack. Used by StateUnprotect.
+func All() Option {
+	return optionFunc(func(opts *Options) {
+		opts.All = true
+	})
+}
+
+// Option is a parameter to be applied to a stack state operation
+type Option interface {
+	ApplyOption(*Options)
+}
+
+// ---------------------------------- implementation details ----------------------------------
+
+// Options is an implementation detail
+type Options struct {
+	// Only select resources whose type matches one of these globs
+	Types []string
+	// Only select resources with properties that satisfy all of these predicates
+	Where []string
+	// Delete protected resources
+	Force bool
+	// Delete the resources that depend on the deleted resources
+	TargetDependents bool
+	// Select every resource in the stack
+	All bool
+}
+
+type optionFunc func(*Options)
+
+// ApplyOption is an implementation detail
+func (o optionFunc) ApplyOption(opts *Options) {
+	o(opts)
+}
@@ -17,10 +17,12 @@
 package optup
 
 import (
+	"context"
 	"io"
 
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/debug"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
 )
 
 // Parallel is the number of resource operations to run in parallel at once during the update
@@ -101,6 +103,14 @@ func EventStreams(channels ...chan<- events.EngineEvent) Option {
 	})
 }
 
+// EventSinks allows specifying one or more sinks to persist the Pulumi event stream, such as an events.LogFile.
+// The operation returns once every event has been written to the sinks.
+func EventSinks(sinks ...events.Sink) Option {
+	return optionFunc(func(opts *Options) {
+		opts.EventSinks = sinks
+	})
+}
+
 // UserAgent specifies the agent responsible for the update, stored in backends as "environment.exec.agent"
 func UserAgent(agent string) Option {
 	return optionFunc(func(opts *Options) {
@@ -115,6 +125,15 @@ func Plan(path string) Option {
 	})
 }
 
+// WithPlan constrains the update to the given plan, such as the plan returned by Stack.PreviewPlan, which may have
+// been inspected and modified since. The update fails if it would perform an operation that the plan does not
+// allow. WithPlan takes precedence over Plan.
+func WithPlan(plan *apitype.DeploymentPlanV1) Option {
+	return optionFunc(func(opts *Options) {
+		opts.DeploymentPlan = plan
+	})
+}
+
 // ShowSecrets configures whether to show config secrets when they appear.
 func ShowSecrets(show bool) Option {
 	return optionFunc(func(opts *Options) {
@@ -122,6 +141,27 @@ func ShowSecrets(show bool) Option {
 	})
 }
 
+// RequireApproval requires approval before deleting or replacing resources that match any of the given patterns.
+// Patterns that begin with "urn:" match resource URNs; all other patterns match resource types. Wildcards (*, **) are
+// also supported. Approval is requested from the ApprovalFunc passed to Approver; if no patterns are given, every
+// delete and replacement requires approval.
+func RequireApproval(patterns ...string) Option {
+	return optionFunc(func(opts *Options) {
+		opts.RequireApproval = append(opts.RequireApproval, patterns...)
+	})
+}
+
+// Approver sets the function that is called to approve deletes and replacements during the update. Steps that the
+// function rejects are skipped and the affected resources are left as they are; the rest of the update continues.
+func Approver(approve ApprovalFunc) Option {
+	return optionFunc(func(opts *Options) {
+		opts.Approver = approve
+	})
+}
+
+// ApprovalFunc is called to approve a delete or replacement. It returns true if the step may proceed.
+type ApprovalFunc func(ctx context.Context, req apitype.StepApprovalRequest) bool
+
 // Option is a parameter to be applied to a Stack.Up() operation
 type Option interface {
 	ApplyOption(*Options)
@@ -154,18 +194,26 @@ type Options struct {
 	ErrorProgressStreams []io.Writer
 	// EventStreams allows specifying one or more channels to receive the Pulumi event stream
 	EventStreams []chan<- events.EngineEvent
+	// EventSinks allows specifying one or more sinks to persist the Pulumi event stream
+	EventSinks []events.Sink
 	// UserAgent specifies the agent responsible for the update, stored in backends as "environment.exec.agent"
 	UserAgent string
 	// Colorize output. Choices are: always, never, raw, auto (default "auto")
 	Color string
 	// Use the update plan at the given path.
 	Plan string
+	// Use the given update plan.
+	DeploymentPlan *apitype.DeploymentPlanV1
 	// Run one or more policy packs as part of this update
 	PolicyPacks []string
 	// Path to JSON file containing the config for the policy pack of the corresponding "--policy-pack" flag
 	PolicyPackConfigs []string
 	// Show config secrets when they appear.
 	ShowSecrets *bool
+	// Require approval for deletes and replacements of resources that match these patterns
+	RequireApproval []string
+	// Approver is called to approve deletes and replacements
+	Approver ApprovalFunc
 }
 
 type optionFunc func(*Options)
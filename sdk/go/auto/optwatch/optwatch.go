@@ -0,0 +1,93 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package optwatch contains functional options to be used with stack watch operations
+// github.com/sdk/v2/go/x/auto Stack.Watch(...optwatch.Option)
+package optwatch
+
+import (
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+)
+
+// Paths are the files and directories to watch for changes, including the files in their subdirectories. Relative
+// paths are relative to the workspace's WorkDir. For programs on disk, the WorkDir is watched by default, except for
+// the workspace's own project and stack settings files, such as Pulumi.<stack>.yaml.
+func Paths(paths ...string) Option {
+	return optionFunc(func(opts *Options) {
+		opts.Paths = paths
+	})
+}
+
+// Trigger is a channel that runs an update each time a value is received from it, for example when the data that an
+// inline program reads has changed.
+func Trigger(trigger <-chan struct{}) Option {
+	return optionFunc(func(opts *Options) {
+		opts.Trigger = trigger
+	})
+}
+
+// Debounce is how long to wait after a change to a watched path before running an update, so that several changes
+// made at once, such as by saving many files, run a single update. Defaults to 500 milliseconds.
+func Debounce(d time.Duration) Option {
+	return optionFunc(func(opts *Options) {
+		opts.Debounce = d
+	})
+}
+
+// UpOptions are the options to run each update with. They must not include EventStreams; use the EventStreams
+// option of the watch instead.
+func UpOptions(opts ...optup.Option) Option {
+	return optionFunc(func(o *Options) {
+		o.UpOptions = opts
+	})
+}
+
+// EventStreams allows specifying one or more channels to receive the Pulumi event stream of every update. The
+// channels are closed once the watch stops.
+func EventStreams(channels ...chan<- events.EngineEvent) Option {
+	return optionFunc(func(opts *Options) {
+		opts.EventStreams = channels
+	})
+}
+
+// Option is a parameter to be applied to a Stack.Watch() operation
+type Option interface {
+	ApplyOption(*Options)
+}
+
+// ---------------------------------- implementation details ----------------------------------
+
+// Options is an implementation detail
+type Options struct {
+	// Paths are the files and directories to watch for changes
+	Paths []string
+	// Trigger runs an update each time a value is received from it
+	Trigger <-chan struct{}
+	// Debounce is how long to wait after a change before running an update
+	Debounce time.Duration
+	// UpOptions are the options to run each update with
+	UpOptions []optup.Option
+	// EventStreams allows specifying one or more channels to receive the Pulumi event stream of every update
+	EventStreams []chan<- events.EngineEvent
+}
+
+type optionFunc func(*Options)
+
+// ApplyOption is an implementation detail
+func (o optionFunc) ApplyOption(opts *Options) {
+	o(opts)
+}
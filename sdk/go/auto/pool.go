@@ -0,0 +1,456 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optdestroy"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optrefresh"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/workspace"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// PoolStack describes a stack for a StackPool to operate on. The stack is created if it does not exist, either from
+// the project in WorkDir or, if Program is set, from an inline program.
+type PoolStack struct {
+	// Name is the name of the stack, which must be unique within the pool.
+	Name string
+	// WorkDir is the directory containing the stack's project. Stacks may share a directory.
+	WorkDir string
+	// ProjectName is the name of the project of an inline program.
+	ProjectName string
+	// Program is the stack's inline program.
+	Program pulumi.RunFunc
+	// DependsOn lists the names of the stacks in the pool that must be operated on before this one, such as the
+	// stack of a network that an application stack runs in. Destroy operates on stacks in the reverse order.
+	DependsOn []string
+	// Config is set on the stack before it is operated on.
+	Config ConfigMap
+	// Options are additional options for the stack's workspace.
+	Options []LocalWorkspaceOption
+	// Hooks are registered with the stack, as by Stack.Use, before it is operated on.
+	Hooks []StackHook
+}
+
+// StackPool runs operations on many stacks at once, in the order of their dependencies. Each stack is given its own
+// LocalWorkspace and its own PULUMI_HOME, which shares the plugins and credentials of the user's, so that concurrent
+// operations don't race on the files of a shared workspace.
+type StackPool struct {
+	stacks map[string]PoolStack
+	// names are the names of the stacks, in the order they were given.
+	names []string
+	opts  stackPoolOptions
+	// runStack runs an operation on a single stack. It is replaced in tests.
+	runStack func(ctx context.Context, s PoolStack, op StackOperation) error
+}
+
+// StackOperation is an operation that a StackPool runs on each of its stacks.
+type StackOperation func(ctx context.Context, stack Stack) error
+
+// StackPoolError is returned by a StackPool when the operation failed for one or more of its stacks.
+type StackPoolError struct {
+	// Errors maps the names of the stacks that the operation failed for to their errors.
+	Errors map[string]error
+	// Skipped lists the names of the stacks that the operation did not run on, either because it failed for a stack
+	// that they depend on, or because the pool stopped after a failure.
+	Skipped []string
+}
+
+func (e *StackPoolError) Error() string {
+	names := make([]string, 0, len(e.Errors))
+	for name := range e.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	msgs := make([]string, len(names))
+	for i, name := range names {
+		msgs[i] = fmt.Sprintf("%s: %v", name, e.Errors[name])
+	}
+	msg := fmt.Sprintf("operation failed for %d stack(s): %s", len(names), strings.Join(msgs, "; "))
+	if len(e.Skipped) > 0 {
+		msg += fmt.Sprintf(" (%d stack(s) skipped)", len(e.Skipped))
+	}
+	return msg
+}
+
+// NewStackPool creates a pool of the given stacks. It returns an error if the names of the stacks are not unique, or
+// if their dependencies name stacks that are not in the pool or form a cycle.
+func NewStackPool(stacks []PoolStack, opts ...StackPoolOption) (*StackPool, error) {
+	p := &StackPool{
+		stacks:   make(map[string]PoolStack, len(stacks)),
+		opts:     stackPoolOptions{MaxConcurrency: runtime.NumCPU()},
+		runStack: runPoolStack,
+	}
+	for _, o := range opts {
+		o.applyStackPoolOption(&p.opts)
+	}
+	if p.opts.MaxConcurrency < 1 {
+		return nil, errors.New("the maximum concurrency must be at least 1")
+	}
+
+	for _, s := range stacks {
+		if s.Name == "" {
+			return nil, errors.New("stack names must not be empty")
+		}
+		if _, has := p.stacks[s.Name]; has {
+			return nil, fmt.Errorf("stack %q is in the pool more than once", s.Name)
+		}
+		p.stacks[s.Name] = s
+		p.names = append(p.names, s.Name)
+	}
+	for _, s := range stacks {
+		for _, dep := range s.DependsOn {
+			if _, has := p.stacks[dep]; !has {
+				return nil, fmt.Errorf("stack %q depends on stack %q, which is not in the pool", s.Name, dep)
+			}
+		}
+	}
+	if err := p.checkCycles(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// checkCycles returns an error if the dependencies of the stacks form a cycle.
+func (p *StackPool) checkCycles() error {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("stack dependencies form a cycle: %s", strings.Join(append(path, name), " -> "))
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, dep := range p.stacks[name].DependsOn {
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+	for _, name := range p.names {
+		if err := visit(name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run runs the operation on each stack in the pool. A stack's operation starts once the operations on the stacks it
+// depends on have succeeded, with no more than the pool's maximum concurrency running at once.
+//
+// Unless the pool continues on errors, the pool stops starting operations after the first failure, but lets those
+// already running finish. Cancel the context to stop them too. If any operation fails, the error is a
+// *StackPoolError.
+func (p *StackPool) Run(ctx context.Context, op StackOperation) error {
+	return p.run(ctx, op, false /*reverse*/)
+}
+
+func (p *StackPool) run(ctx context.Context, op StackOperation, reverse bool) error {
+	// Each stack waits for the stacks it depends on or, when running in reverse, for the stacks that depend on it.
+	waitFor := map[string][]string{}
+	for _, name := range p.names {
+		for _, dep := range p.stacks[name].DependsOn {
+			if reverse {
+				waitFor[dep] = append(waitFor[dep], name)
+			} else {
+				waitFor[name] = append(waitFor[name], dep)
+			}
+		}
+	}
+
+	type outcome struct {
+		name string
+		err  error
+	}
+	outcomes := make(chan outcome)
+
+	result := &StackPoolError{Errors: map[string]error{}}
+	finished, failed := map[string]bool{}, map[string]bool{}
+	pending, running, stopped := append([]string{}, p.names...), 0, false
+	for len(pending) > 0 || running > 0 {
+		// Start the operations on the stacks whose dependencies have finished, in the order the stacks were given.
+		// Skipping a stack may let the stacks after it be skipped too, so keep going until nothing changes.
+		for progress := true; progress; {
+			progress = false
+			remaining := pending[:0]
+			for _, name := range pending {
+				ready, skip := true, stopped || ctx.Err() != nil
+				for _, dep := range waitFor[name] {
+					ready = ready && finished[dep]
+					skip = skip || failed[dep]
+				}
+				switch {
+				case skip:
+					finished[name], failed[name], progress = true, true, true
+					result.Skipped = append(result.Skipped, name)
+				case ready && running < p.opts.MaxConcurrency:
+					running++
+					go func(s PoolStack) {
+						outcomes <- outcome{name: s.Name, err: p.runStack(ctx, s, op)}
+					}(p.stacks[name])
+				default:
+					remaining = append(remaining, name)
+				}
+			}
+			pending = remaining
+		}
+		if running == 0 {
+			continue
+		}
+
+		o := <-outcomes
+		running--
+		finished[o.name] = true
+		if o.err != nil {
+			failed[o.name] = true
+			result.Errors[o.name] = o.err
+			stopped = !p.opts.ContinueOnError
+		}
+	}
+
+	if len(result.Errors) == 0 && len(result.Skipped) == 0 {
+		return nil
+	}
+	if len(result.Errors) == 0 {
+		// Stacks are only skipped without a failure if the context was cancelled.
+		return ctx.Err()
+	}
+	sort.Strings(result.Skipped)
+	return result
+}
+
+// runPoolStack opens the given stack in its own workspace and runs the operation on it.
+func runPoolStack(ctx context.Context, s PoolStack, op StackOperation) error {
+	lwOpts := &localWorkspaceOptions{}
+	for _, o := range s.Options {
+		o.applyLocalWorkspaceOption(lwOpts)
+	}
+	baseHome := lwOpts.PulumiHome
+	if baseHome == "" {
+		var err error
+		if baseHome, err = workspace.GetPulumiHomeDir(); err != nil {
+			return err
+		}
+	}
+	home, err := isolatedPulumiHome(baseHome)
+	if err != nil {
+		return fmt.Errorf("failed to create PULUMI_HOME: %w", err)
+	}
+	defer os.RemoveAll(home)
+
+	opts := append(append([]LocalWorkspaceOption{}, s.Options...), PulumiHome(home))
+	var stack Stack
+	if s.Program != nil {
+		stack, err = UpsertStackInlineSource(ctx, s.Name, s.ProjectName, s.Program, opts...)
+	} else {
+		stack, err = UpsertStackLocalSource(ctx, s.Name, s.WorkDir, opts...)
+	}
+	if err != nil {
+		return err
+	}
+	stack.Use(s.Hooks...)
+	if len(s.Config) > 0 {
+		if err := stack.SetAllConfig(ctx, s.Config); err != nil {
+			return fmt.Errorf("failed to set config: %w", err)
+		}
+	}
+	return op(ctx, stack)
+}
+
+// isolatedPulumiHome creates a PULUMI_HOME for a single stack, which shares the plugins and credentials of the given
+// PULUMI_HOME but has its own workspace and stack selection files.
+func isolatedPulumiHome(base string) (string, error) {
+	home, err := os.MkdirTemp("", "automation-pool-home-")
+	if err != nil {
+		return "", err
+	}
+
+	plugins := filepath.Join(base, workspace.PluginDir)
+	if err := os.MkdirAll(plugins, 0o700); err != nil {
+		contract.IgnoreError(os.RemoveAll(home))
+		return "", err
+	}
+	if err := os.Symlink(plugins, filepath.Join(home, workspace.PluginDir)); err != nil {
+		contract.IgnoreError(os.RemoveAll(home))
+		return "", err
+	}
+
+	creds, err := os.ReadFile(filepath.Join(base, "credentials.json"))
+	switch {
+	case err == nil:
+		err = os.WriteFile(filepath.Join(home, "credentials.json"), creds, 0o600)
+	case os.IsNotExist(err):
+		err = nil
+	}
+	if err != nil {
+		contract.IgnoreError(os.RemoveAll(home))
+		return "", err
+	}
+	return home, nil
+}
+
+// Preview previews each stack in the pool. EventStreams must not be given, since the streams would be closed by the
+// first stack to finish; use Run to give each stack its own options.
+func (p *StackPool) Preview(ctx context.Context, opts ...optpreview.Option) (map[string]PreviewResult, error) {
+	o := &optpreview.Options{}
+	for _, opt := range opts {
+		opt.ApplyOption(o)
+	}
+	if len(o.EventStreams) > 0 {
+		return nil, errors.New("event streams cannot be shared by the stacks of a pool")
+	}
+
+	var m sync.Mutex
+	results := map[string]PreviewResult{}
+	err := p.Run(ctx, func(ctx context.Context, s Stack) error {
+		res, err := s.Preview(ctx, opts...)
+		if err == nil {
+			m.Lock()
+			defer m.Unlock()
+			results[s.Name()] = res
+		}
+		return err
+	})
+	return results, err
+}
+
+// Up updates each stack in the pool. EventStreams must not be given, since the streams would be closed by the first
+// stack to finish; use Run to give each stack its own options.
+func (p *StackPool) Up(ctx context.Context, opts ...optup.Option) (map[string]UpResult, error) {
+	o := &optup.Options{}
+	for _, opt := range opts {
+		opt.ApplyOption(o)
+	}
+	if len(o.EventStreams) > 0 {
+		return nil, errors.New("event streams cannot be shared by the stacks of a pool")
+	}
+
+	var m sync.Mutex
+	results := map[string]UpResult{}
+	err := p.Run(ctx, func(ctx context.Context, s Stack) error {
+		res, err := s.Up(ctx, opts...)
+		if err == nil {
+			m.Lock()
+			defer m.Unlock()
+			results[s.Name()] = res
+		}
+		return err
+	})
+	return results, err
+}
+
+// Refresh refreshes each stack in the pool. EventStreams must not be given, since the streams would be closed by the
+// first stack to finish; use Run to give each stack its own options.
+func (p *StackPool) Refresh(ctx context.Context, opts ...optrefresh.Option) (map[string]RefreshResult, error) {
+	o := &optrefresh.Options{}
+	for _, opt := range opts {
+		opt.ApplyOption(o)
+	}
+	if len(o.EventStreams) > 0 {
+		return nil, errors.New("event streams cannot be shared by the stacks of a pool")
+	}
+
+	var m sync.Mutex
+	results := map[string]RefreshResult{}
+	err := p.Run(ctx, func(ctx context.Context, s Stack) error {
+		res, err := s.Refresh(ctx, opts...)
+		if err == nil {
+			m.Lock()
+			defer m.Unlock()
+			results[s.Name()] = res
+		}
+		return err
+	})
+	return results, err
+}
+
+// Destroy destroys each stack in the pool, in the reverse order of their dependencies so that a stack is destroyed
+// only after the stacks that depend on it. EventStreams must not be given, since the streams would be closed by the
+// first stack to finish.
+func (p *StackPool) Destroy(ctx context.Context, opts ...optdestroy.Option) (map[string]DestroyResult, error) {
+	o := &optdestroy.Options{}
+	for _, opt := range opts {
+		opt.ApplyOption(o)
+	}
+	if len(o.EventStreams) > 0 {
+		return nil, errors.New("event streams cannot be shared by the stacks of a pool")
+	}
+
+	var m sync.Mutex
+	results := map[string]DestroyResult{}
+	err := p.run(ctx, func(ctx context.Context, s Stack) error {
+		res, err := s.Destroy(ctx, opts...)
+		if err == nil {
+			m.Lock()
+			defer m.Unlock()
+			results[s.Name()] = res
+		}
+		return err
+	}, true /*reverse*/)
+	return results, err
+}
+
+// StackPoolOption is an option for a StackPool.
+type StackPoolOption interface {
+	applyStackPoolOption(*stackPoolOptions)
+}
+
+// MaxConcurrency is the maximum number of stacks to operate on at once. It defaults to the number of CPUs.
+func MaxConcurrency(n int) StackPoolOption {
+	return stackPoolOption(func(o *stackPoolOptions) {
+		o.MaxConcurrency = n
+	})
+}
+
+// ContinueOnError keeps the pool operating on the stacks that don't depend on a stack whose operation failed, rather
+// than stopping after the first failure.
+func ContinueOnError() StackPoolOption {
+	return stackPoolOption(func(o *stackPoolOptions) {
+		o.ContinueOnError = true
+	})
+}
+
+type stackPoolOptions struct {
+	MaxConcurrency  int
+	ContinueOnError bool
+}
+
+type stackPoolOption func(*stackPoolOptions)
+
+func (o stackPoolOption) applyStackPoolOption(opts *stackPoolOptions) {
+	o(opts)
+}
@@ -0,0 +1,146 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auto
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestStackPool creates a pool whose operations run on stacks without workspaces, and records the order in which
+// the operations started.
+func newTestStackPool(t *testing.T, stacks []PoolStack, opts ...StackPoolOption) (*StackPool, *[]string) {
+	p, err := NewStackPool(stacks, opts...)
+	require.NoError(t, err)
+
+	var m sync.Mutex
+	var started []string
+	p.runStack = func(ctx context.Context, s PoolStack, op StackOperation) error {
+		m.Lock()
+		started = append(started, s.Name)
+		m.Unlock()
+		return op(ctx, Stack{stackName: s.Name})
+	}
+	return p, &started
+}
+
+func TestNewStackPoolValidation(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewStackPool([]PoolStack{{Name: "a"}, {Name: "a"}})
+	assert.ErrorContains(t, err, `stack "a" is in the pool more than once`)
+
+	_, err = NewStackPool([]PoolStack{{Name: "a", DependsOn: []string{"b"}}})
+	assert.ErrorContains(t, err, `stack "a" depends on stack "b", which is not in the pool`)
+
+	_, err = NewStackPool([]PoolStack{
+		{Name: "a", DependsOn: []string{"b"}},
+		{Name: "b", DependsOn: []string{"c"}},
+		{Name: "c", DependsOn: []string{"a"}},
+	})
+	assert.ErrorContains(t, err, "stack dependencies form a cycle: a -> b -> c -> a")
+
+	_, err = NewStackPool([]PoolStack{{Name: "a"}}, MaxConcurrency(0))
+	assert.ErrorContains(t, err, "the maximum concurrency must be at least 1")
+}
+
+func TestStackPoolDependencyOrder(t *testing.T) {
+	t.Parallel()
+
+	stacks := []PoolStack{
+		{Name: "app1", DependsOn: []string{"network", "database"}},
+		{Name: "database", DependsOn: []string{"network"}},
+		{Name: "network"},
+		{Name: "app2", DependsOn: []string{"network"}},
+	}
+	p, started := newTestStackPool(t, stacks, MaxConcurrency(2))
+
+	require.NoError(t, p.Run(context.Background(), func(context.Context, Stack) error { return nil }))
+	require.Len(t, *started, 4)
+	index := map[string]int{}
+	for i, name := range *started {
+		index[name] = i
+	}
+	assert.Equal(t, 0, index["network"])
+	assert.Less(t, index["database"], index["app1"])
+
+	// Destroy runs in the reverse order.
+	*started = nil
+	require.NoError(t, p.run(context.Background(), func(context.Context, Stack) error { return nil }, true))
+	require.Len(t, *started, 4)
+	for i, name := range *started {
+		index[name] = i
+	}
+	assert.Equal(t, 3, index["network"])
+	assert.Less(t, index["app1"], index["database"])
+}
+
+func TestStackPoolFailures(t *testing.T) {
+	t.Parallel()
+
+	stacks := []PoolStack{
+		{Name: "network"},
+		{Name: "app", DependsOn: []string{"network"}},
+		{Name: "other"},
+	}
+	failNetwork := func(_ context.Context, s Stack) error {
+		if s.Name() == "network" {
+			return errors.New("boom")
+		}
+		return nil
+	}
+
+	// By default, the pool stops after the first failure.
+	p, started := newTestStackPool(t, stacks, MaxConcurrency(1))
+	err := p.Run(context.Background(), failNetwork)
+	var poolErr *StackPoolError
+	require.ErrorAs(t, err, &poolErr)
+	assert.Equal(t, []string{"network"}, *started)
+	assert.EqualError(t, poolErr.Errors["network"], "boom")
+	assert.Equal(t, []string{"app", "other"}, poolErr.Skipped)
+	assert.EqualError(t, err, "operation failed for 1 stack(s): network: boom (2 stack(s) skipped)")
+
+	// Continuing on errors still skips the stacks that depend on the failed stack.
+	p, started = newTestStackPool(t, stacks, MaxConcurrency(1), ContinueOnError())
+	err = p.Run(context.Background(), failNetwork)
+	require.ErrorAs(t, err, &poolErr)
+	assert.Equal(t, []string{"network", "other"}, *started)
+	assert.Equal(t, []string{"app"}, poolErr.Skipped)
+}
+
+func TestIsolatedPulumiHome(t *testing.T) {
+	t.Parallel()
+
+	base := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(base, "credentials.json"), []byte(`{"current":"x"}`), 0o600))
+
+	home, err := isolatedPulumiHome(base)
+	require.NoError(t, err)
+	defer os.RemoveAll(home)
+
+	creds, err := os.ReadFile(filepath.Join(home, "credentials.json"))
+	require.NoError(t, err)
+	assert.Equal(t, `{"current":"x"}`, string(creds))
+
+	require.NoError(t, os.WriteFile(filepath.Join(home, "plugins", "plugin"), nil, 0o600))
+	assert.FileExists(t, filepath.Join(base, "plugins", "plugin"))
+}
@@ -110,6 +110,12 @@ func remoteToLocalOptions(repo GitRepo, opts ...RemoteWorkspaceOption) ([]LocalW
 	if repo.URL == "" {
 		return nil, errors.New("repo.URL is required")
 	}
+	if repo.AuthProvider != nil {
+		return nil, errors.New("repo.AuthProvider cannot be used with remote workspaces")
+	}
+	if repo.Shallow || repo.SparseCheckout || repo.CacheDir != "" {
+		return nil, errors.New("repo.Shallow, repo.SparseCheckout and repo.CacheDir cannot be used with remote workspaces")
+	}
 	if repo.Branch != "" && repo.CommitHash != "" {
 		return nil, errors.New("repo.Branch and repo.CommitHash cannot both be specified")
 	}
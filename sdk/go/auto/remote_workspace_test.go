@@ -85,6 +85,16 @@ func testRemoteStackGitSourceErrors(t *testing.T, fn func(ctx context.Context, s
 			repo:  GitRepo{},
 			err:   "repo.URL is required",
 		},
+		"auth provider": {
+			stack: stack,
+			repo:  GitRepo{URL: remoteTestRepo, Branch: "branch", AuthProvider: GitSSHAgentAuth("git")},
+			err:   "repo.AuthProvider cannot be used with remote workspaces",
+		},
+		"shallow": {
+			stack: stack,
+			repo:  GitRepo{URL: remoteTestRepo, Branch: "branch", Shallow: true},
+			err:   "repo.Shallow, repo.SparseCheckout and repo.CacheDir cannot be used with remote workspaces",
+		},
 		"no branch or commit": {
 			stack: stack,
 			repo:  GitRepo{URL: remoteTestRepo},
@@ -116,6 +116,7 @@ import (
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optdestroy"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/opthistory"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optimport"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optrefresh"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
@@ -135,6 +136,8 @@ import (
 type Stack struct {
 	workspace Workspace
 	stackName string
+	// hooks are called around the stack's operations, outermost first.
+	hooks []StackHook
 }
 
 // FullyQualifiedStackName returns a stack name formatted with the greatest possible specificity:
@@ -207,11 +210,26 @@ func (s *Stack) Workspace() Workspace {
 // https://www.pulumi.com/docs/reference/cli/pulumi_preview/
 func (s *Stack) Preview(ctx context.Context, opts ...optpreview.Option) (PreviewResult, error) {
 	var res PreviewResult
+	err := s.runHooks(ctx, OperationPreview, func(ctx context.Context, op *Operation) error {
+		var err error
+		res, err = s.preview(ctx, opts...)
+		op.ChangeSummary, op.ChangeSet = res.ChangeSummary, &res.ChangeSet
+		return err
+	})
+	return res, err
+}
+
+// preview runs Preview, without the stack's hooks.
+func (s *Stack) preview(ctx context.Context, opts ...optpreview.Option) (PreviewResult, error) {
+	var res PreviewResult
 
 	preOpts := &optpreview.Options{}
 	for _, o := range opts {
 		o.ApplyOption(preOpts)
 	}
+	if engine, ok := s.Workspace().(StackEngine); ok {
+		return engine.PreviewStack(ctx, s.Name(), preOpts)
+	}
 
 	bufferSizeHint := len(preOpts.Replace) + len(preOpts.Target) +
 		len(preOpts.PolicyPacks) + len(preOpts.PolicyPackConfigs)
@@ -273,6 +291,7 @@ func (s *Stack) Preview(ctx context.Context, opts ...optpreview.Option) (Preview
 	args = append(args, sharedArgs...)
 
 	var summaryEvents []apitype.SummaryEvent
+	changes := NewChangeSetRecorder()
 	eventChannel := make(chan events.EngineEvent)
 	eventsDone := make(chan bool)
 	go func() {
@@ -282,6 +301,7 @@ func (s *Stack) Preview(ctx context.Context, opts ...optpreview.Option) (Preview
 				close(eventsDone)
 				return
 			}
+			changes.Record(event)
 			if event.SummaryEvent != nil {
 				summaryEvents = append(summaryEvents, *event.SummaryEvent)
 			}
@@ -291,11 +311,11 @@ func (s *Stack) Preview(ctx context.Context, opts ...optpreview.Option) (Preview
 	eventChannels := []chan<- events.EngineEvent{eventChannel}
 	eventChannels = append(eventChannels, preOpts.EventStreams...)
 
-	t, err := tailLogs("preview", eventChannels)
+	t, err := tailEvents("preview", eventChannels, preOpts.EventSinks)
 	if err != nil {
 		return res, fmt.Errorf("failed to tail logs: %w", err)
 	}
-	defer t.Close()
+	defer contract.IgnoreClose(t)
 	args = append(args, "--event-log", t.Filename)
 
 	stdout, stderr, code, err := s.runPulumiCmdSync(
@@ -304,14 +324,15 @@ func (s *Stack) Preview(ctx context.Context, opts ...optpreview.Option) (Preview
 		preOpts.ErrorProgressStreams, /* additionalErrorOutput */
 		args...,
 	)
+	// Close the file watcher wait for all events to send
+	eventsErr := t.Close()
+	<-eventsDone
+	// The changes are returned even if the preview fails, so that its hooks can see how far it got.
+	res.ChangeSet = changes.ChangeSet()
 	if err != nil {
 		return res, newAutoError(fmt.Errorf("failed to run preview: %w", err), stdout, stderr, code)
 	}
 
-	// Close the file watcher wait for all events to send
-	t.Close()
-	<-eventsDone
-
 	if len(summaryEvents) == 0 {
 		return res, newAutoError(errors.New("failed to get preview summary"), stdout, stderr, code)
 	}
@@ -323,18 +344,97 @@ func (s *Stack) Preview(ctx context.Context, opts ...optpreview.Option) (Preview
 	res.StdErr = stderr
 	res.ChangeSummary = summaryEvents[0].ResourceChanges
 
-	return res, nil
+	return res, eventsErr
+}
+
+// PreviewPlanResult is the output of Stack.PreviewPlan.
+type PreviewPlanResult struct {
+	PreviewResult
+	// Plan describes the operations that the previewed update would perform on each resource. It may be inspected
+	// and modified, and then given to Stack.Up with optup.WithPlan to constrain the update to it.
+	Plan *apitype.DeploymentPlanV1
+}
+
+// PreviewPlan previews an update to the stack, like Preview, and returns a plan of the update's operations. If the
+// Plan option is given, the plan is also saved to that path. Secret values in the plan are encrypted with the stack's
+// secrets provider.
+func (s *Stack) PreviewPlan(ctx context.Context, opts ...optpreview.Option) (PreviewPlanResult, error) {
+	preOpts := &optpreview.Options{}
+	for _, o := range opts {
+		o.ApplyOption(preOpts)
+	}
+
+	planPath := preOpts.Plan
+	if planPath == "" {
+		planDir, err := os.MkdirTemp("", "automation-plan-")
+		if err != nil {
+			return PreviewPlanResult{}, fmt.Errorf("failed to create plan directory: %w", err)
+		}
+		defer os.RemoveAll(planDir)
+		planPath = filepath.Join(planDir, "plan.json")
+		opts = append(opts[:len(opts):len(opts)], optpreview.Plan(planPath))
+	}
+
+	preview, err := s.Preview(ctx, opts...)
+	if err != nil {
+		return PreviewPlanResult{}, err
+	}
+	plan, err := readPlanFile(planPath)
+	if err != nil {
+		return PreviewPlanResult{}, fmt.Errorf("failed to read plan: %w", err)
+	}
+	return PreviewPlanResult{PreviewResult: preview, Plan: plan}, nil
+}
+
+// readPlanFile reads a plan saved by a preview.
+func readPlanFile(path string) (*apitype.DeploymentPlanV1, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var plan apitype.DeploymentPlanV1
+	if err := json.Unmarshal(b, &plan); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// writePlanFile writes a plan in the format that an update reads.
+func writePlanFile(path string, plan *apitype.DeploymentPlanV1) error {
+	b, err := json.Marshal(plan)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o600)
 }
 
 // Up creates or updates the resources in a stack by executing the program in the Workspace.
 // https://www.pulumi.com/docs/reference/cli/pulumi_up/
 func (s *Stack) Up(ctx context.Context, opts ...optup.Option) (UpResult, error) {
 	var res UpResult
+	err := s.runHooks(ctx, OperationUp, func(ctx context.Context, op *Operation) error {
+		var err error
+		res, err = s.up(ctx, opts...)
+		op.ChangeSet = &res.ChangeSet
+		if err == nil {
+			op.Summary, op.Outputs = &res.Summary, res.Outputs
+		}
+		return err
+	})
+	return res, err
+}
+
+// up runs Up, without the stack's hooks.
+func (s *Stack) up(ctx context.Context, opts ...optup.Option) (UpResult, error) {
+	var res UpResult
 
 	upOpts := &optup.Options{}
 	for _, o := range opts {
 		o.ApplyOption(upOpts)
 	}
+	if engine, ok := s.Workspace().(StackEngine); ok {
+		return engine.UpStack(ctx, s.Name(), upOpts)
+	}
 
 	bufferSizeHint := len(upOpts.Replace) + len(upOpts.Target) + len(upOpts.PolicyPacks) + len(upOpts.PolicyPackConfigs)
 	sharedArgs := make([]string, 0, bufferSizeHint)
@@ -373,9 +473,23 @@ func (s *Stack) Up(ctx context.Context, opts ...optup.Option) (UpResult, error)
 	if upOpts.Color != "" {
 		sharedArgs = append(sharedArgs, fmt.Sprintf("--color=%s", upOpts.Color))
 	}
-	if upOpts.Plan != "" {
+	if upOpts.DeploymentPlan != nil {
+		planDir, err := os.MkdirTemp("", "automation-plan-")
+		if err != nil {
+			return res, fmt.Errorf("failed to write plan: %w", err)
+		}
+		defer os.RemoveAll(planDir)
+		planPath := filepath.Join(planDir, "plan.json")
+		if err := writePlanFile(planPath, upOpts.DeploymentPlan); err != nil {
+			return res, fmt.Errorf("failed to write plan: %w", err)
+		}
+		sharedArgs = append(sharedArgs, fmt.Sprintf("--plan=%s", planPath))
+	} else if upOpts.Plan != "" {
 		sharedArgs = append(sharedArgs, fmt.Sprintf("--plan=%s", upOpts.Plan))
 	}
+	for _, pattern := range upOpts.RequireApproval {
+		sharedArgs = append(sharedArgs, fmt.Sprintf("--require-approval=%s", pattern))
+	}
 
 	// Apply the remote args, if needed.
 	sharedArgs = append(sharedArgs, s.remoteArgs()...)
@@ -392,18 +506,43 @@ func (s *Stack) Up(ctx context.Context, opts ...optup.Option) (UpResult, error)
 	}
 	args = append(args, fmt.Sprintf("--exec-kind=%s", kind))
 
-	if len(upOpts.EventStreams) > 0 {
-		eventChannels := upOpts.EventStreams
-		t, err := tailLogs("up", eventChannels)
+	if upOpts.Approver != nil {
+		server, err := startApprovalServer(ctx, upOpts.Approver)
 		if err != nil {
-			return res, fmt.Errorf("failed to tail logs: %w", err)
+			return res, err
 		}
-		defer t.Close()
-		args = append(args, "--event-log", t.Filename)
+		defer contract.IgnoreClose(server)
+
+		args = append(args, "--approval-callback="+server.url)
 	}
 
+	changes := NewChangeSetRecorder()
+	eventChannel := make(chan events.EngineEvent)
+	eventsDone := make(chan bool)
+	go func() {
+		for event := range eventChannel {
+			changes.Record(event)
+		}
+		close(eventsDone)
+	}()
+
+	eventChannels := []chan<- events.EngineEvent{eventChannel}
+	eventChannels = append(eventChannels, upOpts.EventStreams...)
+
+	t, err := tailEvents("up", eventChannels, upOpts.EventSinks)
+	if err != nil {
+		return res, fmt.Errorf("failed to tail logs: %w", err)
+	}
+	defer contract.IgnoreClose(t)
+	args = append(args, "--event-log", t.Filename)
+
 	args = append(args, sharedArgs...)
 	stdout, stderr, code, err := s.runPulumiCmdSync(ctx, upOpts.ProgressStreams, upOpts.ErrorProgressStreams, args...)
+	// Close the file watcher and wait for all events to be recorded.
+	eventsErr := t.Close()
+	<-eventsDone
+	// The changes are returned even if the update fails, so that the resources that failed can be found.
+	res.ChangeSet = changes.ChangeSet()
 	if err != nil {
 		return res, newAutoError(fmt.Errorf("failed to run update: %w", err), stdout, stderr, code)
 	}
@@ -427,28 +566,41 @@ func (s *Stack) Up(ctx context.Context, opts ...optup.Option) (UpResult, error)
 		return res, err
 	}
 
-	res = UpResult{
-		Outputs: outs,
-		StdOut:  stdout,
-		StdErr:  stderr,
-	}
+	res.Outputs, res.StdOut, res.StdErr = outs, stdout, stderr
 
 	if len(history) > 0 {
 		res.Summary = history[0]
 	}
 
-	return res, nil
+	return res, eventsErr
 }
 
 // Refresh compares the current stack’s resource state with the state known to exist in the actual
 // cloud provider. Any such changes are adopted into the current stack.
 func (s *Stack) Refresh(ctx context.Context, opts ...optrefresh.Option) (RefreshResult, error) {
 	var res RefreshResult
+	err := s.runHooks(ctx, OperationRefresh, func(ctx context.Context, op *Operation) error {
+		var err error
+		res, err = s.refresh(ctx, opts...)
+		if err == nil {
+			op.Summary = &res.Summary
+		}
+		return err
+	})
+	return res, err
+}
+
+// refresh runs Refresh, without the stack's hooks.
+func (s *Stack) refresh(ctx context.Context, opts ...optrefresh.Option) (RefreshResult, error) {
+	var res RefreshResult
 
 	refreshOpts := &optrefresh.Options{}
 	for _, o := range opts {
 		o.ApplyOption(refreshOpts)
 	}
+	if engine, ok := s.Workspace().(StackEngine); ok {
+		return engine.RefreshStack(ctx, s.Name(), refreshOpts)
+	}
 
 	args := make([]string, 0, len(refreshOpts.Target))
 
@@ -478,13 +630,14 @@ func (s *Stack) Refresh(ctx context.Context, opts ...optrefresh.Option) (Refresh
 	}
 	args = append(args, fmt.Sprintf("--exec-kind=%s", execKind))
 
-	if len(refreshOpts.EventStreams) > 0 {
-		eventChannels := refreshOpts.EventStreams
-		t, err := tailLogs("refresh", eventChannels)
+	var t *eventTail
+	if len(refreshOpts.EventStreams) > 0 || len(refreshOpts.EventSinks) > 0 {
+		var err error
+		t, err = tailEvents("refresh", refreshOpts.EventStreams, refreshOpts.EventSinks)
 		if err != nil {
 			return res, fmt.Errorf("failed to tail logs: %w", err)
 		}
-		defer t.Close()
+		defer contract.IgnoreClose(t)
 		args = append(args, "--event-log", t.Filename)
 	}
 
@@ -497,6 +650,8 @@ func (s *Stack) Refresh(ctx context.Context, opts ...optrefresh.Option) (Refresh
 		refreshOpts.ErrorProgressStreams, /* additionalErrorOutputs */
 		args...,
 	)
+	// Wait for all events to be sent to the streams and sinks.
+	eventsErr := t.Close()
 	if err != nil {
 		return res, newAutoError(fmt.Errorf("failed to refresh stack: %w", err), stdout, stderr, code)
 	}
@@ -526,17 +681,34 @@ func (s *Stack) Refresh(ctx context.Context, opts ...optrefresh.Option) (Refresh
 		StdErr:  stderr,
 	}
 
-	return res, nil
+	return res, eventsErr
 }
 
 // Destroy deletes all resources in a stack, leaving all history and configuration intact.
 func (s *Stack) Destroy(ctx context.Context, opts ...optdestroy.Option) (DestroyResult, error) {
 	var res DestroyResult
+	err := s.runHooks(ctx, OperationDestroy, func(ctx context.Context, op *Operation) error {
+		var err error
+		res, err = s.destroy(ctx, opts...)
+		if err == nil {
+			op.Summary = &res.Summary
+		}
+		return err
+	})
+	return res, err
+}
+
+// destroy runs Destroy, without the stack's hooks.
+func (s *Stack) destroy(ctx context.Context, opts ...optdestroy.Option) (DestroyResult, error) {
+	var res DestroyResult
 
 	destroyOpts := &optdestroy.Options{}
 	for _, o := range opts {
 		o.ApplyOption(destroyOpts)
 	}
+	if engine, ok := s.Workspace().(StackEngine); ok {
+		return engine.DestroyStack(ctx, s.Name(), destroyOpts)
+	}
 
 	args := make([]string, 0, len(destroyOpts.Target))
 
@@ -566,13 +738,14 @@ func (s *Stack) Destroy(ctx context.Context, opts ...optdestroy.Option) (Destroy
 	}
 	args = append(args, fmt.Sprintf("--exec-kind=%s", execKind))
 
-	if len(destroyOpts.EventStreams) > 0 {
-		eventChannels := destroyOpts.EventStreams
-		t, err := tailLogs("destroy", eventChannels)
+	var t *eventTail
+	if len(destroyOpts.EventStreams) > 0 || len(destroyOpts.EventSinks) > 0 {
+		var err error
+		t, err = tailEvents("destroy", destroyOpts.EventStreams, destroyOpts.EventSinks)
 		if err != nil {
 			return res, fmt.Errorf("failed to tail logs: %w", err)
 		}
-		defer t.Close()
+		defer contract.IgnoreClose(t)
 		args = append(args, "--event-log", t.Filename)
 	}
 
@@ -585,6 +758,8 @@ func (s *Stack) Destroy(ctx context.Context, opts ...optdestroy.Option) (Destroy
 		destroyOpts.ErrorProgressStreams, /* additionalErrorOutputs */
 		args...,
 	)
+	// Wait for all events to be sent to the streams and sinks.
+	eventsErr := t.Close()
 	if err != nil {
 		return res, newAutoError(fmt.Errorf("failed to destroy stack: %w", err), stdout, stderr, code)
 	}
@@ -614,7 +789,177 @@ func (s *Stack) Destroy(ctx context.Context, opts ...optdestroy.Option) (Destroy
 		StdErr:  stderr,
 	}
 
-	return res, nil
+	return res, eventsErr
+}
+
+// ImportResource describes an existing cloud resource to import into a stack with Stack.ImportResources,
+// in the format of the resources in the file given to `pulumi import --file`.
+type ImportResource struct {
+	// The type token of the resource.
+	Type string `json:"type"`
+	// The name of the resource.
+	Name string `json:"name"`
+	// The ID of the resource, in the format specific to its type.
+	ID string `json:"id"`
+	// The name of the parent of the resource in the name table, if any.
+	Parent string `json:"parent,omitempty"`
+	// The name of the provider of the resource in the name table, if it is not the default provider.
+	Provider string `json:"provider,omitempty"`
+	// The version of the provider to import the resource with.
+	Version string `json:"version,omitempty"`
+	// The URL to download the provider plugin from.
+	PluginDownloadURL string `json:"pluginDownloadUrl,omitempty"`
+	// The input properties to import the resource with. Defaults to the required properties.
+	Properties []string `json:"properties,omitempty"`
+}
+
+// ImportResources imports existing cloud resources into the stack, and generates the code that declares them.
+func (s *Stack) ImportResources(
+	ctx context.Context, resources []ImportResource, opts ...optimport.Option,
+) (ImportResult, error) {
+	var res ImportResult
+	err := s.runHooks(ctx, OperationImport, func(ctx context.Context, op *Operation) error {
+		var err error
+		res, err = s.importResources(ctx, resources, opts...)
+		if err == nil {
+			op.Summary = &res.Summary
+		}
+		return err
+	})
+	return res, err
+}
+
+// importCodeExtensions are the extensions of the files that `pulumi import` writes the code in each language to when
+// --out is a directory.
+var importCodeExtensions = map[string]string{
+	"dotnet": ".cs",
+	"go":     ".go",
+	"nodejs": ".ts",
+	"python": ".py",
+	"java":   ".java",
+	"yaml":   ".yaml",
+}
+
+// importResources runs ImportResources, without the stack's hooks.
+func (s *Stack) importResources(
+	ctx context.Context, resources []ImportResource, opts ...optimport.Option,
+) (ImportResult, error) {
+	var res ImportResult
+
+	importOpts := &optimport.Options{}
+	for _, o := range opts {
+		o.ApplyOption(importOpts)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "pulumi-import-")
+	if err != nil {
+		return res, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	importFile, err := json.Marshal(map[string]interface{}{
+		"nameTable": importOpts.NameTable,
+		"resources": resources,
+	})
+	if err != nil {
+		return res, fmt.Errorf("failed to marshal resources to import: %w", err)
+	}
+	importFilePath := filepath.Join(tmpDir, "import.json")
+	if err = os.WriteFile(importFilePath, importFile, 0o600); err != nil {
+		return res, fmt.Errorf("failed to write import file: %w", err)
+	}
+	codeDir := filepath.Join(tmpDir, "code")
+	if err = os.Mkdir(codeDir, 0o700); err != nil {
+		return res, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+
+	args := debug.AddArgs(&importOpts.DebugLogOpts, nil)
+	args = append(args, "import", "--yes", "--skip-preview", "--file", importFilePath, "--out", codeDir)
+	if importOpts.Message != "" {
+		args = append(args, fmt.Sprintf("--message=%q", importOpts.Message))
+	}
+	if importOpts.Protect != nil {
+		args = append(args, fmt.Sprintf("--protect=%t", *importOpts.Protect))
+	}
+	if importOpts.GenerateCode != nil {
+		args = append(args, fmt.Sprintf("--generate-code=%t", *importOpts.GenerateCode))
+	}
+	for _, language := range importOpts.Languages {
+		args = append(args, fmt.Sprintf("--language=%s", language))
+	}
+	if importOpts.Parallel > 0 {
+		args = append(args, fmt.Sprintf("--parallel=%d", importOpts.Parallel))
+	}
+	if importOpts.UserAgent != "" {
+		args = append(args, fmt.Sprintf("--exec-agent=%s", importOpts.UserAgent))
+	}
+	if importOpts.Color != "" {
+		args = append(args, fmt.Sprintf("--color=%s", importOpts.Color))
+	}
+	execKind := constant.ExecKindAutoLocal
+	if s.Workspace().Program() != nil {
+		execKind = constant.ExecKindAutoInline
+	}
+	args = append(args, fmt.Sprintf("--exec-kind=%s", execKind))
+
+	var t *eventTail
+	if len(importOpts.EventStreams) > 0 || len(importOpts.EventSinks) > 0 {
+		var err error
+		t, err = tailEvents("import", importOpts.EventStreams, importOpts.EventSinks)
+		if err != nil {
+			return res, fmt.Errorf("failed to tail logs: %w", err)
+		}
+		defer contract.IgnoreClose(t)
+		args = append(args, "--event-log", t.Filename)
+	}
+
+	stdout, stderr, code, err := s.runPulumiCmdSync(
+		ctx,
+		importOpts.ProgressStreams,      /* additionalOutputs */
+		importOpts.ErrorProgressStreams, /* additionalErrorOutputs */
+		args...,
+	)
+	// Wait for all events to be sent to the streams and sinks.
+	eventsErr := t.Close()
+	if err != nil {
+		return res, newAutoError(fmt.Errorf("failed to import resources: %w", err), stdout, stderr, code)
+	}
+
+	// The code in each language is written to a file named after the language, e.g. nodejs.ts.
+	generatedCode := map[string]string{}
+	for language, ext := range importCodeExtensions {
+		contents, err := os.ReadFile(filepath.Join(codeDir, language+ext))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return res, fmt.Errorf("failed to read generated code: %w", err)
+		}
+		generatedCode[language] = string(contents)
+	}
+
+	historyOpts := []opthistory.Option{}
+	if showSecrets := importOpts.ShowSecrets; showSecrets != nil {
+		historyOpts = append(historyOpts, opthistory.ShowSecrets(*showSecrets))
+	}
+	history, err := s.History(ctx, 1 /*pageSize*/, 1 /*page*/, historyOpts...)
+	if err != nil {
+		return res, fmt.Errorf("failed to import resources: %w", err)
+	}
+
+	var summary UpdateSummary
+	if len(history) > 0 {
+		summary = history[0]
+	}
+
+	res = ImportResult{
+		GeneratedCode: generatedCode,
+		Summary:       summary,
+		StdOut:        stdout,
+		StdErr:        stderr,
+	}
+
+	return res, eventsErr
 }
 
 // Outputs get the current set of Stack outputs from the last Stack.Up().
@@ -631,6 +976,9 @@ func (s *Stack) History(ctx context.Context,
 	for _, opt := range opts {
 		opt.ApplyOption(&options)
 	}
+	if engine, ok := s.Workspace().(StackEngine); ok {
+		return engine.StackHistory(ctx, s.Name(), pageSize, page, &options)
+	}
 	showSecrets := true
 	if options.ShowSecrets != nil {
 		showSecrets = *options.ShowSecrets
@@ -771,6 +1119,13 @@ func (s *Stack) Info(ctx context.Context) (StackSummary, error) {
 // if a resource operation was pending when the update was canceled.
 // This command is not supported for local backends.
 func (s *Stack) Cancel(ctx context.Context) error {
+	return s.runHooks(ctx, OperationCancel, func(ctx context.Context, _ *Operation) error {
+		return s.cancel(ctx)
+	})
+}
+
+// cancel runs Cancel, without the stack's hooks.
+func (s *Stack) cancel(ctx context.Context) error {
 	stdout, stderr, errCode, err := s.runPulumiCmdSync(
 		ctx,
 		nil, /* additionalOutput */
@@ -795,6 +1150,26 @@ func (s *Stack) Import(ctx context.Context, state apitype.UntypedDeployment) err
 	return s.Workspace().ImportStack(ctx, s.Name(), state)
 }
 
+// QueryState returns the resources in the stack's state that match the given expression, in the syntax of
+// `pulumi state query`, e.g. `type == "aws:s3/bucket:Bucket" and outputs.tags.env == "prod"`.
+// Secret values are returned as "[secret]".
+func (s *Stack) QueryState(ctx context.Context, expr string) ([]apitype.ResourceV3, error) {
+	stdout, stderr, errCode, err := s.runPulumiCmdSync(
+		ctx,
+		nil, /* additionalOutput */
+		nil, /* additionalErrorOutput */
+		"state", "query", "--json", "--", expr)
+	if err != nil {
+		return nil, newAutoError(fmt.Errorf("failed to query state: %w", err), stdout, stderr, errCode)
+	}
+
+	var resources []apitype.ResourceV3
+	if err = json.Unmarshal([]byte(stdout), &resources); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal state query result: %w", err)
+	}
+	return resources, nil
+}
+
 // UpdateSummary provides a summary of a Stack lifecycle operation (up/preview/refresh/destroy).
 type UpdateSummary struct {
 	Version     int               `json:"version"`
@@ -823,6 +1198,9 @@ type UpResult struct {
 	StdErr  string
 	Outputs OutputMap
 	Summary UpdateSummary
+	// ChangeSet describes the changes that the update made to each resource. It is also returned, along with the
+	// error, if the update fails.
+	ChangeSet ChangeSet
 }
 
 // GetPermalink returns the permalink URL in the Pulumi Console for the update operation.
@@ -892,6 +1270,9 @@ type PreviewResult struct {
 	StdOut        string
 	StdErr        string
 	ChangeSummary map[apitype.OpType]int
+	// ChangeSet describes the changes that the preview found for each resource. It is also returned, along with the
+	// error, if the preview fails.
+	ChangeSet ChangeSet
 }
 
 // GetPermalink returns the permalink URL in the Pulumi Console for the preview operation.
@@ -911,6 +1292,20 @@ func (rr *RefreshResult) GetPermalink() (string, error) {
 	return GetPermalink(rr.StdOut)
 }
 
+// ImportResult is the output of a successful Stack.ImportResources operation
+type ImportResult struct {
+	// GeneratedCode maps each language that code was generated in to the code that declares the imported resources.
+	GeneratedCode map[string]string
+	StdOut        string
+	StdErr        string
+	Summary       UpdateSummary
+}
+
+// GetPermalink returns the permalink URL in the Pulumi Console for the import operation.
+func (ir *ImportResult) GetPermalink() (string, error) {
+	return GetPermalink(ir.StdOut)
+}
+
 // DestroyResult is the output of a successful Stack.Destroy operation
 type DestroyResult struct {
 	StdOut  string
@@ -932,6 +1327,17 @@ func (s *Stack) runPulumiCmdSync(
 	additionalErrorOutput []io.Writer,
 	args ...string,
 ) (string, string, int, error) {
+	if _, ok := s.Workspace().(StackEngine); ok {
+		var command string
+		for _, arg := range args {
+			if !strings.HasPrefix(arg, "-") {
+				command = arg
+				break
+			}
+		}
+		return "", "", -1, fmt.Errorf("running pulumi %s: %w", command, ErrUnsupportedByEngine)
+	}
+
 	var env []string
 	debugEnv := fmt.Sprintf("%s=%s", "PULUMI_DEBUG_COMMANDS", "true")
 	env = append(env, debugEnv)
@@ -959,8 +1365,7 @@ func (s *Stack) runPulumiCmdSync(
 	if err != nil {
 		return "", "", -1, fmt.Errorf("failed to exec command, error getting additional args: %w", err)
 	}
-	args = append(args, additionalArgs...)
-	args = append(args, "--stack", s.Name())
+	args = withFlags(args, append(additionalArgs, "--stack", s.Name())...)
 
 	stdout, stderr, errCode, err := runPulumiCommandSync(
 		ctx,
@@ -1078,6 +1483,8 @@ type languageRuntimeServer struct {
 	state  int
 	cancel chan bool
 	done   <-chan error
+	// abort is closed to cancel a running program.
+	abort chan struct{}
 }
 
 // isNestedInvocation returns true if pulumi.RunWithContext is on the stack.
@@ -1107,6 +1514,7 @@ func startLanguageRuntimeServer(fn pulumi.RunFunc) (*languageRuntimeServer, erro
 	s := &languageRuntimeServer{
 		fn:     fn,
 		cancel: make(chan bool),
+		abort:  make(chan struct{}),
 	}
 	s.c = sync.NewCond(&s.m)
 
@@ -1125,6 +1533,12 @@ func startLanguageRuntimeServer(fn pulumi.RunFunc) (*languageRuntimeServer, erro
 	return s, nil
 }
 
+// Abort cancels the program if it is running, and then closes the server.
+func (s *languageRuntimeServer) Abort() error {
+	close(s.abort)
+	return s.Close()
+}
+
 func (s *languageRuntimeServer) Close() error {
 	s.m.Lock()
 	switch s.state {
@@ -1168,6 +1582,16 @@ func (s *languageRuntimeServer) Run(ctx context.Context, req *pulumirpc.RunReque
 		s.c.Broadcast()
 	}()
 
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-s.abort:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
 	var engineAddress string
 	if len(req.Args) > 0 {
 		engineAddress = req.Args[0]
@@ -1271,6 +1695,48 @@ func watchFile(path string, receivers []chan<- events.EngineEvent) (*fileWatcher
 	}, nil
 }
 
+// eventTail tails the event log of an operation into its event streams and sinks.
+type eventTail struct {
+	*fileWatcher
+	sinksDone func() error
+}
+
+// tailEvents tails the event log of the given command into the given streams and sinks.
+func tailEvents(command string, streams []chan<- events.EngineEvent, sinks []events.Sink) (*eventTail, error) {
+	var sinksDone func() error
+	if len(sinks) > 0 {
+		var sinkStream chan<- events.EngineEvent
+		sinkStream, sinksDone = events.SinkStream(sinks...)
+		streams = append(append([]chan<- events.EngineEvent{}, streams...), sinkStream)
+	}
+
+	t, err := tailLogs(command, streams)
+	if err != nil {
+		if sinksDone != nil {
+			// Nothing will send events to the sinks, so close their stream.
+			close(streams[len(streams)-1])
+		}
+		return nil, err
+	}
+	return &eventTail{fileWatcher: t, sinksDone: sinksDone}, nil
+}
+
+// Close waits for every event to be read from the log and written to the sinks, and returns the first error returned
+// by the sinks. It may be called more than once, and on a nil tail.
+func (t *eventTail) Close() error {
+	if t == nil {
+		return nil
+	}
+	t.fileWatcher.Close()
+	if t.sinksDone == nil {
+		return nil
+	}
+	if err := t.sinksDone(); err != nil {
+		return fmt.Errorf("failed to write events to sink: %w", err)
+	}
+	return nil
+}
+
 func tailLogs(command string, receivers []chan<- events.EngineEvent) (*fileWatcher, error) {
 	logDir, err := os.MkdirTemp("", fmt.Sprintf("automation-logs-%s-", command))
 	if err != nil {
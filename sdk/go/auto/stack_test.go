@@ -16,12 +16,20 @@ package auto
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optimport"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optstate"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -135,3 +143,309 @@ func TestUpdatePlans(t *testing.T) {
 	assert.Equal(t, "destroy", dRes.Summary.Kind)
 	assert.Equal(t, "succeeded", dRes.Summary.Result)
 }
+
+// fakePulumiCLI puts a fake `pulumi` executable on the PATH, which appends its arguments to the returned file, one
+// line per invocation, and then runs the given shell script with them.
+func fakePulumiCLI(t *testing.T, script string) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake CLI is a shell script")
+	}
+
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args")
+	cli := fmt.Sprintf("#!/bin/sh\necho \"$@\" >> %q\n%s", argsFile, script)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pulumi"), []byte(cli), 0o700)) //nolint:gosec
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return argsFile
+}
+
+//nolint:paralleltest // sets environment variables
+func TestImportResources(t *testing.T) {
+	importFile := filepath.Join(t.TempDir(), "import.json")
+	argsFile := fakePulumiCLI(t, fmt.Sprintf(`
+case "$1" in
+import)
+	while [ $# -gt 0 ]; do
+		case "$1" in
+		--file) cp "$2" %q ;;
+		--out) out="$2" ;;
+		esac
+		shift
+	done
+	echo 'const id = new random.RandomId("id");' > "$out/nodejs.ts"
+	echo 'id = random.RandomId("id")' > "$out/python.py"
+	echo 'module app' > "$out/go.mod"
+	;;
+stack)
+	echo '[{"kind":"import","result":"succeeded"}]'
+	;;
+esac
+`, importFile))
+
+	ctx := context.Background()
+	s := Stack{workspace: &LocalWorkspace{workDir: t.TempDir()}, stackName: "dev"}
+
+	res, err := s.ImportResources(ctx, []ImportResource{{
+		Type:       "random:index/randomId:RandomId",
+		Name:       "id",
+		ID:         "abc",
+		Parent:     "parent",
+		Properties: []string{"byteLength"},
+	}},
+		optimport.NameTable(map[string]string{"parent": "urn:pulumi:dev::proj::my:component:Parent::parent"}),
+		optimport.Protect(false),
+		optimport.Languages("nodejs", "python"),
+		optimport.Color("never"),
+	)
+	require.NoError(t, err)
+	// Only the files that the code in each language is written to are read.
+	assert.Equal(t, map[string]string{
+		"nodejs": "const id = new random.RandomId(\"id\");\n",
+		"python": "id = random.RandomId(\"id\")\n",
+	}, res.GeneratedCode)
+	assert.Equal(t, "import", res.Summary.Kind)
+	assert.Equal(t, "succeeded", res.Summary.Result)
+
+	// The resources and the name table are passed to the CLI in an import file.
+	contents, err := os.ReadFile(importFile)
+	require.NoError(t, err)
+	var file struct {
+		NameTable map[string]string `json:"nameTable"`
+		Resources []ImportResource  `json:"resources"`
+	}
+	require.NoError(t, json.Unmarshal(contents, &file))
+	assert.Equal(t, "urn:pulumi:dev::proj::my:component:Parent::parent", file.NameTable["parent"])
+	require.Len(t, file.Resources, 1)
+	assert.Equal(t, "parent", file.Resources[0].Parent)
+	assert.Equal(t, []string{"byteLength"}, file.Resources[0].Properties)
+
+	args, err := os.ReadFile(argsFile)
+	require.NoError(t, err)
+	importArgs := strings.Split(string(args), "\n")[0]
+	assert.Contains(t, importArgs, "import --yes --skip-preview")
+	assert.Contains(t, importArgs, "--protect=false")
+	assert.Contains(t, importArgs, "--language=nodejs --language=python")
+	assert.Contains(t, importArgs, "--color=never")
+	assert.Contains(t, importArgs, "--stack dev")
+}
+
+//nolint:paralleltest // sets environment variables
+func TestPreviewPlanAndUpWithPlan(t *testing.T) {
+	dir := t.TempDir()
+	savedPlan, usedPlan := filepath.Join(dir, "saved.json"), filepath.Join(dir, "used.json")
+	plan := &apitype.DeploymentPlanV1{
+		ResourcePlans: map[resource.URN]apitype.ResourcePlanV1{
+			"urn:pulumi:dev::proj::random:index/randomId:RandomId::id": {Steps: []apitype.OpType{apitype.OpCreate}},
+		},
+	}
+	b, err := json.Marshal(plan)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(savedPlan, b, 0o600))
+
+	// The fake CLI saves the plan given above when previewing, and keeps a copy of the plan that it is given when
+	// updating.
+	argsFile := fakePulumiCLI(t, fmt.Sprintf(`
+cmd="$1"
+sub="$2"
+while [ $# -gt 0 ]; do
+	case "$1" in
+	--event-log) log="$2" ;;
+	--save-plan=*) cp %q "${1#--save-plan=}" ;;
+	--plan=*) cp "${1#--plan=}" %q ;;
+	esac
+	shift
+done
+case "$cmd $sub" in
+preview*) echo '{"sequence":0,"timestamp":0,"summaryEvent":{"resourceChanges":{"create":1}}}' > "$log" ;;
+"stack output") echo '{}' ;;
+"stack history") echo '[]' ;;
+esac
+`, savedPlan, usedPlan))
+
+	ctx := context.Background()
+	s := Stack{workspace: &LocalWorkspace{workDir: t.TempDir()}, stackName: "dev"}
+
+	preview, err := s.PreviewPlan(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, plan, preview.Plan)
+	assert.Equal(t, 1, preview.ChangeSummary[apitype.OpCreate])
+
+	_, err = s.Up(ctx, optup.WithPlan(preview.Plan))
+	require.NoError(t, err)
+	used, err := os.ReadFile(usedPlan)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(b), string(used))
+
+	// The plans are passed to the CLI in temporary files, which are removed once the commands are done.
+	args, err := os.ReadFile(argsFile)
+	require.NoError(t, err)
+	for _, flag := range []string{"--save-plan=", "--plan="} {
+		var path string
+		for _, arg := range strings.Fields(string(args)) {
+			if strings.HasPrefix(arg, flag) {
+				path = strings.TrimPrefix(arg, flag)
+			}
+		}
+		require.NotEmpty(t, path, "missing %s", flag)
+		_, err := os.Stat(path)
+		assert.True(t, os.IsNotExist(err), "%s was not removed", path)
+	}
+}
+
+//nolint:paralleltest // sets environment variables
+func TestStateOperations(t *testing.T) {
+	// The fake CLI fails with the messages that each command prints when resources can't be found or deleted.
+	argsFile := fakePulumiCLI(t, `
+sub="$2"
+for arg in "$@"; do
+	case "$sub $arg" in
+	"delete "*::protected)
+		echo "error: $arg can't be safely deleted because it is protected." \
+			"Re-run this command with --force to force deletion" >&2
+		exit 255
+		;;
+	"delete "*::vpc)
+		echo "error: $arg can't be safely deleted because the following resources depend on it:" >&2
+		echo " * \"subnet\"        (urn:pulumi:dev::app::aws:ec2/subnet:Subnet::subnet)" >&2
+		echo "" >&2
+		echo "Delete those resources first or pass --target-dependents." >&2
+		exit 255
+		;;
+	"delete "*::missing)
+		echo "error: No such resource \"$arg\" exists in the current state" >&2
+		exit 255
+		;;
+	"unprotect "*::missing-*)
+		echo "error: no resources match the given URNs and filters" >&2
+		exit 255
+		;;
+	"rename "*::missing)
+		echo "error: The input URN does not correspond to an existing resource" >&2
+		exit 255
+		;;
+	"move "*::missing)
+		echo "error: no resources match [$arg]" >&2
+		exit 255
+		;;
+	esac
+done
+`)
+
+	ctx := context.Background()
+	s := Stack{workspace: &LocalWorkspace{workDir: t.TempDir()}, stackName: "dev"}
+	urn := func(name string) string {
+		return "urn:pulumi:dev::app::aws:ec2/vpc:Vpc::" + name
+	}
+
+	require.NoError(t, s.StateDelete(ctx, []string{urn("a"), urn("b")},
+		optstate.Types("aws:ec2/vpc:Vpc"), optstate.Where("tags.env=staging"),
+		optstate.Force(), optstate.TargetDependents()))
+	require.NoError(t, s.StateUnprotect(ctx, nil, optstate.All()))
+	require.NoError(t, s.StateRename(ctx, urn("*"), "{name}-old", optstate.Where("tags.env!=prod")))
+	require.NoError(t, s.StateMove(ctx, "org/app/prod", []string{urn("a")}))
+
+	err := s.StateDelete(ctx, []string{urn("protected")})
+	assert.True(t, IsResourceProtectedError(err))
+	assert.False(t, IsResourceHasDependentsError(err))
+	assert.ErrorContains(t, err, "failed to delete resources")
+
+	err = s.StateDelete(ctx, []string{urn("vpc")})
+	assert.True(t, IsResourceHasDependentsError(err))
+	assert.False(t, IsResourceProtectedError(err))
+
+	// Each command reports a resource that can't be found in its own words.
+	err = s.StateDelete(ctx, []string{urn("missing")})
+	assert.True(t, IsResourceNotFoundError(err))
+	assert.False(t, IsResourceProtectedError(err))
+
+	err = s.StateUnprotect(ctx, []string{urn("missing-*")})
+	assert.True(t, IsResourceNotFoundError(err))
+	assert.ErrorContains(t, err, "failed to unprotect resources")
+
+	err = s.StateRename(ctx, urn("missing"), "renamed")
+	assert.True(t, IsResourceNotFoundError(err))
+	assert.ErrorContains(t, err, "failed to rename resources")
+
+	err = s.StateMove(ctx, "org/app/prod", []string{urn("missing")})
+	assert.True(t, IsResourceNotFoundError(err))
+	assert.ErrorContains(t, err, "failed to move resources")
+
+	args, err := os.ReadFile(argsFile)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"state delete --yes --type=aws:ec2/vpc:Vpc --where=tags.env=staging --force --target-dependents " +
+			urn("a") + " " + urn("b") + " --stack dev --non-interactive",
+		"state unprotect --yes --all --stack dev --non-interactive",
+		"state rename --yes --where=tags.env!=prod " + urn("*") + " {name}-old --stack dev --non-interactive",
+		"state move --yes --dest org/app/prod " + urn("a") + " --stack dev --non-interactive",
+		"state delete --yes " + urn("protected") + " --stack dev --non-interactive",
+		"state delete --yes " + urn("vpc") + " --stack dev --non-interactive",
+		"state delete --yes " + urn("missing") + " --stack dev --non-interactive",
+		"state unprotect --yes " + urn("missing-*") + " --stack dev --non-interactive",
+		"state rename --yes " + urn("missing") + " renamed --stack dev --non-interactive",
+		"state move --yes --dest org/app/prod " + urn("missing") + " --stack dev --non-interactive",
+	}, strings.Split(strings.TrimSpace(string(args)), "\n"))
+}
+
+//nolint:paralleltest // sets environment variables
+func TestQueryState(t *testing.T) {
+	argsFile := fakePulumiCLI(t, `
+for arg in "$@"; do
+	if [ -n "$flagsDone" ]; then
+		expr="$arg"
+	fi
+	if [ "$arg" = "--" ]; then
+		flagsDone=1
+	fi
+done
+case "$expr" in
+"type == \"aws:s3/bucket:Bucket\"")
+	cat <<'JSON'
+[
+  {
+    "urn": "urn:pulumi:dev::app::aws:s3/bucket:Bucket::logs",
+    "custom": true,
+    "id": "logs-1234",
+    "type": "aws:s3/bucket:Bucket",
+    "outputs": {"bucket": "logs-1234", "tags": {"env": "prod"}, "secret": "[secret]"},
+    "protect": true
+  }
+]
+JSON
+	;;
+*)
+	echo "error: invalid query: 1:6: expected a value" >&2
+	exit 255
+	;;
+esac
+`)
+
+	ctx := context.Background()
+	s := Stack{workspace: &LocalWorkspace{workDir: t.TempDir()}, stackName: "dev"}
+
+	resources, err := s.QueryState(ctx, `type == "aws:s3/bucket:Bucket"`)
+	require.NoError(t, err)
+	require.Len(t, resources, 1)
+	assert.Equal(t, resource.URN("urn:pulumi:dev::app::aws:s3/bucket:Bucket::logs"), resources[0].URN)
+	assert.Equal(t, resource.ID("logs-1234"), resources[0].ID)
+	assert.Equal(t, "aws:s3/bucket:Bucket", string(resources[0].Type))
+	assert.True(t, resources[0].Custom)
+	assert.True(t, resources[0].Protect)
+	assert.Equal(t, map[string]interface{}{"env": "prod"}, resources[0].Outputs["tags"])
+	assert.Equal(t, "[secret]", resources[0].Outputs["secret"])
+
+	// The expression is passed after the flags, so one that starts with a '-' is not taken for a flag.
+	_, err = s.QueryState(ctx, "-type ==")
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "failed to query state")
+	var ae autoError
+	require.ErrorAs(t, err, &ae)
+	assert.Contains(t, ae.stderr, "invalid query")
+
+	args, err := os.ReadFile(argsFile)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`state query --json --stack dev --non-interactive -- type == "aws:s3/bucket:Bucket"`,
+		"state query --json --stack dev --non-interactive -- -type ==",
+	}, strings.Split(strings.TrimSpace(string(args)), "\n"))
+}
@@ -0,0 +1,96 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auto
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optstate"
+)
+
+// StateDelete deletes the resources with the given URNs, which may contain globs, from the stack's state.
+// Protected resources and resources that other resources depend on are not deleted unless optstate.Force and
+// optstate.TargetDependents are given; use IsResourceProtectedError and IsResourceHasDependentsError to detect
+// those failures.
+func (s *Stack) StateDelete(ctx context.Context, urns []string, opts ...optstate.Option) error {
+	stateOpts := applyStateOptions(opts)
+	args := append([]string{"state", "delete", "--yes"}, stateSelectorArgs(stateOpts)...)
+	if stateOpts.Force {
+		args = append(args, "--force")
+	}
+	if stateOpts.TargetDependents {
+		args = append(args, "--target-dependents")
+	}
+	return s.runStateCmd(ctx, "delete resources", append(args, urns...))
+}
+
+// StateUnprotect unprotects the resources with the given URNs, which may contain globs, in the stack's state.
+func (s *Stack) StateUnprotect(ctx context.Context, urns []string, opts ...optstate.Option) error {
+	stateOpts := applyStateOptions(opts)
+	args := append([]string{"state", "unprotect", "--yes"}, stateSelectorArgs(stateOpts)...)
+	if stateOpts.All {
+		args = append(args, "--all")
+	}
+	return s.runStateCmd(ctx, "unprotect resources", append(args, urns...))
+}
+
+// StateRename renames the resource with the given URN in the stack's state, and updates the references to it from
+// other resources. If the URN contains globs or the resources are selected with optstate.Types or optstate.Where,
+// every selected resource is renamed, and the new name must contain "{name}", which is replaced by the current name
+// of each resource.
+func (s *Stack) StateRename(ctx context.Context, urn, newName string, opts ...optstate.Option) error {
+	stateOpts := applyStateOptions(opts)
+	args := append([]string{"state", "rename", "--yes"}, stateSelectorArgs(stateOpts)...)
+	return s.runStateCmd(ctx, "rename resources", append(args, urn, newName))
+}
+
+// StateMove moves the resources with the given URNs, which may contain globs, from this stack's state to the state
+// of the destination stack, along with their children, parents and providers.
+func (s *Stack) StateMove(ctx context.Context, destStack string, urns []string) error {
+	args := []string{"state", "move", "--yes", "--dest", destStack}
+	return s.runStateCmd(ctx, "move resources", append(args, urns...))
+}
+
+func (s *Stack) runStateCmd(ctx context.Context, action string, args []string) error {
+	stdout, stderr, errCode, err := s.runPulumiCmdSync(
+		ctx,
+		nil, /* additionalOutput */
+		nil, /* additionalErrorOutput */
+		args...)
+	if err != nil {
+		return newAutoError(fmt.Errorf("failed to %s: %w", action, err), stdout, stderr, errCode)
+	}
+	return nil
+}
+
+func applyStateOptions(opts []optstate.Option) *optstate.Options {
+	stateOpts := &optstate.Options{}
+	for _, o := range opts {
+		o.ApplyOption(stateOpts)
+	}
+	return stateOpts
+}
+
+func stateSelectorArgs(opts *optstate.Options) []string {
+	var args []string
+	for _, t := range opts.Types {
+		args = append(args, fmt.Sprintf("--type=%s", t))
+	}
+	for _, w := range opts.Where {
+		args = append(args, fmt.Sprintf("--where=%s", w))
+	}
+	return args
+}
@@ -0,0 +1,298 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optwatch"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
+)
+
+// defaultWatchDebounce is how long Watch waits after a change before running an update, by default.
+const defaultWatchDebounce = 500 * time.Millisecond
+
+// WatchResult is the result of an update run by Stack.Watch.
+type WatchResult struct {
+	// Iteration numbers the updates of the watch, starting at 1.
+	Iteration int
+	// Changed lists the watched files whose changes caused the update. It is empty for the first update, and for
+	// updates caused by the watch's trigger.
+	Changed []string
+	// Result is the result of the update.
+	Result UpResult
+	// Err is the error of the update, if it failed.
+	Err error
+}
+
+// Watch updates the stack, then updates it again each time one of the watched paths changes or the watch's trigger
+// fires, until ctx is cancelled. This works for inline programs as well as programs on disk: each update runs the
+// program anew. A failed update does not stop the watch.
+//
+// The result of each update is sent on the returned channel, which must be received from for the watch to continue.
+// Changes made during an update run a single update once it completes. The channel is closed once the watch stops.
+// https://www.pulumi.com/docs/reference/cli/pulumi_watch/
+func (s *Stack) Watch(ctx context.Context, opts ...optwatch.Option) (<-chan WatchResult, error) {
+	watchOpts := &optwatch.Options{Debounce: defaultWatchDebounce}
+	for _, o := range opts {
+		o.ApplyOption(watchOpts)
+	}
+	upOpts := &optup.Options{}
+	for _, o := range watchOpts.UpOptions {
+		o.ApplyOption(upOpts)
+	}
+	if len(upOpts.EventStreams) > 0 {
+		return nil, errors.New("event streams must be given to the watch rather than to its updates")
+	}
+
+	paths := watchOpts.Paths
+	var ignore func(path string) bool
+	if len(paths) == 0 && watchOpts.Trigger == nil {
+		if s.Workspace().Program() != nil {
+			return nil, errors.New("an inline program must be watched with paths or a trigger")
+		}
+		// The workspace rewrites its own settings files, e.g. when an update saves the stack's config, so changes to
+		// them must not run another update.
+		workDir := s.Workspace().WorkDir()
+		paths = []string{workDir}
+		ignore = func(path string) bool {
+			return filepath.Dir(path) == filepath.Clean(workDir) && isWorkspaceSettingsFile(filepath.Base(path))
+		}
+	}
+
+	var changes <-chan []string
+	var stop func()
+	if len(paths) > 0 {
+		w, err := watchPaths(s.Workspace().WorkDir(), paths, watchOpts.Debounce, ignore)
+		if err != nil {
+			return nil, fmt.Errorf("failed to watch paths: %w", err)
+		}
+		changes, stop = w.changes, w.close
+	}
+
+	results := make(chan WatchResult)
+	go func() {
+		defer close(results)
+		if stop != nil {
+			defer stop()
+		}
+		defer func() {
+			for _, stream := range watchOpts.EventStreams {
+				close(stream)
+			}
+		}()
+
+		trigger := watchOpts.Trigger
+		var changed []string
+		for iteration := 1; ; iteration++ {
+			res, err := s.watchUpdate(ctx, watchOpts)
+			select {
+			case results <- WatchResult{Iteration: iteration, Changed: changed, Result: res, Err: err}:
+			case <-ctx.Done():
+				return
+			}
+
+			for waiting := true; waiting; {
+				select {
+				case <-ctx.Done():
+					return
+				case changed = <-changes:
+					waiting = false
+				case _, ok := <-trigger:
+					if !ok {
+						// A closed trigger never fires again.
+						trigger = nil
+						continue
+					}
+					changed, waiting = nil, false
+				}
+			}
+		}
+	}()
+	return results, nil
+}
+
+// watchUpdate runs a single update of a watch, sending its events to the watch's event streams.
+func (s *Stack) watchUpdate(ctx context.Context, watchOpts *optwatch.Options) (UpResult, error) {
+	opts := watchOpts.UpOptions
+	if len(watchOpts.EventStreams) > 0 {
+		// The event streams of an update are closed when it completes, so forward its events to the watch's.
+		updateEvents, forwarded := make(chan events.EngineEvent), make(chan struct{})
+		go func() {
+			defer close(forwarded)
+			for e := range updateEvents {
+				for _, stream := range watchOpts.EventStreams {
+					stream <- e
+				}
+			}
+		}()
+		defer func() { <-forwarded }()
+		opts = append(append([]optup.Option{}, opts...), optup.EventStreams(updateEvents))
+	}
+	return s.Up(ctx, opts...)
+}
+
+// pathWatcher reports changes to a set of files and directories, once no further changes have been made to them for
+// a while.
+type pathWatcher struct {
+	watcher *fsnotify.Watcher
+	ignore  func(path string) bool
+	changes chan []string
+	done    chan struct{}
+}
+
+// watchPaths watches the given paths, which are relative to root unless they are absolute. Changes to the paths for
+// which ignore, if it is set, returns true are not reported.
+func watchPaths(
+	root string, paths []string, debounce time.Duration, ignore func(path string) bool,
+) (*pathWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &pathWatcher{
+		watcher: watcher,
+		ignore:  ignore,
+		changes: make(chan []string),
+		done:    make(chan struct{}),
+	}
+
+	for _, p := range paths {
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(root, p)
+		}
+		if err := w.add(p); err != nil {
+			contract.IgnoreClose(watcher)
+			return nil, err
+		}
+	}
+
+	go w.run(debounce)
+	return w, nil
+}
+
+// add watches the given path and, if it is a directory, each of the directories beneath it.
+func (w *pathWatcher) add(path string) error {
+	return filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			if p == path {
+				return w.watcher.Add(p)
+			}
+			return nil
+		}
+		if p != path && ignoreWatchDir(d.Name()) {
+			return filepath.SkipDir
+		}
+		return w.watcher.Add(p)
+	})
+}
+
+// ignoreWatchDir returns true for the directories that are not watched beneath a watched directory: hidden
+// directories, such as .git, and directories of dependencies and build artifacts that running a program may change.
+func ignoreWatchDir(name string) bool {
+	return strings.HasPrefix(name, ".") || name == "node_modules" || name == "__pycache__" || name == "venv"
+}
+
+// isWorkspaceSettingsFile returns true for the names of the project and stack settings files that a LocalWorkspace
+// keeps in its directory, such as Pulumi.yaml and Pulumi.dev.yaml.
+func isWorkspaceSettingsFile(name string) bool {
+	if !strings.HasPrefix(name, "Pulumi.") {
+		return false
+	}
+	for _, ext := range settingsExtensions {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *pathWatcher) run(debounce time.Duration) {
+	pending := map[string]bool{}
+	var timer *time.Timer
+	var quiet <-chan time.Time
+	// Once the changes are quiet, they are sent as batch on ready.
+	var batch []string
+	var ready chan []string
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case e, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if e.Op == fsnotify.Chmod || w.ignore != nil && w.ignore(e.Name) {
+				continue
+			}
+			if e.Op&fsnotify.Create != 0 {
+				if info, err := os.Stat(e.Name); err == nil && info.IsDir() && !ignoreWatchDir(info.Name()) {
+					// Errors are ignored, as the directory may already have been removed.
+					contract.IgnoreError(w.add(e.Name))
+				}
+			}
+			pending[e.Name] = true
+			if timer == nil {
+				timer = time.NewTimer(debounce)
+			} else {
+				if !timer.Stop() {
+					// Drain the timer if it fired but its value has not been received.
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(debounce)
+			}
+			quiet, batch, ready = timer.C, nil, nil
+		case <-w.watcher.Errors:
+			// Errors, such as the kernel's event queue overflowing, are not fatal to the watch.
+		case <-quiet:
+			quiet, batch, ready = nil, sortedChanges(pending), w.changes
+		case ready <- batch:
+			pending, batch, ready = map[string]bool{}, nil, nil
+		}
+	}
+}
+
+func sortedChanges(pending map[string]bool) []string {
+	changes := make([]string, 0, len(pending))
+	for p := range pending {
+		changes = append(changes, p)
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+func (w *pathWatcher) close() {
+	close(w.done)
+	contract.IgnoreClose(w.watcher)
+}
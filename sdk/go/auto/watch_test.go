@@ -0,0 +1,101 @@
+// Copyright 2016-2023, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auto
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receiveChanges(t *testing.T, w *pathWatcher) []string {
+	select {
+	case changes := <-w.changes:
+		return changes
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for changes")
+		return nil
+	}
+}
+
+func TestWatchPaths(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "src"), 0o700))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "node_modules"), 0o700))
+
+	w, err := watchPaths(root, []string{"."}, 50*time.Millisecond, nil)
+	require.NoError(t, err)
+	defer w.close()
+
+	// Several changes made at once are reported together.
+	main, lib := filepath.Join(root, "main.go"), filepath.Join(root, "src", "lib.go")
+	require.NoError(t, os.WriteFile(main, []byte("package main"), 0o600))
+	require.NoError(t, os.WriteFile(lib, []byte("package src"), 0o600))
+	assert.Equal(t, []string{main, lib}, receiveChanges(t, w))
+
+	// Changes in ignored directories are not reported, while those in new directories are.
+	require.NoError(t, os.WriteFile(filepath.Join(root, "node_modules", "dep.js"), nil, 0o600))
+	newDir := filepath.Join(root, "pkg")
+	require.NoError(t, os.Mkdir(newDir, 0o700))
+	assert.Equal(t, []string{newDir}, receiveChanges(t, w))
+
+	file := filepath.Join(newDir, "file.go")
+	require.NoError(t, os.WriteFile(file, nil, 0o600))
+	assert.Equal(t, []string{file}, receiveChanges(t, w))
+}
+
+func TestWatchPathsIgnore(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	ignore := func(path string) bool {
+		return filepath.Dir(path) == root && isWorkspaceSettingsFile(filepath.Base(path))
+	}
+	w, err := watchPaths(root, []string{"."}, 50*time.Millisecond, ignore)
+	require.NoError(t, err)
+	defer w.close()
+
+	// The workspace's settings files are ignored, but files with similar names elsewhere are not.
+	require.NoError(t, os.WriteFile(filepath.Join(root, "Pulumi.yaml"), nil, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "Pulumi.dev.yaml"), nil, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "Pulumi.prod.json"), nil, 0o600))
+	main := filepath.Join(root, "main.go")
+	require.NoError(t, os.WriteFile(main, []byte("package main"), 0o600))
+	assert.Equal(t, []string{main}, receiveChanges(t, w))
+
+	src := filepath.Join(root, "src")
+	require.NoError(t, os.Mkdir(src, 0o700))
+	assert.Equal(t, []string{src}, receiveChanges(t, w))
+	nested := filepath.Join(src, "Pulumi.dev.yaml")
+	require.NoError(t, os.WriteFile(nested, nil, 0o600))
+	assert.Equal(t, []string{nested}, receiveChanges(t, w))
+}
+
+func TestIsWorkspaceSettingsFile(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{"Pulumi.yaml", "Pulumi.yml", "Pulumi.json", "Pulumi.dev.yaml", "Pulumi.org.dev.yml"} {
+		assert.True(t, isWorkspaceSettingsFile(name), name)
+	}
+	for _, name := range []string{"Pulumi.go", "main.go", "pulumi.yaml", "Pulumi", "config.yaml"} {
+		assert.False(t, isWorkspaceSettingsFile(name), name)
+	}
+}
@@ -19,3 +19,20 @@ type CLIVersionResponse struct {
 	LatestVersion        string `json:"latestVersion"`
 	OldestWithoutWarning string `json:"oldestWithoutWarning"`
 }
+
+// StepApprovalRequest is sent by the CLI to an approval callback to ask whether a delete or replacement that requires
+// approval may proceed.
+type StepApprovalRequest struct {
+	// URN is the URN of the resource that would be deleted or replaced.
+	URN string `json:"urn"`
+	// Type is the type of the resource.
+	Type string `json:"type"`
+	// Op is the operation that requires approval, either OpDelete or OpReplace.
+	Op OpType `json:"op"`
+}
+
+// StepApprovalResponse is the response from an approval callback.
+type StepApprovalResponse struct {
+	// Approved is true if the step may proceed.
+	Approved bool `json:"approved"`
+}
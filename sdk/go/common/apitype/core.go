@@ -333,6 +333,16 @@ type ResourceV3 struct {
 	Created *time.Time `json:"created,omitempty" yaml:"created,omitempty"`
 	// Modified tracks when the resource state was last altered. Checkpoints prior to early 2023 do not include this.
 	Modified *time.Time `json:"modified,omitempty" yaml:"modified,omitempty"`
+	// Hooks is the list of lifecycle hooks registered for this resource by the program.
+	Hooks []ResourceHookV1 `json:"hooks,omitempty" yaml:"hooks,omitempty"`
+}
+
+// ResourceHookV1 describes a lifecycle hook registered for a resource.
+type ResourceHookV1 struct {
+	// Name is the name of the hook.
+	Name string `json:"name" yaml:"name"`
+	// Kind is the point in the resource's lifecycle at which the hook runs, e.g. "before-delete".
+	Kind string `json:"kind" yaml:"kind"`
 }
 
 // ManifestV1 captures meta-information about this checkpoint file, such as versions of binaries, etc.
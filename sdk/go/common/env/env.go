@@ -69,6 +69,14 @@ fail without a --force parameter.`)
 var DebugGRPC = env.String("DEBUG_GRPC", `Enables debug tracing of Pulumi gRPC internals.
 The variable should be set to the log file to which gRPC debug traces will be sent.`)
 
+var RecordDeployment = env.String("RECORD_DEPLOYMENT", `Records each deployment so that it can be replayed offline.
+The variable should be set to the file to which the recording will be written. The recording includes the stack's
+state and all provider, language host, and resource monitor RPCs, including secret values in plaintext.`)
+
+var StepTimeout = env.Int("STEP_TIMEOUT", `The default deadline, in seconds, for each create, update and delete of a
+deployment. An operation that does not finish in time fails and is left pending in the stack's state. A resource's
+custom timeouts take precedence.`)
+
 // Environment variables that affect the self-managed backend.
 var (
 	SelfManagedStateNoLegacyWarning = env.Bool("SELF_MANAGED_STATE_NO_LEGACY_WARNING",
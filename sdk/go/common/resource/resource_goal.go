@@ -43,6 +43,8 @@ type Goal struct {
 	// if set, the providers Delete method will not be called for this resource
 	// if specified resource is being deleted as well.
 	DeletedWith URN
+	// lifecycle hooks that the engine runs around the steps for this resource.
+	Hooks []Hook
 }
 
 // NewGoal allocates a new resource goal state.
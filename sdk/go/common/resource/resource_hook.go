@@ -0,0 +1,35 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resource
+
+// HookKind identifies the point in a resource's lifecycle at which a hook runs.
+type HookKind string
+
+const (
+	HookBeforeCreate HookKind = "before-create" // the hook runs before the resource is created.
+	HookAfterCreate  HookKind = "after-create"  // the hook runs after the resource has been created.
+	HookBeforeUpdate HookKind = "before-update" // the hook runs before the resource is updated.
+	HookAfterUpdate  HookKind = "after-update"  // the hook runs after the resource has been updated.
+	HookBeforeDelete HookKind = "before-delete" // the hook runs before the resource is deleted.
+	HookAfterDelete  HookKind = "after-delete"  // the hook runs after the resource has been deleted.
+)
+
+// Hook is a lifecycle hook that a program registered for a resource. The engine calls back into the program to run
+// the hook around the steps it executes for the resource.
+type Hook struct {
+	Name   string   // the name of the hook, passed back to the program when the hook is invoked.
+	Kind   HookKind // the point in the resource's lifecycle at which the hook runs.
+	Target string   // the address of the program's ResourceHooks server; not persisted, as it changes between runs.
+}
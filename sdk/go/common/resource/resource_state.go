@@ -50,6 +50,7 @@ type State struct {
 	DeletedWith             URN                   // If set, the providers Delete method will not be called for this resource if specified resource is being deleted as well.
 	Created                 *time.Time            // If set, the time when the state was initially added to the state file. (i.e. Create, Import)
 	Modified                *time.Time            // If set, the time when the state was last modified in the state file.
+	Hooks                   []Hook                // the lifecycle hooks registered for this resource by the program.
 }
 
 func (s *State) GetAliasURNs() []URN {
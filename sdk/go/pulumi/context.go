@@ -61,6 +61,9 @@ type Context struct {
 	rpcsLock            sync.Mutex // a lock protecting the RPC count and event.
 	rpcError            error      // the first error (if any) encountered during an RPC.
 
+	hooks     *resourceHookServer // the server for resource lifecycle hooks, started on first use.
+	hooksLock sync.Mutex          // a lock protecting the resource hooks server.
+
 	join workGroup // the waitgroup for non-RPC async work associated with this context
 
 	Log Log // the logging interface for the Pulumi log stream.
@@ -171,17 +174,23 @@ func (ctx *Context) Context() context.Context {
 
 // Close implements io.Closer and relinquishes any outstanding resources held by the context.
 func (ctx *Context) Close() error {
+	var result error
+	if ctx.hooks != nil {
+		if err := ctx.hooks.close(); err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
 	if ctx.engineConn != nil {
 		if err := ctx.engineConn.Close(); err != nil {
-			return err
+			result = multierror.Append(result, err)
 		}
 	}
 	if ctx.monitorConn != nil {
 		if err := ctx.monitorConn.Close(); err != nil {
-			return err
+			result = multierror.Append(result, err)
 		}
 	}
-	return nil
+	return result
 }
 
 // wait waits for all asynchronous work associated with this context to drain. RPCs may not be queued once wait
@@ -938,6 +947,7 @@ func (ctx *Context) registerResource(
 				ReplaceOnChanges:        inputs.replaceOnChanges,
 				RetainOnDelete:          inputs.retainOnDelete,
 				DeletedWith:             inputs.deletedWith,
+				Hooks:                   inputs.hooks,
 			})
 			if err != nil {
 				logging.V(9).Infof("RegisterResource(%s, %s): error: %v", t, name, err)
@@ -1336,6 +1346,7 @@ type resourceInputs struct {
 	replaceOnChanges        []string
 	retainOnDelete          bool
 	deletedWith             string
+	hooks                   []*pulumirpc.RegisterResourceRequest_ResourceHook
 }
 
 func (ctx *Context) resolveAliasParent(alias Alias, spec *pulumirpc.Alias_Spec) error {
@@ -1581,6 +1592,11 @@ func (ctx *Context) prepareResourceInputs(res Resource, props Input, t string, o
 		deletedWithURN = urn
 	}
 
+	hooks, err := ctx.registerResourceHooks(opts.Hooks)
+	if err != nil {
+		return nil, fmt.Errorf("registering resource hooks: %w", err)
+	}
+
 	return &resourceInputs{
 		parent:                  string(resOpts.parentURN),
 		deps:                    deps,
@@ -1601,6 +1617,7 @@ func (ctx *Context) prepareResourceInputs(res Resource, props Input, t string, o
 		replaceOnChanges:        resOpts.replaceOnChanges,
 		retainOnDelete:          opts.RetainOnDelete,
 		deletedWith:             string(deletedWithURN),
+		hooks:                   hooks,
 	}, nil
 }
 
@@ -262,6 +262,18 @@ func (m *mockMonitor) RegisterResourceOutputs(ctx context.Context, in *pulumirpc
 	return &empty.Empty{}, nil
 }
 
+func (m *mockMonitor) RegisterResourceHook(ctx context.Context, in *pulumirpc.RegisterResourceHookRequest,
+	opts ...grpc.CallOption,
+) (*empty.Empty, error) {
+	return &empty.Empty{}, nil
+}
+
+func (m *mockMonitor) SignalAndWaitForShutdown(ctx context.Context, in *empty.Empty,
+	opts ...grpc.CallOption,
+) (*empty.Empty, error) {
+	return &empty.Empty{}, nil
+}
+
 type mockEngine struct {
 	logger       *log.Logger
 	rootResource string
@@ -374,6 +374,10 @@ type ResourceOptions struct {
 	// DeletedWith holds a container resource that, if deleted,
 	// also deletes this resource.
 	DeletedWith Resource
+
+	// Hooks is a list of lifecycle hooks that the engine runs
+	// around the steps it performs on this resource.
+	Hooks []*ResourceHook
 }
 
 // NewResourceOptions builds a preview of the effect of the provided options.
@@ -408,6 +412,7 @@ type resourceOptions struct {
 	PluginDownloadURL       string
 	RetainOnDelete          bool
 	DeletedWith             Resource
+	Hooks                   []*ResourceHook
 }
 
 func resourceOptionsSnapshot(ro *resourceOptions) *ResourceOptions {
@@ -468,6 +473,7 @@ func resourceOptionsSnapshot(ro *resourceOptions) *ResourceOptions {
 		PluginDownloadURL:       ro.PluginDownloadURL,
 		RetainOnDelete:          ro.RetainOnDelete,
 		DeletedWith:             ro.DeletedWith,
+		Hooks:                   ro.Hooks,
 	}
 }
 
@@ -881,3 +887,11 @@ func DeletedWith(r Resource) ResourceOption {
 		ro.DeletedWith = r
 	})
 }
+
+// Hooks registers lifecycle hooks that the engine runs around the steps it performs on this resource,
+// for example before it is deleted or after it is created. A hook that returns an error fails the step.
+func Hooks(hooks ...*ResourceHook) ResourceOption {
+	return resourceOption(func(ro *resourceOptions) {
+		ro.Hooks = append(ro.Hooks, hooks...)
+	})
+}
@@ -0,0 +1,264 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pulumi
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/plugin"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/rpcutil"
+	pulumirpc "github.com/pulumi/pulumi/sdk/v3/proto/go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ResourceHookKind identifies the point in a resource's lifecycle at which a hook runs.
+type ResourceHookKind int
+
+const (
+	// BeforeCreate hooks run before the resource is created.
+	BeforeCreate = ResourceHookKind(pulumirpc.ResourceHookKind_BEFORE_CREATE)
+	// AfterCreate hooks run after the resource has been created.
+	AfterCreate = ResourceHookKind(pulumirpc.ResourceHookKind_AFTER_CREATE)
+	// BeforeUpdate hooks run before the resource is updated.
+	BeforeUpdate = ResourceHookKind(pulumirpc.ResourceHookKind_BEFORE_UPDATE)
+	// AfterUpdate hooks run after the resource has been updated.
+	AfterUpdate = ResourceHookKind(pulumirpc.ResourceHookKind_AFTER_UPDATE)
+	// BeforeDelete hooks run before the resource is deleted.
+	BeforeDelete = ResourceHookKind(pulumirpc.ResourceHookKind_BEFORE_DELETE)
+	// AfterDelete hooks run after the resource has been deleted.
+	AfterDelete = ResourceHookKind(pulumirpc.ResourceHookKind_AFTER_DELETE)
+)
+
+func (k ResourceHookKind) String() string {
+	return pulumirpc.ResourceHookKind(k).String()
+}
+
+// ResourceHookArgs describes the resource and step that a lifecycle hook is running for.
+type ResourceHookArgs struct {
+	// Kind is the point in the resource's lifecycle at which the hook is running.
+	Kind ResourceHookKind
+	// URN is the URN of the resource.
+	URN URN
+	// ID is the provider-assigned ID of the resource, if it has one.
+	ID ID
+	// Type is the type of the resource.
+	Type string
+	// OldInputs and OldOutputs are the resource's state before the step, if any.
+	OldInputs, OldOutputs resource.PropertyMap
+	// NewInputs and NewOutputs are the resource's state after the step, if any. NewOutputs is only populated for
+	// hooks that run after the step.
+	NewInputs, NewOutputs resource.PropertyMap
+}
+
+// ResourceHookFunc is the function that implements a lifecycle hook. Returning an error fails the step that the
+// hook is running for.
+type ResourceHookFunc func(ctx context.Context, args *ResourceHookArgs) error
+
+// ResourceHook is a named lifecycle hook that the engine runs around a step on a resource, for example to drain a
+// node before it is deleted or to smoke test an endpoint after it is created. Hooks are attached to resources using
+// the Hooks resource option.
+//
+// The engine records the hooks attached to a resource in the stack's state, so delete hooks also run when the resource
+// is removed from the program. The program must still make such a hook available to the engine, either by attaching
+// it to another resource or by registering it with Context.RegisterResourceHooks.
+type ResourceHook struct {
+	// Name uniquely identifies the hook within the program.
+	Name string
+	// Kind is the point in the resource's lifecycle at which the hook runs.
+	Kind ResourceHookKind
+	// Func is the function that implements the hook.
+	Func ResourceHookFunc
+}
+
+// resourceHookServer serves the ResourceHooks interface so that the engine can call back into the program to run
+// the hooks registered for its resources.
+type resourceHookServer struct {
+	pulumirpc.UnimplementedResourceHooksServer
+
+	m      sync.Mutex
+	hooks  map[string]*ResourceHook
+	addr   string
+	cancel chan bool
+	done   <-chan error
+}
+
+func newResourceHookServer() (*resourceHookServer, error) {
+	server := &resourceHookServer{
+		hooks:  make(map[string]*ResourceHook),
+		cancel: make(chan bool),
+	}
+	handle, err := rpcutil.ServeWithOptions(rpcutil.ServeOptions{
+		Cancel: server.cancel,
+		Init: func(srv *grpc.Server) error {
+			pulumirpc.RegisterResourceHooksServer(srv, server)
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	server.addr = fmt.Sprintf("127.0.0.1:%v", handle.Port)
+	server.done = handle.Done
+	return server, nil
+}
+
+// register records the given hooks and returns their RPC representation.
+func (s *resourceHookServer) register(
+	hooks []*ResourceHook,
+) ([]*pulumirpc.RegisterResourceRequest_ResourceHook, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	rpcHooks := make([]*pulumirpc.RegisterResourceRequest_ResourceHook, len(hooks))
+	for i, hook := range hooks {
+		if hook.Name == "" {
+			return nil, fmt.Errorf("resource hooks must have a name")
+		}
+		if hook.Func == nil {
+			return nil, fmt.Errorf("resource hook '%v' must have a function", hook.Name)
+		}
+		if pulumirpc.ResourceHookKind(hook.Kind) == pulumirpc.ResourceHookKind_RESOURCE_HOOK_KIND_UNSPECIFIED {
+			return nil, fmt.Errorf("resource hook '%v' must have a kind", hook.Name)
+		}
+		if existing, has := s.hooks[hook.Name]; has && existing != hook {
+			return nil, fmt.Errorf("a different resource hook named '%v' has already been registered", hook.Name)
+		}
+		s.hooks[hook.Name] = hook
+
+		rpcHooks[i] = &pulumirpc.RegisterResourceRequest_ResourceHook{
+			Name:   hook.Name,
+			Kind:   pulumirpc.ResourceHookKind(hook.Kind),
+			Target: s.addr,
+		}
+	}
+	return rpcHooks, nil
+}
+
+func (s *resourceHookServer) InvokeResourceHook(ctx context.Context,
+	req *pulumirpc.ResourceHookRequest,
+) (*pulumirpc.ResourceHookResponse, error) {
+	s.m.Lock()
+	hook, has := s.hooks[req.GetName()]
+	s.m.Unlock()
+	if !has {
+		return nil, fmt.Errorf("unknown resource hook '%v'", req.GetName())
+	}
+
+	unmarshal := func(props *structpb.Struct) (resource.PropertyMap, error) {
+		if props == nil {
+			return nil, nil
+		}
+		return plugin.UnmarshalProperties(props, plugin.MarshalOptions{
+			KeepUnknowns:  true,
+			KeepSecrets:   true,
+			KeepResources: true,
+		})
+	}
+
+	args := &ResourceHookArgs{
+		Kind: ResourceHookKind(req.GetKind()),
+		URN:  URN(req.GetUrn()),
+		ID:   ID(req.GetId()),
+		Type: req.GetType(),
+	}
+	var err error
+	if args.OldInputs, err = unmarshal(req.GetOldInputs()); err != nil {
+		return nil, err
+	}
+	if args.OldOutputs, err = unmarshal(req.GetOldOutputs()); err != nil {
+		return nil, err
+	}
+	if args.NewInputs, err = unmarshal(req.GetNewInputs()); err != nil {
+		return nil, err
+	}
+	if args.NewOutputs, err = unmarshal(req.GetNewOutputs()); err != nil {
+		return nil, err
+	}
+
+	if err := hook.Func(ctx, args); err != nil {
+		return &pulumirpc.ResourceHookResponse{Error: err.Error()}, nil
+	}
+	return &pulumirpc.ResourceHookResponse{}, nil
+}
+
+// close stops the server and waits for it to exit.
+func (s *resourceHookServer) close() error {
+	close(s.cancel)
+	return <-s.done
+}
+
+// registerResourceHooks starts the context's ResourceHooks server if necessary and registers the given hooks with it.
+func (ctx *Context) registerResourceHooks(
+	hooks []*ResourceHook,
+) ([]*pulumirpc.RegisterResourceRequest_ResourceHook, error) {
+	if len(hooks) == 0 {
+		return nil, nil
+	}
+
+	ctx.hooksLock.Lock()
+	defer ctx.hooksLock.Unlock()
+	if ctx.hooks == nil {
+		server, err := newResourceHookServer()
+		if err != nil {
+			return nil, fmt.Errorf("starting resource hooks server: %w", err)
+		}
+		ctx.hooks = server
+	}
+	return ctx.hooks.register(hooks)
+}
+
+// RegisterResourceHooks makes the given hooks available to the engine without attaching them to a resource. This allows
+// the engine to run the delete hooks of resources that have been removed from the program.
+func (ctx *Context) RegisterResourceHooks(hooks ...*ResourceHook) error {
+	rpcHooks, err := ctx.registerResourceHooks(hooks)
+	if err != nil {
+		return fmt.Errorf("registering resource hooks: %w", err)
+	}
+	for _, hook := range rpcHooks {
+		_, err := ctx.monitor.RegisterResourceHook(ctx.ctx, &pulumirpc.RegisterResourceHookRequest{
+			Name:   hook.Name,
+			Target: hook.Target,
+		})
+		if err != nil {
+			return fmt.Errorf("registering resource hook '%v': %w", hook.Name, err)
+		}
+	}
+	return nil
+}
+
+// waitForShutdown keeps the context's ResourceHooks server running until the engine has finished the deployment, so
+// that the engine can run the hooks for steps that it performs after the program has finished, such as deletes.
+func (ctx *Context) waitForShutdown() error {
+	ctx.hooksLock.Lock()
+	hasHooks := ctx.hooks != nil
+	ctx.hooksLock.Unlock()
+	if !hasHooks {
+		return nil
+	}
+
+	_, err := ctx.monitor.SignalAndWaitForShutdown(ctx.ctx, &empty.Empty{})
+	if status.Code(err) == codes.Unimplemented {
+		// Older engines do not run hooks after the program has finished, so there is nothing to wait for.
+		return nil
+	}
+	return err
+}
@@ -131,6 +131,14 @@ func RunWithContext(ctx *Context, body RunFunc) error {
 		return err
 	}
 
+	// If the program registered any resource hooks, keep serving them until the engine has finished any steps that
+	// may run them, such as deletes of resources that are no longer in the program.
+	if result == nil {
+		if err = ctx.waitForShutdown(); err != nil {
+			return err
+		}
+	}
+
 	// Propagate the error from the body, if any.
 	return result
 }
@@ -30,6 +30,11 @@ type ResourceMonitorClient interface {
 	ReadResource(ctx context.Context, in *ReadResourceRequest, opts ...grpc.CallOption) (*ReadResourceResponse, error)
 	RegisterResource(ctx context.Context, in *RegisterResourceRequest, opts ...grpc.CallOption) (*RegisterResourceResponse, error)
 	RegisterResourceOutputs(ctx context.Context, in *RegisterResourceOutputsRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	RegisterResourceHook(ctx context.Context, in *RegisterResourceHookRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// SignalAndWaitForShutdown tells the engine that the program has finished registering resources and blocks until
+	// the engine has finished the deployment, so that the program can keep serving resource hooks for the steps the
+	// engine runs after the program is done, such as deletes.
+	SignalAndWaitForShutdown(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error)
 }
 
 type resourceMonitorClient struct {
@@ -126,6 +131,24 @@ func (c *resourceMonitorClient) RegisterResourceOutputs(ctx context.Context, in
 	return out, nil
 }
 
+func (c *resourceMonitorClient) RegisterResourceHook(ctx context.Context, in *RegisterResourceHookRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/pulumirpc.ResourceMonitor/RegisterResourceHook", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *resourceMonitorClient) SignalAndWaitForShutdown(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/pulumirpc.ResourceMonitor/SignalAndWaitForShutdown", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ResourceMonitorServer is the server API for ResourceMonitor service.
 // All implementations must embed UnimplementedResourceMonitorServer
 // for forward compatibility
@@ -137,6 +160,11 @@ type ResourceMonitorServer interface {
 	ReadResource(context.Context, *ReadResourceRequest) (*ReadResourceResponse, error)
 	RegisterResource(context.Context, *RegisterResourceRequest) (*RegisterResourceResponse, error)
 	RegisterResourceOutputs(context.Context, *RegisterResourceOutputsRequest) (*emptypb.Empty, error)
+	RegisterResourceHook(context.Context, *RegisterResourceHookRequest) (*emptypb.Empty, error)
+	// SignalAndWaitForShutdown tells the engine that the program has finished registering resources and blocks until
+	// the engine has finished the deployment, so that the program can keep serving resource hooks for the steps the
+	// engine runs after the program is done, such as deletes.
+	SignalAndWaitForShutdown(context.Context, *emptypb.Empty) (*emptypb.Empty, error)
 	mustEmbedUnimplementedResourceMonitorServer()
 }
 
@@ -165,6 +193,12 @@ func (UnimplementedResourceMonitorServer) RegisterResource(context.Context, *Reg
 func (UnimplementedResourceMonitorServer) RegisterResourceOutputs(context.Context, *RegisterResourceOutputsRequest) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RegisterResourceOutputs not implemented")
 }
+func (UnimplementedResourceMonitorServer) RegisterResourceHook(context.Context, *RegisterResourceHookRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterResourceHook not implemented")
+}
+func (UnimplementedResourceMonitorServer) SignalAndWaitForShutdown(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SignalAndWaitForShutdown not implemented")
+}
 func (UnimplementedResourceMonitorServer) mustEmbedUnimplementedResourceMonitorServer() {}
 
 // UnsafeResourceMonitorServer may be embedded to opt out of forward compatibility for this service.
@@ -307,6 +341,42 @@ func _ResourceMonitor_RegisterResourceOutputs_Handler(srv interface{}, ctx conte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ResourceMonitor_RegisterResourceHook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterResourceHookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ResourceMonitorServer).RegisterResourceHook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pulumirpc.ResourceMonitor/RegisterResourceHook",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ResourceMonitorServer).RegisterResourceHook(ctx, req.(*RegisterResourceHookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ResourceMonitor_SignalAndWaitForShutdown_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ResourceMonitorServer).SignalAndWaitForShutdown(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pulumirpc.ResourceMonitor/SignalAndWaitForShutdown",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ResourceMonitorServer).SignalAndWaitForShutdown(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ResourceMonitor_ServiceDesc is the grpc.ServiceDesc for ResourceMonitor service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -338,6 +408,14 @@ var ResourceMonitor_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RegisterResourceOutputs",
 			Handler:    _ResourceMonitor_RegisterResourceOutputs_Handler,
 		},
+		{
+			MethodName: "RegisterResourceHook",
+			Handler:    _ResourceMonitor_RegisterResourceHook_Handler,
+		},
+		{
+			MethodName: "SignalAndWaitForShutdown",
+			Handler:    _ResourceMonitor_SignalAndWaitForShutdown_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -348,3 +426,89 @@ var ResourceMonitor_ServiceDesc = grpc.ServiceDesc{
 	},
 	Metadata: "pulumi/resource.proto",
 }
+
+// ResourceHooksClient is the client API for ResourceHooks service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ResourceHooksClient interface {
+	InvokeResourceHook(ctx context.Context, in *ResourceHookRequest, opts ...grpc.CallOption) (*ResourceHookResponse, error)
+}
+
+type resourceHooksClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewResourceHooksClient(cc grpc.ClientConnInterface) ResourceHooksClient {
+	return &resourceHooksClient{cc}
+}
+
+func (c *resourceHooksClient) InvokeResourceHook(ctx context.Context, in *ResourceHookRequest, opts ...grpc.CallOption) (*ResourceHookResponse, error) {
+	out := new(ResourceHookResponse)
+	err := c.cc.Invoke(ctx, "/pulumirpc.ResourceHooks/InvokeResourceHook", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ResourceHooksServer is the server API for ResourceHooks service.
+// All implementations must embed UnimplementedResourceHooksServer
+// for forward compatibility
+type ResourceHooksServer interface {
+	InvokeResourceHook(context.Context, *ResourceHookRequest) (*ResourceHookResponse, error)
+	mustEmbedUnimplementedResourceHooksServer()
+}
+
+// UnimplementedResourceHooksServer must be embedded to have forward compatible implementations.
+type UnimplementedResourceHooksServer struct {
+}
+
+func (UnimplementedResourceHooksServer) InvokeResourceHook(context.Context, *ResourceHookRequest) (*ResourceHookResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InvokeResourceHook not implemented")
+}
+func (UnimplementedResourceHooksServer) mustEmbedUnimplementedResourceHooksServer() {}
+
+// UnsafeResourceHooksServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ResourceHooksServer will
+// result in compilation errors.
+type UnsafeResourceHooksServer interface {
+	mustEmbedUnimplementedResourceHooksServer()
+}
+
+func RegisterResourceHooksServer(s grpc.ServiceRegistrar, srv ResourceHooksServer) {
+	s.RegisterService(&ResourceHooks_ServiceDesc, srv)
+}
+
+func _ResourceHooks_InvokeResourceHook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResourceHookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ResourceHooksServer).InvokeResourceHook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pulumirpc.ResourceHooks/InvokeResourceHook",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ResourceHooksServer).InvokeResourceHook(ctx, req.(*ResourceHookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ResourceHooks_ServiceDesc is the grpc.ServiceDesc for ResourceHooks service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ResourceHooks_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "pulumirpc.ResourceHooks",
+	HandlerType: (*ResourceHooksServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "InvokeResourceHook",
+			Handler:    _ResourceHooks_InvokeResourceHook_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "pulumi/resource.proto",
+}